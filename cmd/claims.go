@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gsigler/etch/internal/claim"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/urfave/cli/v2"
+)
+
+func claimsCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "claims",
+		Usage: "List the tasks agents have claimed, or break a claim",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "break",
+				Usage: "remove the claim on a task, given as plan#task (e.g. auth#1.2); repeatable",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			if refs := c.StringSlice("break"); len(refs) > 0 {
+				for _, ref := range refs {
+					plan, task, ok := strings.Cut(ref, "#")
+					if !ok || plan == "" || task == "" {
+						return etcherr.Usage(fmt.Sprintf("invalid claim %q", ref)).
+							WithHint("name the task as plan#task, e.g. --break auth#1.2")
+					}
+					broken, found, err := claim.Break(rootDir, plan, task)
+					if err != nil {
+						return err
+					}
+					if !found {
+						return etcherr.Project(fmt.Sprintf("no claim on %s", ref)).
+							WithHint("run 'etch claims' to see the claimed tasks")
+					}
+					fmt.Printf("  ✓ broke claim on %s (%s)\n", ref, broken.Owner)
+				}
+				return nil
+			}
+
+			claims, listErr := claim.List(rootDir)
+			if len(claims) == 0 && listErr == nil {
+				fmt.Println("No claims.")
+				return nil
+			}
+			now := time.Now()
+			for _, cl := range claims {
+				fmt.Printf("  %s  %s  pid %d on %s  %s\n", cl.Ref(), cl.Owner, cl.PID, cl.Host, describeExpiry(cl, now))
+			}
+			if listErr != nil {
+				return etcherr.Project(fmt.Sprintf("some claims could not be read:\n%v", listErr)).
+					WithHint("remove them with 'etch claims --break plan#task'")
+			}
+			return nil
+		},
+	}
+}
+
+// describeExpiry says when a claim's lease runs out, or ran out.
+func describeExpiry(cl claim.Claim, now time.Time) string {
+	if cl.Expired(now) {
+		return "expired " + status.FormatDuration(now.Sub(cl.Expires)) + " ago"
+	}
+	return "expires in " + status.FormatDuration(cl.Expires.Sub(now))
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/claim"
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestClaims_ListAndBreak(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	t.Setenv("ETCH_ACTOR", "human:alice")
+	app := &cli.App{Commands: []*cli.Command{claimsCmd(), progressCmd()}}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "claims"})
+	})
+	if err != nil || !strings.Contains(output, "No claims.") {
+		t.Errorf("empty list: err %v, output:\n%s", err, output)
+	}
+
+	if _, err := claim.Acquire(dir, "test-plan", "1.1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "claims"})
+	})
+	if err != nil || !strings.Contains(output, "test-plan#1.1  human:alice  pid ") || !strings.Contains(output, "expires in 1h") {
+		t.Errorf("list: err %v, output:\n%s", err, output)
+	}
+
+	// Someone else holds the task, so it can't be started here.
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "claimed by human:alice") {
+		t.Errorf("expected progress start to be refused, got %v", err)
+	}
+
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "claims", "--break", "test-plan#2.1"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no claim on test-plan#2.1") {
+		t.Errorf("expected an error breaking an unclaimed task, got %v", err)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "claims", "--break", "test-plan#1.1"})
+	})
+	if err != nil || !strings.Contains(output, "broke claim on test-plan#1.1 (human:alice)") {
+		t.Errorf("break: err %v, output:\n%s", err, output)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"})
+	})
+	if err != nil || !strings.Contains(output, "Task 1.1 started") {
+		t.Errorf("progress start after break: err %v, output:\n%s", err, output)
+	}
+}
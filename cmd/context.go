@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gsigler/etch/internal/claim"
 	etchcontext "github.com/gsigler/etch/internal/context"
 	etcherr "github.com/gsigler/etch/internal/errors"
 	"github.com/gsigler/etch/internal/models"
@@ -41,16 +44,27 @@ func contextCmd() *cli.Command {
 	}
 }
 
+// rootEnvVar names the environment variable that pins the project root.
+const rootEnvVar = "ETCH_ROOT"
+
 // resolvedContext holds the results of argument resolution and context assembly.
 type resolvedContext struct {
 	RootDir string
+	Plan    *models.Plan
 	Task    *models.Task
 	Result  etchcontext.Result
+	Claim   *claim.Claim // set when the task was claimed for the caller
 }
 
+// maxClaimAttempts bounds how many auto-selected tasks resolveContextArgs
+// tries to claim when others are taking them at the same time.
+const maxClaimAttempts = 3
+
 // resolveContextArgs parses CLI arguments, resolves the plan and task, and
-// assembles the context. Shared between `etch context` and `etch run`.
-func resolveContextArgs(c *cli.Context) (*resolvedContext, error) {
+// assembles the context. Shared between `etch context` and `etch run`. With
+// a claimTTL, the task is claimed for that long before its context is
+// assembled; the caller releases the claim.
+func resolveContextArgs(c *cli.Context, claimTTL time.Duration) (*resolvedContext, error) {
 	rootDir, err := findProjectRoot()
 	if err != nil {
 		return nil, err
@@ -81,12 +95,44 @@ func resolveContextArgs(c *cli.Context) (*resolvedContext, error) {
 		return nil, err
 	}
 
+	var held *claim.Claim
+	if claimTTL > 0 {
+		if plan, task, held, err = claimTask(rootDir, plans, planSlug, taskID, plan, task, claimTTL); err != nil {
+			return nil, err
+		}
+	}
+
 	result, err := etchcontext.Assemble(rootDir, plan, task)
 	if err != nil {
+		if held != nil {
+			held.Release()
+		}
 		return nil, err
 	}
 
-	return &resolvedContext{RootDir: rootDir, Task: task, Result: result}, nil
+	return &resolvedContext{RootDir: rootDir, Plan: plan, Task: task, Result: result, Claim: held}, nil
+}
+
+// claimTask claims task for ttl. If someone else holds it, a task named with
+// --task is refused, while an auto-selected one is passed over for the next
+// runnable task.
+func claimTask(rootDir string, plans []*models.Plan, planSlug, taskID string, plan *models.Plan, task *models.Task, ttl time.Duration) (*models.Plan, *models.Task, *claim.Claim, error) {
+	for attempt := 1; ; attempt++ {
+		cl, err := claim.Acquire(rootDir, plan.Slug, task.FullID(), ttl)
+		if err == nil {
+			return plan, task, cl, nil
+		}
+		if !errors.Is(err, claim.ErrHeld) {
+			return nil, nil, nil, err
+		}
+		if taskID != "" || attempt == maxClaimAttempts {
+			return nil, nil, nil, cl.HeldError()
+		}
+		// Selection skips claimed tasks, so this picks another.
+		if plan, task, err = etchcontext.ResolveTask(plans, planSlug, "", rootDir); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 }
 
 // resolvedFeature holds the results of feature argument resolution and context assembly.
@@ -153,7 +199,7 @@ func runContext(c *cli.Context) error {
 		return runFeatureContext(c)
 	}
 
-	rc, err := resolveContextArgs(c)
+	rc, err := resolveContextArgs(c, 0)
 	if err != nil {
 		return err
 	}
@@ -210,8 +256,17 @@ func runFeatureContext(c *cli.Context) error {
 	return nil
 }
 
-// findProjectRoot walks up from cwd looking for .etch directory.
+// findProjectRoot locates the directory containing .etch, searching upward
+// from the working directory. ETCH_ROOT overrides the search so that agents
+// running in a task worktree report progress to the main project.
 func findProjectRoot() (string, error) {
+	if root := os.Getenv(rootEnvVar); root != "" {
+		if _, err := os.Stat(filepath.Join(root, ".etch")); err != nil {
+			return "", etcherr.Project(fmt.Sprintf("%s=%s is not an etch project", rootEnvVar, root)).
+				WithHint("unset " + rootEnvVar + " or point it at a directory containing .etch")
+		}
+		return root, nil
+	}
 	dir, err := os.Getwd()
 	if err != nil {
 		return "", etcherr.WrapIO("getting working directory", err)
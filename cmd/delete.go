@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/parser"
 	"github.com/urfave/cli/v2"
 )
 
@@ -70,6 +72,11 @@ func runDelete(slug string, skipConfirm bool) error {
 		}
 	}
 
+	title := slug
+	if plan, err := parser.ParseFile(planPath); err == nil {
+		title = plan.Title
+	}
+
 	// Remove plan file.
 	if err := os.Remove(planPath); err != nil {
 		return etcherr.WrapIO("removing plan file", err)
@@ -87,5 +94,8 @@ func runDelete(slug string, skipConfirm bool) error {
 
 	fmt.Printf("Deleted plan '%s' (%d progress files, %d context files removed).\n",
 		slug, len(progressFiles), len(contextFiles))
-	return nil
+
+	// Record the deletion last, so a failure here never leaves the plan's
+	// progress and context files behind.
+	return events.Append(rootDir, events.Event{Actor: events.Actor(), Type: events.PlanDeleted, Plan: slug, Before: title})
 }
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/forecast"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/urfave/cli/v2"
+)
+
+func forecastCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "forecast",
+		Usage:     "Estimate the sessions and time left in a plan",
+		ArgsUsage: "[plan-slug]",
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+
+			slug := c.Args().First()
+			var selected []*models.Plan
+			for _, p := range plans {
+				if slug == "" && p.Status != models.StatusCompleted || p.Slug == slug {
+					selected = append(selected, p)
+				}
+			}
+			if slug != "" && len(selected) == 0 {
+				return etcherr.Project(fmt.Sprintf("plan %q not found", slug)).
+					WithHint("run 'etch list' to see available plans")
+			}
+
+			// Calibrate from every plan, not just the ones shown.
+			now := time.Now()
+			cal := forecast.Calibrate(forecast.History(rootDir, plans), now)
+			for i, p := range selected {
+				allProgress, err := progress.ReadAll(rootDir, p.Slug)
+				if err != nil {
+					return etcherr.WrapIO(fmt.Sprintf("reading progress for %s", p.Slug), err)
+				}
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Print(formatForecast(forecast.Plan(p, allProgress, cal, now), cal, now))
+			}
+			if len(selected) == 0 {
+				fmt.Println("All plans are completed.")
+				return nil
+			}
+			fmt.Println()
+			fmt.Println(cal.Describe())
+			return nil
+		},
+	}
+}
+
+// formatForecast renders a plan's forecast: the totals, then each
+// unfinished task with critical-path tasks marked.
+func formatForecast(fc forecast.Forecast, cal forecast.Calibration, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n", fc.Title, fc.Slug)
+	if fc.Remaining == 0 {
+		b.WriteString("  Nothing left to do.\n")
+		return b.String()
+	}
+
+	unfinished := 0
+	for _, t := range fc.Tasks {
+		if t.Remaining > 0 {
+			unfinished++
+		}
+	}
+	fmt.Fprintf(&b, "  Remaining:     %.1f sessions across %d tasks\n", fc.Remaining, unfinished)
+	fmt.Fprintf(&b, "  Critical path: %s (%.1f sessions)\n", strings.Join(fc.CriticalPath, " → "), fc.CriticalSessions)
+	if fc.Work > 0 {
+		fmt.Fprintf(&b, "  Work:          ~%s at %s per session\n", roundDuration(fc.Work), roundDuration(cal.SessionDuration))
+	}
+	if !fc.ETA.IsZero() {
+		days := fc.ETA.Sub(now).Hours() / 24
+		fmt.Fprintf(&b, "  ETA:           %s (~%.1f days at %.1f sessions/day)\n", fc.ETA.Format("2006-01-02"), days, cal.SessionsPerDay)
+	} else {
+		b.WriteString("  ETA:           not enough history to estimate calendar time\n")
+	}
+
+	b.WriteString("\n")
+	for _, t := range fc.Tasks {
+		if t.Remaining == 0 {
+			continue
+		}
+		mark := " "
+		if t.Critical {
+			mark = "*"
+		}
+		complexity := string(t.Complexity)
+		if complexity == "" {
+			complexity = "-"
+		}
+		fmt.Fprintf(&b, "  %s %-6s %-12s %-7s %d done, %.1f to go  %s\n", mark, t.ID, t.Status, complexity, t.Sessions, t.Remaining, t.Title)
+	}
+	b.WriteString("  * on the critical path\n")
+	return b.String()
+}
+
+// roundDuration renders d to the minute, or to the hour past a day, e.g.
+// "3h15m" or "52h".
+func roundDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		d = d.Round(time.Minute)
+	} else {
+		d = d.Round(time.Hour)
+	}
+	s := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
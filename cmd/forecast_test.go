@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestForecast(t *testing.T) {
+	setupTestProject(t, nextPlan)
+	app := &cli.App{Commands: []*cli.Command{forecastCmd()}}
+
+	out := captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "forecast", "test-plan"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{
+		"Remaining:     6.0 sessions across 3 tasks",
+		"Critical path: 1.2 → 1.3 (5.0 sessions)",
+		"not enough history",
+		"* 1.2",
+		"Sessions per task: small 1.0 (default)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("forecast missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := app.Run([]string{"etch", "forecast", "nope"}); err == nil {
+		t.Error("expected an error for an unknown plan")
+	}
+}
+
+func TestRoundDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		45*time.Minute + 20*time.Second: "45m",
+		3*time.Hour + 14*time.Minute:    "3h14m",
+		2 * time.Hour:                   "2h",
+		52*time.Hour + 20*time.Minute:   "52h",
+	} {
+		if got := roundDuration(d); got != want {
+			t.Errorf("roundDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/graph"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/urfave/cli/v2"
+)
+
+func graphCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "graph",
+		Usage:     "Render a plan's task dependency graph",
+		ArgsUsage: "[plan-slug]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "ascii",
+				Usage: "output format: dot, mermaid, or ascii",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			format := c.String("format")
+			switch format {
+			case "dot", "mermaid", "ascii":
+			default:
+				return etcherr.Usage(fmt.Sprintf("unknown format: %s", format)).
+					WithHint("use --format dot, mermaid, or ascii")
+			}
+
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+			plan, err := selectPlan(plans, c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			out, err := renderGraph(rootDir, plan, format)
+			if err != nil {
+				return err
+			}
+			fmt.Print(out)
+			return nil
+		},
+	}
+}
+
+// selectPlan resolves slug to a plan, prompting when slug is empty and
+// more than one plan exists.
+func selectPlan(plans []*models.Plan, slug string) (*models.Plan, error) {
+	if len(plans) == 0 {
+		return nil, etcherr.Project("no plans found").
+			WithHint("run 'etch plan <description>' to create one")
+	}
+	if slug == "" {
+		if len(plans) == 1 {
+			return plans[0], nil
+		}
+		picked, err := pickPlan(plans)
+		if err != nil {
+			return nil, err
+		}
+		slug = picked
+	}
+	for _, p := range plans {
+		if p.Slug == slug {
+			return p, nil
+		}
+	}
+	return nil, etcherr.Project(fmt.Sprintf("plan not found: %s", slug)).
+		WithHint("run 'etch list' to see available plans")
+}
+
+// renderGraph reconciles the plan's status and renders its dependency graph.
+func renderGraph(rootDir string, plan *models.Plan, format string) (string, error) {
+	statuses, err := status.Run(rootDir, plan.Slug)
+	if err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "", etcherr.Project(fmt.Sprintf("plan not found: %s", plan.Slug)).
+			WithHint("run 'etch list' to see available plans")
+	}
+
+	g := graph.Build(plan, statuses[0])
+	switch format {
+	case "dot":
+		return g.DOT(), nil
+	case "mermaid":
+		return g.Mermaid(), nil
+	default:
+		return g.ASCII(), nil
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+)
+
+func TestRenderGraph_UsesReconciledStatus(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, dir)
+	content := strings.Replace(planWithDanglingDep, "Task 7", "Task 1", 1)
+	writePlan(t, dir, "g", content)
+	os.WriteFile(filepath.Join(dir, ".etch", "progress", "g--task-1.1--001.md"),
+		[]byte("# Session: Task 1.1\n**Plan:** g\n**Task:** 1.1\n**Session:** 001\n**Status:** completed\n"), 0o644)
+
+	plans, err := etchcontext.DiscoverPlans(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := selectPlan(plans, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := renderGraph(dir, plan, "mermaid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"t1_1 --> t1_2", "class t1_1 completed", "class t1_2 runnable"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestSelectPlan_Unknown(t *testing.T) {
+	dir := setupEtchProject(t)
+	writePlan(t, dir, "g", planWithDanglingDep)
+	plans, _ := etchcontext.DiscoverPlans(dir)
+
+	if _, err := selectPlan(plans, "nope"); err == nil {
+		t.Fatal("expected error for unknown plan")
+	}
+}
@@ -65,6 +65,10 @@ func runInit() error {
 		".etch/backups/",
 		".etch/context/",
 		".etch/config.toml",
+		".etch/worktrees/",
+		".etch/transcripts/",
+		".etch/locks/",
+		".etch/**/.lock",
 	)
 
 	if err := appendGitignore(ignoreLines); err != nil {
@@ -144,4 +148,52 @@ const defaultConfig = `# Etch configuration
 # Plan defaults
 [defaults]
 # complexity_guide = "small = single focused session, medium = may need iteration, large = multiple sessions likely"
+
+# Task sessions
+[run]
+# worktree = false        # run each task in its own git worktree and branch
+# worktree_base = "HEAD"  # where new task branches start
+# commit = false          # commit a session's changes when it ends
+# claim_ttl = "5m"        # how long a task's claim outlives a crashed run
+
+# Coding agent for plan, replan, run, and swarm
+[agent]
+# backend = "claude"  # or the name of a command defined under [agents]
+
+# [agents.aider]
+# command = "aider"
+# args = ["--yes-always", "--message-file", "{prompt_file}"]
+# prompt = "file"     # how the prompt is passed: stdin, arg, or file
+
+# What etch status writes and flags
+[status]
+# auto_sync = false    # let etch status write plan files, as etch sync does
+# stale_after = "30m"  # flag in-progress sessions with no heartbeat for this long
+
+# How etch run and etch next pick the next task
+[scheduler]
+# strategy = "weighted"  # or "order" for plan file order
+# [scheduler.weights]
+# priority = 8
+# critical_path = 4
+# fan_out = 2
+# complexity = 1
+
+# Shell commands run on task and plan events
+[hooks]
+# on_task_start = ""
+# on_task_done = "go test ./..."
+# on_task_blocked = ""
+# on_task_failed = ""
+# on_plan_complete = ""
+# veto = ["on_task_done"]  # hooks whose failure stops the change
+# timeout = "10m"
+
+# Webhooks are POSTed the same events as JSON once the change is saved
+# [[webhooks]]
+# url = "https://example.com/etch"
+# secret = ""    # signs the body with HMAC-SHA256 in X-Etch-Signature
+# events = []    # hook names to send; empty sends all
+# retries = 3
+# timeout = "10s"
 `
@@ -103,6 +103,7 @@ func TestRunInitGitignoreNoTrackProgress(t *testing.T) {
 		".etch/backups/",
 		".etch/context/",
 		".etch/config.toml",
+		".etch/**/.lock",
 	}
 	for _, entry := range expectedEntries {
 		if !strings.Contains(content, entry) {
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/urfave/cli/v2"
+)
+
+func logCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "log",
+		Usage:     "Show the history of changes to plans",
+		ArgsUsage: "[plan-slug]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "task",
+				Aliases: []string{"t"},
+				Usage:   "only show changes to this task (e.g. 1.2)",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "only show changes after a date (2006-01-02), time (RFC 3339), or age (90m, 2d, 1w)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			f := events.Filter{Plan: c.Args().First(), Task: c.String("task")}
+			if s := c.String("since"); s != "" {
+				if f.Since, err = parseSince(s, time.Now()); err != nil {
+					return err
+				}
+			}
+			if f.Plan != "" && f.Task != "" {
+				// Accept short IDs like "2" for plans that still exist.
+				plans, err := etchcontext.DiscoverPlans(rootDir)
+				if err != nil {
+					return err
+				}
+				for _, p := range plans {
+					if p.Slug == f.Plan {
+						if t := p.ResolveTaskID(f.Task); t != nil {
+							f.Task = t.FullID()
+						}
+					}
+				}
+			}
+
+			evs, err := events.Read(rootDir, f)
+			if err != nil {
+				return err
+			}
+			if len(evs) == 0 {
+				fmt.Println("No changes recorded.")
+				return nil
+			}
+			for _, e := range evs {
+				fmt.Println(formatEvent(e))
+			}
+			return nil
+		},
+	}
+}
+
+// parseSince reads a --since value relative to now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	return time.Time{}, etcherr.Usage(fmt.Sprintf("invalid --since value %q", s)).
+		WithHint("use a date like 2026-01-02, an RFC 3339 time, or an age like 90m, 2d, or 1w")
+}
+
+// formatEvent renders an event as one line of the log.
+func formatEvent(e events.Event) string {
+	where := e.Plan
+	if e.Task != "" {
+		where += "#" + e.Task
+	}
+	return fmt.Sprintf("%s  %-24s %-18s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Actor, where, describeEvent(e))
+}
+
+func describeEvent(e events.Event) string {
+	switch e.Type {
+	case events.TaskStatus:
+		s := fmt.Sprintf("status %s → %s", orNone(e.Before), orNone(e.After))
+		if e.Reason != "" {
+			s += ": " + e.Reason
+		}
+		return s
+	case events.PlanStatus:
+		return fmt.Sprintf("plan status %s → %s", orNone(e.Before), orNone(e.After))
+	case events.Criterion:
+		if e.After == "met" {
+			return fmt.Sprintf("checked %q", e.Subject)
+		}
+		return fmt.Sprintf("unchecked %q", e.Subject)
+	case events.CommentAdded:
+		return fmt.Sprintf("comment added: %q", firstLine(e.After))
+	case events.CommentDeleted:
+		return fmt.Sprintf("comment deleted: %q", firstLine(e.Before))
+	case events.Priority:
+		return fmt.Sprintf("priority %s → %s", orNone(e.Before), orNone(e.After))
+	case events.Replan:
+		s := fmt.Sprintf("replanned %s (%s → %s)", e.Subject, e.Before, e.After)
+		if e.Reason != "" {
+			s += ": " + e.Reason
+		}
+		return s
+	case events.PlanDeleted:
+		return fmt.Sprintf("deleted plan %q", e.Before)
+	}
+	return fmt.Sprintf("%s %s → %s", e.Type, orNone(e.Before), orNone(e.After))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// firstLine shortens multi-line text to its first line.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " …"
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/events"
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestLog_RecordsProgressCommands(t *testing.T) {
+	t.Setenv(events.ActorEnvVar, "human:tester")
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	app := &cli.App{Commands: []*cli.Command{progressCmd(), logCmd()}}
+
+	for _, args := range [][]string{
+		{"etch", "progress", "start", "-p", "test-plan", "-t", "1"},
+		{"etch", "progress", "criteria", "-p", "test-plan", "-t", "1", "--check", "Thing works"},
+	} {
+		if err := app.Run(args); err != nil {
+			t.Fatalf("%v: %v", args[1:3], err)
+		}
+	}
+
+	evs, err := events.Read(dir, events.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %+v", evs)
+	}
+	if e := evs[0]; e.Type != events.TaskStatus || e.Plan != "test-plan" || e.Task != "1.1" || e.Before != "pending" || e.After != "in_progress" || e.Actor != "human:tester" {
+		t.Errorf("status event = %+v", e)
+	}
+	if e := evs[1]; e.Type != events.Criterion || e.Subject != "Thing works" || e.After != "met" {
+		t.Errorf("criterion event = %+v", e)
+	}
+
+	out := captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "log", "test-plan", "-t", "1"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"human:tester", "test-plan#1.1", "status pending → in_progress", `checked "Thing works"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q:\n%s", want, out)
+		}
+	}
+
+	out = captureStdout(t, func() {
+		app.Run([]string{"etch", "log", "other-plan"})
+	})
+	if !strings.Contains(out, "No changes recorded.") {
+		t.Errorf("expected no changes for another plan, got:\n%s", out)
+	}
+}
+
+func TestLog_RecordsDelete(t *testing.T) {
+	t.Setenv(events.ActorEnvVar, "human:tester")
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+
+	captureStdout(t, func() {
+		if err := runDelete("test-plan", true); err != nil {
+			t.Fatal(err)
+		}
+	})
+	evs, _ := events.Read(dir, events.Filter{Plan: "test-plan"})
+	if len(evs) != 1 || evs[0].Type != events.PlanDeleted || evs[0].Before != "Test Plan" {
+		t.Errorf("events = %+v", evs)
+	}
+}
+
+func TestRecordReplan(t *testing.T) {
+	t.Setenv(events.ActorEnvVar, "human:tester")
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	path := filepath.Join(dir, ".etch", "plans", "test-plan.md")
+	before, _ := os.ReadFile(path)
+	e := events.Event{Actor: events.Actor(), Type: events.Replan, Subject: "plan", Reason: "scope changed"}
+
+	// Unchanged plans aren't logged.
+	if err := recordReplan(path, before, e); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte(string(before)+"\n### Task 2: More [pending]\n"), 0o644)
+	if err := recordReplan(path, before, e); err != nil {
+		t.Fatal(err)
+	}
+
+	evs, _ := events.Read(dir, events.Filter{})
+	if len(evs) != 1 || evs[0].Before != "1 tasks" || evs[0].After != "2 tasks" || evs[0].Plan != "test-plan" {
+		t.Fatalf("events = %+v", evs)
+	}
+	if got := describeEvent(evs[0]); got != "replanned plan (1 tasks → 2 tasks): scope changed" {
+		t.Errorf("describeEvent = %q", got)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"90m":                  now.Add(-90 * time.Minute),
+		"2d":                   now.Add(-48 * time.Hour),
+		"1w":                   now.Add(-7 * 24 * time.Hour),
+		"2026-03-01":           time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2026-03-01T08:30:00Z": time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC),
+	} {
+		got, err := parseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"yesterday", "-2d", "3x"} {
+		if _, err := parseSince(bad, now); err == nil {
+			t.Errorf("parseSince(%q): expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/gsigler/etch/internal/lsp"
+	"github.com/urfave/cli/v2"
+)
+
+func lspCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "lsp",
+		Usage: "Run a language server for plan files over stdio",
+		Action: func(c *cli.Context) error {
+			return lsp.NewServer(c.App.Version).Serve(c.Context, os.Stdin, os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/mcp"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/swarm"
+	"github.com/gsigler/etch/internal/transition"
+	"github.com/urfave/cli/v2"
+)
+
+func mcpCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "mcp",
+		Usage: "Serve etch's progress commands as Model Context Protocol tools over stdio",
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			srv := &mcp.Server{Name: "etch", Version: c.App.Version, Tools: mcpTools(rootDir)}
+			return srv.Serve(c.Context, os.Stdin, os.Stdout)
+		},
+	}
+}
+
+// mcpTaskArgs identifies a task. Plan may be omitted when the task ID is
+// unique across plans.
+type mcpTaskArgs struct {
+	Plan string `json:"plan"`
+	Task string `json:"task"`
+}
+
+var (
+	planProp = mcp.Property{Type: "string", Description: "plan slug; optional when the task ID is unique across plans"}
+	taskProp = mcp.Property{Type: "string", Description: "task ID, e.g. 1.2"}
+)
+
+// taskSchema returns the schema of a tool taking a task plus extra
+// required string arguments.
+func taskSchema(extra map[string]string) mcp.Schema {
+	s := mcp.Schema{
+		Type:       "object",
+		Properties: map[string]mcp.Property{"plan": planProp, "task": taskProp},
+		Required:   []string{"task"},
+	}
+	for name, desc := range extra {
+		s.Properties[name] = mcp.Property{Type: "string", Description: desc}
+		s.Required = append(s.Required, name)
+	}
+	return s
+}
+
+// taskTool builds a tool that resolves its task and passes the remaining
+// arguments to fn.
+func taskTool(rootDir, name, desc string, extra map[string]string, fn func(plan *models.Plan, task *models.Task, args map[string]string) (string, error)) mcp.Tool {
+	return mcp.Tool{
+		Name:        name,
+		Description: desc,
+		InputSchema: taskSchema(extra),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args map[string]string
+			if err := mcp.DecodeArgs(raw, &args); err != nil {
+				return "", err
+			}
+			if args["task"] == "" {
+				return "", etcherr.Usage("task is required")
+			}
+			for field := range extra {
+				if strings.TrimSpace(args[field]) == "" {
+					return "", etcherr.Usage(field + " is required")
+				}
+			}
+			plan, task, err := findTask(rootDir, args["plan"], args["task"])
+			if err != nil {
+				return "", err
+			}
+			return fn(plan, task, args)
+		},
+	}
+}
+
+// mcpTools returns the tools served by etch mcp for the project at rootDir.
+func mcpTools(rootDir string) []mcp.Tool {
+	planOnly := mcp.Schema{Type: "object", Properties: map[string]mcp.Property{"plan": {Type: "string", Description: "plan slug; all plans when omitted"}}}
+
+	return []mcp.Tool{
+		{
+			Name:        "get_task_context",
+			Description: "Get the implementation context for a task: plan overview, task details, prerequisites, previous sessions and the progress file to update. Picks the next runnable task when task is omitted.",
+			InputSchema: mcp.Schema{Type: "object", Properties: map[string]mcp.Property{"plan": planProp, "task": taskProp}},
+			Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args mcpTaskArgs
+				if err := mcp.DecodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				plan, task, err := findTask(rootDir, args.Plan, args.Task)
+				if err != nil {
+					return "", err
+				}
+				return etchcontext.Render(rootDir, plan, task)
+			},
+		},
+		{
+			Name:        "list_runnable_tasks",
+			Description: "List pending tasks whose dependencies are all completed, as JSON.",
+			InputSchema: planOnly,
+			Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args mcpTaskArgs
+				if err := mcp.DecodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				return runnableTasksJSON(rootDir, args.Plan)
+			},
+		},
+		{
+			Name:        "get_plan_status",
+			Description: "Get the status of every task and criterion in a plan, or in all plans, as JSON.",
+			InputSchema: planOnly,
+			Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				var args mcpTaskArgs
+				if err := mcp.DecodeArgs(raw, &args); err != nil {
+					return "", err
+				}
+				plans, err := status.Run(rootDir, args.Plan)
+				if err != nil {
+					return "", err
+				}
+				return status.FormatJSON(plans)
+			},
+		},
+		taskTool(rootDir, "start_task", "Mark a task in progress, creating its session progress file if needed.", nil,
+			func(plan *models.Plan, task *models.Task, _ map[string]string) (string, error) {
+				sessionNum, err := transition.Start(rootDir, plan, task)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Task %s started (session %03d)", task.FullID(), sessionNum), nil
+			}),
+		taskTool(rootDir, "update_progress", "Log a progress update for a task in its current session file.",
+			map[string]string{"message": "what was done"},
+			func(plan *models.Plan, task *models.Task, args map[string]string) (string, error) {
+				if err := logProgress(rootDir, plan, task, args["message"]); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Logged update for Task %s", task.FullID()), nil
+			}),
+		taskTool(rootDir, "check_criterion", "Check off an acceptance criterion. Matches the exact text, or else the first unchecked criterion containing it.",
+			map[string]string{"criterion": "criterion text, or a distinctive part of it"},
+			func(plan *models.Plan, task *models.Task, args map[string]string) (string, error) {
+				desc, ok := checkCriterion(rootDir, plan, task, args["criterion"])
+				if !ok {
+					return "", etcherr.Usage(fmt.Sprintf("no unchecked criterion of Task %s matches %q", task.FullID(), args["criterion"])).
+						WithHint("call get_plan_status to see the task's criteria")
+				}
+				return fmt.Sprintf("Checked: %s", desc), nil
+			}),
+		taskTool(rootDir, "complete_task", "Mark a task completed. Runs its verify commands first, if it has any, and fails if they do.", nil,
+			func(plan *models.Plan, task *models.Task, _ map[string]string) (string, error) {
+				var out bytes.Buffer
+				unchecked, err := transition.Complete(context.Background(), rootDir, plan, task, &out)
+				if err != nil {
+					if out.Len() > 0 {
+						return "", fmt.Errorf("%w\n%s", err, out.String())
+					}
+					return "", err
+				}
+				fmt.Fprintf(&out, "Task %s completed\n", task.FullID())
+				if len(unchecked) > 0 {
+					fmt.Fprintf(&out, "Warning: %d unchecked acceptance criteria:\n", len(unchecked))
+					for _, desc := range unchecked {
+						fmt.Fprintf(&out, "  - [ ] %s\n", desc)
+					}
+				}
+				return strings.TrimRight(out.String(), "\n"), nil
+			}),
+		taskTool(rootDir, "block_task", "Mark a task blocked and record why.",
+			map[string]string{"reason": "what is blocking the task"},
+			func(plan *models.Plan, task *models.Task, args map[string]string) (string, error) {
+				if err := transition.Stop(rootDir, plan, task, models.StatusBlocked, args["reason"]); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Task %s blocked: %s", task.FullID(), args["reason"]), nil
+			}),
+		taskTool(rootDir, "fail_task", "Mark a task failed and record why.",
+			map[string]string{"reason": "why the task failed"},
+			func(plan *models.Plan, task *models.Task, args map[string]string) (string, error) {
+				if err := transition.Stop(rootDir, plan, task, models.StatusFailed, args["reason"]); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Task %s failed: %s", task.FullID(), args["reason"]), nil
+			}),
+	}
+}
+
+// runnableTask is one entry of list_runnable_tasks.
+type runnableTask struct {
+	Plan  string `json:"plan"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func runnableTasksJSON(rootDir, planFilter string) (string, error) {
+	plans, err := status.Run(rootDir, planFilter)
+	if err != nil {
+		return "", err
+	}
+	status.SortPlanStatuses(plans)
+
+	tasks := []runnableTask{}
+	for _, ps := range plans {
+		titles := make(map[string]string)
+		for _, f := range ps.Features {
+			for _, t := range f.Tasks {
+				titles[t.ID] = t.Title
+			}
+		}
+		for _, id := range swarm.Frontier(ps) {
+			tasks = append(tasks, runnableTask{Plan: ps.Slug, ID: id, Title: titles[id]})
+		}
+	}
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
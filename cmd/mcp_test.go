@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/mcp"
+)
+
+const mcpPlan = `# Plan: Test Plan
+
+## Overview
+
+A test plan.
+
+### Task 1: Do the thing [pending]
+**Complexity:** small
+
+Implement the thing.
+
+**Acceptance Criteria:**
+- [ ] Thing works
+- [ ] Thing is tested
+
+### Task 2: Do the next thing [pending]
+**Complexity:** small
+**Depends on:** Task 1
+
+Implement the next thing.
+
+**Acceptance Criteria:**
+- [ ] Next thing works
+`
+
+// callTool calls one of etch's MCP tools through the server and returns
+// the text it produced and whether the call failed.
+func callTool(t *testing.T, rootDir, name string, args map[string]string) (string, bool) {
+	t.Helper()
+	req, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	var out bytes.Buffer
+	srv := &mcp.Server{Name: "etch", Version: "test", Tools: mcpTools(rootDir)}
+	if err := srv.Serve(context.Background(), bytes.NewReader(append(req, '\n')), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var resp struct {
+		Result struct {
+			Content []struct{ Text string }
+			IsError bool
+		}
+		Error *struct{ Message string }
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v\n%s", err, out.String())
+	}
+	if resp.Error != nil {
+		t.Fatalf("%s: protocol error: %s", name, resp.Error.Message)
+	}
+	return resp.Result.Content[0].Text, resp.Result.IsError
+}
+
+func readPlan(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, ".etch", "plans", "test-plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMCP_ToolsList(t *testing.T) {
+	dir := setupTestProject(t, mcpPlan)
+	names := make(map[string]bool)
+	for _, tool := range mcpTools(dir) {
+		names[tool.Name] = true
+		if tool.Description == "" || tool.InputSchema.Type != "object" {
+			t.Errorf("%s: missing description or schema", tool.Name)
+		}
+	}
+	for _, want := range []string{
+		"get_task_context", "list_runnable_tasks", "get_plan_status", "start_task",
+		"update_progress", "check_criterion", "complete_task", "block_task", "fail_task",
+	} {
+		if !names[want] {
+			t.Errorf("missing tool %s", want)
+		}
+	}
+}
+
+func TestMCP_TaskLifecycle(t *testing.T) {
+	dir := setupTestProject(t, mcpPlan)
+
+	text, isErr := callTool(t, dir, "get_task_context", map[string]string{"plan": "test-plan"})
+	if isErr || !strings.Contains(text, "Do the thing") {
+		t.Fatalf("get_task_context: %s", text)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".etch", "progress", "*.md")); len(matches) != 0 {
+		t.Errorf("get_task_context should not create session files, found %v", matches)
+	}
+
+	text, _ = callTool(t, dir, "list_runnable_tasks", nil)
+	var runnable []runnableTask
+	if err := json.Unmarshal([]byte(text), &runnable); err != nil {
+		t.Fatalf("list_runnable_tasks output: %v\n%s", err, text)
+	}
+	if len(runnable) != 1 || runnable[0].ID != "1.1" || runnable[0].Plan != "test-plan" {
+		t.Errorf("runnable = %+v, want only 1.1", runnable)
+	}
+
+	// Plan is optional when the task ID is unambiguous.
+	text, isErr = callTool(t, dir, "start_task", map[string]string{"task": "1.1"})
+	if isErr || text != "Task 1.1 started (session 001)" {
+		t.Fatalf("start_task: %s", text)
+	}
+
+	text, isErr = callTool(t, dir, "update_progress", map[string]string{"task": "1.1", "message": "Wrote the thing"})
+	if isErr || text != "Logged update for Task 1.1" {
+		t.Fatalf("update_progress: %s", text)
+	}
+	session, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--001.md"))
+	if !strings.Contains(string(session), "Wrote the thing") {
+		t.Error("update should be logged in the session file")
+	}
+
+	text, isErr = callTool(t, dir, "check_criterion", map[string]string{"task": "1.1", "criterion": "works"})
+	if isErr || text != "Checked: Thing works" {
+		t.Fatalf("check_criterion: %s", text)
+	}
+	if !strings.Contains(readPlan(t, dir), "- [x] Thing works") {
+		t.Error("criterion should be checked in the plan")
+	}
+
+	text, isErr = callTool(t, dir, "check_criterion", map[string]string{"task": "1.1", "criterion": "flies"})
+	if !isErr || !strings.Contains(text, "no unchecked criterion") {
+		t.Errorf("expected unmatched criterion to fail, got %q", text)
+	}
+
+	text, isErr = callTool(t, dir, "complete_task", map[string]string{"plan": "test-plan", "task": "1.1"})
+	if isErr || !strings.Contains(text, "Task 1.1 completed") || !strings.Contains(text, "- [ ] Thing is tested") {
+		t.Fatalf("complete_task: %s", text)
+	}
+	if !strings.Contains(readPlan(t, dir), "### Task 1: Do the thing [completed]") {
+		t.Error("task should be completed in the plan")
+	}
+
+	text, _ = callTool(t, dir, "list_runnable_tasks", map[string]string{"plan": "test-plan"})
+	if !strings.Contains(text, `"id": "1.2"`) || strings.Contains(text, `"id": "1.1"`) {
+		t.Errorf("expected only 1.2 runnable after completing 1.1, got %s", text)
+	}
+
+	text, _ = callTool(t, dir, "get_plan_status", map[string]string{"plan": "test-plan"})
+	var plans []struct {
+		CompletedTasks int `json:"completed_tasks"`
+	}
+	if err := json.Unmarshal([]byte(text), &plans); err != nil || len(plans) != 1 || plans[0].CompletedTasks != 1 {
+		t.Errorf("get_plan_status = %s", text)
+	}
+}
+
+func TestMCP_BlockAndFail(t *testing.T) {
+	dir := setupTestProject(t, mcpPlan)
+
+	text, isErr := callTool(t, dir, "block_task", map[string]string{"task": "1.1", "reason": "waiting on API keys"})
+	if !isErr || !strings.Contains(text, "etch progress start") {
+		t.Errorf("blocking without a session should fail with a hint, got %q", text)
+	}
+
+	callTool(t, dir, "start_task", map[string]string{"task": "1.1"})
+	text, isErr = callTool(t, dir, "block_task", map[string]string{"task": "1.1", "reason": "waiting on API keys"})
+	if isErr || text != "Task 1.1 blocked: waiting on API keys" {
+		t.Fatalf("block_task: %s", text)
+	}
+	session, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--001.md"))
+	if !strings.Contains(string(session), "- waiting on API keys") {
+		t.Error("reason should be recorded under Blockers")
+	}
+
+	text, isErr = callTool(t, dir, "fail_task", map[string]string{"task": "1.1", "reason": "tests never pass"})
+	if isErr || !strings.Contains(readPlan(t, dir), "Do the thing [failed]") {
+		t.Errorf("fail_task: %s", text)
+	}
+}
+
+func TestMCP_ArgumentErrors(t *testing.T) {
+	dir := setupTestProject(t, mcpPlan)
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]string
+		want string
+	}{
+		{"start_task", nil, "task is required"},
+		{"update_progress", map[string]string{"task": "1.1"}, "message is required"},
+		{"start_task", map[string]string{"plan": "nope", "task": "1.1"}, `no plan found with slug "nope"`},
+		{"start_task", map[string]string{"task": "9.9"}, `task "9.9" not found`},
+	} {
+		text, isErr := callTool(t, dir, tc.tool, tc.args)
+		if !isErr || !strings.Contains(text, tc.want) {
+			t.Errorf("%s %v: got %q (isError=%v), want error containing %q", tc.tool, tc.args, text, isErr, tc.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/urfave/cli/v2"
+)
+
+func migrateCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Upgrade progress files to the current format",
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			migrated, migrateErr := progress.MigrateAll(rootDir)
+			for _, path := range migrated {
+				rel, _ := filepath.Rel(rootDir, path)
+				fmt.Printf("  ✓ %s\n", rel)
+			}
+			if len(migrated) > 0 {
+				fmt.Printf("Upgraded %d progress %s to schema %d.\n", len(migrated), status.Plural(len(migrated), "file", "files"), progress.SchemaVersion)
+			} else if migrateErr == nil {
+				fmt.Printf("Progress files are up to date (schema %d).\n", progress.SchemaVersion)
+			}
+			if migrateErr != nil {
+				return etcherr.Project(fmt.Sprintf("some progress files could not be upgraded:\n%v", migrateErr)).
+					WithHint("fix the files listed above by hand, then run 'etch migrate' again")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestMigrate_UpgradesLegacyProgressFiles(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("in_progress"))
+	path := filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--001.md")
+	os.WriteFile(path, []byte("# Session: Task 1.1 – Do the thing\n**Plan:** test-plan\n**Task:** 1.1\n**Session:** 001\n**Status:** in_progress\n\n## Changes Made\n- foo.go\n"), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{migrateCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "migrate"})
+	})
+	if err != nil {
+		t.Fatalf("migrate error: %v", err)
+	}
+	if !strings.Contains(output, "test-plan--task-1.1--001.md") || !strings.Contains(output, "Upgraded 1 progress file to schema 3.") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "---\nschema: 3\n") || !strings.Contains(string(data), "status: in_progress\n") || !strings.Contains(string(data), "- foo.go\n") {
+		t.Errorf("file not upgraded:\n%s", data)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "migrate"})
+	})
+	if err != nil || !strings.Contains(output, "up to date") {
+		t.Errorf("second migrate: err %v, output %q", err, output)
+	}
+}
+
+func TestMigrate_ReportsFilesItCannotUpgrade(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	os.WriteFile(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--001.md"), []byte("**Task:** 1.1\n**Status:** finished\n"), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{migrateCmd()}}
+	var err error
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "migrate"})
+	})
+	if err == nil || !strings.Contains(err.Error(), `status "finished"`) {
+		t.Errorf("expected the bad status to be reported, got %v", err)
+	}
+}
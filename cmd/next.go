@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/schedule"
+	"github.com/urfave/cli/v2"
+)
+
+func nextCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "next",
+		Usage: "Show the tasks ready to run, best first",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "count",
+				Aliases: []string{"n"},
+				Value:   5,
+				Usage:   "number of tasks to show",
+			},
+			&cli.BoolFlag{
+				Name:  "explain",
+				Usage: "show how each task was scored",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			if c.Int("count") < 1 {
+				return etcherr.Usage("--count must be at least 1")
+			}
+
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+			ranked, err := etchcontext.Rank(rootDir, plans)
+			if err != nil {
+				return err
+			}
+			if len(ranked) == 0 {
+				fmt.Println("No tasks are ready to run.")
+				return nil
+			}
+			fmt.Print(formatNext(ranked, c.Int("count"), c.Bool("explain")))
+			return nil
+		},
+	}
+}
+
+// formatNext renders the first n ranked tasks, with each factor's score
+// if explain is set.
+func formatNext(ranked []schedule.Ranked, n int, explain bool) string {
+	var b strings.Builder
+	for i, r := range ranked {
+		if i == n {
+			fmt.Fprintf(&b, "… and %d more\n", len(ranked)-n)
+			break
+		}
+		fmt.Fprintf(&b, "%d. %-24s %s\n", i+1, r.Ref(), r.Task.Title)
+		if !explain {
+			continue
+		}
+		fmt.Fprintf(&b, "   score %.2f\n", r.Score)
+		for _, f := range r.Factors {
+			if f.Score != 0 {
+				fmt.Fprintf(&b, "     %+.2f  %s\n", f.Score, f.Detail)
+			} else {
+				fmt.Fprintf(&b, "            %s\n", f.Detail)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+const nextPlan = `# Plan: Test Plan
+
+## Feature 1: Core
+
+### Task 1.1: Quick fix [pending]
+**Complexity:** small
+
+Fix it.
+
+- [ ] Fixed
+
+### Task 1.2: Foundation [pending]
+**Complexity:** large
+
+Lay the groundwork.
+
+- [ ] Done
+
+### Task 1.3: Build on it [pending]
+**Depends on:** Task 1.2
+
+More work.
+
+- [ ] Done
+`
+
+func TestNext(t *testing.T) {
+	setupTestProject(t, nextPlan)
+	app := &cli.App{Commands: []*cli.Command{nextCmd()}}
+
+	out := captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "next"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "test-plan#1.2") || !strings.Contains(lines[1], "test-plan#1.1") {
+		t.Errorf("expected 1.2 (which 1.3 waits on) then 1.1:\n%s", out)
+	}
+
+	out = captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "next", "-n", "1", "--explain"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"score", "1 task depends on it", "large complexity", "… and 1 more"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("--explain output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -7,12 +7,11 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/gsigler/etch/internal/claude"
-	"github.com/gsigler/etch/internal/config"
 	etcherr "github.com/gsigler/etch/internal/errors"
 	"github.com/gsigler/etch/internal/generator"
 	"github.com/gsigler/etch/internal/parser"
 	"github.com/gsigler/etch/internal/serializer"
+	"github.com/gsigler/etch/internal/skill"
 	"github.com/urfave/cli/v2"
 )
 
@@ -48,11 +47,10 @@ func planCmd() *cli.Command {
 				return err
 			}
 
-			cfg, err := config.Load(rootDir)
+			a, err := loadAgent(rootDir)
 			if err != nil {
-				return etcherr.WrapConfig("loading config", err)
+				return err
 			}
-			_ = cfg // loaded for slug generation; no API key needed
 
 			slug := generator.Slugify(c.String("name"))
 			if generator.SlugExists(rootDir, slug) {
@@ -73,17 +71,18 @@ func planCmd() *cli.Command {
 				}
 			}
 
-			// Build the prompt for Claude Code to create the plan.
-			prompt := fmt.Sprintf(
-				"/etch-plan --slug %s %s",
-				slug,
-				description,
-			)
+			// Build the prompt for the agent to create the plan. Agents
+			// without Claude Code skills get the skill's instructions inline.
+			args := fmt.Sprintf("--slug %s %s", slug, description)
+			prompt := "/etch-plan " + args
+			if !a.Skills() {
+				prompt = skill.Prompt(skill.Content, args)
+			}
 
-			fmt.Printf("Launching Claude Code to generate plan for: %s\n", description)
+			fmt.Printf("Launching %s to generate plan for: %s\n", a.Name(), description)
 			fmt.Printf("Target: .etch/plans/%s.md\n\n", slug)
 
-			if err := claude.Run(prompt, rootDir); err != nil {
+			if err := a.Interactive(prompt, rootDir); err != nil {
 				return err
 			}
 
@@ -91,7 +90,7 @@ func planCmd() *cli.Command {
 			planPath := filepath.Join(rootDir, ".etch", "plans", slug+".md")
 			if _, err := os.Stat(planPath); os.IsNotExist(err) {
 				return etcherr.New(etcherr.CatIO, "plan file was not created").
-					WithHint(fmt.Sprintf("expected file at .etch/plans/%s.md — the Claude Code session may have ended before completing the plan", slug))
+					WithHint(fmt.Sprintf("expected file at .etch/plans/%s.md — the %s session may have ended before completing the plan", slug, a.Name()))
 			}
 
 			// Apply priority surgically if flag was set.
@@ -120,6 +119,8 @@ func planCmd() *cli.Command {
 			}
 			fmt.Printf("  Tasks:    %d\n", taskCount)
 
+			reportValidation(rootDir, planPath)
+
 			return nil
 		},
 	}
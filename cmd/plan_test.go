@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestPlan_CustomAgentGetsSkillInstructions(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, dir)
+	promptCopy := filepath.Join(t.TempDir(), "prompt.txt")
+	writeAgentScript(t, dir, `cat > `+promptCopy+`
+cat > .etch/plans/auth.md <<'PLAN'
+# Plan: Auth
+
+### Task 1: Login [pending]
+**Complexity:** small
+**Files:** auth.go
+
+Add login.
+
+**Acceptance Criteria:**
+- [ ] Login works
+PLAN
+`, "stdin")
+
+	app := &cli.App{Commands: []*cli.Command{planCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "plan", "--name", "auth", "add login"})
+	})
+	if err != nil {
+		t.Fatalf("plan error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Launching script") || !strings.Contains(output, "Tasks:    1") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	prompt, _ := os.ReadFile(promptCopy)
+	if strings.HasPrefix(string(prompt), "/etch-plan") || strings.Contains(string(prompt), "$ARGUMENTS") {
+		t.Errorf("expected the skill expanded inline, got:\n%s", prompt)
+	}
+	if !strings.Contains(string(prompt), "--slug auth add login") || !strings.Contains(string(prompt), "# Plan: <Title>") {
+		t.Errorf("prompt missing arguments or format instructions:\n%s", prompt)
+	}
+}
@@ -1,8 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"regexp"
+	"os"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/gsigler/etch/internal/models"
 	"github.com/gsigler/etch/internal/progress"
 	"github.com/gsigler/etch/internal/serializer"
+	"github.com/gsigler/etch/internal/transition"
 	"github.com/urfave/cli/v2"
 )
 
@@ -59,85 +61,39 @@ func progressStartCmd() *cli.Command {
 	}
 }
 
-func runProgressStart(c *cli.Context) error {
+// resolveProgressTask resolves the --plan and --task flags of a progress
+// subcommand.
+func resolveProgressTask(c *cli.Context) (string, *models.Plan, *models.Task, error) {
 	rootDir, err := findProjectRoot()
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
+	plan, task, err := findTask(rootDir, c.String("plan"), c.String("task"))
+	return rootDir, plan, task, err
+}
 
+// findTask resolves a task by plan slug and task ID.
+func findTask(rootDir, planSlug, taskID string) (*models.Plan, *models.Task, error) {
 	plans, err := etchcontext.DiscoverPlans(rootDir)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	return etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+}
 
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+func runProgressStart(c *cli.Context) error {
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
-
-	// Update task status in the plan file.
-	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusInProgress); err != nil {
-		return etcherr.WrapIO("updating task status", err).
-			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
-	}
-
-	// Find or create session progress file.
-	allProgress, err := progress.ReadAll(rootDir, plan.Slug)
+	sessionNum, err := transition.Start(rootDir, plan, task)
 	if err != nil {
-		return etcherr.WrapIO("reading progress files", err)
-	}
-
-	var sessionNum int
-	var progressPath string
-
-	if sessions, ok := allProgress[task.FullID()]; ok && len(sessions) > 0 {
-		// Reuse the latest session file.
-		latest := sessions[len(sessions)-1]
-		sessionNum = latest.SessionNumber
-		progressPath = progressFilePath(rootDir, plan.Slug, task.FullID(), sessionNum)
-
-		// Update the status line in the existing progress file.
-		if err := progress.UpdateStatus(progressPath, "in_progress"); err != nil {
-			return etcherr.WrapIO("updating progress file status", err)
-		}
-	} else {
-		// Create a new session file.
-		progressPath, err = progress.WriteSession(rootDir, plan, task)
-		if err != nil {
-			return etcherr.WrapIO("creating progress file", err)
-		}
-		// Extract session number from filename.
-		sessionNum = extractSessionNumber(progressPath)
-
-		// Update the status line from pending to in_progress.
-		if err := progress.UpdateStatus(progressPath, "in_progress"); err != nil {
-			return etcherr.WrapIO("updating progress file status", err)
-		}
+		return err
 	}
-
 	fmt.Printf("Task %s started (session %03d)\n", task.FullID(), sessionNum)
 	return nil
 }
 
-func progressFilePath(rootDir, planSlug, taskID string, session int) string {
-	return fmt.Sprintf("%s/.etch/progress/%s--task-%s--%03d.md", rootDir, planSlug, taskID, session)
-}
-
-var sessionNumRe = regexp.MustCompile(`--(\d{3})\.md$`)
-
-func extractSessionNumber(path string) int {
-	m := sessionNumRe.FindStringSubmatch(path)
-	if m == nil {
-		return 1
-	}
-	var n int
-	fmt.Sscanf(m[1], "%d", &n)
-	return n
-}
-
 func progressUpdateCmd() *cli.Command {
 	return &cli.Command{
 		Name:  "update",
@@ -155,39 +111,34 @@ func progressUpdateCmd() *cli.Command {
 }
 
 func runProgressUpdate(c *cli.Context) error {
-	rootDir, err := findProjectRoot()
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
-
-	plans, err := etchcontext.DiscoverPlans(rootDir)
-	if err != nil {
+	if err := logProgress(rootDir, plan, task, c.String("message")); err != nil {
 		return err
 	}
+	fmt.Printf("Logged update for Task %s\n", task.FullID())
+	return nil
+}
 
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+// logProgress appends a timestamped message to Changes Made in the task's
+// latest session file and refreshes the session's heartbeat.
+func logProgress(rootDir string, plan *models.Plan, task *models.Task, message string) error {
+	sessionPath, err := transition.LatestSession(rootDir, plan, task)
 	if err != nil {
 		return err
 	}
 
-	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
-	if err != nil {
-		return etcherr.WrapIO("finding session file", err).
-			WithHint(fmt.Sprintf("run 'etch progress start -p %s -t %s' first to create a session", plan.Slug, task.FullID()))
-	}
-
-	message := c.String("message")
 	timestamp := time.Now().Format("15:04")
 	entry := fmt.Sprintf("- [%s] %s", timestamp, message)
 
 	if err := progress.AppendToSection(sessionPath, "Changes Made", entry); err != nil {
 		return etcherr.WrapIO("appending to progress file", err)
 	}
-
-	fmt.Printf("Logged update for Task %s\n", task.FullID())
+	if err := progress.Heartbeat(sessionPath); err != nil {
+		return etcherr.WrapIO("recording heartbeat", err)
+	}
 	return nil
 }
 
@@ -203,46 +154,16 @@ func progressDoneCmd() *cli.Command {
 }
 
 func runProgressDone(c *cli.Context) error {
-	rootDir, err := findProjectRoot()
-	if err != nil {
-		return err
-	}
-
-	plans, err := etchcontext.DiscoverPlans(rootDir)
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
 
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+	unchecked, err := transition.Complete(context.Background(), rootDir, plan, task, os.Stdout)
 	if err != nil {
 		return err
 	}
 
-	// Update plan file status to completed.
-	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusCompleted); err != nil {
-		return etcherr.WrapIO("updating task status", err).
-			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
-	}
-
-	// Update progress file status to completed.
-	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
-	if err == nil {
-		if err := progress.UpdateStatus(sessionPath, "completed"); err != nil {
-			return etcherr.WrapIO("updating progress file status", err)
-		}
-	}
-
-	// Check for unchecked acceptance criteria and warn.
-	var unchecked []string
-	for _, c := range task.Criteria {
-		if !c.IsMet {
-			unchecked = append(unchecked, c.Description)
-		}
-	}
-
 	fmt.Printf("Task %s completed\n", task.FullID())
 	if len(unchecked) > 0 {
 		fmt.Printf("Warning: %d unchecked acceptance criteria:\n", len(unchecked))
@@ -270,20 +191,7 @@ func progressCriteriaCmd() *cli.Command {
 }
 
 func runProgressCriteria(c *cli.Context) error {
-	rootDir, err := findProjectRoot()
-	if err != nil {
-		return err
-	}
-
-	plans, err := etchcontext.DiscoverPlans(rootDir)
-	if err != nil {
-		return err
-	}
-
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
@@ -293,38 +201,18 @@ func runProgressCriteria(c *cli.Context) error {
 	var unmatched []string
 
 	for _, checkText := range checks {
-		// Try exact match first.
-		err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), checkText, true)
-		if err == nil {
-			// Also update progress file.
-			updateProgressCriterion(rootDir, plan.Slug, task.FullID(), checkText)
-			fmt.Printf("  ✓ %s\n", checkText)
-			matched++
-			continue
-		}
-
-		// Try substring match (case-insensitive).
-		found := false
-		for _, criterion := range task.Criteria {
-			if criterion.IsMet {
-				continue
-			}
-			if strings.Contains(strings.ToLower(criterion.Description), strings.ToLower(checkText)) {
-				err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), criterion.Description, true)
-				if err == nil {
-					updateProgressCriterion(rootDir, plan.Slug, task.FullID(), criterion.Description)
-					fmt.Printf("  ✓ %s (matched: %s)\n", checkText, criterion.Description)
-					matched++
-					found = true
-					break
-				}
-			}
-		}
-
-		if !found {
+		desc, ok := checkCriterion(rootDir, plan, task, checkText)
+		switch {
+		case !ok:
 			fmt.Printf("  ✗ %s (no match)\n", checkText)
 			unmatched = append(unmatched, checkText)
+			continue
+		case desc == checkText:
+			fmt.Printf("  ✓ %s\n", checkText)
+		default:
+			fmt.Printf("  ✓ %s (matched: %s)\n", checkText, desc)
 		}
+		matched++
 	}
 
 	fmt.Printf("Checked %d/%d criteria for Task %s\n", matched, len(checks), task.FullID())
@@ -337,6 +225,32 @@ func runProgressCriteria(c *cli.Context) error {
 	return nil
 }
 
+// checkCriterion checks off the criterion matching text exactly, or else
+// the first unchecked criterion containing it (case-insensitive), in the
+// plan and the latest session file. It returns the matched criterion.
+func checkCriterion(rootDir string, plan *models.Plan, task *models.Task, text string) (string, bool) {
+	// Try exact match first.
+	if err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), text, true); err == nil {
+		// Also update progress file.
+		updateProgressCriterion(rootDir, plan.Slug, task.FullID(), text)
+		return text, true
+	}
+
+	// Try substring match (case-insensitive).
+	for _, criterion := range task.Criteria {
+		if criterion.IsMet {
+			continue
+		}
+		if strings.Contains(strings.ToLower(criterion.Description), strings.ToLower(text)) {
+			if err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), criterion.Description, true); err == nil {
+				updateProgressCriterion(rootDir, plan.Slug, task.FullID(), criterion.Description)
+				return criterion.Description, true
+			}
+		}
+	}
+	return "", false
+}
+
 // updateProgressCriterion updates the criterion in the progress file, if a session exists.
 func updateProgressCriterion(rootDir, planSlug, taskID, criterionText string) {
 	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, planSlug, taskID)
@@ -363,49 +277,14 @@ func progressBlockCmd() *cli.Command {
 }
 
 func runProgressBlock(c *cli.Context) error {
-	rootDir, err := findProjectRoot()
-	if err != nil {
-		return err
-	}
-
-	plans, err := etchcontext.DiscoverPlans(rootDir)
-	if err != nil {
-		return err
-	}
-
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
-
-	// Update plan file status to blocked.
-	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusBlocked); err != nil {
-		return etcherr.WrapIO("updating task status", err).
-			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
-	}
-
-	// Find latest session file.
-	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
-	if err != nil {
-		return etcherr.WrapIO("finding session file", err).
-			WithHint(fmt.Sprintf("run 'etch progress start -p %s -t %s' first to create a session", plan.Slug, task.FullID()))
-	}
-
-	// Update progress file status to blocked.
-	if err := progress.UpdateStatus(sessionPath, "blocked"); err != nil {
-		return etcherr.WrapIO("updating progress file status", err)
-	}
-
-	// Append reason to Blockers section.
 	reason := c.String("reason")
-	entry := fmt.Sprintf("- %s", reason)
-	if err := progress.AppendToSection(sessionPath, "Blockers", entry); err != nil {
-		return etcherr.WrapIO("appending to blockers section", err)
+	if err := transition.Stop(rootDir, plan, task, models.StatusBlocked, reason); err != nil {
+		return err
 	}
-
 	fmt.Printf("Task %s blocked: %s\n", task.FullID(), reason)
 	return nil
 }
@@ -426,49 +305,14 @@ func progressFailCmd() *cli.Command {
 }
 
 func runProgressFail(c *cli.Context) error {
-	rootDir, err := findProjectRoot()
-	if err != nil {
-		return err
-	}
-
-	plans, err := etchcontext.DiscoverPlans(rootDir)
+	rootDir, plan, task, err := resolveProgressTask(c)
 	if err != nil {
 		return err
 	}
-
-	planSlug := c.String("plan")
-	taskID := c.String("task")
-
-	plan, task, err := etchcontext.ResolveTask(plans, planSlug, taskID, rootDir)
-	if err != nil {
-		return err
-	}
-
-	// Update plan file status to failed.
-	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusFailed); err != nil {
-		return etcherr.WrapIO("updating task status", err).
-			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
-	}
-
-	// Find latest session file.
-	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
-	if err != nil {
-		return etcherr.WrapIO("finding session file", err).
-			WithHint(fmt.Sprintf("run 'etch progress start -p %s -t %s' first to create a session", plan.Slug, task.FullID()))
-	}
-
-	// Update progress file status to failed.
-	if err := progress.UpdateStatus(sessionPath, "failed"); err != nil {
-		return etcherr.WrapIO("updating progress file status", err)
-	}
-
-	// Append reason to Blockers section.
 	reason := c.String("reason")
-	entry := fmt.Sprintf("- %s", reason)
-	if err := progress.AppendToSection(sessionPath, "Blockers", entry); err != nil {
-		return etcherr.WrapIO("appending to blockers section", err)
+	if err := transition.Stop(rootDir, plan, task, models.StatusFailed, reason); err != nil {
+		return err
 	}
-
 	fmt.Printf("Task %s failed: %s\n", task.FullID(), reason)
 	return nil
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -71,7 +72,7 @@ func TestProgressStart_CreatesSessionFile(t *testing.T) {
 	// Check that the progress file status is in_progress.
 	data, _ := os.ReadFile(matches[0])
 	content := string(data)
-	if !strings.Contains(content, "**Status:** in_progress") {
+	if !strings.Contains(content, "status: in_progress") {
 		t.Error("progress file should have status in_progress")
 	}
 
@@ -165,7 +166,7 @@ func TestProgressDone_CompletesTask(t *testing.T) {
 	matches, _ := filepath.Glob(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--*.md"))
 	if len(matches) > 0 {
 		data, _ := os.ReadFile(matches[0])
-		if !strings.Contains(string(data), "**Status:** completed") {
+		if !strings.Contains(string(data), "status: completed") {
 			t.Error("progress file should have status completed")
 		}
 	}
@@ -247,7 +248,7 @@ func TestProgressBlock_BlocksTask(t *testing.T) {
 	matches, _ := filepath.Glob(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--*.md"))
 	data, _ := os.ReadFile(matches[0])
 	content := string(data)
-	if !strings.Contains(content, "**Status:** blocked") {
+	if !strings.Contains(content, "status: blocked") {
 		t.Error("progress file should have status blocked")
 	}
 	if !strings.Contains(content, "Waiting on API") {
@@ -279,7 +280,7 @@ func TestProgressFail_FailsTask(t *testing.T) {
 	matches, _ := filepath.Glob(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--*.md"))
 	data, _ := os.ReadFile(matches[0])
 	content := string(data)
-	if !strings.Contains(content, "**Status:** failed") {
+	if !strings.Contains(content, "status: failed") {
 		t.Error("progress file should have status failed")
 	}
 	if !strings.Contains(content, "Tests broken") {
@@ -299,3 +300,42 @@ func TestProgressStart_NoTask(t *testing.T) {
 		t.Fatal("expected error with no --task flag")
 	}
 }
+
+func TestProgressHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use POSIX shell commands")
+	}
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte(`
+[hooks]
+on_task_start = "echo $ETCH_TASK $ETCH_TO_STATUS > started.txt"
+on_task_done = "exit 1"
+on_task_blocked = "cat > blocked.json"
+veto = ["on_task_done"]
+`), 0o644)
+	app := &cli.App{Commands: []*cli.Command{progressCmd()}}
+	planPath := filepath.Join(dir, ".etch", "plans", "test-plan.md")
+
+	if err := app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "started.txt")); string(data) != "1.1 in_progress\n" {
+		t.Errorf("on_task_start saw %q", data)
+	}
+
+	err := app.Run([]string{"etch", "progress", "done", "-p", "test-plan", "-t", "1"})
+	if err == nil || !strings.Contains(err.Error(), "on_task_done hook failed") {
+		t.Fatalf("expected the on_task_done veto, got %v", err)
+	}
+	if data, _ := os.ReadFile(planPath); !strings.Contains(string(data), "[in_progress]") {
+		t.Errorf("a vetoed task should stay in progress:\n%s", data)
+	}
+
+	if err := app.Run([]string{"etch", "progress", "block", "-p", "test-plan", "-t", "1", "--reason", "waiting on API keys"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "blocked.json"))
+	if !strings.Contains(string(data), `"reason":"waiting on API keys"`) || !strings.Contains(string(data), `"session":1`) {
+		t.Errorf("on_task_blocked payload = %s", data)
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gsigler/etch/internal/claim"
+	"github.com/gsigler/etch/internal/config"
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/serializer"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/transition"
+	"github.com/urfave/cli/v2"
+)
+
+func recoverCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "recover",
+		Usage:     "Reset tasks whose sessions were abandoned",
+		ArgsUsage: "[plan-slug]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "task",
+				Aliases: []string{"t"},
+				Usage:   "recover this task's session in progress, stale or not",
+			},
+			&cli.BoolFlag{
+				Name:  "fail",
+				Usage: "mark recovered tasks failed instead of pending",
+			},
+			&cli.StringFlag{
+				Name:  "reason",
+				Usage: "why the sessions were abandoned (default: when they were last active)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			cfg, err := config.Load(rootDir)
+			if err != nil {
+				return err
+			}
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+
+			slug := c.Args().First()
+			var targets []abandonedSession
+			if id := c.String("task"); id != "" {
+				target, err := sessionInProgress(rootDir, plans, slug, id)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
+			} else {
+				if targets, err = staleSessions(rootDir, plans, slug, cfg.Status.StaleAfter, time.Now()); err != nil {
+					return err
+				}
+				if len(targets) == 0 {
+					fmt.Printf("No stale sessions: every session in progress has been active in the last %s.\n",
+						status.FormatDuration(cfg.Status.StaleAfter))
+					return nil
+				}
+			}
+
+			to := models.StatusPending
+			if c.Bool("fail") {
+				to = models.StatusFailed
+			}
+			for _, a := range targets {
+				reason := c.String("reason")
+				if reason == "" {
+					reason = a.defaultReason()
+				}
+				if err := recoverSession(rootDir, a, to, reason); err != nil {
+					return err
+				}
+				fmt.Printf("  ✓ %s#%s session %03d → %s: %s\n", a.plan.Slug, a.task.FullID(), a.session.SessionNumber, to, reason)
+			}
+			fmt.Printf("Recovered %d %s.\n", len(targets), status.Plural(len(targets), "task", "tasks"))
+			return nil
+		},
+	}
+}
+
+// abandonedSession is the latest session of a task, left in progress by an
+// agent that is no longer working on it.
+type abandonedSession struct {
+	plan    *models.Plan
+	task    *models.Task
+	session models.SessionProgress
+}
+
+func (a abandonedSession) defaultReason() string {
+	seen := a.session.LastSeen()
+	if seen.IsZero() {
+		return "session abandoned"
+	}
+	return "session abandoned, last active " + seen.Local().Format("2006-01-02 15:04")
+}
+
+// staleSessions finds the tasks, in the plan with slug or in every plan,
+// whose latest session has been idle for longer than staleAfter.
+func staleSessions(rootDir string, plans []*models.Plan, slug string, staleAfter time.Duration, now time.Time) ([]abandonedSession, error) {
+	var found []abandonedSession
+	matched := false
+	for _, plan := range plans {
+		if slug != "" && plan.Slug != slug {
+			continue
+		}
+		matched = true
+		allProgress, err := progress.ReadAll(rootDir, plan.Slug)
+		if err != nil {
+			return nil, etcherr.WrapIO(fmt.Sprintf("reading progress for %s", plan.Slug), err)
+		}
+		for i := range plan.Features {
+			for j := range plan.Features[i].Tasks {
+				task := &plan.Features[i].Tasks[j]
+				sessions := allProgress[task.FullID()]
+				if len(sessions) == 0 {
+					continue
+				}
+				if latest := sessions[len(sessions)-1]; latest.Stale(now, staleAfter) {
+					found = append(found, abandonedSession{plan: plan, task: task, session: latest})
+				}
+			}
+		}
+	}
+	if slug != "" && !matched {
+		return nil, etcherr.Project(fmt.Sprintf("plan %q not found", slug)).
+			WithHint("run 'etch list' to see available plans")
+	}
+	return found, nil
+}
+
+// sessionInProgress returns the task's latest session, which must still be
+// in progress.
+func sessionInProgress(rootDir string, plans []*models.Plan, slug, taskID string) (abandonedSession, error) {
+	plan, task, err := etchcontext.ResolveTask(plans, slug, taskID, rootDir)
+	if err != nil {
+		return abandonedSession{}, err
+	}
+	allProgress, err := progress.ReadAll(rootDir, plan.Slug)
+	if err != nil {
+		return abandonedSession{}, etcherr.WrapIO(fmt.Sprintf("reading progress for %s", plan.Slug), err)
+	}
+	sessions := allProgress[task.FullID()]
+	if len(sessions) == 0 || sessions[len(sessions)-1].Status != string(models.StatusInProgress) {
+		return abandonedSession{}, etcherr.Project(fmt.Sprintf("task %s has no session in progress", task.FullID())).
+			WithHint(fmt.Sprintf("run 'etch status %s' to see its sessions", plan.Slug))
+	}
+	return abandonedSession{plan: plan, task: task, session: sessions[len(sessions)-1]}, nil
+}
+
+// recoverSession marks a's session abandoned, stopping its clock when it
+// was last active, records reason under its Blockers, and moves the task to
+// status in the plan so it can be picked up again.
+func recoverSession(rootDir string, a abandonedSession, to models.Status, reason string) error {
+	// Pending has no hook, and webhooks aren't told about it either.
+	notify := func() {}
+	if to != models.StatusPending {
+		var err error
+		if notify, err = transition.Fire(rootDir, a.plan, a.task, to, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := serializer.UpdateTaskStatusBecause(a.plan.FilePath, a.task.FullID(), to, reason); err != nil {
+		return etcherr.WrapIO("updating task status", err).
+			WithHint(fmt.Sprintf("could not update task %s in plan file", a.task.FullID()))
+	}
+
+	path := progress.SessionPath(rootDir, a.plan.Slug, a.task.FullID(), a.session.SessionNumber)
+	lastSeen := a.session.LastSeen()
+	if lastSeen.IsZero() {
+		lastSeen = time.Now()
+	}
+	if err := progress.Abandon(path, lastSeen); err != nil {
+		return etcherr.WrapIO("marking session abandoned", err)
+	}
+	if err := progress.AppendToSection(path, "Blockers", "- Abandoned: "+reason); err != nil {
+		return etcherr.WrapIO("appending to blockers section", err)
+	}
+	// Whoever claimed the task is gone too; let it be claimed again.
+	if _, _, err := claim.Break(rootDir, a.plan.Slug, a.task.FullID()); err != nil {
+		return err
+	}
+	notify()
+	return nil
+}
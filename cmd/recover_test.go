@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/progress"
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestRecover_StaleSession(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("in_progress"))
+	t.Setenv("ETCH_ACTOR", "human:tester")
+	planPath := filepath.Join(dir, ".etch", "plans", "test-plan.md")
+	progressPath := progress.SessionPath(dir, "test-plan", "1.1", 1)
+	os.WriteFile(progressPath, []byte(`---
+schema: 3
+plan: test-plan
+task: "1.1"
+session: 1
+status: in_progress
+started: 2026-01-01T10:00:00Z
+heartbeat: 2026-01-01T10:40:00Z
+---
+
+# Session: Task 1.1 – Do the thing
+
+## Blockers
+`), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{recoverCmd(), progressCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "recover"})
+	})
+	if err != nil {
+		t.Fatalf("recover error: %v", err)
+	}
+	if !strings.Contains(output, "test-plan#1.1 session 001 → pending") || !strings.Contains(output, "Recovered 1 task.") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	if data, _ := os.ReadFile(planPath); !strings.Contains(string(data), "### Task 1: Do the thing [pending]") {
+		t.Errorf("task not reset to pending:\n%s", data)
+	}
+	sp, err := progress.ReadSession(progressPath, "test-plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Status != "abandoned" || sp.Ended.IsZero() || sp.Active.Minutes() != 40 {
+		t.Errorf("session not abandoned at its last heartbeat: status %q, ended %v, active %v", sp.Status, sp.Ended, sp.Active)
+	}
+	if !strings.Contains(sp.Blockers, "Abandoned: session abandoned, last active") {
+		t.Errorf("reason not recorded under Blockers: %q", sp.Blockers)
+	}
+	evs, _ := events.Read(dir, events.Filter{})
+	if len(evs) != 1 || evs[0].After != "pending" || !strings.Contains(evs[0].Reason, "session abandoned") {
+		t.Errorf("expected the reset in the event log with its reason, got %+v", evs)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "recover"})
+	})
+	if err != nil || !strings.Contains(output, "No stale sessions") {
+		t.Errorf("second recover: err %v, output:\n%s", err, output)
+	}
+
+	// Starting the task again opens a new session rather than reviving the
+	// abandoned one.
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"})
+	})
+	if err != nil || !strings.Contains(output, "session 002") {
+		t.Errorf("progress start: err %v, output:\n%s", err, output)
+	}
+}
+
+func TestRecover_TaskFail(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	app := &cli.App{Commands: []*cli.Command{recoverCmd(), progressCmd()}}
+
+	var err error
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "recover", "-t", "1"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no session in progress") {
+		t.Errorf("expected an error for a task with no session, got %v", err)
+	}
+
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh session isn't stale, so only naming the task recovers it.
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "recover"})
+	})
+	if err != nil || !strings.Contains(output, "No stale sessions") {
+		t.Errorf("recover without -t: err %v, output:\n%s", err, output)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "recover", "-t", "1", "--fail", "--reason", "agent was killed"})
+	})
+	if err != nil {
+		t.Fatalf("recover -t error: %v", err)
+	}
+	if !strings.Contains(output, "→ failed: agent was killed") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "test-plan.md")); !strings.Contains(string(data), "### Task 1: Do the thing [failed]") {
+		t.Errorf("task not marked failed:\n%s", data)
+	}
+	sp, err := progress.ReadSession(progress.SessionPath(dir, "test-plan", "1.1", 1), "test-plan")
+	if err != nil || sp.Status != "abandoned" || !strings.Contains(sp.Blockers, "Abandoned: agent was killed") {
+		t.Errorf("session: %+v, err %v", sp, err)
+	}
+}
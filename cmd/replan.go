@@ -1,12 +1,13 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
-	"github.com/gsigler/etch/internal/claude"
 	etchcontext "github.com/gsigler/etch/internal/context"
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
 	"github.com/gsigler/etch/internal/generator"
 	"github.com/gsigler/etch/internal/models"
 	"github.com/gsigler/etch/internal/parser"
@@ -54,6 +55,11 @@ Examples:
 				return err
 			}
 
+			a, err := loadAgent(rootDir)
+			if err != nil {
+				return err
+			}
+
 			// Discover plans.
 			plans, err := etchcontext.DiscoverPlans(rootDir)
 			if err != nil {
@@ -100,6 +106,7 @@ Examples:
 			fmt.Printf("Backup saved to: %s\n\n", backupPath)
 
 			var prompt string
+			replanned := events.Event{Actor: events.Actor(), Type: events.Replan, Plan: plan.Slug, Subject: "plan", Reason: c.String("reason")}
 			if targetStr == "" {
 				// Whole-plan replan.
 				fmt.Printf("Replanning entire plan: %s\n", plan.Title)
@@ -133,6 +140,8 @@ Examples:
 					targetDesc = fmt.Sprintf("Feature %d: %s", target.FeatureNum, target.Feature.Title)
 				}
 				fmt.Printf("Replanning %s\n", targetDesc)
+				replanned.Task = target.TaskID
+				replanned.Subject = targetDesc
 
 				prompt = fmt.Sprintf(
 					"I need to replan part of an etch implementation plan.\n\n"+
@@ -154,10 +163,13 @@ Examples:
 				prompt += fmt.Sprintf("\n\n**Reason for replanning:** %s", reason)
 			}
 
-			fmt.Println("Launching Claude Code to replan...")
+			fmt.Printf("Launching %s to replan...\n", a.Name())
 			fmt.Println()
 
-			if err := claude.RunWithStdin(prompt, rootDir); err != nil {
+			if err := a.Interactive(prompt, rootDir); err != nil {
+				return err
+			}
+			if err := recordReplan(plan.FilePath, planContent, replanned); err != nil {
 				return err
 			}
 
@@ -176,6 +188,7 @@ Examples:
 				fmt.Printf("Backup: %s\n", backupPath)
 			} else {
 				fmt.Println("\nPlan updated successfully.")
+				reportValidation(rootDir, plan.FilePath)
 			}
 
 			return nil
@@ -183,6 +196,31 @@ Examples:
 	}
 }
 
+// recordReplan logs a replan if the plan file changed from before, noting
+// the task count on each side.
+func recordReplan(path string, before []byte, e events.Event) error {
+	after, err := os.ReadFile(path)
+	if err != nil || bytes.Equal(before, after) {
+		return nil
+	}
+	e.Before = taskCount(before)
+	e.After = taskCount(after)
+	return events.Record(path, e)
+}
+
+// taskCount describes how many tasks a plan's content holds.
+func taskCount(content []byte) string {
+	plan, err := parser.Parse(bytes.NewReader(content))
+	if err != nil {
+		return "unparseable"
+	}
+	n := 0
+	for _, f := range plan.Features {
+		n += len(f.Tasks)
+	}
+	return fmt.Sprintf("%d tasks", n)
+}
+
 func findReplanPlan(plans []*models.Plan, slug string) *models.Plan {
 	for _, p := range plans {
 		if p.Slug == slug {
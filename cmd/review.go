@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gsigler/etch/internal/api"
+	"github.com/gsigler/etch/internal/config"
 	etchcontext "github.com/gsigler/etch/internal/context"
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/generator"
 	"github.com/gsigler/etch/internal/tui"
 	"github.com/urfave/cli/v2"
 )
@@ -59,7 +64,12 @@ func reviewCmd() *cli.Command {
 				}
 			}
 
-			m := tui.New(plan, plan.FilePath)
+			refineFn, err := newRefineFunc(rootDir)
+			if err != nil {
+				return err
+			}
+
+			m := tui.New(plan, plan.FilePath, tui.WithRefineFunc(refineFn))
 			p := tea.NewProgram(m, tea.WithAltScreen())
 			if _, err := p.Run(); err != nil {
 				return etcherr.WrapIO("TUI error", err)
@@ -68,3 +78,36 @@ func reviewCmd() *cli.Command {
 		},
 	}
 }
+
+// newRefineFunc builds the refinement function used by the review TUI. It
+// streams from the Anthropic API when an API key is configured and falls back
+// to a non-interactive Claude Code session otherwise.
+func newRefineFunc(rootDir string) (tui.RefineFunc, error) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey, err := cfg.ResolveAPIKey(); err == nil {
+		client := api.NewClient(apiKey, cfg.API.Model)
+		return func(ctx context.Context, planContent string, comments []string, onToken func(string)) (string, error) {
+			return generator.Refine(ctx, client, planContent, formatRefineComments(comments), onToken)
+		}, nil
+	}
+
+	return func(ctx context.Context, planContent string, comments []string, onToken func(string)) (string, error) {
+		return generator.RefineWithClaude(ctx, planContent, formatRefineComments(comments), rootDir, onToken)
+	}, nil
+}
+
+// formatRefineComments renders the TUI's collected comments as the review
+// comments block of the refinement prompt.
+func formatRefineComments(comments []string) string {
+	var b strings.Builder
+	for _, c := range comments {
+		b.WriteString("> 💬 ")
+		b.WriteString(strings.ReplaceAll(c, "\n", "\n> "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -30,6 +30,7 @@ func Execute() (bool, error) {
 			planCmd(),
 			reviewCmd(),
 			statusCmd(),
+			syncCmd(),
 			contextCmd(),
 			runCmd(),
 			replanCmd(),
@@ -39,6 +40,19 @@ func Execute() (bool, error) {
 			skillCmd(),
 			progressCmd(),
 			priorityCmd(),
+			validateCmd(),
+			graphCmd(),
+			swarmCmd(),
+			verifyCmd(),
+			mcpCmd(),
+			serveCmd(),
+			lspCmd(),
+			logCmd(),
+			nextCmd(),
+			forecastCmd(),
+			migrateCmd(),
+			recoverCmd(),
+			claimsCmd(),
 		},
 	}
 
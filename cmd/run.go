@@ -1,19 +1,35 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/gsigler/etch/internal/agent"
+	"github.com/gsigler/etch/internal/claim"
 	"github.com/gsigler/etch/internal/claude"
+	"github.com/gsigler/etch/internal/config"
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/worktree"
 	"github.com/urfave/cli/v2"
 )
 
+// defaultHeadlessTimeout bounds unattended sessions started by
+// `etch run --headless` and `etch swarm`.
+const defaultHeadlessTimeout = 30 * time.Minute
+
 func runCmd() *cli.Command {
 	return &cli.Command{
 		Name:  "run",
-		Usage: "Launch Claude Code with assembled context for a task",
+		Usage: "Launch the configured agent with assembled context for a task",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "plan",
@@ -30,6 +46,23 @@ func runCmd() *cli.Command {
 				Aliases: []string{"f"},
 				Usage:   "feature number (e.g. 2) — run all pending tasks in a feature",
 			},
+			&cli.BoolFlag{
+				Name:  "headless",
+				Usage: "run without a terminal (for CI and scripts), saving a transcript",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: defaultHeadlessTimeout,
+				Usage: "with --headless, kill the session after this long (0 for no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "worktree",
+				Usage: "run the task in its own git worktree and branch (default from [run] worktree in config)",
+			},
+			&cli.BoolFlag{
+				Name:  "commit",
+				Usage: "commit the session's changes when it ends (default from [run] commit in config)",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if c.String("feature") != "" && c.String("task") != "" {
@@ -38,24 +71,77 @@ func runCmd() *cli.Command {
 			}
 
 			if c.String("feature") != "" {
+				if c.Bool("headless") {
+					return etcherr.Usage("--headless does not support --feature").
+						WithHint("run tasks individually with --task, or use 'etch swarm' to run a plan unattended")
+				}
+				if c.Bool("worktree") {
+					return etcherr.Usage("--worktree does not support --feature").
+						WithHint("run tasks individually with --task to give each its own branch")
+				}
 				return runFeature(c)
 			}
 
-			rc, err := resolveContextArgs(c)
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			cfg, err := config.Load(rootDir)
+			if err != nil {
+				return err
+			}
+			a, err := agentFor(cfg)
+			if err != nil {
+				return err
+			}
+
+			// Claim the task so other runs and swarms leave it alone, and
+			// hold the claim until the agent exits.
+			rc, err := resolveContextArgs(c, cfg.Run.ClaimTTL)
 			if err != nil {
 				return err
 			}
+			defer rc.Claim.Release()
+			defer rc.Claim.Keep(cfg.Run.ClaimTTL, config.ClaimRenewInterval)()
+
+			if err := progress.SetField(rc.Result.ProgressPath, "agent", a.Name()); err != nil {
+				return etcherr.WrapIO("recording session agent", err)
+			}
+			useWorktree := cfg.Run.Worktree
+			if c.IsSet("worktree") {
+				useWorktree = c.Bool("worktree")
+			}
+			workDir := rc.RootDir
+			if useWorktree {
+				if workDir, err = prepareWorktree(rc, cfg.Run.WorktreeBase); err != nil {
+					return err
+				}
+			}
+			commit := cfg.Run.Commit
+			if c.IsSet("commit") {
+				commit = c.Bool("commit")
+			}
+			diff, err := startSessionDiff(workDir, rc.Plan, rc.Task, rc.Result.ProgressPath)
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("headless") {
+				return runHeadless(rc, a, workDir, c.Duration("timeout"), diff, commit)
+			}
 
 			task := rc.Task
 			result := rc.Result
-			rootDir := rc.RootDir
 
 			relContext, _ := filepath.Rel(rootDir, result.ContextPath)
 			relProgress, _ := filepath.Rel(rootDir, result.ProgressPath)
 
-			fmt.Printf("Launching Claude for Task %s — %s (session %03d)\n\n", task.FullID(), task.Title, result.SessionNum)
+			fmt.Printf("Launching %s for Task %s — %s (session %03d)\n\n", a.Name(), task.FullID(), task.Title, result.SessionNum)
 			fmt.Printf("  Context file:  %s\n", relContext)
 			fmt.Printf("  Progress file: %s\n", relProgress)
+			if workDir != rootDir {
+				fmt.Printf("  Worktree:      %s\n", describeWorktree(rc, workDir))
+			}
 			fmt.Printf("  Token estimate: ~%dk tokens\n\n", result.TokenEstimate/1000)
 
 			if result.TokenEstimate > 80000 {
@@ -69,16 +155,98 @@ func runCmd() *cli.Command {
 					WithHint("context file may have been removed: " + result.ContextPath)
 			}
 
-			return claude.RunWithStdin(string(content), rootDir)
+			if err := progress.RecordStart(result.ProgressPath); err != nil {
+				return etcherr.WrapIO("recording session start", err)
+			}
+			stopHeartbeat := progress.KeepAlive(result.ProgressPath, progress.HeartbeatInterval)
+			// Changes the agent makes through etch are attributed to it.
+			os.Setenv(events.ActorEnvVar, events.AgentActor(rc.Plan.Slug, task.FullID(), result.SessionNum))
+			os.Setenv(claim.EnvVar, rc.Claim.ID)
+			runErr := a.Interactive(string(content), workDir)
+			os.Unsetenv(events.ActorEnvVar)
+			os.Unsetenv(claim.EnvVar)
+			stopHeartbeat()
+			if err := progress.RecordEnd(result.ProgressPath); err != nil && runErr == nil {
+				runErr = etcherr.WrapIO("recording session end", err)
+			}
+			if err := reportSessionDiff(diff, commit); err != nil && runErr == nil {
+				runErr = err
+			}
+			// Sync now so the session's outcome, and the hooks it
+			// triggers, apply without waiting for 'etch sync'.
+			if _, _, err := status.Sync(rootDir, rc.Plan.Slug, false); err != nil && runErr == nil {
+				runErr = err
+			}
+			return runErr
 		},
 	}
 }
 
+// loadAgent returns the project's configured agent backend, checking that
+// it can run.
+func loadAgent(rootDir string) (agent.Agent, error) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	return agentFor(cfg)
+}
+
+// agentFor returns the agent backend selected in cfg, checking that it can run.
+func agentFor(cfg config.Config) (agent.Agent, error) {
+	a, err := agent.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Available(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// prepareWorktree creates the task's worktree, or reuses the one from an
+// earlier session, and records its branch in the session's progress file.
+// It returns the directory to launch the agent in.
+func prepareWorktree(rc *resolvedContext, base string) (string, error) {
+	if !worktree.IsRepo(rc.RootDir) {
+		return "", etcherr.Project("worktree isolation requires a git repository").
+			WithHint("run 'git init' and commit first, or run without --worktree")
+	}
+
+	taskID := rc.Task.FullID()
+	path := worktree.Path(rc.RootDir, rc.Plan.Slug, taskID)
+	branch := worktree.BranchName(rc.Plan.Slug, taskID)
+	if _, err := worktree.Ensure(rc.RootDir, path, branch, base); err != nil {
+		return "", err
+	}
+	if err := progress.SetField(rc.Result.ProgressPath, "branch", branch); err != nil {
+		return "", etcherr.WrapIO("recording worktree branch", err)
+	}
+
+	// The session runs in the worktree; point its etch commands back at
+	// this project so progress lands in one place.
+	os.Setenv(rootEnvVar, rc.RootDir)
+	return path, nil
+}
+
+// describeWorktree formats a task's worktree directory and branch for display.
+func describeWorktree(rc *resolvedContext, workDir string) string {
+	rel, err := filepath.Rel(rc.RootDir, workDir)
+	if err != nil {
+		rel = workDir
+	}
+	return fmt.Sprintf("%s (branch %s)", rel, worktree.BranchName(rc.Plan.Slug, rc.Task.FullID()))
+}
+
 func runFeature(c *cli.Context) error {
 	rf, err := resolveFeatureArgs(c)
 	if err != nil {
 		return err
 	}
+	a, err := loadAgent(rf.RootDir)
+	if err != nil {
+		return err
+	}
 
 	feature := rf.Feature
 	result := rf.Result
@@ -86,8 +254,8 @@ func runFeature(c *cli.Context) error {
 
 	relContext, _ := filepath.Rel(rootDir, result.ContextPath)
 
-	fmt.Printf("Launching Claude for Feature %d — %s (%d tasks, session %03d)\n\n",
-		feature.Number, feature.Title, len(result.ProgressPaths), result.SessionNum)
+	fmt.Printf("Launching %s for Feature %d — %s (%d tasks, session %03d)\n\n",
+		a.Name(), feature.Number, feature.Title, len(result.ProgressPaths), result.SessionNum)
 	fmt.Printf("  Context file:  %s\n", relContext)
 	fmt.Printf("  Token estimate: ~%dk tokens\n\n", result.TokenEstimate/1000)
 
@@ -102,5 +270,221 @@ func runFeature(c *cli.Context) error {
 			WithHint("context file may have been removed: " + result.ContextPath)
 	}
 
-	return claude.RunWithStdin(string(content), rootDir)
+	return a.Interactive(string(content), rootDir)
+}
+
+// runHeadless runs a task's session in workDir without a terminal, then
+// reconciles status and reports how the session ended.
+func runHeadless(rc *resolvedContext, a agent.Agent, workDir string, timeout time.Duration, diff *sessionDiff, commit bool) error {
+	task := rc.Task
+	result := rc.Result
+	rootDir := rc.RootDir
+
+	content, err := os.ReadFile(result.ContextPath)
+	if err != nil {
+		return etcherr.WrapIO("reading context file", err).
+			WithHint("context file may have been removed: " + result.ContextPath)
+	}
+
+	transcript := claude.TranscriptPath(rootDir, rc.Plan.Slug, task.FullID(), result.SessionNum)
+	relContext, _ := filepath.Rel(rootDir, result.ContextPath)
+	relTranscript, _ := filepath.Rel(rootDir, transcript)
+
+	fmt.Printf("Running Task %s — %s headless with %s (session %03d)\n\n", task.FullID(), task.Title, a.Name(), result.SessionNum)
+	fmt.Printf("  Context file: %s\n", relContext)
+	fmt.Printf("  Transcript:   %s\n", relTranscript)
+	if workDir != rootDir {
+		fmt.Printf("  Worktree:     %s\n", describeWorktree(rc, workDir))
+	}
+	if timeout > 0 {
+		fmt.Printf("  Timeout:      %s\n", timeout)
+	}
+	fmt.Println()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := progress.RecordStart(result.ProgressPath); err != nil {
+		return etcherr.WrapIO("recording session start", err)
+	}
+	stopHeartbeat := progress.KeepAlive(result.ProgressPath, progress.HeartbeatInterval)
+	res, runErr := a.Headless(ctx, string(content), agent.HeadlessOptions{
+		WorkDir:        workDir,
+		TranscriptPath: transcript,
+		Timeout:        timeout,
+		Env: []string{
+			events.ActorEnvVar + "=" + events.AgentActor(rc.Plan.Slug, task.FullID(), result.SessionNum),
+			claim.EnvVar + "=" + rc.Claim.ID,
+		},
+	})
+	stopHeartbeat()
+	if err := progress.RecordEnd(result.ProgressPath); err != nil && runErr == nil {
+		runErr = etcherr.WrapIO("recording session end", err)
+	}
+	fmt.Print(formatHeadlessResult(res))
+	if err := reportSessionDiff(diff, commit); err != nil && runErr == nil {
+		runErr = err
+	}
+
+	// Sync even after a failure so the plan reflects what was reported.
+	taskStatus := ""
+	if plans, _, err := status.Sync(rootDir, rc.Plan.Slug, false); err == nil && len(plans) == 1 {
+		for _, f := range plans[0].Features {
+			for _, t := range f.Tasks {
+				if t.ID == task.FullID() {
+					taskStatus = string(t.Status)
+				}
+			}
+		}
+	}
+	if taskStatus != "" {
+		fmt.Printf("  Task status: %s\n", taskStatus)
+	}
+
+	return runErr
+}
+
+// sessionDiff tracks the git changes a task session makes in its work tree.
+type sessionDiff struct {
+	dir          string            // work tree the session runs in
+	base         string            // commit checked out when the session started
+	dirty        worktree.Snapshot // uncommitted files when the session started
+	plan         *models.Plan
+	task         *models.Task
+	progressPath string
+}
+
+// startSessionDiff records the commit a session starts from in its progress
+// file so its changes can be diffed when it ends, and snapshots the files
+// already uncommitted so they aren't taken for the session's. It returns nil
+// when dir is not a git repository with at least one commit.
+func startSessionDiff(dir string, plan *models.Plan, task *models.Task, progressPath string) (*sessionDiff, error) {
+	if !worktree.IsRepo(dir) {
+		return nil, nil
+	}
+	head, err := worktree.Head(dir)
+	if err != nil {
+		return nil, nil
+	}
+	dirty, err := worktree.TakeSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := progress.SetField(progressPath, "base_commit", head); err != nil {
+		return nil, etcherr.WrapIO("recording session base commit", err)
+	}
+	return &sessionDiff{dir: dir, base: head, dirty: dirty, plan: plan, task: task, progressPath: progressPath}, nil
+}
+
+// sessionChanges describes what a finished session changed.
+type sessionChanges struct {
+	Files      []string // changed files, relative to the work tree
+	OutOfScope []string // changed files not covered by the task's **Files:**
+	Committed  string   // commit message, if the changes were committed
+}
+
+// finish appends the files changed since the session started to the progress
+// file's Changes Made section, flagging any outside the task's **Files:**
+// scope, and commits those files when commit is set. Files that were
+// already uncommitted and that the session left as they were are not its
+// changes. Files the session already listed are not repeated, and etch's own
+// files are ignored.
+func (d *sessionDiff) finish(commit bool) (sessionChanges, error) {
+	var sc sessionChanges
+	if d == nil {
+		return sc, nil
+	}
+
+	files, err := worktree.ChangedFiles(d.dir, d.base)
+	if err != nil {
+		return sc, err
+	}
+	sp, err := progress.ReadSession(d.progressPath, d.plan.Slug)
+	if err != nil {
+		return sc, etcherr.WrapIO("reading progress file", err)
+	}
+
+	for _, f := range files {
+		if strings.HasPrefix(f, ".etch/") || !d.dirty.Touched(d.dir, f) {
+			continue
+		}
+		sc.Files = append(sc.Files, f)
+		entry := f
+		if !d.task.InScope(f) {
+			sc.OutOfScope = append(sc.OutOfScope, f)
+			entry += " (outside task scope)"
+		}
+		if listedChange(sp.ChangesMade, f) {
+			continue
+		}
+		if err := progress.AppendToSection(d.progressPath, "Changes Made", "- "+entry); err != nil {
+			return sc, etcherr.WrapIO("recording changed files", err)
+		}
+	}
+
+	if commit && len(sc.Files) > 0 {
+		msg := taskCommitMessage(d.plan, d.task)
+		committed, err := worktree.CommitFiles(d.dir, msg, sc.Files)
+		if err != nil {
+			return sc, err
+		}
+		if committed {
+			sc.Committed = msg
+		}
+	}
+	return sc, nil
+}
+
+// listedChange reports whether a Changes Made entry already mentions file.
+func listedChange(items []string, file string) bool {
+	for _, item := range items {
+		if strings.Contains(item, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// taskCommitMessage is the commit message for a task's work.
+func taskCommitMessage(plan *models.Plan, task *models.Task) string {
+	return fmt.Sprintf("%s: task %s — %s", plan.Slug, task.FullID(), task.Title)
+}
+
+// reportSessionDiff finishes a session's diff and prints what changed.
+func reportSessionDiff(d *sessionDiff, commit bool) error {
+	sc, err := d.finish(commit)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return nil
+	}
+	fmt.Printf("  Changed files: %d (recorded in progress file)\n", len(sc.Files))
+	if len(sc.OutOfScope) > 0 {
+		fmt.Printf("  ⚠ Outside the task's files: %s\n", strings.Join(sc.OutOfScope, ", "))
+	}
+	if sc.Committed != "" {
+		fmt.Printf("  Committed: %s\n", sc.Committed)
+	}
+	return nil
+}
+
+// formatHeadlessResult summarizes a headless session for the terminal.
+func formatHeadlessResult(res agent.Result) string {
+	icon := "✓"
+	if res.ExitCode != 0 || res.TimedOut || res.IsError {
+		icon = "✗"
+	}
+	s := fmt.Sprintf("%s Session finished in %s (exit %d)\n", icon, res.Duration.Round(time.Second), res.ExitCode)
+	if res.NumTurns > 0 {
+		s += fmt.Sprintf("  Turns:  %d\n", res.NumTurns)
+	}
+	if u := res.Usage; u != nil {
+		s += fmt.Sprintf("  Tokens: %d (input %d, output %d, cache read %d, cache write %d)\n",
+			u.Total(), u.InputTokens, u.OutputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens)
+	}
+	if res.CostUSD > 0 {
+		s += fmt.Sprintf("  Cost:   $%.2f\n", res.CostUSD)
+	}
+	return s
 }
@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/agent"
+	"github.com/gsigler/etch/internal/claim"
+	"github.com/gsigler/etch/internal/claude"
+	etchcontext "github.com/gsigler/etch/internal/context"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/worktree"
+	cli "github.com/urfave/cli/v2"
+)
+
+// installFakeClaude puts a shell script named claude at the front of PATH.
+func installFakeClaude(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake claude script requires a POSIX shell")
+	}
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "claude"), []byte("#!/bin/sh\n"+script), 0o755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunHeadless_WritesTranscriptAndReconciles(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	installFakeClaude(t, `cat >/dev/null
+sed -i.bak 's/^status: pending/status: completed/' .etch/progress/test-plan--task-1.1--001.md
+echo '{"type":"result","subtype":"success","is_error":false,"result":"ok","num_turns":2,"usage":{"input_tokens":10,"output_tokens":5}}'
+`)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	})
+	if err != nil {
+		t.Fatalf("run --headless error: %v\n%s", err, output)
+	}
+
+	for _, want := range []string{"exit 0", "Tokens: 15", "Task status: completed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	transcript := claude.TranscriptPath(dir, "test-plan", "1.1", 1)
+	if data, err := os.ReadFile(transcript); err != nil || !strings.Contains(string(data), `"type":"result"`) {
+		t.Errorf("expected transcript at %s, got %q (%v)", transcript, data, err)
+	}
+
+	planData, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "test-plan.md"))
+	if !strings.Contains(string(planData), "Do the thing [completed]") {
+		t.Error("plan file should be reconciled after the session")
+	}
+}
+
+func TestRunHeadless_FailureReturnsError(t *testing.T) {
+	setupTestProject(t, minimalPlanFile("pending"))
+	installFakeClaude(t, `exit 3`)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	})
+	if err == nil {
+		t.Fatal("expected error for non-zero exit")
+	}
+	if !strings.Contains(output, "✗ Session finished") || !strings.Contains(output, "exit 3") {
+		t.Errorf("expected failure summary, got:\n%s", output)
+	}
+}
+
+func TestRunHeadless_ClaimsTask(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	installFakeClaude(t, `cat >/dev/null
+grep -q "\"id\": \"$ETCH_CLAIM\"" .etch/locks/test-plan--task-1.1.json && echo held > claim-seen
+`)
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+
+	other, err := claim.Acquire(dir, "test-plan", "1.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "task test-plan#1.1 is claimed") {
+		t.Fatalf("expected run to be refused while the task is claimed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "claim-seen")); err == nil {
+		t.Fatal("agent ran on a claimed task")
+	}
+
+	other.Release()
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	})
+	if err != nil {
+		t.Fatalf("run --headless error: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "claim-seen")); err != nil {
+		t.Error("agent did not run under the task's claim")
+	}
+	if claims, _ := claim.List(dir); len(claims) != 0 {
+		t.Errorf("claim not released after the session: %+v", claims)
+	}
+}
+
+// writeAgentScript writes a shell script to a temp dir and configures it as
+// the project's agent backend, receiving its prompt as described by mode.
+func writeAgentScript(t *testing.T, dir, script, mode string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("script agents require a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "agent.sh")
+	os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755)
+	cfg := fmt.Sprintf("[agent]\nbackend = \"script\"\n\n[agents.script]\ncommand = %q\nprompt = %q\n", path, mode)
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte(cfg), 0o644)
+}
+
+func TestRunHeadless_CustomAgent(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	writeAgentScript(t, dir, `grep -c 'Task 1.1' "$1"
+sed -i.bak 's/^status: pending/status: completed/' .etch/progress/test-plan--task-1.1--001.md
+`, "file")
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	})
+	if err != nil {
+		t.Fatalf("run --headless error: %v\n%s", err, output)
+	}
+	for _, want := range []string{"headless with script", "exit 0", "Task status: completed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+func TestRun_UnknownAgent(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte("[agent]\nbackend = \"aider\"\n"), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	err := app.Run([]string{"etch", "run", "-p", "test-plan", "-t", "1", "--headless"})
+	if err == nil || !strings.Contains(err.Error(), `unknown agent backend "aider"`) {
+		t.Fatalf("expected unknown backend error, got %v", err)
+	}
+}
+
+func TestRunHeadless_RejectsFeature(t *testing.T) {
+	setupTestProject(t, minimalPlanFile("pending"))
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	err := app.Run([]string{"etch", "run", "-f", "1", "--headless"})
+	if err == nil || !strings.Contains(err.Error(), "--headless does not support --feature") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestRun_WorktreeIsolatesTask(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "swarm", "-t", "1.1", "--headless", "--worktree"})
+	})
+	if err != nil {
+		t.Fatalf("run --worktree error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "branch etch/swarm/task-1.1") {
+		t.Errorf("expected worktree branch in output:\n%s", output)
+	}
+
+	wt := worktree.Path(dir, "swarm", "1.1")
+	if _, err := os.Stat(filepath.Join(wt, "task-1.1.txt")); err != nil {
+		t.Errorf("expected session to run in the worktree: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "task-1.1.txt")); err == nil {
+		t.Error("session should not write to the project root")
+	}
+	prog, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "swarm--task-1.1--001.md"))
+	if !strings.Contains(string(prog), "agent: claude\nbranch: etch/swarm/task-1.1") {
+		t.Errorf("expected branch recorded in progress file:\n%s", prog)
+	}
+
+	// A later session for the same task reuses the worktree.
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "swarm", "-t", "1.1", "--headless", "--worktree"})
+	})
+	if err != nil {
+		t.Fatalf("second run --worktree error: %v\n%s", err, output)
+	}
+}
+
+func TestRun_WorktreeFromConfig(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte("[run]\nworktree = true\n"), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "run", "-p", "swarm", "-t", "1.1", "--headless"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if _, err := os.Stat(worktree.Path(dir, "swarm", "1.1")); err != nil {
+		t.Errorf("expected [run] worktree to create a worktree: %v", err)
+	}
+
+	// --worktree=false overrides the config.
+	captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "run", "-p", "swarm", "-t", "1.2", "--headless", "--worktree=false"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if _, err := os.Stat(worktree.Path(dir, "swarm", "1.2")); err == nil {
+		t.Error("expected --worktree=false to run in the project root")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "task-1.2.txt")); err != nil {
+		t.Errorf("expected session to run in the project root: %v", err)
+	}
+}
+
+func TestRun_WorktreeRejectsFeature(t *testing.T) {
+	setupTestProject(t, minimalPlanFile("pending"))
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	err := app.Run([]string{"etch", "run", "-f", "1", "--worktree"})
+	if err == nil || !strings.Contains(err.Error(), "--worktree does not support --feature") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestRun_RecordsSessionDiff(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+	writePlan(t, dir, "swarm", strings.Replace(swarmPlan, "Do first.", "**Files:** src/\nDo first.", 1))
+	// Work in progress from before the session is neither reported nor
+	// committed with it.
+	os.WriteFile(filepath.Join(dir, "wip.txt"), []byte("mine\n"), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{runCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "run", "-p", "swarm", "-t", "1.1", "--headless", "--commit"})
+	})
+	if err != nil {
+		t.Fatalf("run error: %v\n%s", err, output)
+	}
+
+	for _, want := range []string{"Changed files: 1", "Outside the task's files: task-1.1.txt", "Committed: swarm: task 1.1 — First"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	prog, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "swarm--task-1.1--001.md"))
+	if !strings.Contains(string(prog), "base_commit: ") {
+		t.Errorf("expected base commit in progress header:\n%s", prog)
+	}
+	if !strings.Contains(string(prog), "## Changes Made\n<!-- List files created or modified -->\n- task-1.1.txt (outside task scope)\n") {
+		t.Errorf("expected changed file in Changes Made:\n%s", prog)
+	}
+
+	out, _ := exec.Command("git", "-C", dir, "log", "-1", "--format=%s").Output()
+	if strings.TrimSpace(string(out)) != "swarm: task 1.1 — First" {
+		t.Errorf("expected session commit, got %q", out)
+	}
+	if out, _ := exec.Command("git", "-C", dir, "status", "--porcelain").Output(); strings.TrimSpace(string(out)) != "?? wip.txt" {
+		t.Errorf("expected only the earlier work left uncommitted, got %q", out)
+	}
+}
+
+func TestSessionDiff_SkipsListedChanges(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+	plans, _ := etchcontext.DiscoverPlans(dir)
+	plan := plans[0]
+	task := plan.TaskByID("1.1")
+	result, err := etchcontext.Assemble(dir, plan, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := startSessionDiff(dir, plan, task, result.ProgressPath)
+	if err != nil || diff == nil {
+		t.Fatalf("startSessionDiff = %v, %v", diff, err)
+	}
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"), 0o644)
+	progress.AppendToSection(result.ProgressPath, "Changes Made", "- a.go (created) — the agent listed this one")
+
+	sc, err := diff.finish(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Files) != 2 || sc.Committed != "" {
+		t.Errorf("unexpected changes: %+v", sc)
+	}
+	prog, _ := os.ReadFile(result.ProgressPath)
+	if strings.Count(string(prog), "a.go") != 1 || !strings.Contains(string(prog), "- b.go\n") {
+		t.Errorf("expected only b.go to be appended:\n%s", prog)
+	}
+}
+
+func TestStartSessionDiff_NotARepo(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	diff, err := startSessionDiff(dir, nil, nil, "")
+	if err != nil || diff != nil {
+		t.Fatalf("expected no tracking outside git, got %v, %v", diff, err)
+	}
+	if _, err := diff.finish(true); err != nil {
+		t.Errorf("finish on nil diff: %v", err)
+	}
+}
+
+func TestFormatHeadlessResult(t *testing.T) {
+	out := formatHeadlessResult(agent.Result{
+		Duration: 90 * time.Second,
+		TimedOut: true,
+		ExitCode: -1,
+		CostUSD:  1.5,
+	})
+	if !strings.Contains(out, "✗ Session finished in 1m30s (exit -1)") || !strings.Contains(out, "Cost:   $1.50") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+}
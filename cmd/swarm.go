@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gsigler/etch/internal/agent"
+	"github.com/gsigler/etch/internal/claim"
+	"github.com/gsigler/etch/internal/claude"
+	"github.com/gsigler/etch/internal/config"
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/swarm"
+	"github.com/gsigler/etch/internal/worktree"
+	"github.com/urfave/cli/v2"
+)
+
+func swarmCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "swarm",
+		Usage: "Run a plan's runnable tasks in parallel headless agent sessions",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "plan",
+				Aliases: []string{"p"},
+				Usage:   "plan slug",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   2,
+				Usage:   "maximum number of concurrent sessions",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: defaultHeadlessTimeout,
+				Usage: "kill a session that runs longer than this (0 for no limit)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			if c.Int("jobs") < 1 {
+				return etcherr.Usage("--jobs must be at least 1")
+			}
+			if !worktree.IsRepo(rootDir) {
+				return etcherr.Project("etch swarm requires a git repository").
+					WithHint("each task runs in its own git worktree — run 'git init' and commit first")
+			}
+
+			cfg, err := config.Load(rootDir)
+			if err != nil {
+				return err
+			}
+			a, err := agentFor(cfg)
+			if err != nil {
+				return err
+			}
+
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+			plan, err := selectPlan(plans, c.String("plan"))
+			if err != nil {
+				return err
+			}
+
+			// Sessions run in worktrees; point their etch commands back at
+			// this project so progress lands in one place.
+			os.Setenv(rootEnvVar, rootDir)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			sess := newSwarmSession(rootDir, plan, a, c.Duration("timeout"), cfg.Run.ClaimTTL)
+			return runSwarm(ctx, rootDir, plan, c.Int("jobs"), sess.launch, sess.describe)
+		},
+	}
+}
+
+// runSwarm drives the scheduler for plan and prints a summary. describe
+// returns extra detail (branch, transcript) to show for a task; it may be nil.
+func runSwarm(ctx context.Context, rootDir string, plan *models.Plan, jobs int, launch swarm.Launcher, describe func(taskID string) string) error {
+	fmt.Printf("Swarming %s with up to %d session(s)\n\n", plan.Slug, jobs)
+
+	res, err := swarm.Run(ctx, swarm.Options{
+		Jobs:   jobs,
+		Launch: launch,
+		Reconcile: func() (status.PlanStatus, error) {
+			plans, _, err := status.Sync(rootDir, plan.Slug, false)
+			if err != nil {
+				return status.PlanStatus{}, err
+			}
+			if len(plans) == 0 {
+				return status.PlanStatus{}, etcherr.Project(fmt.Sprintf("plan not found: %s", plan.Slug))
+			}
+			return plans[0], nil
+		},
+		Out: os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Sessions:")
+	if len(res.Outcomes) == 0 {
+		fmt.Println("  none — no runnable tasks")
+	}
+	for _, o := range res.Outcomes {
+		fmt.Printf("  %s Task %s (%s, %s)\n", o.Status.Icon(), o.TaskID, o.Status, o.Duration.Round(time.Second))
+		if describe != nil {
+			if d := describe(o.TaskID); d != "" {
+				fmt.Printf("      %s\n", d)
+			}
+		}
+	}
+	fmt.Printf("\n%d/%d tasks completed\n", res.Final.CompletedTasks, res.Final.TotalTasks)
+
+	switch {
+	case res.Failed != nil:
+		return etcherr.Project(fmt.Sprintf("task %s did not complete: %v", res.Failed.TaskID, res.Failed.Err)).
+			WithHint("check the transcript in " + claude.TranscriptsDir + ", then fix and re-run 'etch swarm' or 'etch run -t " + res.Failed.TaskID + "'")
+	case ctx.Err() != nil:
+		return etcherr.Usage("swarm interrupted")
+	case res.PlanDone:
+		fmt.Println("✓ Plan complete. Task branches are ready to merge.")
+	default:
+		fmt.Println("No more runnable tasks — remaining tasks are in progress, claimed, blocked, or failed.")
+		fmt.Println("Run 'etch status " + plan.Slug + "' for details.")
+	}
+	return nil
+}
+
+// swarmSession launches each task as a headless agent session in its own
+// git worktree. A task's worktree starts from the branches of its completed
+// dependencies, whether they finished in this swarm or an earlier one, so
+// dependent work builds on top of them.
+type swarmSession struct {
+	rootDir  string
+	plan     *models.Plan
+	agent    agent.Agent
+	timeout  time.Duration
+	claimTTL time.Duration // how long a task's claim lasts without renewal
+
+	mu      sync.Mutex
+	results map[string]agent.Result // task ID → how its session ended
+}
+
+func newSwarmSession(rootDir string, plan *models.Plan, a agent.Agent, timeout, claimTTL time.Duration) *swarmSession {
+	return &swarmSession{
+		rootDir:  rootDir,
+		plan:     plan,
+		agent:    a,
+		timeout:  timeout,
+		claimTTL: claimTTL,
+		results:  make(map[string]agent.Result),
+	}
+}
+
+func (s *swarmSession) launch(ctx context.Context, taskID string) error {
+	task := s.plan.TaskByID(taskID)
+	if task == nil {
+		return etcherr.Project(fmt.Sprintf("task %s not found in plan %s", taskID, s.plan.Slug))
+	}
+
+	// Claim the task so no other run or swarm starts it as well.
+	cl, err := claim.Acquire(s.rootDir, s.plan.Slug, taskID, s.claimTTL)
+	if errors.Is(err, claim.ErrHeld) {
+		// Someone else is on it; leave it to them.
+		return swarm.Skip(cl.HeldError())
+	}
+	if err != nil {
+		return err
+	}
+	defer cl.Release()
+	defer cl.Keep(s.claimTTL, config.ClaimRenewInterval)()
+
+	result, err := etchcontext.Assemble(s.rootDir, s.plan, task)
+	if err != nil {
+		return err
+	}
+	prompt, err := os.ReadFile(result.ContextPath)
+	if err != nil {
+		return etcherr.WrapIO("reading context file", err)
+	}
+
+	wtPath := worktree.Path(s.rootDir, s.plan.Slug, taskID)
+	branch := worktree.BranchName(s.plan.Slug, taskID)
+	// A task launched again, after a failed or interrupted session or
+	// 'etch recover', picks up the worktree and branch it left behind.
+	base, merges := s.baseFor(task)
+	if _, err := worktree.Ensure(s.rootDir, wtPath, branch, base); err != nil {
+		return err
+	}
+	if err := progress.SetField(result.ProgressPath, "branch", branch); err != nil {
+		return etcherr.WrapIO("recording worktree branch", err)
+	}
+	if err := progress.SetField(result.ProgressPath, "agent", s.agent.Name()); err != nil {
+		return etcherr.WrapIO("recording session agent", err)
+	}
+	for _, b := range merges {
+		if err := worktree.Merge(wtPath, b); err != nil {
+			return err
+		}
+	}
+
+	diff, err := startSessionDiff(wtPath, s.plan, task, result.ProgressPath)
+	if err != nil {
+		return err
+	}
+
+	if err := progress.RecordStart(result.ProgressPath); err != nil {
+		return etcherr.WrapIO("recording session start", err)
+	}
+	stopHeartbeat := progress.KeepAlive(result.ProgressPath, progress.HeartbeatInterval)
+	res, err := s.agent.Headless(ctx, string(prompt), agent.HeadlessOptions{
+		WorkDir:        wtPath,
+		TranscriptPath: claude.TranscriptPath(s.rootDir, s.plan.Slug, taskID, result.SessionNum),
+		Timeout:        s.timeout,
+		Env: []string{
+			events.ActorEnvVar + "=" + events.AgentActor(s.plan.Slug, taskID, result.SessionNum),
+			claim.EnvVar + "=" + cl.ID,
+		},
+	})
+	stopHeartbeat()
+	if endErr := progress.RecordEnd(result.ProgressPath); endErr != nil && err == nil {
+		err = etcherr.WrapIO("recording session end", endErr)
+	}
+	s.mu.Lock()
+	s.results[taskID] = res
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	_, err = diff.finish(true)
+	return err
+}
+
+// baseFor picks the starting point for a task's worktree: the branch of its
+// first dependency, plus the dependency branches to merge in. All of them are
+// merged, since a reused worktree or branch may not have started from the
+// first. The swarm only launches tasks whose dependencies are complete, and
+// their work stays on their task branches until merged, so each dependency
+// with a branch contributes it. Dependencies without one, such as tasks
+// completed outside a worktree, are assumed to be on HEAD.
+func (s *swarmSession) baseFor(task *models.Task) (string, []string) {
+	singleFeature := len(s.plan.Features) == 1
+	var depBranches []string
+	for _, dep := range task.DependsOn {
+		depID := status.DependencyID(dep, singleFeature)
+		if depID == "" {
+			continue
+		}
+		if b := worktree.BranchName(s.plan.Slug, depID); worktree.BranchExists(s.rootDir, b) {
+			depBranches = append(depBranches, b)
+		}
+	}
+	if len(depBranches) == 0 {
+		return "HEAD", nil
+	}
+	return depBranches[0], depBranches
+}
+
+// describe returns the worktree branch, transcript path, and token usage
+// for a task.
+func (s *swarmSession) describe(taskID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rel := func(p string) string {
+		if r, err := filepath.Rel(s.rootDir, p); err == nil {
+			return r
+		}
+		return p
+	}
+	d := "branch " + worktree.BranchName(s.plan.Slug, taskID)
+	if res, ok := s.results[taskID]; ok {
+		d += ", transcript " + rel(res.TranscriptPath)
+		if res.Usage != nil {
+			d += fmt.Sprintf(", %d tokens", res.Usage.Total())
+		}
+	}
+	return d
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/agent"
+	"github.com/gsigler/etch/internal/claim"
+	etchcontext "github.com/gsigler/etch/internal/context"
+	"github.com/gsigler/etch/internal/worktree"
+)
+
+const swarmPlan = `# Plan: Swarm
+
+## Feature 1: Core
+
+### Task 1.1: First [pending]
+Do first.
+
+### Task 1.2: Second [pending]
+**Depends on:** Task 1.1
+Do second.
+`
+
+// setupSwarmRepo creates an etch project inside a git repository with one
+// commit, and puts a fake claude on PATH that completes its task's progress
+// file and writes a file into its worktree.
+func setupSwarmRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not on PATH")
+	}
+
+	dir := setupEtchProject(t)
+	writePlan(t, dir, "swarm", swarmPlan)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".etch/\n"), 0o644)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	installFakeClaude(t, `task=$(grep -o '^## Your Task: Task [0-9.]*' | sed 's/.*Task //')
+echo "working on $task"
+echo "$task" > "task-$task.txt"
+sed -i.bak 's/^status: pending/status: completed/' "$ETCH_ROOT"/.etch/progress/swarm--task-$task--*.md
+`)
+	t.Setenv(rootEnvVar, dir)
+	return dir
+}
+
+// completingAgent returns an agent that behaves like the fake claude from
+// setupSwarmRepo, without a subprocess.
+func completingAgent(dir string) *agent.Fake {
+	taskRe := regexp.MustCompile(`## Your Task: Task ([0-9.]+)`)
+	return &agent.Fake{Func: func(c agent.Call) error {
+		task := taskRe.FindStringSubmatch(c.Prompt)[1]
+		os.WriteFile(filepath.Join(c.WorkDir, "task-"+task+".txt"), []byte(task), 0o644)
+		progress, _ := filepath.Glob(filepath.Join(dir, ".etch", "progress", "swarm--task-"+task+"--*.md"))
+		for _, p := range progress {
+			data, _ := os.ReadFile(p)
+			os.WriteFile(p, []byte(strings.Replace(string(data), "status: pending", "status: completed", 1)), 0o644)
+		}
+		return nil
+	}}
+}
+
+func TestRunSwarm_CompletesPlanInWorktrees(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+
+	plans, err := etchcontext.DiscoverPlans(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := plans[0]
+	fake := completingAgent(dir)
+	sess := newSwarmSession(dir, plan, fake, time.Minute, time.Minute)
+
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runSwarm(context.Background(), dir, plan, 2, sess.launch, sess.describe)
+	})
+	if runErr != nil {
+		t.Fatalf("runSwarm: %v\n%s", runErr, output)
+	}
+	if !strings.Contains(output, "2/2 tasks completed") || !strings.Contains(output, "Plan complete") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 || !calls[0].Headless || calls[1].WorkDir != worktree.Path(dir, "swarm", "1.2") {
+		t.Errorf("unexpected agent sessions: %+v", calls)
+	}
+
+	planData, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "swarm.md"))
+	if !strings.Contains(string(planData), "First [completed]") || !strings.Contains(string(planData), "Second [completed]") {
+		t.Errorf("expected both tasks completed in plan file:\n%s", planData)
+	}
+
+	// Task 1.2 started from 1.1's branch, so its worktree has both files.
+	wt := worktree.Path(dir, "swarm", "1.2")
+	for _, f := range []string{"task-1.1.txt", "task-1.2.txt"} {
+		if _, err := os.Stat(filepath.Join(wt, f)); err != nil {
+			t.Errorf("expected %s in task 1.2 worktree: %v", f, err)
+		}
+	}
+
+	prog, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "swarm--task-1.2--001.md"))
+	if !strings.Contains(string(prog), "branch: etch/swarm/task-1.2") {
+		t.Errorf("expected branch recorded in progress file:\n%s", prog)
+	}
+}
+
+func TestRunSwarm_ReusesExistingWorktrees(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+	plans, _ := etchcontext.DiscoverPlans(dir)
+
+	// An earlier swarm left task 1.1's worktree behind, and task 1.2's
+	// branch without its worktree.
+	if err := worktree.Add(dir, worktree.Path(dir, "swarm", "1.1"), worktree.BranchName("swarm", "1.1"), "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	wt := worktree.Path(dir, "swarm", "1.2")
+	if err := worktree.Add(dir, wt, worktree.BranchName("swarm", "1.2"), "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", dir, "worktree", "remove", wt).CombinedOutput(); err != nil {
+		t.Fatalf("git worktree remove: %v\n%s", err, out)
+	}
+
+	sess := newSwarmSession(dir, plans[0], completingAgent(dir), time.Minute, time.Minute)
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runSwarm(context.Background(), dir, plans[0], 2, sess.launch, sess.describe)
+	})
+	if runErr != nil {
+		t.Fatalf("runSwarm: %v\n%s", runErr, output)
+	}
+	if !strings.Contains(output, "2/2 tasks completed") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	// Task 1.2's old branch didn't start from 1.1's, so it was merged in.
+	for _, f := range []string{"task-1.1.txt", "task-1.2.txt"} {
+		if _, err := os.Stat(filepath.Join(wt, f)); err != nil {
+			t.Errorf("expected %s in task 1.2 worktree: %v", f, err)
+		}
+	}
+}
+
+func TestRunSwarm_BuildsOnEarlierSwarmBranches(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+
+	// An earlier swarm completed task 1.1 on its branch, which hasn't been
+	// merged yet.
+	wt := worktree.Path(dir, "swarm", "1.1")
+	if err := worktree.Add(dir, wt, worktree.BranchName("swarm", "1.1"), "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(wt, "task-1.1.txt"), []byte("1.1"), 0o644)
+	if _, err := worktree.CommitFiles(wt, "task 1.1", []string{"task-1.1.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	writePlan(t, dir, "swarm", strings.Replace(swarmPlan, "First [pending]", "First [completed]", 1))
+	plans, _ := etchcontext.DiscoverPlans(dir)
+
+	fake := completingAgent(dir)
+	sess := newSwarmSession(dir, plans[0], fake, time.Minute, time.Minute)
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runSwarm(context.Background(), dir, plans[0], 2, sess.launch, sess.describe)
+	})
+	if runErr != nil {
+		t.Fatalf("runSwarm: %v\n%s", runErr, output)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Errorf("expected only task 1.2 to run, got %+v", calls)
+	}
+	if _, err := os.Stat(filepath.Join(worktree.Path(dir, "swarm", "1.2"), "task-1.1.txt")); err != nil {
+		t.Errorf("task 1.2 did not start from task 1.1's branch: %v", err)
+	}
+}
+
+func TestRunSwarm_StopsOnFailure(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+
+	plans, _ := etchcontext.DiscoverPlans(dir)
+	launched := 0
+	fail := func(ctx context.Context, taskID string) error {
+		launched++
+		return os.ErrDeadlineExceeded
+	}
+
+	var runErr error
+	captureStdout(t, func() {
+		runErr = runSwarm(context.Background(), dir, plans[0], 2, fail, nil)
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "task 1.1 did not complete") {
+		t.Fatalf("expected failure for task 1.1, got %v", runErr)
+	}
+	if launched != 1 {
+		t.Errorf("expected dependent task not to launch, launched %d", launched)
+	}
+}
+
+func TestRunSwarm_SkipsClaimedTask(t *testing.T) {
+	dir := setupSwarmRepo(t)
+	chdirTo(t, dir)
+	plans, _ := etchcontext.DiscoverPlans(dir)
+
+	// Another 'etch run' holds task 1.1.
+	if _, err := claim.Acquire(dir, "swarm", "1.1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	fake := &agent.Fake{}
+	sess := newSwarmSession(dir, plans[0], fake, time.Minute, time.Minute)
+
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runSwarm(context.Background(), dir, plans[0], 2, sess.launch, sess.describe)
+	})
+	if runErr != nil {
+		t.Fatalf("expected the claimed task to be skipped, not failed: %v\n%s", runErr, output)
+	}
+	if !strings.Contains(output, "skipped task 1.1: task swarm#1.1 is claimed") || !strings.Contains(output, "No more runnable tasks") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("agent launched for a claimed task: %+v", calls)
+	}
+}
+
+func TestFindProjectRoot_EtchRootOverride(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, t.TempDir())
+	t.Setenv(rootEnvVar, dir)
+
+	got, err := findProjectRoot()
+	if err != nil || got != dir {
+		t.Fatalf("findProjectRoot = %q, %v; want %q", got, err, dir)
+	}
+
+	t.Setenv(rootEnvVar, t.TempDir())
+	if _, err := findProjectRoot(); err == nil {
+		t.Error("expected error when ETCH_ROOT has no .etch directory")
+	}
+}
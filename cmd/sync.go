@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/urfave/cli/v2"
+)
+
+func syncCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "sync",
+		Usage:     "Write task status and criteria from progress files into plans",
+		ArgsUsage: "[plan-slug]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "show the lines that would change without writing them",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			slug := c.Args().First()
+			dryRun := c.Bool("dry-run")
+			plans, changes, err := status.Sync(rootDir, slug, dryRun)
+			if err != nil {
+				return err
+			}
+			if slug != "" && len(plans) == 0 {
+				return etcherr.Project(fmt.Sprintf("plan %q not found", slug)).
+					WithHint("run 'etch list' to see available plans")
+			}
+			fmt.Print(formatChanges(rootDir, changes, dryRun))
+			return nil
+		},
+	}
+}
+
+// formatChanges renders the lines sync changed, or would change with
+// dryRun, grouped by file.
+func formatChanges(rootDir string, changes []status.Change, dryRun bool) string {
+	if len(changes) == 0 {
+		return "Plans are in sync with their progress files.\n"
+	}
+
+	var b strings.Builder
+	file := ""
+	files := 0
+	for _, c := range changes {
+		if c.File != file {
+			if file != "" {
+				b.WriteString("\n")
+			}
+			file = c.File
+			files++
+			rel, err := filepath.Rel(rootDir, file)
+			if err != nil {
+				rel = file
+			}
+			b.WriteString(rel + "\n")
+		}
+		if c.Before != "" || c.After == "" {
+			fmt.Fprintf(&b, "%5d - %s\n", c.Line, c.Before)
+		}
+		if c.After != "" || c.Before == "" {
+			fmt.Fprintf(&b, "      + %s\n", c.After)
+		}
+	}
+
+	verb := "Changed"
+	if dryRun {
+		verb = "Would change"
+	}
+	fmt.Fprintf(&b, "\n%s %d %s in %d %s.\n", verb, len(changes), status.Plural(len(changes), "line", "lines"), files, status.Plural(files, "plan", "plans"))
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestSync_DryRunThenWrite(t *testing.T) {
+	dir := setupTestProject(t, minimalPlanFile("pending"))
+	t.Setenv("ETCH_ACTOR", "human:tester")
+	planPath := filepath.Join(dir, ".etch", "plans", "test-plan.md")
+	progressPath := progress.SessionPath(dir, "test-plan", "1.1", 1)
+	os.WriteFile(progressPath, []byte(`---
+schema: 1
+plan: test-plan
+task: "1.1"
+session: 1
+status: completed
+started: "2026-01-01 10:00"
+---
+
+# Session: Task 1.1 – Do the thing
+
+## Acceptance Criteria Updates
+- [x] Thing works
+`), 0o644)
+
+	app := &cli.App{Commands: []*cli.Command{syncCmd()}}
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "sync", "--dry-run"})
+	})
+	if err != nil {
+		t.Fatalf("sync --dry-run error: %v", err)
+	}
+	for _, want := range []string{
+		filepath.Join(".etch", "plans", "test-plan.md"),
+		"- ### Task 1: Do the thing [pending]",
+		"+ ### Task 1: Do the thing [completed]",
+		"+ - [x] Thing works",
+		"+ # Plan: Test Plan [completed]",
+		"Would change 3 lines in 1 plan.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in dry-run output:\n%s", want, output)
+		}
+	}
+	if data, _ := os.ReadFile(planPath); string(data) != minimalPlanFile("pending") {
+		t.Errorf("dry run changed the plan file:\n%s", data)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "sync"})
+	})
+	if err != nil {
+		t.Fatalf("sync error: %v", err)
+	}
+	if !strings.Contains(output, "Changed 3 lines in 1 plan.") {
+		t.Errorf("unexpected sync output:\n%s", output)
+	}
+	data, _ := os.ReadFile(planPath)
+	if !strings.Contains(string(data), "Do the thing [completed]") || !strings.Contains(string(data), "- [x] Thing works") {
+		t.Errorf("sync didn't write the plan file:\n%s", data)
+	}
+
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "sync"})
+	})
+	if err != nil || !strings.Contains(output, "in sync") {
+		t.Errorf("second sync: err %v, output %q", err, output)
+	}
+}
+
+func TestSync_UnknownPlan(t *testing.T) {
+	setupTestProject(t, minimalPlanFile("pending"))
+	app := &cli.App{Commands: []*cli.Command{syncCmd()}}
+	captureStdout(t, func() {
+		if err := app.Run([]string{"etch", "sync", "nope"}); err == nil {
+			t.Error("expected an error for an unknown plan")
+		}
+	})
+}
+
+func TestFormatChanges(t *testing.T) {
+	root := filepath.FromSlash("/p")
+	changes := []status.Change{
+		{Plan: "a", File: filepath.Join(root, "a.md"), Line: 3, Before: "old", After: "new"},
+		{Plan: "b", File: filepath.Join(root, "b.md"), Line: 9, Before: "gone"},
+	}
+	got := formatChanges(root, changes, false)
+	want := "a.md\n    3 - old\n      + new\n\nb.md\n    9 - gone\n\nChanged 2 lines in 2 plans.\n"
+	if got != want {
+		t.Errorf("formatChanges =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/validate"
+	"github.com/urfave/cli/v2"
+)
+
+func validateCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "Check plan files for structural problems",
+		ArgsUsage: "[plan-slug]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "output in JSON format",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+			return runValidate(rootDir, c.Args().First(), c.Bool("json"))
+		},
+	}
+}
+
+func runValidate(rootDir, slug string, asJSON bool) error {
+	paths, err := planPathsForValidation(rootDir, slug)
+	if err != nil {
+		return err
+	}
+
+	var reports []validate.Report
+	for _, path := range paths {
+		r, err := validate.File(path)
+		if err != nil {
+			return etcherr.WrapIO(fmt.Sprintf("validating %s", filepath.Base(path)), err)
+		}
+		reports = append(reports, r)
+	}
+
+	if asJSON {
+		out, err := validate.FormatJSON(reports)
+		if err != nil {
+			return etcherr.WrapIO("formatting JSON output", err)
+		}
+		fmt.Println(out)
+	} else {
+		fmt.Print(validate.FormatText(reports, rootDir))
+	}
+
+	errCount := 0
+	for _, r := range reports {
+		errCount += r.Errors()
+	}
+	if errCount > 0 {
+		return etcherr.Parse(fmt.Sprintf("%d validation error(s) found", errCount)).
+			WithHint("fix the reported lines, or run 'etch replan' to regenerate the affected tasks")
+	}
+	return nil
+}
+
+// planPathsForValidation returns the plan file for slug, or every plan file
+// when slug is empty. Unlike DiscoverPlans it includes files that fail to
+// parse, since those are exactly the ones worth validating.
+func planPathsForValidation(rootDir, slug string) ([]string, error) {
+	plansDir := filepath.Join(rootDir, ".etch", "plans")
+	if slug != "" {
+		path := filepath.Join(plansDir, slug+".md")
+		if _, err := os.Stat(path); err != nil {
+			return nil, etcherr.Project(fmt.Sprintf("plan not found: %s", slug)).
+				WithHint("run 'etch list' to see available plans")
+		}
+		return []string{path}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(plansDir, "*.md"))
+	if err != nil {
+		return nil, etcherr.WrapIO("globbing plans", err)
+	}
+	if len(paths) == 0 {
+		return nil, etcherr.Project("no plan files found").
+			WithHint("run 'etch plan <description>' to create one")
+	}
+	return paths, nil
+}
+
+// reportValidation validates a freshly written plan and prints any
+// diagnostics. It never fails the calling command: problems are surfaced as
+// warnings so the user can fix them with 'etch open' or 'etch replan'.
+func reportValidation(rootDir, planPath string) {
+	r, err := validate.File(planPath)
+	if err != nil || len(r.Diagnostics) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Validation found %d error(s) and %d warning(s):\n", r.Errors(), r.Warnings())
+	text := validate.FormatText([]validate.Report{r}, rootDir)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Println("  " + line)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const planWithDanglingDep = `# Plan: Broken
+
+### Task 1: First [pending]
+**Complexity:** small
+
+**Acceptance Criteria:**
+- [ ] Works
+
+### Task 2: Second [pending]
+**Complexity:** small
+**Depends on:** Task 7
+
+**Acceptance Criteria:**
+- [ ] Works
+`
+
+func TestRunValidate_ReportsErrors(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, dir)
+	writePlan(t, dir, "broken", planWithDanglingDep)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runValidate(dir, "broken", false)
+	})
+
+	if err == nil {
+		t.Fatal("expected error for plan with a dangling dependency")
+	}
+	if !strings.Contains(output, ".etch/plans/broken.md:11: error: task 1.2 depends on unknown task 1.7") {
+		t.Errorf("expected line-numbered diagnostic, got:\n%s", output)
+	}
+}
+
+func TestRunValidate_JSON(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, dir)
+	writePlan(t, dir, "broken", planWithDanglingDep)
+	writePlan(t, dir, "ok", strings.Replace(planWithDanglingDep, "Task 7", "Task 1", 1))
+
+	output := captureStdout(t, func() {
+		runValidate(dir, "", true)
+	})
+
+	var reports []struct {
+		Slug        string `json:"slug"`
+		Diagnostics []struct {
+			Line     int    `json:"line"`
+			Severity string `json:"severity"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(output), &reports); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, output)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	for _, r := range reports {
+		switch r.Slug {
+		case "broken":
+			if len(r.Diagnostics) != 1 || r.Diagnostics[0].Line != 11 || r.Diagnostics[0].Severity != "error" {
+				t.Errorf("unexpected diagnostics for broken: %+v", r.Diagnostics)
+			}
+		case "ok":
+			if len(r.Diagnostics) != 0 {
+				t.Errorf("expected no diagnostics for ok, got %+v", r.Diagnostics)
+			}
+		}
+	}
+}
+
+func TestRunValidate_UnknownPlan(t *testing.T) {
+	dir := setupEtchProject(t)
+	chdirTo(t, dir)
+
+	if err := runValidate(dir, "missing", false); err == nil {
+		t.Fatal("expected error for unknown plan")
+	}
+}
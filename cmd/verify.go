@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/transition"
+	"github.com/gsigler/etch/internal/verify"
+	"github.com/urfave/cli/v2"
+)
+
+func verifyCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "Run a task's acceptance criteria verify commands and record the results",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "plan",
+				Aliases: []string{"p"},
+				Usage:   "plan slug",
+			},
+			&cli.StringFlag{
+				Name:     "task",
+				Aliases:  []string{"t"},
+				Usage:    "task ID (e.g. 1.2)",
+				Required: true,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: transition.VerifyTimeout,
+				Usage: "fail a verify command that runs longer than this (0 for no limit)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			rootDir, err := findProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			plans, err := etchcontext.DiscoverPlans(rootDir)
+			if err != nil {
+				return err
+			}
+			plan, task, err := etchcontext.ResolveTask(plans, c.String("plan"), c.String("task"), rootDir)
+			if err != nil {
+				return err
+			}
+			if !verify.Verifiable(task) {
+				return etcherr.Usage(fmt.Sprintf("task %s has no verifiable criteria", task.FullID())).
+					WithHint("add a command to a criterion, e.g. - [ ] Tests pass `verify: go test ./...`")
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			fmt.Printf("Verifying Task %s — %s\n", task.FullID(), task.Title)
+			results, err := transition.Verify(ctx, rootDir, plan, task, c.Duration("timeout"))
+			if err != nil {
+				return err
+			}
+			fmt.Print(transition.FormatVerifyResults(results))
+			return transition.VerifyFailures(task, results)
+		},
+	}
+}
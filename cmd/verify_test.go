@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+const verifyPlan = `# Plan: Test Plan
+
+### Task 1: Do the thing [in_progress]
+**Files:** marker
+
+**Acceptance Criteria:**
+- [ ] Marker exists ` + "`verify: test -f marker`" + `
+- [ ] Reviewed by a human
+`
+
+const markerCriterion = "Marker exists `verify: test -f marker`"
+
+func setupVerifyProject(t *testing.T) (string, *cli.App) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("verify commands require a POSIX shell")
+	}
+	dir := setupTestProject(t, verifyPlan)
+	app := &cli.App{Commands: []*cli.Command{progressCmd(), verifyCmd()}}
+	if err := app.Run([]string{"etch", "progress", "start", "-p", "test-plan", "-t", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	return dir, app
+}
+
+func readPlanAndSession(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	planData, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "test-plan.md"))
+	progData, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "test-plan--task-1.1--001.md"))
+	return string(planData), string(progData)
+}
+
+func TestVerify_TicksAndUnticks(t *testing.T) {
+	dir, app := setupVerifyProject(t)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = app.Run([]string{"etch", "verify", "-t", "1"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 verifiable criteria failed") {
+		t.Fatalf("expected failure, got %v", err)
+	}
+	if !strings.Contains(output, "✗ "+markerCriterion+" (exit status 1)") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	os.WriteFile(filepath.Join(dir, "marker"), nil, 0o644)
+	output = captureStdout(t, func() {
+		err = app.Run([]string{"etch", "verify", "-t", "1"})
+	})
+	if err != nil {
+		t.Fatalf("expected verify to pass: %v\n%s", err, output)
+	}
+	plan, sess := readPlanAndSession(t, dir)
+	if !strings.Contains(plan, "- [x] "+markerCriterion) || !strings.Contains(sess, "- [x] "+markerCriterion) {
+		t.Errorf("expected criterion ticked in plan and progress file:\n%s\n%s", plan, sess)
+	}
+	if !strings.Contains(plan, "- [ ] Reviewed by a human") {
+		t.Error("criteria without verify commands should be left alone")
+	}
+
+	os.Remove(filepath.Join(dir, "marker"))
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "verify", "-t", "1"})
+	})
+	plan, sess = readPlanAndSession(t, dir)
+	if !strings.Contains(plan, "- [ ] "+markerCriterion) || !strings.Contains(sess, "- [ ] "+markerCriterion) {
+		t.Errorf("expected criterion unticked in plan and progress file:\n%s\n%s", plan, sess)
+	}
+}
+
+func TestVerify_NoVerifiableCriteria(t *testing.T) {
+	setupTestProject(t, minimalPlanFile("pending"))
+	app := &cli.App{Commands: []*cli.Command{verifyCmd()}}
+
+	err := app.Run([]string{"etch", "verify", "-t", "1"})
+	if err == nil || !strings.Contains(err.Error(), "no verifiable criteria") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestProgressDone_RefusesFailingVerify(t *testing.T) {
+	dir, app := setupVerifyProject(t)
+
+	var err error
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "done", "-p", "test-plan", "-t", "1"})
+	})
+	if err == nil {
+		t.Fatal("expected progress done to refuse while a verify command fails")
+	}
+	plan, sess := readPlanAndSession(t, dir)
+	if strings.Contains(plan, "[completed]") || strings.Contains(sess, "status: completed") {
+		t.Error("task should not be completed")
+	}
+
+	os.WriteFile(filepath.Join(dir, "marker"), nil, 0o644)
+	captureStdout(t, func() {
+		err = app.Run([]string{"etch", "progress", "done", "-p", "test-plan", "-t", "1"})
+	})
+	if err != nil {
+		t.Fatalf("expected progress done to succeed: %v", err)
+	}
+	plan, _ = readPlanAndSession(t, dir)
+	if !strings.Contains(plan, "[completed]") || !strings.Contains(plan, "- [x] "+markerCriterion) {
+		t.Errorf("expected task completed with criterion ticked:\n%s", plan)
+	}
+}
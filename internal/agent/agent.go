@@ -0,0 +1,54 @@
+// Package agent runs coding agent sessions. Claude Code is the default
+// backend; others are configured as commands in .etch/config.toml.
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gsigler/etch/internal/claude"
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// HeadlessOptions configures an unattended session.
+type HeadlessOptions = claude.HeadlessOptions
+
+// Result describes how an unattended session ended. Backends that don't
+// report turns, cost, or usage leave those fields zero.
+type Result = claude.HeadlessResult
+
+// Agent is a backend etch hands prompts to.
+type Agent interface {
+	// Name identifies the backend in messages, e.g. "claude".
+	Name() string
+	// Available returns an error explaining why the backend can't run,
+	// such as its command missing from PATH.
+	Available() error
+	// Skills reports whether the backend understands Claude Code skill
+	// invocations like "/etch-plan". Backends that don't are sent the
+	// skill's instructions instead.
+	Skills() bool
+	// Interactive runs a session attached to the user's terminal.
+	Interactive(prompt, workDir string) error
+	// Headless runs an unattended session. The result is filled in even
+	// when an error is returned.
+	Headless(ctx context.Context, prompt string, opts HeadlessOptions) (Result, error)
+}
+
+// New returns the backend selected by [agent] backend in cfg. A command
+// defined under [agents] takes precedence over a built-in of the same name.
+func New(cfg config.Config) (Agent, error) {
+	name := cfg.Agent.Backend
+	if name == "" {
+		name = config.DefaultAgent
+	}
+	if def, ok := cfg.Agents[name]; ok {
+		return NewCommand(name, def)
+	}
+	if name == "claude" {
+		return Claude{}, nil
+	}
+	return nil, etcherr.Config(fmt.Sprintf("unknown agent backend %q", name)).
+		WithHint(fmt.Sprintf("use \"claude\" or define [agents.%s] in .etch/config.toml", name))
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New(config.Config{})
+	if err != nil || a.Name() != "claude" || !a.Skills() {
+		t.Fatalf("default backend = %v, %v; want claude", a, err)
+	}
+
+	cfg := config.Config{
+		Agent:  config.AgentConfig{Backend: "aider"},
+		Agents: map[string]config.AgentCommand{"aider": {Command: "aider"}},
+	}
+	a, err = New(cfg)
+	if err != nil || a.Name() != "aider" || a.Skills() {
+		t.Fatalf("New = %v, %v; want aider command backend", a, err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"unknown backend", config.Config{Agent: config.AgentConfig{Backend: "nope"}}},
+		{"missing command", config.Config{
+			Agent:  config.AgentConfig{Backend: "x"},
+			Agents: map[string]config.AgentCommand{"x": {}},
+		}},
+		{"bad prompt mode", config.Config{
+			Agent:  config.AgentConfig{Backend: "x"},
+			Agents: map[string]config.AgentCommand{"x": {Command: "x", Prompt: "pigeon"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			var etchErr *etcherr.Error
+			if !errors.As(err, &etchErr) || etchErr.Category != etcherr.CatConfig || etchErr.Hint == "" {
+				t.Fatalf("expected config error with hint, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := &Fake{Func: func(c Call) error {
+		if c.Headless {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+
+	if err := f.Interactive("hi", "/work"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := f.Headless(context.Background(), "go", HeadlessOptions{WorkDir: "/wt"})
+	if err == nil || res.ExitCode != 1 {
+		t.Errorf("Headless = %+v, %v; want failure", res, err)
+	}
+
+	calls := f.Calls()
+	if len(calls) != 2 || calls[0] != (Call{Prompt: "hi", WorkDir: "/work"}) || calls[1] != (Call{Prompt: "go", WorkDir: "/wt", Headless: true}) {
+		t.Errorf("Calls = %+v", calls)
+	}
+}
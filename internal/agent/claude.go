@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gsigler/etch/internal/claude"
+)
+
+// Claude runs sessions with the Claude Code CLI.
+type Claude struct{}
+
+func (Claude) Name() string { return "claude" }
+
+func (Claude) Available() error { return claude.Available() }
+
+func (Claude) Skills() bool { return true }
+
+// Interactive passes skill invocations ("/etch-plan ...") as an argument,
+// since Claude Code only expands them there, and pipes anything else via
+// stdin to stay clear of argument length limits.
+func (Claude) Interactive(prompt, workDir string) error {
+	if strings.HasPrefix(prompt, "/") {
+		return claude.Run(prompt, workDir)
+	}
+	return claude.RunWithStdin(prompt, workDir)
+}
+
+func (Claude) Headless(ctx context.Context, prompt string, opts HeadlessOptions) (Result, error) {
+	return claude.Headless(ctx, prompt, opts)
+}
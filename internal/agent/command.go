@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// Ways a command backend receives its prompt.
+const (
+	PromptStdin = "stdin"
+	PromptArg   = "arg"
+	PromptFile  = "file"
+)
+
+// Placeholders replaced in a command backend's arguments.
+const (
+	promptPlaceholder     = "{prompt}"
+	promptFilePlaceholder = "{prompt_file}"
+)
+
+// Command runs sessions with a command configured under [agents.<name>],
+// such as Aider, Codex CLI, or an in-house script.
+type Command struct {
+	name string
+	def  config.AgentCommand
+}
+
+// NewCommand validates def and returns a backend that runs it.
+func NewCommand(name string, def config.AgentCommand) (*Command, error) {
+	section := "[agents." + name + "] in .etch/config.toml"
+	if strings.TrimSpace(def.Command) == "" {
+		return nil, etcherr.Config(fmt.Sprintf("agent %q has no command", name)).
+			WithHint("set command under " + section)
+	}
+	switch def.Prompt {
+	case "":
+		def.Prompt = PromptStdin
+	case PromptStdin, PromptArg, PromptFile:
+	default:
+		return nil, etcherr.Config(fmt.Sprintf("agent %q has unknown prompt mode %q", name, def.Prompt)).
+			WithHint("set prompt to \"stdin\", \"arg\", or \"file\" under " + section)
+	}
+	return &Command{name: name, def: def}, nil
+}
+
+func (c *Command) Name() string { return c.name }
+
+func (c *Command) Available() error {
+	if _, err := exec.LookPath(c.def.Command); err != nil {
+		return etcherr.Config(fmt.Sprintf("%s agent command %q not found", c.name, c.def.Command)).
+			WithHint("install it, or fix command under [agents." + c.name + "] in .etch/config.toml")
+	}
+	return nil
+}
+
+func (c *Command) Skills() bool { return false }
+
+// Interactive runs the command with the terminal attached. When the prompt
+// goes via stdin, the session can't also read from the terminal.
+func (c *Command) Interactive(prompt, workDir string) error {
+	cmd, cleanup, err := c.command(context.Background(), prompt, c.def.Args)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	cmd.Dir = workDir
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return c.execError(err, "")
+	}
+	return nil
+}
+
+// Headless runs the command without a terminal, saving its combined output
+// as the transcript. HeadlessArgs are used in place of Args when set.
+func (c *Command) Headless(ctx context.Context, prompt string, opts HeadlessOptions) (Result, error) {
+	res := Result{TranscriptPath: opts.TranscriptPath}
+
+	var transcript io.Writer = io.Discard
+	if opts.TranscriptPath != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.TranscriptPath), 0o755); err != nil {
+			return res, etcherr.WrapIO("creating transcripts directory", err)
+		}
+		f, err := os.Create(opts.TranscriptPath)
+		if err != nil {
+			return res, etcherr.WrapIO("creating transcript file", err)
+		}
+		defer f.Close()
+		transcript = f
+	}
+
+	runCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	args := c.def.Args
+	if len(c.def.HeadlessArgs) > 0 {
+		args = c.def.HeadlessArgs
+	}
+	cmd, cleanup, err := c.command(runCtx, prompt, args)
+	if err != nil {
+		return res, err
+	}
+	defer cleanup()
+
+	var tail bytes.Buffer
+	out := io.MultiWriter(transcript, &tail)
+	cmd.Dir = opts.WorkDir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	waitErr := cmd.Run()
+	res.Duration = time.Since(start)
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	res.Result = lastLine(tail.String())
+
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		res.TimedOut = true
+		return res, etcherr.New(etcherr.CatAPI, fmt.Sprintf("%s session timed out after %s", c.name, opts.Timeout)).
+			WithHint("raise the timeout with --timeout, or split the task into smaller pieces")
+	case ctx.Err() != nil:
+		return res, etcherr.Wrap(etcherr.CatIO, c.name+" session cancelled", ctx.Err())
+	case waitErr != nil:
+		return res, c.execError(waitErr, res.Result)
+	}
+	return res, nil
+}
+
+// command builds the command for a session, substituting the prompt into
+// args. The returned cleanup removes the prompt file, if one was written.
+func (c *Command) command(ctx context.Context, prompt string, args []string) (*exec.Cmd, func(), error) {
+	cleanup := func() {}
+
+	usesFile := c.def.Prompt == PromptFile
+	for _, a := range args {
+		if strings.Contains(a, promptFilePlaceholder) {
+			usesFile = true
+		}
+	}
+	var promptFile string
+	if usesFile {
+		f, err := os.CreateTemp("", "etch-prompt-*.md")
+		if err != nil {
+			return nil, cleanup, etcherr.WrapIO("creating prompt file", err)
+		}
+		promptFile = f.Name()
+		cleanup = func() { os.Remove(promptFile) }
+		_, err = f.WriteString(prompt)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			cleanup()
+			return nil, func() {}, etcherr.WrapIO("writing prompt file", err)
+		}
+	}
+
+	var argv []string
+	placedPrompt, placedFile := false, false
+	for _, a := range args {
+		placedPrompt = placedPrompt || strings.Contains(a, promptPlaceholder)
+		placedFile = placedFile || strings.Contains(a, promptFilePlaceholder)
+		a = strings.ReplaceAll(a, promptFilePlaceholder, promptFile)
+		a = strings.ReplaceAll(a, promptPlaceholder, prompt)
+		argv = append(argv, a)
+	}
+	switch c.def.Prompt {
+	case PromptArg:
+		if !placedPrompt {
+			argv = append(argv, prompt)
+		}
+	case PromptFile:
+		if !placedFile {
+			argv = append(argv, promptFile)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, c.def.Command, argv...)
+	if c.def.Prompt == PromptStdin {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+	return cmd, cleanup, nil
+}
+
+func (c *Command) execError(err error, output string) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		hint := c.name + " exited with code " + strconv.Itoa(exitErr.ExitCode())
+		if output != "" {
+			hint += ": " + output
+		}
+		return etcherr.New(etcherr.CatAPI, c.name+" session exited with non-zero status").WithHint(hint)
+	}
+	return etcherr.Wrap(etcherr.CatIO, "failed to run "+c.name, err).
+		WithHint("check that " + c.def.Command + " is installed and working")
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
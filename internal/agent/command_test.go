@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// scriptAgent writes a shell script to a temp dir and returns a command
+// backend that runs it.
+func scriptAgent(t *testing.T, script string, def config.AgentCommand) *Command {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("script agents require a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "agent.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	def.Command = path
+	c, err := NewCommand("script", def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCommand_PromptModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		def    config.AgentCommand
+	}{
+		{"stdin", `cat`, config.AgentCommand{}},
+		{"arg appended", `echo "$2"`, config.AgentCommand{Prompt: PromptArg, Args: []string{"--yes"}}},
+		{"arg placeholder", `echo "$2"`, config.AgentCommand{Prompt: PromptArg, Args: []string{"-m", "{prompt}", "--yes"}}},
+		{"file appended", `cat "$1"`, config.AgentCommand{Prompt: PromptFile}},
+		{"file placeholder", `cat "$2"`, config.AgentCommand{Prompt: PromptFile, Args: []string{"--message-file", "{prompt_file}"}}},
+		{"headless args", `cat "$1"`, config.AgentCommand{Args: []string{"--chat"}, HeadlessArgs: []string{"{prompt_file}"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := scriptAgent(t, tt.script, tt.def)
+			transcript := filepath.Join(t.TempDir(), "t", "session.log")
+			res, err := c.Headless(context.Background(), "do the task", HeadlessOptions{
+				WorkDir:        t.TempDir(),
+				TranscriptPath: transcript,
+			})
+			if err != nil {
+				t.Fatalf("Headless: %v", err)
+			}
+			if res.Result != "do the task" || res.ExitCode != 0 {
+				t.Errorf("result = %+v, want prompt echoed", res)
+			}
+			if data, _ := os.ReadFile(transcript); !strings.Contains(string(data), "do the task") {
+				t.Errorf("transcript = %q", data)
+			}
+		})
+	}
+}
+
+func TestCommand_PromptFileRemoved(t *testing.T) {
+	c := scriptAgent(t, `echo "$1"`, config.AgentCommand{Prompt: PromptFile})
+	res, err := c.Headless(context.Background(), "x", HeadlessOptions{WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(res.Result); !os.IsNotExist(err) {
+		t.Errorf("prompt file %s was not removed", res.Result)
+	}
+}
+
+func TestCommand_Failures(t *testing.T) {
+	c := scriptAgent(t, "echo bad news\nexit 3", config.AgentCommand{})
+	res, err := c.Headless(context.Background(), "x", HeadlessOptions{WorkDir: t.TempDir()})
+	var etchErr *etcherr.Error
+	if !errors.As(err, &etchErr) || !strings.Contains(etchErr.Hint, "code 3: bad news") {
+		t.Errorf("err = %v, want exit code and output in hint", err)
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", res.ExitCode)
+	}
+
+	c = scriptAgent(t, "exec sleep 5", config.AgentCommand{})
+	res, err = c.Headless(context.Background(), "x", HeadlessOptions{WorkDir: t.TempDir(), Timeout: 50 * time.Millisecond})
+	if err == nil || !res.TimedOut {
+		t.Errorf("expected timeout, got %+v, %v", res, err)
+	}
+}
+
+func TestCommand_Available(t *testing.T) {
+	c := scriptAgent(t, "true", config.AgentCommand{})
+	if err := c.Available(); err != nil {
+		t.Errorf("Available: %v", err)
+	}
+
+	missing, err := NewCommand("ghost", config.AgentCommand{Command: "etch-no-such-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := missing.Available(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Available = %v, want not found", err)
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Call records one session started on a Fake.
+type Call struct {
+	Prompt   string
+	WorkDir  string
+	Headless bool
+}
+
+// Fake is an Agent for tests. It records each session and runs Func, if
+// set, in place of a real agent. It is safe for concurrent use.
+type Fake struct {
+	// Func simulates a session; its error is returned by the session.
+	Func func(call Call) error
+	// Unavailable is returned by Available.
+	Unavailable error
+	// UsesSkills is returned by Skills.
+	UsesSkills bool
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) Available() error { return f.Unavailable }
+
+func (f *Fake) Skills() bool { return f.UsesSkills }
+
+func (f *Fake) Interactive(prompt, workDir string) error {
+	return f.run(Call{Prompt: prompt, WorkDir: workDir})
+}
+
+func (f *Fake) Headless(ctx context.Context, prompt string, opts HeadlessOptions) (Result, error) {
+	start := time.Now()
+	err := f.run(Call{Prompt: prompt, WorkDir: opts.WorkDir, Headless: true})
+	res := Result{Duration: time.Since(start), TranscriptPath: opts.TranscriptPath}
+	if err != nil {
+		res.ExitCode = 1
+	}
+	return res, err
+}
+
+// Calls returns the sessions started so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *Fake) run(c Call) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	f.mu.Unlock()
+	if f.Func != nil {
+		return f.Func(c)
+	}
+	return nil
+}
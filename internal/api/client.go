@@ -3,6 +3,7 @@ package api
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -97,7 +98,7 @@ func (c *Client) Send(system, userMessage string) (string, error) {
 		Messages:  []message{{Role: "user", Content: userMessage}},
 	}
 
-	resp, err := c.doWithRetry(body)
+	resp, err := c.doWithRetry(context.Background(), body)
 	if err != nil {
 		return "", err
 	}
@@ -114,8 +115,9 @@ func (c *Client) Send(system, userMessage string) (string, error) {
 type StreamCallback func(text string)
 
 // SendStream makes a streaming Messages API request and calls cb with each text delta.
-// Returns the full accumulated text.
-func (c *Client) SendStream(system, userMessage string, cb StreamCallback) (string, error) {
+// Returns the full accumulated text. Cancelling ctx aborts the request and
+// closes the stream.
+func (c *Client) SendStream(ctx context.Context, system, userMessage string, cb StreamCallback) (string, error) {
 	body := messagesRequest{
 		Model:     c.Model,
 		MaxTokens: c.maxTokens(),
@@ -124,7 +126,7 @@ func (c *Client) SendStream(system, userMessage string, cb StreamCallback) (stri
 		Stream:    true,
 	}
 
-	resp, err := c.doWithRetry(body)
+	resp, err := c.doWithRetry(ctx, body)
 	if err != nil {
 		return "", err
 	}
@@ -171,7 +173,7 @@ func parseSSE(r io.Reader, cb StreamCallback) (string, error) {
 	return full.String(), nil
 }
 
-func (c *Client) doWithRetry(body messagesRequest) (*http.Response, error) {
+func (c *Client) doWithRetry(ctx context.Context, body messagesRequest) (*http.Response, error) {
 	payload, err := json.Marshal(body)
 	if err != nil {
 		return nil, etcherr.WrapAPI("encoding request", err)
@@ -182,7 +184,7 @@ func (c *Client) doWithRetry(body messagesRequest) (*http.Response, error) {
 		backoff = initialRetryBackoff
 	}
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		req, err := http.NewRequest("POST", c.BaseURL+messagesPath, bytes.NewReader(payload))
+		req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+messagesPath, bytes.NewReader(payload))
 		if err != nil {
 			return nil, etcherr.WrapAPI("creating HTTP request", err)
 		}
@@ -206,7 +208,11 @@ func (c *Client) doWithRetry(body messagesRequest) (*http.Response, error) {
 		case resp.StatusCode == 429:
 			drainBody(resp)
 			if attempt < maxRetries-1 {
-				time.Sleep(backoff)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return nil, etcherr.WrapAPI("sending request", ctx.Err())
+				}
 				backoff *= 2
 				continue
 			}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -107,7 +108,7 @@ func TestSendStream_Success(t *testing.T) {
 	c.BaseURL = srv.URL
 
 	var chunks []string
-	text, err := c.SendStream("sys", "hi", func(chunk string) {
+	text, err := c.SendStream(context.Background(), "sys", "hi", func(chunk string) {
 		chunks = append(chunks, chunk)
 	})
 	if err != nil {
@@ -140,7 +141,7 @@ func TestSendStream_SkipsNonTextEvents(t *testing.T) {
 	c := NewClient("key", "model")
 	c.BaseURL = srv.URL
 
-	text, err := c.SendStream("", "test", nil)
+	text, err := c.SendStream(context.Background(), "", "test", nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
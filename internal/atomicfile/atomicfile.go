@@ -0,0 +1,128 @@
+// Package atomicfile is the single place etch mutates plan and progress
+// files. Writers hold an advisory lock on a ".lock" file in the target's
+// directory for the whole read-modify-write cycle, so concurrent etch
+// processes (and goroutines within one) apply their edits one after another
+// instead of overwriting each other. Every write goes to a temporary file
+// that is renamed over the target, so readers never see a torn file and
+// need no lock.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// LockName is the name of the lock file created in each directory etch
+// writes to. It is never renamed or removed.
+const LockName = ".lock"
+
+// mutexes serialises goroutines in this process before they take the OS
+// lock, keyed by lock file path.
+var mutexes sync.Map
+
+// Lock takes the exclusive lock guarding path and every other file in its
+// directory, blocking until it is available. The returned function releases
+// it. The lock is advisory: only writers that go through this package
+// honour it.
+func Lock(path string) (unlock func(), err error) {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, etcherr.WrapIO("resolving "+path, err)
+	}
+	lockPath := filepath.Join(dir, LockName)
+
+	v, _ := mutexes.LoadOrStore(lockPath, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		mu.Unlock()
+		return nil, etcherr.WrapIO("opening lock file", err).
+			WithHint("Check that " + dir + " exists and is writable")
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		mu.Unlock()
+		return nil, etcherr.WrapIO("locking "+lockPath, err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+		mu.Unlock()
+	}, nil
+}
+
+// WriteFile atomically replaces the file at path with data. An existing
+// file keeps its permissions; a new one gets perm. WriteFile doesn't lock:
+// callers that read the file first must hold Lock across both steps.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return etcherr.WrapIO("creating temporary file", err).
+			WithHint("Check file permissions for " + filepath.Dir(path))
+	}
+	tmpPath := tmp.Name()
+	fail := func(msg string, err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return etcherr.WrapIO(msg, err).WithHint("Check file permissions for " + path)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return fail("writing "+filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail("syncing "+filepath.Base(path), err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fail("setting permissions on "+filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fail("writing "+filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return etcherr.WrapIO("replacing "+filepath.Base(path), err).WithHint("Check file permissions for " + path)
+	}
+	return nil
+}
+
+// Replace locks path and atomically overwrites it with data, for writers
+// that don't depend on the file's current contents.
+func Replace(path string, data []byte, perm os.FileMode) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return WriteFile(path, data, perm)
+}
+
+// Update locks path, passes its current contents to fn, and atomically
+// writes back what fn returns. Nothing is written if fn fails.
+func Update(path string, fn func(data []byte) ([]byte, error)) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return etcherr.WrapIO("reading "+filepath.Base(path), err).
+			WithHint("Check that the file exists at " + path)
+	}
+	out, err := fn(data)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, out, 0o644)
+}
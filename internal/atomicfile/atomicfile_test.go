@@ -0,0 +1,208 @@
+package atomicfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// increment adds one to the counter stored in path.
+func increment(path string) error {
+	return Update(path, func(data []byte) ([]byte, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(n + 1)), nil
+	})
+}
+
+// TestHelperProcess is run as a child by TestUpdate_ConcurrentProcesses.
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv("ATOMICFILE_COUNTER")
+	if path == "" {
+		t.Skip("helper process")
+	}
+	n, _ := strconv.Atoi(os.Getenv("ATOMICFILE_INCREMENTS"))
+	for i := 0; i < n; i++ {
+		if err := increment(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func readCounter(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		t.Fatalf("counter file corrupted: %q", data)
+	}
+	return n
+}
+
+func TestUpdate_ConcurrentGoroutines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	os.WriteFile(path, []byte("0"), 0o644)
+
+	const workers, each = 50, 20
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				if err := increment(path); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := readCounter(t, path); got != workers*each {
+		t.Errorf("counter = %d, want %d (lost updates)", got, workers*each)
+	}
+}
+
+func TestUpdate_ConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+	path := filepath.Join(t.TempDir(), "counter")
+	os.WriteFile(path, []byte("0"), 0o644)
+
+	const procs, goroutines, each = 6, 10, 25
+	var wg sync.WaitGroup
+	for p := 0; p < procs; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+			cmd.Env = append(os.Environ(), "ATOMICFILE_COUNTER="+path, "ATOMICFILE_INCREMENTS="+strconv.Itoa(each))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("helper process: %v\n%s", err, out)
+			}
+		}()
+	}
+	// This process competes with the children at the same time.
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				if err := increment(path); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := readCounter(t, path), (procs+goroutines)*each; got != want {
+		t.Errorf("counter = %d, want %d (lost updates)", got, want)
+	}
+}
+
+func TestWriteFile_ReadersNeverSeeTornWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.md")
+	a := bytes.Repeat([]byte("a"), 1<<20)
+	b := bytes.Repeat([]byte("b"), 1<<20)
+	os.WriteFile(path, a, 0o644)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			data := a
+			if i%2 == 0 {
+				data = b
+			}
+			if err := Replace(path, data, 0o644); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != len(a) || (!bytes.Equal(data, a) && !bytes.Equal(data, b)) {
+			t.Fatalf("read a torn file of %d bytes", len(data))
+		}
+	}
+}
+
+func TestWriteFile_KeepsModeAndLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.md")
+	os.WriteFile(path, []byte("old"), 0o600)
+
+	if err := WriteFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("content = %q", data)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("unexpected files left behind: %v", names)
+	}
+}
+
+func TestUpdate_FnErrorLeavesFileUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.md")
+	os.WriteFile(path, []byte("keep"), 0o644)
+
+	err := Update(path, func([]byte) ([]byte, error) {
+		return []byte("lost"), fmt.Errorf("boom")
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep" {
+		t.Errorf("content = %q, want unchanged", data)
+	}
+}
+
+func TestUpdate_MissingFile(t *testing.T) {
+	err := Update(filepath.Join(t.TempDir(), "missing.md"), func(data []byte) ([]byte, error) {
+		t.Error("fn called for a missing file")
+		return data, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "reading missing.md") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
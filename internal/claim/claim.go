@@ -0,0 +1,296 @@
+// Package claim keeps two agents from working on the same task at once. A
+// claim is a lease file in .etch/locks/, created with O_EXCL so only one
+// process can take it, recording who holds the task and until when. The
+// holder renews the lease while its agent works; once it expires, the task
+// can be claimed again.
+package claim
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gsigler/etch/internal/atomicfile"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+)
+
+const locksDir = ".etch/locks"
+
+// EnvVar passes a claim's ID to the agent session working under it, so the
+// etch commands the agent runs are recognised as the claim's holder.
+const EnvVar = "ETCH_CLAIM"
+
+// ErrHeld is returned when a task is claimed by someone else.
+var ErrHeld = errors.New("task is claimed")
+
+// Claim is a lease on one task.
+type Claim struct {
+	ID      string    `json:"id"`
+	Plan    string    `json:"plan"`
+	Task    string    `json:"task"`
+	Owner   string    `json:"owner"` // actor that took the claim, e.g. "human:alice"
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+
+	path string
+}
+
+// Ref returns the claimed task's plan-qualified ID, e.g. "auth#1.2".
+func (c Claim) Ref() string {
+	return Ref(c.Plan, c.Task)
+}
+
+// Ref returns the plan-qualified ID of a task, as claims are keyed.
+func Ref(plan, task string) string {
+	return plan + "#" + task
+}
+
+// Expired reports whether the lease ran out before now.
+func (c Claim) Expired(now time.Time) bool {
+	return !now.Before(c.Expires)
+}
+
+// Mine reports whether this process works under c: it took the claim, or
+// was started by the process that did.
+func (c Claim) Mine() bool {
+	return c.ID != "" && c.ID == os.Getenv(EnvVar)
+}
+
+// HeldError reports that c's holder has the task, and how to get it back.
+func (c Claim) HeldError() error {
+	return etcherr.Project(fmt.Sprintf("task %s is claimed by %s (pid %d on %s) until %s",
+		c.Ref(), c.Owner, c.PID, c.Host, c.Expires.Local().Format("15:04"))).
+		WithHint(fmt.Sprintf("wait for its session to finish, or run 'etch claims --break %s' if its agent is gone", c.Ref()))
+}
+
+func path(rootDir, plan, task string) string {
+	return filepath.Join(rootDir, locksDir, fmt.Sprintf("%s--task-%s.json", plan, task))
+}
+
+// Acquire claims a task for ttl. A claim this process already holds is
+// renewed, and an expired one is taken over. If someone else holds the
+// task, Acquire returns their claim and ErrHeld.
+func Acquire(rootDir, plan, task string, ttl time.Duration) (*Claim, error) {
+	p := path(rootDir, plan, task)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, etcherr.WrapIO("creating locks dir", err)
+	}
+	unlock, err := atomicfile.Lock(p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	now := time.Now().Truncate(time.Second)
+	existing, err := read(p)
+	switch {
+	case err == nil && existing.Mine():
+		existing.Expires = now.Add(ttl)
+		return existing, write(existing)
+	case err == nil && !existing.Expired(now):
+		return existing, ErrHeld
+	case err == nil:
+		if err := os.Remove(p); err != nil {
+			return nil, etcherr.WrapIO("removing expired claim", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	c := &Claim{
+		ID:      newID(),
+		Plan:    plan,
+		Task:    task,
+		Owner:   events.Actor(),
+		PID:     os.Getpid(),
+		Host:    host,
+		Created: now,
+		Expires: now.Add(ttl),
+		path:    p,
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, etcherr.WrapIO("encoding claim", err)
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, etcherr.WrapIO("creating claim", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(p)
+		return nil, etcherr.WrapIO("writing claim", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(p)
+		return nil, etcherr.WrapIO("writing claim", err)
+	}
+	return c, nil
+}
+
+// Renew extends the claim to ttl from now. It returns ErrHeld if the claim
+// was broken or taken over.
+func (c *Claim) Renew(ttl time.Duration) error {
+	unlock, err := atomicfile.Lock(c.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := read(c.path)
+	if err != nil || current.ID != c.ID {
+		return ErrHeld
+	}
+	c.Expires = time.Now().Truncate(time.Second).Add(ttl)
+	return write(c)
+}
+
+// Keep renews the claim every interval until the returned function is
+// called. A claim that was broken or taken over is reported on stderr and
+// no longer renewed. Other failed renewals are ignored: if they persist, the
+// claim expires as if its holder had stopped.
+func (c *Claim) Keep(ttl, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if errors.Is(c.Renew(ttl), ErrHeld) {
+					fmt.Fprintf(os.Stderr, "warning: the claim on %s was broken or taken over; another agent may start the task\n", c.Ref())
+					<-done
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// Release gives up the claim, unless it was already broken or taken over.
+func (c *Claim) Release() error {
+	unlock, err := atomicfile.Lock(c.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if current, err := read(c.path); err != nil || current.ID != c.ID {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil {
+		return etcherr.WrapIO("releasing claim", err)
+	}
+	return nil
+}
+
+// List returns every claim in the project at rootDir, expired or not, by
+// task. Claim files that can't be read are reported in the error, and the
+// rest are still returned.
+func List(rootDir string) ([]Claim, error) {
+	matches, _ := filepath.Glob(filepath.Join(rootDir, locksDir, "*.json"))
+	var claims []Claim
+	var errs []error
+	for _, m := range matches {
+		c, err := read(m)
+		if err != nil {
+			if !os.IsNotExist(err) { // a missing file was released since the glob
+				errs = append(errs, err)
+			}
+			continue
+		}
+		claims = append(claims, *c)
+	}
+	sort.Slice(claims, func(i, j int) bool { return claims[i].Ref() < claims[j].Ref() })
+	return claims, errors.Join(errs...)
+}
+
+// Others returns the unexpired claims held by someone other than this
+// process, keyed by Ref.
+func Others(rootDir string, now time.Time) map[string]Claim {
+	claims, _ := List(rootDir)
+	others := make(map[string]Claim)
+	for _, c := range claims {
+		if !c.Expired(now) && !c.Mine() {
+			others[c.Ref()] = c
+		}
+	}
+	return others
+}
+
+// Break removes the claim on a task, whoever holds it, and returns it.
+// It returns false if the task wasn't claimed.
+func Break(rootDir, plan, task string) (Claim, bool, error) {
+	p := path(rootDir, plan, task)
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return Claim{}, false, nil
+	}
+	unlock, err := atomicfile.Lock(p)
+	if err != nil {
+		return Claim{}, false, err
+	}
+	defer unlock()
+
+	c, err := read(p)
+	if os.IsNotExist(err) {
+		return Claim{}, false, nil
+	}
+	if err := os.Remove(p); err != nil {
+		return Claim{}, false, etcherr.WrapIO("breaking claim", err)
+	}
+	if c == nil {
+		// Unreadable, but removed all the same.
+		return Claim{Plan: plan, Task: task}, true, nil
+	}
+	return *c, true, nil
+}
+
+// read parses the claim file at p. A missing file is reported with an
+// error satisfying os.IsNotExist.
+func read(p string) (*Claim, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var c Claim
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, etcherr.WrapParse(fmt.Sprintf("reading claim %s", filepath.Base(p)), err).
+			WithHint("run 'etch claims --break " + strings.Replace(strings.TrimSuffix(filepath.Base(p), ".json"), "--task-", "#", 1) + "' to remove it")
+	}
+	c.path = p
+	return &c, nil
+}
+
+// write replaces the claim's file; callers hold its lock.
+func write(c *Claim) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return etcherr.WrapIO("encoding claim", err)
+	}
+	return atomicfile.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package claim
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ETCH_ACTOR", "human:alice")
+
+	c, err := Acquire(dir, "auth", "1.2", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Ref() != "auth#1.2" || c.Owner != "human:alice" || c.PID != os.Getpid() || c.ID == "" {
+		t.Errorf("unexpected claim: %+v", c)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".etch", "locks", "auth--task-1.2.json")); err != nil {
+		t.Errorf("claim file not written: %v", err)
+	}
+
+	// Another process, without the claim's ID, is refused.
+	held, err := Acquire(dir, "auth", "1.2", time.Hour)
+	if !errors.Is(err, ErrHeld) || held.ID != c.ID {
+		t.Fatalf("expected ErrHeld with the holder's claim, got %+v, %v", held, err)
+	}
+	if others := Others(dir, time.Now()); others["auth#1.2"].ID != c.ID {
+		t.Errorf("Others = %+v", others)
+	}
+
+	// The agent session working under the claim renews it.
+	t.Setenv(EnvVar, c.ID)
+	renewed, err := Acquire(dir, "auth", "1.2", 2*time.Hour)
+	if err != nil || renewed.ID != c.ID || !renewed.Expires.After(c.Expires) {
+		t.Errorf("expected the claim renewed, got %+v, %v", renewed, err)
+	}
+	if others := Others(dir, time.Now()); len(others) != 0 {
+		t.Errorf("own claim listed in Others: %+v", others)
+	}
+}
+
+func TestAcquire_Expired(t *testing.T) {
+	dir := t.TempDir()
+
+	old, err := Acquire(dir, "auth", "1.2", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if others := Others(dir, time.Now()); len(others) != 0 {
+		t.Errorf("expired claim listed in Others: %+v", others)
+	}
+
+	c, err := Acquire(dir, "auth", "1.2", time.Hour)
+	if err != nil {
+		t.Fatalf("expired claim not taken over: %v", err)
+	}
+	if c.ID == old.ID {
+		t.Error("expected a new claim")
+	}
+
+	// The old holder can neither renew nor release the new claim.
+	if err := old.Renew(time.Hour); !errors.Is(err, ErrHeld) {
+		t.Errorf("Renew after takeover: %v", err)
+	}
+	if err := old.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if claims, _ := List(dir); len(claims) != 1 || claims[0].ID != c.ID {
+		t.Errorf("new claim released by the old holder: %+v", claims)
+	}
+
+	if err := c.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if claims, _ := List(dir); len(claims) != 0 {
+		t.Errorf("claim not released: %+v", claims)
+	}
+}
+
+func TestListAndBreak(t *testing.T) {
+	dir := t.TempDir()
+	for _, task := range []string{"2.1", "1.1"} {
+		if _, err := Acquire(dir, "auth", task, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".etch", "locks", "auth--task-3.1.json"), []byte("{"), 0o644)
+
+	claims, err := List(dir)
+	if len(claims) != 2 || claims[0].Task != "1.1" || claims[1].Task != "2.1" {
+		t.Errorf("List = %+v", claims)
+	}
+	if err == nil {
+		t.Error("expected an error for the unreadable claim")
+	}
+
+	broken, found, err := Break(dir, "auth", "2.1")
+	if err != nil || !found || broken.Task != "2.1" {
+		t.Fatalf("Break = %+v, %v, %v", broken, found, err)
+	}
+	if _, found, err := Break(dir, "auth", "2.1"); err != nil || found {
+		t.Errorf("second Break = %v, %v", found, err)
+	}
+	if _, found, err := Break(dir, "auth", "3.1"); err != nil || !found {
+		t.Errorf("Break of an unreadable claim = %v, %v", found, err)
+	}
+	if claims, err := List(dir); err != nil || len(claims) != 1 || claims[0].Task != "1.1" {
+		t.Errorf("after Break: %+v, %v", claims, err)
+	}
+}
+
+func TestKeep(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Acquire(dir, "auth", "1.2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := c.Expires
+
+	stop := c.Keep(time.Hour, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	claims, _ := List(dir)
+	if len(claims) != 1 || !claims[0].Expires.After(first) {
+		t.Errorf("claim not renewed: %+v", claims)
+	}
+
+	// A broken claim isn't brought back by later renewals.
+	Break(dir, "auth", "1.2")
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	if claims, _ := List(dir); len(claims) != 0 {
+		t.Errorf("broken claim renewed: %+v", claims)
+	}
+}
@@ -1,9 +1,13 @@
 package claude
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 
 	etcherr "github.com/gsigler/etch/internal/errors"
 )
@@ -12,10 +16,9 @@ import (
 // specified working directory. The user's terminal is connected directly
 // so they can interact with Claude Code during the session.
 func Run(prompt, workDir string) error {
-	path, err := exec.LookPath("claude")
+	path, err := lookPath()
 	if err != nil {
-		return etcherr.New(etcherr.CatConfig, "claude CLI not found on PATH").
-			WithHint("install Claude Code: https://docs.anthropic.com/en/docs/claude-code")
+		return err
 	}
 
 	cmd := exec.Command(path, prompt)
@@ -36,10 +39,9 @@ func Run(prompt, workDir string) error {
 // length limits for large context prompts (equivalent to `cat file | claude`).
 // stdout and stderr remain connected to the user's terminal.
 func RunWithStdin(prompt, workDir string) error {
-	path, err := exec.LookPath("claude")
+	path, err := lookPath()
 	if err != nil {
-		return etcherr.New(etcherr.CatConfig, "claude CLI not found on PATH").
-			WithHint("install Claude Code: https://docs.anthropic.com/en/docs/claude-code")
+		return err
 	}
 
 	cmd := exec.Command(path)
@@ -71,6 +73,21 @@ func RunWithStdin(prompt, workDir string) error {
 	return nil
 }
 
+// Available returns an error if the claude CLI is not on PATH.
+func Available() error {
+	_, err := lookPath()
+	return err
+}
+
+func lookPath() (string, error) {
+	path, err := exec.LookPath("claude")
+	if err != nil {
+		return "", etcherr.New(etcherr.CatConfig, "claude CLI not found on PATH").
+			WithHint("install Claude Code: https://docs.anthropic.com/en/docs/claude-code")
+	}
+	return path, nil
+}
+
 func handleExecError(err error) error {
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		return etcherr.New(etcherr.CatAPI, "claude session exited with non-zero status").
@@ -79,3 +96,67 @@ func handleExecError(err error) error {
 	return etcherr.Wrap(etcherr.CatIO, "failed to run claude", err).
 		WithHint("check that claude is installed and working")
 }
+
+// OutputCallback is called with each chunk of output as it is produced.
+type OutputCallback func(text string)
+
+// Print runs the claude CLI non-interactively (`claude -p`), piping the
+// prompt via stdin and capturing stdout instead of attaching the terminal.
+// If cb is non-nil it is called with each chunk of output as it arrives.
+// Cancelling ctx kills the subprocess. Returns the full captured output.
+func Print(ctx context.Context, prompt, workDir string, cb OutputCallback) (string, error) {
+	path, err := lookPath()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, path, "-p")
+	cmd.Dir = workDir
+	cmd.Stdin = strings.NewReader(prompt)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", etcherr.Wrap(etcherr.CatIO, "failed to create stdout pipe", err).
+			WithHint("check system resources")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", etcherr.Wrap(etcherr.CatIO, "failed to start claude", err).
+			WithHint("check that claude is installed and working")
+	}
+
+	var out strings.Builder
+	buf := make([]byte, 4096)
+	for {
+		n, readErr := stdout.Read(buf)
+		if n > 0 {
+			chunk := string(buf[:n])
+			out.WriteString(chunk)
+			if cb != nil {
+				cb(chunk)
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return out.String(), etcherr.Wrap(etcherr.CatIO, "claude session cancelled", ctx.Err())
+		}
+		execErr := handleExecError(err)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			var etchErr *etcherr.Error
+			if errors.As(execErr, &etchErr) {
+				etchErr.Hint += ": " + msg
+			}
+		}
+		return out.String(), execErr
+	}
+
+	return out.String(), nil
+}
@@ -1,10 +1,15 @@
 package claude
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	etcherr "github.com/gsigler/etch/internal/errors"
 )
@@ -85,3 +90,77 @@ func TestRun_WorkDirIsSet(t *testing.T) {
 		t.Fatal("expected error for nonexistent workDir")
 	}
 }
+
+// fakeClaude installs a shell script named claude at the front of PATH.
+func fakeClaude(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake claude script requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "claude")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestPrint_CapturesOutput(t *testing.T) {
+	fakeClaude(t, `[ "$1" = "-p" ] || exit 3
+cat
+echo done`)
+
+	var chunks []string
+	out, err := Print(context.Background(), "hello", t.TempDir(), func(s string) { chunks = append(chunks, s) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "hellodone\n" {
+		t.Errorf("output = %q, want %q", out, "hellodone\n")
+	}
+	if strings.Join(chunks, "") != out {
+		t.Errorf("callback chunks %q do not add up to output %q", chunks, out)
+	}
+}
+
+func TestPrint_NonZeroExitIncludesStderr(t *testing.T) {
+	fakeClaude(t, `echo "not logged in" >&2
+exit 1`)
+
+	_, err := Print(context.Background(), "hello", t.TempDir(), nil)
+	if err == nil {
+		t.Fatal("expected error for non-zero exit")
+	}
+	var etchErr *etcherr.Error
+	if !errors.As(err, &etchErr) {
+		t.Fatalf("expected etcherr.Error, got %T: %v", err, err)
+	}
+	if !strings.Contains(etchErr.Hint, "not logged in") {
+		t.Errorf("expected stderr in hint, got %q", etchErr.Hint)
+	}
+}
+
+func TestPrint_CancelStopsClaude(t *testing.T) {
+	fakeClaude(t, `echo started
+exec sleep 30`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	_, err := Print(ctx, "hello", t.TempDir(), func(string) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Print took %s after cancel", elapsed)
+	}
+}
+
+func TestPrint_ClaudeNotOnPath(t *testing.T) {
+	t.Setenv("PATH", "")
+
+	_, err := Print(context.Background(), "hello", t.TempDir(), nil)
+	var etchErr *etcherr.Error
+	if !errors.As(err, &etchErr) || etchErr.Category != etcherr.CatConfig {
+		t.Fatalf("expected config error, got %v", err)
+	}
+}
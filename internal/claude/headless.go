@@ -0,0 +1,194 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// TranscriptsDir holds headless session transcripts, relative to the project root.
+const TranscriptsDir = ".etch/transcripts"
+
+// TranscriptPath returns the transcript file for a task session, e.g.
+// ".etch/transcripts/auth-system--task-1.2--003.jsonl".
+func TranscriptPath(rootDir, planSlug, taskID string, session int) string {
+	return filepath.Join(rootDir, TranscriptsDir, fmt.Sprintf("%s--task-%s--%03d.jsonl", planSlug, taskID, session))
+}
+
+// HeadlessOptions configures an unattended Claude Code session.
+type HeadlessOptions struct {
+	// WorkDir is the directory the session runs in.
+	WorkDir string
+	// TranscriptPath receives every stream-json event, one per line. The
+	// parent directory is created if needed. Empty disables the transcript.
+	TranscriptPath string
+	// Timeout kills the session after this long. Zero means no limit.
+	Timeout time.Duration
+	// Env holds extra KEY=value environment variables for the session.
+	Env []string
+}
+
+// Usage holds the token counts Claude Code reports for a session.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Total returns all input and output tokens, including cache reads and writes.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// HeadlessResult describes how a headless session ended. Fields taken from
+// Claude Code's final result event are zero if it never reported one.
+type HeadlessResult struct {
+	ExitCode       int
+	Duration       time.Duration
+	TimedOut       bool
+	TranscriptPath string
+	Result         string // final result text
+	IsError        bool
+	NumTurns       int
+	CostUSD        float64
+	Usage          *Usage // nil if no usage was reported
+}
+
+// streamEvent is the subset of a stream-json line etch reads. Only the final
+// "result" event carries usage and cost.
+type streamEvent struct {
+	Type     string  `json:"type"`
+	Subtype  string  `json:"subtype"`
+	IsError  bool    `json:"is_error"`
+	Result   string  `json:"result"`
+	NumTurns int     `json:"num_turns"`
+	CostUSD  float64 `json:"total_cost_usd"`
+	Usage    *Usage  `json:"usage"`
+}
+
+// Headless runs Claude Code as an unattended session in print mode with
+// stream-json output, piping the prompt via stdin. File edits are accepted
+// automatically and etch commands are allowed, since no one is attached to
+// approve them. The session is killed when ctx is cancelled or the timeout
+// elapses.
+//
+// The result is filled in even when an error is returned, so callers can
+// report the exit code and duration of failed sessions.
+func Headless(ctx context.Context, prompt string, opts HeadlessOptions) (HeadlessResult, error) {
+	res := HeadlessResult{TranscriptPath: opts.TranscriptPath}
+
+	path, err := lookPath()
+	if err != nil {
+		return res, err
+	}
+
+	var transcript io.Writer = io.Discard
+	if opts.TranscriptPath != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.TranscriptPath), 0o755); err != nil {
+			return res, etcherr.WrapIO("creating transcripts directory", err)
+		}
+		f, err := os.Create(opts.TranscriptPath)
+		if err != nil {
+			return res, etcherr.WrapIO("creating transcript file", err)
+		}
+		defer f.Close()
+		transcript = f
+	}
+
+	runCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(runCtx, path, "-p",
+		"--output-format", "stream-json",
+		"--verbose",
+		"--permission-mode", "acceptEdits",
+		"--allowedTools", "Bash(etch:*)")
+	cmd.Dir = opts.WorkDir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.WaitDelay = 5 * time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return res, etcherr.Wrap(etcherr.CatIO, "failed to create stdout pipe", err).
+			WithHint("check system resources")
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return res, etcherr.Wrap(etcherr.CatIO, "failed to start claude", err).
+			WithHint("check that claude is installed and working")
+	}
+
+	readStream(stdout, transcript, &res)
+	waitErr := cmd.Wait()
+	res.Duration = time.Since(start)
+	res.ExitCode = cmd.ProcessState.ExitCode()
+
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		res.TimedOut = true
+		return res, etcherr.New(etcherr.CatAPI, fmt.Sprintf("claude session timed out after %s", opts.Timeout)).
+			WithHint("raise the timeout with --timeout, or split the task into smaller pieces")
+	case ctx.Err() != nil:
+		return res, etcherr.Wrap(etcherr.CatIO, "claude session cancelled", ctx.Err())
+	case waitErr != nil:
+		execErr := handleExecError(waitErr)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			var etchErr *etcherr.Error
+			if errors.As(execErr, &etchErr) {
+				etchErr.Hint += ": " + msg
+			}
+		}
+		return res, execErr
+	case res.IsError:
+		return res, etcherr.New(etcherr.CatAPI, "claude session reported an error").
+			WithHint(res.Result)
+	}
+	return res, nil
+}
+
+// readStream copies stream-json lines from r to transcript and records the
+// final result event in res. Lines that aren't JSON are kept in the
+// transcript but otherwise ignored.
+func readStream(r io.Reader, transcript io.Writer, res *HeadlessResult) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			transcript.Write(line)
+			var ev streamEvent
+			if json.Unmarshal(line, &ev) == nil && ev.Type == "result" {
+				res.Result = ev.Result
+				res.IsError = ev.IsError
+				res.NumTurns = ev.NumTurns
+				res.CostUSD = ev.CostUSD
+				res.Usage = ev.Usage
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
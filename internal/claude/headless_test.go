@@ -0,0 +1,113 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+const streamScript = `echo "args: $*" >&2
+echo '{"type":"system","subtype":"init"}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"working"}]}}'
+echo '{"type":"result","subtype":"success","is_error":false,"result":"done","num_turns":3,"total_cost_usd":0.25,"usage":{"input_tokens":100,"output_tokens":40,"cache_read_input_tokens":10}}'
+`
+
+func TestHeadless_RecordsTranscriptAndUsage(t *testing.T) {
+	fakeClaude(t, streamScript)
+	transcript := filepath.Join(t.TempDir(), "transcripts", "p--task-1.1--001.jsonl")
+
+	res, err := Headless(context.Background(), "prompt", HeadlessOptions{
+		WorkDir:        t.TempDir(),
+		TranscriptPath: transcript,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.ExitCode != 0 || res.Result != "done" || res.NumTurns != 3 || res.CostUSD != 0.25 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if res.Usage == nil || res.Usage.InputTokens != 100 || res.Usage.OutputTokens != 40 || res.Usage.Total() != 150 {
+		t.Errorf("unexpected usage: %+v", res.Usage)
+	}
+	if res.Duration <= 0 {
+		t.Error("expected a positive duration")
+	}
+
+	data, err := os.ReadFile(transcript)
+	if err != nil {
+		t.Fatalf("reading transcript: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("expected 3 transcript lines, got %d:\n%s", lines, data)
+	}
+}
+
+func TestHeadless_UsesStreamJSONFlags(t *testing.T) {
+	fakeClaude(t, `case "$*" in
+  *"-p --output-format stream-json --verbose"*) exit 0 ;;
+  *) exit 9 ;;
+esac`)
+
+	res, err := Headless(context.Background(), "prompt", HeadlessOptions{WorkDir: t.TempDir()})
+	if err != nil || res.ExitCode != 0 {
+		t.Fatalf("expected stream-json flags to be passed, got exit %d: %v", res.ExitCode, err)
+	}
+}
+
+func TestHeadless_NonZeroExit(t *testing.T) {
+	fakeClaude(t, `echo "rate limited" >&2
+exit 2`)
+
+	res, err := Headless(context.Background(), "prompt", HeadlessOptions{WorkDir: t.TempDir()})
+	var etchErr *etcherr.Error
+	if !errors.As(err, &etchErr) || !strings.Contains(etchErr.Hint, "rate limited") {
+		t.Fatalf("expected stderr in error hint, got %v", err)
+	}
+	if res.ExitCode != 2 {
+		t.Errorf("exit code = %d, want 2", res.ExitCode)
+	}
+}
+
+func TestHeadless_Timeout(t *testing.T) {
+	fakeClaude(t, `exec sleep 5`)
+
+	start := time.Now()
+	res, err := Headless(context.Background(), "prompt", HeadlessOptions{
+		WorkDir: t.TempDir(),
+		Timeout: 100 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if !res.TimedOut {
+		t.Error("expected TimedOut to be set")
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("timeout not enforced, took %s", time.Since(start))
+	}
+}
+
+func TestHeadless_ResultError(t *testing.T) {
+	fakeClaude(t, `echo '{"type":"result","subtype":"error_max_turns","is_error":true,"result":"max turns reached"}'`)
+
+	_, err := Headless(context.Background(), "prompt", HeadlessOptions{WorkDir: t.TempDir()})
+	var etchErr *etcherr.Error
+	if !errors.As(err, &etchErr) || etchErr.Hint != "max turns reached" {
+		t.Fatalf("expected result error, got %v", err)
+	}
+}
+
+func TestTranscriptPath(t *testing.T) {
+	got := TranscriptPath("/root", "auth", "1.2", 3)
+	want := filepath.Join("/root", ".etch", "transcripts", "auth--task-1.2--003.jsonl")
+	if got != want {
+		t.Errorf("TranscriptPath = %q, want %q", got, want)
+	}
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	etcherr "github.com/gsigler/etch/internal/errors"
@@ -11,6 +14,19 @@ import (
 const (
 	DefaultModel           = "claude-sonnet-4-20250514"
 	DefaultComplexityGuide = "small = single focused session, medium = may need iteration, large = multiple sessions likely"
+	DefaultWorktreeBase    = "HEAD"
+	DefaultAgent           = "claude"
+	DefaultHookTimeout     = 10 * time.Minute
+	DefaultWebhookTimeout  = 10 * time.Second
+	DefaultWebhookRetries  = 3
+	DefaultScheduler       = "weighted"
+	DefaultStaleAfter      = 30 * time.Minute
+	DefaultClaimTTL        = 5 * time.Minute
+
+	// ClaimRenewInterval is how often etch run and etch swarm renew a
+	// task's claim while its agent works. claim_ttl must cover at least
+	// two renewals, so one late renewal doesn't let the claim lapse.
+	ClaimRenewInterval = time.Minute
 
 	configPath = ".etch/config.toml"
 	envKeyName = "ANTHROPIC_API_KEY"
@@ -20,6 +36,15 @@ const (
 type Config struct {
 	API      APIConfig      `toml:"api"`
 	Defaults DefaultsConfig `toml:"defaults"`
+	Run      RunConfig      `toml:"run"`
+	Agent    AgentConfig    `toml:"agent"`
+	// Agents defines custom agent backends by name, e.g. [agents.aider].
+	Agents map[string]AgentCommand `toml:"agents"`
+	Hooks  HooksConfig             `toml:"hooks"`
+	// Webhooks are notified of the same events as hooks, e.g. [[webhooks]].
+	Webhooks  []Webhook       `toml:"webhooks"`
+	Scheduler SchedulerConfig `toml:"scheduler"`
+	Status    StatusConfig    `toml:"status"`
 }
 
 // APIConfig holds AI provider settings.
@@ -33,6 +58,146 @@ type DefaultsConfig struct {
 	ComplexityGuide string `toml:"complexity_guide"`
 }
 
+// RunConfig holds settings for launching task sessions.
+type RunConfig struct {
+	// Worktree runs each task in its own git worktree and branch.
+	Worktree bool `toml:"worktree"`
+	// WorktreeBase is the commit or branch new task branches start from.
+	WorktreeBase string `toml:"worktree_base"`
+	// Commit commits a session's changes when it ends.
+	Commit bool `toml:"commit"`
+	// ClaimTTL is how long a task's claim lasts without being renewed, and
+	// so how soon a crashed run's task can be claimed again.
+	ClaimTTL time.Duration `toml:"claim_ttl"`
+}
+
+// AgentConfig selects the coding agent that runs sessions.
+type AgentConfig struct {
+	// Backend is "claude" or the name of a command defined under [agents].
+	Backend string `toml:"backend"`
+}
+
+// AgentCommand defines a custom agent backend that runs a command.
+type AgentCommand struct {
+	// Command is the executable to run.
+	Command string `toml:"command"`
+	// Args are passed to Command for interactive sessions. "{prompt}" and
+	// "{prompt_file}" are replaced with the prompt or the path of a file
+	// holding it.
+	Args []string `toml:"args"`
+	// HeadlessArgs replace Args for unattended sessions, if set.
+	HeadlessArgs []string `toml:"headless_args"`
+	// Prompt is how the prompt is delivered: "stdin" (the default), "arg",
+	// or "file".
+	Prompt string `toml:"prompt"`
+}
+
+// StatusConfig holds settings for reading plan status.
+type StatusConfig struct {
+	// AutoSync writes reconciled status back to plan files whenever status
+	// is read, as etch sync does.
+	AutoSync bool `toml:"auto_sync"`
+	// StaleAfter is how long an in-progress session can go without a
+	// heartbeat before it is flagged as stale.
+	StaleAfter time.Duration `toml:"stale_after"`
+}
+
+// SchedulerConfig selects how etch run and etch next pick the next task.
+type SchedulerConfig struct {
+	// Strategy is "weighted" (the default) or "order" for plan file order.
+	Strategy string `toml:"strategy"`
+	// Weights tune the "weighted" strategy.
+	Weights SchedulerWeights `toml:"weights"`
+}
+
+// SchedulerWeights set how much each factor counts when ranking tasks.
+// Each factor scores between 0 and 1 before it is weighted.
+type SchedulerWeights struct {
+	Priority     float64 `toml:"priority"`
+	CriticalPath float64 `toml:"critical_path"`
+	FanOut       float64 `toml:"fan_out"`
+	Complexity   float64 `toml:"complexity"`
+}
+
+// DefaultSchedulerWeights favour plan priority, then the critical path,
+// then tasks others depend on, then small tasks.
+var DefaultSchedulerWeights = SchedulerWeights{
+	Priority:     8,
+	CriticalPath: 4,
+	FanOut:       2,
+	Complexity:   1,
+}
+
+// HookNames lists the lifecycle hooks that can be set under [hooks].
+var HookNames = []string{"on_task_start", "on_task_done", "on_task_blocked", "on_task_failed", "on_plan_complete"}
+
+// HooksConfig holds shell commands run on task and plan lifecycle events.
+type HooksConfig struct {
+	OnTaskStart    string `toml:"on_task_start"`
+	OnTaskDone     string `toml:"on_task_done"`
+	OnTaskBlocked  string `toml:"on_task_blocked"`
+	OnTaskFailed   string `toml:"on_task_failed"`
+	OnPlanComplete string `toml:"on_plan_complete"`
+	// Veto names the hooks whose failure cancels the transition that
+	// triggered them. Failures of other hooks are only reported.
+	Veto []string `toml:"veto"`
+	// Timeout kills a hook that runs longer than this.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// Command returns the command configured for the named hook.
+func (h HooksConfig) Command(name string) string {
+	switch name {
+	case "on_task_start":
+		return h.OnTaskStart
+	case "on_task_done":
+		return h.OnTaskDone
+	case "on_task_blocked":
+		return h.OnTaskBlocked
+	case "on_task_failed":
+		return h.OnTaskFailed
+	case "on_plan_complete":
+		return h.OnPlanComplete
+	}
+	return ""
+}
+
+// Vetoes reports whether a failure of the named hook cancels its transition.
+func (h HooksConfig) Vetoes(name string) bool {
+	for _, v := range h.Veto {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is a URL that lifecycle events are POSTed to as JSON.
+type Webhook struct {
+	URL string `toml:"url"`
+	// Secret, if set, signs each request body with HMAC-SHA256.
+	Secret string `toml:"secret"`
+	// Events limits the webhook to these hook names; empty means all.
+	Events []string `toml:"events"`
+	// Retries is how many times a failed delivery is retried.
+	Retries *int `toml:"retries"`
+	// Timeout abandons a delivery attempt that takes longer than this.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+// Wants reports whether the webhook is sent the named event.
+func (w Webhook) Wants(name string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Load reads config from .etch/config.toml relative to the given project root,
 // applies defaults, and resolves the API key from the environment if not set
 // in the config file.
@@ -44,6 +209,23 @@ func Load(projectRoot string) (Config, error) {
 		Defaults: DefaultsConfig{
 			ComplexityGuide: DefaultComplexityGuide,
 		},
+		Run: RunConfig{
+			WorktreeBase: DefaultWorktreeBase,
+			ClaimTTL:     DefaultClaimTTL,
+		},
+		Agent: AgentConfig{
+			Backend: DefaultAgent,
+		},
+		Hooks: HooksConfig{
+			Timeout: DefaultHookTimeout,
+		},
+		Scheduler: SchedulerConfig{
+			Strategy: DefaultScheduler,
+			Weights:  DefaultSchedulerWeights,
+		},
+		Status: StatusConfig{
+			StaleAfter: DefaultStaleAfter,
+		},
 	}
 
 	path := filepath.Join(projectRoot, configPath)
@@ -65,6 +247,56 @@ func Load(projectRoot string) (Config, error) {
 	if cfg.Defaults.ComplexityGuide == "" {
 		cfg.Defaults.ComplexityGuide = DefaultComplexityGuide
 	}
+	if cfg.Run.WorktreeBase == "" {
+		cfg.Run.WorktreeBase = DefaultWorktreeBase
+	}
+	if cfg.Run.ClaimTTL == 0 {
+		cfg.Run.ClaimTTL = DefaultClaimTTL
+	}
+	if cfg.Run.ClaimTTL < 2*ClaimRenewInterval {
+		return Config{}, etcherr.Config(fmt.Sprintf("claim_ttl %s under [run] is too short", cfg.Run.ClaimTTL)).
+			WithHint(fmt.Sprintf("claims are renewed every %s; set claim_ttl to at least %s", ClaimRenewInterval, 2*ClaimRenewInterval))
+	}
+	if cfg.Agent.Backend == "" {
+		cfg.Agent.Backend = DefaultAgent
+	}
+	if cfg.Hooks.Timeout <= 0 {
+		cfg.Hooks.Timeout = DefaultHookTimeout
+	}
+	for _, name := range cfg.Hooks.Veto {
+		if !isHookName(name) {
+			return Config{}, etcherr.Config(fmt.Sprintf("unknown hook %q in [hooks] veto", name)).
+				WithHint(fmt.Sprintf("use one of: %s", strings.Join(HookNames, ", ")))
+		}
+	}
+
+	if cfg.Scheduler.Strategy == "" {
+		cfg.Scheduler.Strategy = DefaultScheduler
+	}
+	if cfg.Status.StaleAfter <= 0 {
+		cfg.Status.StaleAfter = DefaultStaleAfter
+	}
+
+	for i := range cfg.Webhooks {
+		w := &cfg.Webhooks[i]
+		if w.URL == "" {
+			return Config{}, etcherr.Config(fmt.Sprintf("webhook %d has no url", i+1)).
+				WithHint("set url under each [[webhooks]] in .etch/config.toml")
+		}
+		for _, name := range w.Events {
+			if !isHookName(name) {
+				return Config{}, etcherr.Config(fmt.Sprintf("unknown event %q in webhook %s", name, w.URL)).
+					WithHint(fmt.Sprintf("use one of: %s", strings.Join(HookNames, ", ")))
+			}
+		}
+		if w.Retries == nil {
+			n := DefaultWebhookRetries
+			w.Retries = &n
+		}
+		if w.Timeout <= 0 {
+			w.Timeout = DefaultWebhookTimeout
+		}
+	}
 
 	// Env var overrides config file API key.
 	if envKey := os.Getenv(envKeyName); envKey != "" {
@@ -74,6 +306,15 @@ func Load(projectRoot string) (Config, error) {
 	return cfg, nil
 }
 
+func isHookName(name string) bool {
+	for _, n := range HookNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ResolveAPIKey returns the API key from the config, or an error with a
 // helpful message if no key is available.
 func (c Config) ResolveAPIKey() (string, error) {
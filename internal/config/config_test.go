@@ -157,3 +157,210 @@ func TestLoadInvalidTOML(t *testing.T) {
 		t.Fatal("expected error for invalid TOML, got nil")
 	}
 }
+
+func TestLoadRunConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Run.Worktree || cfg.Run.WorktreeBase != DefaultWorktreeBase || cfg.Run.ClaimTTL != DefaultClaimTTL {
+		t.Errorf("unexpected run defaults: %+v", cfg.Run)
+	}
+
+	writeConfig(t, dir, `
+[run]
+worktree = true
+worktree_base = "main"
+commit = true
+claim_ttl = "10m"
+`)
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Run.Worktree || cfg.Run.WorktreeBase != "main" || !cfg.Run.Commit || cfg.Run.ClaimTTL.Minutes() != 10 {
+		t.Errorf("Run = %+v, want worktree on from main with commits and 10m claims", cfg.Run)
+	}
+
+	// A claim must outlast a missed renewal.
+	writeConfig(t, dir, "[run]\nclaim_ttl = \"30s\"\n")
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "claim_ttl 30s under [run] is too short") {
+		t.Errorf("expected a short claim_ttl to be rejected, got %v", err)
+	}
+
+	writeConfig(t, dir, "[run]\nworktree = true\n")
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Run.WorktreeBase != DefaultWorktreeBase {
+		t.Errorf("WorktreeBase = %q, want default", cfg.Run.WorktreeBase)
+	}
+}
+
+func TestLoadAgentConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Agent.Backend != DefaultAgent {
+		t.Errorf("Backend = %q, want %q", cfg.Agent.Backend, DefaultAgent)
+	}
+
+	writeConfig(t, dir, `
+[agent]
+backend = "aider"
+
+[agents.aider]
+command = "aider"
+args = ["--yes-always", "--message-file", "{prompt_file}"]
+prompt = "file"
+`)
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Agent.Backend != "aider" {
+		t.Errorf("Backend = %q, want aider", cfg.Agent.Backend)
+	}
+	a, ok := cfg.Agents["aider"]
+	if !ok || a.Command != "aider" || a.Prompt != "file" || len(a.Args) != 3 {
+		t.Errorf("Agents[aider] = %+v", a)
+	}
+}
+
+func TestLoadHooksConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Hooks.Timeout != DefaultHookTimeout || cfg.Hooks.Command("on_task_done") != "" {
+		t.Errorf("default Hooks = %+v", cfg.Hooks)
+	}
+
+	writeConfig(t, dir, `
+[hooks]
+on_task_done = "make test"
+on_plan_complete = "make docs"
+veto = ["on_task_done"]
+timeout = "90s"
+`)
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Hooks.Command("on_task_done") != "make test" || cfg.Hooks.Command("on_plan_complete") != "make docs" {
+		t.Errorf("Hooks = %+v", cfg.Hooks)
+	}
+	if !cfg.Hooks.Vetoes("on_task_done") || cfg.Hooks.Vetoes("on_plan_complete") {
+		t.Errorf("Veto = %v", cfg.Hooks.Veto)
+	}
+	if cfg.Hooks.Timeout.Seconds() != 90 {
+		t.Errorf("Timeout = %v, want 90s", cfg.Hooks.Timeout)
+	}
+
+	writeConfig(t, dir, "[hooks]\nveto = [\"on_task_finished\"]\n")
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "on_task_finished") {
+		t.Errorf("expected an error for an unknown veto hook, got %v", err)
+	}
+}
+
+func TestLoadWebhooksConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	writeConfig(t, dir, `
+[[webhooks]]
+url = "https://example.com/etch"
+secret = "s3cret"
+events = ["on_task_done", "on_plan_complete"]
+
+[[webhooks]]
+url = "https://chat.example.com/hook"
+retries = 0
+timeout = "2s"
+`)
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Webhooks) != 2 {
+		t.Fatalf("Webhooks = %+v", cfg.Webhooks)
+	}
+	w := cfg.Webhooks[0]
+	if w.Secret != "s3cret" || *w.Retries != DefaultWebhookRetries || w.Timeout != DefaultWebhookTimeout {
+		t.Errorf("first webhook = %+v", w)
+	}
+	if !w.Wants("on_task_done") || w.Wants("on_task_start") {
+		t.Errorf("Events = %v", w.Events)
+	}
+	w = cfg.Webhooks[1]
+	if *w.Retries != 0 || w.Timeout.Seconds() != 2 || !w.Wants("on_task_start") {
+		t.Errorf("second webhook = %+v", w)
+	}
+
+	writeConfig(t, dir, "[[webhooks]]\nsecret = \"x\"\n")
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "no url") {
+		t.Errorf("expected an error for a webhook without a url, got %v", err)
+	}
+	writeConfig(t, dir, "[[webhooks]]\nurl = \"https://example.com\"\nevents = [\"task_done\"]\n")
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "task_done") {
+		t.Errorf("expected an error for an unknown event, got %v", err)
+	}
+}
+
+func TestLoadSchedulerConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Scheduler.Strategy != DefaultScheduler || cfg.Scheduler.Weights != DefaultSchedulerWeights {
+		t.Errorf("default Scheduler = %+v", cfg.Scheduler)
+	}
+
+	// Weights left out keep their defaults; zero turns a factor off.
+	writeConfig(t, dir, "[scheduler.weights]\nfan_out = 0\ncomplexity = 3\n")
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultSchedulerWeights
+	want.FanOut, want.Complexity = 0, 3
+	if cfg.Scheduler.Strategy != DefaultScheduler || cfg.Scheduler.Weights != want {
+		t.Errorf("Scheduler = %+v, want weights %+v", cfg.Scheduler, want)
+	}
+}
+
+func TestLoadStatusConfig(t *testing.T) {
+	t.Setenv(envKeyName, "")
+
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Status.AutoSync || cfg.Status.StaleAfter != DefaultStaleAfter {
+		t.Errorf("default Status = %+v", cfg.Status)
+	}
+
+	writeConfig(t, dir, "[status]\nauto_sync = true\nstale_after = \"2h\"\n")
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Status.AutoSync || cfg.Status.StaleAfter.Hours() != 2 {
+		t.Errorf("Status = %+v", cfg.Status)
+	}
+}
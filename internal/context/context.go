@@ -5,11 +5,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/gsigler/etch/internal/claim"
+	"github.com/gsigler/etch/internal/config"
 	etcherr "github.com/gsigler/etch/internal/errors"
 	"github.com/gsigler/etch/internal/models"
 	"github.com/gsigler/etch/internal/parser"
 	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/schedule"
+	"github.com/gsigler/etch/internal/status"
 )
 
 const (
@@ -94,7 +99,7 @@ func ResolveFeature(plans []*models.Plan, planSlug string, featureNumber int) (*
 // featurePendingTasks returns the ordered list of pending tasks in a feature
 // whose dependencies are satisfied or are within the feature itself, skipping
 // completed tasks.
-func featurePendingTasks(plan *models.Plan, feature *models.Feature, allProgress map[string][]models.SessionProgress) []*models.Task {
+func featurePendingTasks(plan *models.Plan, feature *models.Feature, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup) []*models.Task {
 	// Build set of task IDs within this feature for intra-feature dep checking.
 	featureTaskIDs := make(map[string]bool)
 	for _, t := range feature.Tasks {
@@ -108,7 +113,7 @@ func featurePendingTasks(plan *models.Plan, feature *models.Feature, allProgress
 		if status != models.StatusPending {
 			continue
 		}
-		if featureDepsReady(plan, task, featureTaskIDs, allProgress) {
+		if featureDepsReady(plan, task, featureTaskIDs, allProgress, cross) {
 			result = append(result, task)
 		}
 	}
@@ -118,19 +123,20 @@ func featurePendingTasks(plan *models.Plan, feature *models.Feature, allProgress
 // featureDepsReady checks if a task's dependencies are satisfied for feature-level
 // execution. A dependency is satisfied if it's completed, or if it belongs to the
 // same feature (intra-feature deps are allowed since the feature runs as a group).
-func featureDepsReady(plan *models.Plan, task *models.Task, featureTaskIDs map[string]bool, allProgress map[string][]models.SessionProgress) bool {
+func featureDepsReady(plan *models.Plan, task *models.Task, featureTaskIDs map[string]bool, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup) bool {
 	for _, dep := range task.DependsOn {
-		depID := extractTaskID(dep)
+		target, isCross := resolveDependency(plan, dep, allProgress, cross)
 		// Intra-feature dependencies are considered satisfied.
-		if featureTaskIDs[depID] {
+		if !isCross && featureTaskIDs[extractTaskID(dep)] {
 			continue
 		}
-		depTask := plan.TaskByID(depID)
-		if depTask == nil {
+		if target.task == nil {
+			if isCross {
+				return false // unknown cross-plan dependency, treat as unmet
+			}
 			continue
 		}
-		status := effectiveStatus(depTask, allProgress)
-		if status != models.StatusCompleted {
+		if target.status != models.StatusCompleted {
 			return false
 		}
 	}
@@ -162,7 +168,7 @@ func ResolveTask(plans []*models.Plan, planSlug, taskID string, rootDir string)
 
 	// Auto-select if no task ID given.
 	if taskID == "" {
-		return autoSelectTask(candidates, rootDir)
+		return autoSelectTask(plans, planSlug, rootDir)
 	}
 
 	// Resolve task ID within candidates.
@@ -180,82 +186,114 @@ func ResolveTask(plans []*models.Plan, planSlug, taskID string, rootDir string)
 // resolveTaskID resolves a task ID within a plan.
 // Supports "1.2" (full ID) and "2" (task number in single-feature plan).
 func resolveTaskID(plan *models.Plan, id string) *models.Task {
-	// Try full ID first (e.g. "1.2", "1.3b").
-	if task := plan.TaskByID(id); task != nil {
-		return task
-	}
+	return plan.ResolveTaskID(id)
+}
 
-	// Try as bare task number in single-feature plan (e.g. "2" → "1.2").
-	if len(plan.Features) == 1 {
-		fullID := fmt.Sprintf("1.%s", id)
-		if task := plan.TaskByID(fullID); task != nil {
-			return task
+// autoSelectTask picks the runnable task the configured scheduler ranks
+// first, considering every plan but only choosing from planSlug if it is set.
+func autoSelectTask(plans []*models.Plan, planSlug, rootDir string) (*models.Plan, *models.Task, error) {
+	ranked, err := Rank(rootDir, plans)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range ranked {
+		if planSlug == "" || r.Plan.Slug == planSlug {
+			return r.Plan, r.Task, nil
 		}
 	}
-
-	return nil
+	return nil, nil, etcherr.Project("no pending tasks with satisfied dependencies").
+		WithHint("all tasks may be completed or blocked — run 'etch status' to check")
 }
 
-// autoSelectTask finds the next pending task where all dependencies are satisfied.
-func autoSelectTask(plans []*models.Plan, rootDir string) (*models.Plan, *models.Task, error) {
-	type candidate struct {
-		plan *models.Plan
-		task *models.Task
+// NeedsPlanPicker returns true if auto-select would be ambiguous across
+// plans: runnable tasks span several plans and the two plans ranked first
+// have the same priority. The plans are returned best first.
+func NeedsPlanPicker(plans []*models.Plan, rootDir string) (bool, []*models.Plan) {
+	ranked, err := Rank(rootDir, plans)
+	if err != nil {
+		return false, nil
 	}
-	var candidates []candidate
-
-	for _, plan := range plans {
-		allProgress, _ := progress.ReadAll(rootDir, plan.Slug)
-		for i := range plan.Features {
-			for j := range plan.Features[i].Tasks {
-				task := &plan.Features[i].Tasks[j]
-				status := effectiveStatus(task, allProgress)
-				if status != models.StatusPending {
-					continue
-				}
-				if allDepsCompleted(plan, task, allProgress) {
-					candidates = append(candidates, candidate{plan, task})
-				}
-			}
+	seen := make(map[string]bool)
+	var result []*models.Plan
+	for _, r := range ranked {
+		if !seen[r.Plan.Slug] {
+			seen[r.Plan.Slug] = true
+			result = append(result, r.Plan)
 		}
 	}
 
-	if len(candidates) == 0 {
-		return nil, nil, etcherr.Project("no pending tasks with satisfied dependencies").
-			WithHint("all tasks may be completed or blocked — run 'etch status' to check")
+	if len(result) <= 1 || result[0].Priority != result[1].Priority {
+		return false, nil
 	}
+	return true, result
+}
 
-	// If only one candidate or all from same plan, return first.
-	return candidates[0].plan, candidates[0].task, nil
+// Rank returns the runnable tasks across plans, best first, as ordered by
+// the scheduler configured in the project at rootDir.
+func Rank(rootDir string, plans []*models.Plan) ([]schedule.Ranked, error) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	s, err := schedule.New(cfg.Scheduler)
+	if err != nil {
+		return nil, err
+	}
+	return s.Rank(ScheduleGraph(rootDir, plans)), nil
 }
 
-// NeedsPlanPicker returns true if auto-select would be ambiguous across plans.
-func NeedsPlanPicker(plans []*models.Plan, rootDir string) (bool, []*models.Plan) {
-	planSet := make(map[string]*models.Plan)
+// ScheduleGraph builds the dependency graph across plans, with each task's
+// status taken from its progress files. Plans outside the list that tasks
+// depend on are loaded as needed, but their tasks are never runnable, and
+// neither are tasks someone else has claimed.
+func ScheduleGraph(rootDir string, plans []*models.Plan) *schedule.Graph {
+	g := &schedule.Graph{}
+	cross := status.NewPlanLookup(rootDir, plans...)
+	claimed := claim.Others(rootDir, time.Now())
+	nodes := make(map[string]*schedule.Node)
+	progressFor := make(map[string]map[string][]models.SessionProgress)
+
 	for _, plan := range plans {
 		allProgress, _ := progress.ReadAll(rootDir, plan.Slug)
+		progressFor[plan.Slug] = allProgress
 		for i := range plan.Features {
 			for j := range plan.Features[i].Tasks {
 				task := &plan.Features[i].Tasks[j]
-				status := effectiveStatus(task, allProgress)
-				if status != models.StatusPending {
-					continue
-				}
-				if allDepsCompleted(plan, task, allProgress) {
-					planSet[plan.Slug] = plan
-				}
+				n := &schedule.Node{Plan: plan, Task: task, Status: effectiveStatus(task, allProgress)}
+				_, held := claimed[n.Ref()]
+				n.Runnable = n.Status == models.StatusPending && !held && allDepsCompleted(plan, task, allProgress, cross)
+				nodes[n.Ref()] = n
+				g.Nodes = append(g.Nodes, n)
 			}
 		}
 	}
 
-	if len(planSet) <= 1 {
-		return false, nil
-	}
-	var result []*models.Plan
-	for _, p := range planSet {
-		result = append(result, p)
+	for _, n := range g.Nodes {
+		if nodes[n.Ref()] != n {
+			continue // a plan listed twice
+		}
+		for _, dep := range n.Task.DependsOn {
+			target, isCross := resolveDependency(n.Plan, dep, progressFor[n.Plan.Slug], cross)
+			if target.task == nil {
+				continue
+			}
+			ref := n.Plan.Slug + "#" + target.ref
+			if isCross {
+				ref = target.ref
+			}
+			d := nodes[ref]
+			if d == nil {
+				slug, _, _ := models.ParseCrossPlanDep(ref)
+				depPlan, _ := cross.Load(slug)
+				d = &schedule.Node{Plan: depPlan, Task: target.task, Status: target.status}
+				nodes[ref] = d
+			}
+			if d != n {
+				n.Deps = append(n.Deps, d)
+			}
+		}
 	}
-	return true, result
+	return g
 }
 
 // effectiveStatus returns a task's status, considering progress files.
@@ -264,30 +302,76 @@ func effectiveStatus(task *models.Task, allProgress map[string][]models.SessionP
 	if len(sessions) == 0 {
 		return task.Status
 	}
-	// Use the latest session's status if filled in.
+	// Use the latest session's status if filled in. An abandoned session
+	// leaves the status etch recover set in the plan.
 	latest := sessions[len(sessions)-1]
-	if latest.Status != "" && latest.Status != "pending" {
+	if latest.Status != "" && latest.Status != "pending" && latest.Status != models.SessionAbandoned {
 		return models.ParseStatus(latest.Status)
 	}
 	return task.Status
 }
 
 // allDepsCompleted checks if all of a task's dependencies are completed.
-func allDepsCompleted(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress) bool {
+// Unknown same-plan dependencies are skipped, but an unresolvable cross-plan
+// dependency counts as unmet so work never starts ahead of another plan.
+func allDepsCompleted(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup) bool {
 	for _, dep := range task.DependsOn {
-		depID := extractTaskID(dep)
-		depTask := plan.TaskByID(depID)
-		if depTask == nil {
+		target, isCross := resolveDependency(plan, dep, allProgress, cross)
+		if target.task == nil {
+			if isCross {
+				return false
+			}
 			continue // unknown dependency, skip
 		}
-		status := effectiveStatus(depTask, allProgress)
-		if status != models.StatusCompleted {
+		if target.status != models.StatusCompleted {
 			return false
 		}
 	}
 	return true
 }
 
+// depTarget is the task a dependency string resolved to.
+type depTarget struct {
+	ref      string // "1.2", or "auth-system#1.2" for cross-plan deps
+	task     *models.Task
+	status   models.Status
+	progress map[string][]models.SessionProgress
+}
+
+// resolveDependency resolves a dependency string to its task, following
+// cross-plan references like "auth-system#1.2". target.task is nil if the
+// dependency can't be found; isCross reports whether it names another plan.
+func resolveDependency(plan *models.Plan, dep string, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup) (target depTarget, isCross bool) {
+	if slug, id, ok := models.ParseCrossPlanDep(dep); ok {
+		depPlan, depProgress := cross.Load(slug)
+		if depPlan == nil {
+			return depTarget{ref: slug + "#" + id}, true
+		}
+		depTask := depPlan.ResolveTaskID(id)
+		if depTask == nil {
+			return depTarget{ref: slug + "#" + id}, true
+		}
+		return depTarget{
+			ref:      slug + "#" + depTask.FullID(),
+			task:     depTask,
+			status:   effectiveStatus(depTask, depProgress),
+			progress: depProgress,
+		}, true
+	}
+
+	depID := extractTaskID(dep)
+	depTask := plan.TaskByID(depID)
+	if depTask == nil {
+		return depTarget{ref: depID}, false
+	}
+	return depTarget{
+		ref:      depTask.FullID(),
+		task:     depTask,
+		status:   effectiveStatus(depTask, allProgress),
+		progress: allProgress,
+	}, false
+}
+
 // extractTaskID extracts a task ID from dependency strings like "Task 1.2" or "1.2".
 func extractTaskID(dep string) string {
 	dep = strings.TrimSpace(dep)
@@ -320,7 +404,7 @@ func Assemble(rootDir string, plan *models.Plan, task *models.Task) (Result, err
 	sessionNum := sessionNumberFromPath(progressPath)
 
 	// Build context content.
-	content := buildTemplate(plan, task, allProgress, sessionNum, progressPath, rootDir)
+	content := buildTemplate(plan, task, allProgress, status.NewPlanLookup(rootDir, plan), sessionNum, progressPath, rootDir)
 
 	// Write context file.
 	ctxDir := filepath.Join(rootDir, contextDir)
@@ -344,6 +428,24 @@ func Assemble(rootDir string, plan *models.Plan, task *models.Task) (Result, err
 	}, nil
 }
 
+// Render builds the context prompt for the given plan and task without
+// writing anything. It points at the task's latest session file, or at the
+// file its first session would use.
+func Render(rootDir string, plan *models.Plan, task *models.Task) (string, error) {
+	allProgress, err := progress.ReadAll(rootDir, plan.Slug)
+	if err != nil {
+		allProgress = make(map[string][]models.SessionProgress)
+	}
+
+	progressPath, sessionNum, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
+	if err != nil {
+		sessionNum = 1
+		progressPath = progress.SessionPath(rootDir, plan.Slug, task.FullID(), sessionNum)
+	}
+
+	return buildTemplate(plan, task, allProgress, status.NewPlanLookup(rootDir, plan), sessionNum, progressPath, rootDir), nil
+}
+
 // AssembleFeature builds a combined context prompt for all actionable tasks in a feature.
 func AssembleFeature(rootDir string, plan *models.Plan, feature *models.Feature) (FeatureResult, error) {
 	allProgress, err := progress.ReadAll(rootDir, plan.Slug)
@@ -351,7 +453,8 @@ func AssembleFeature(rootDir string, plan *models.Plan, feature *models.Feature)
 		allProgress = make(map[string][]models.SessionProgress)
 	}
 
-	tasks := featurePendingTasks(plan, feature, allProgress)
+	cross := status.NewPlanLookup(rootDir, plan)
+	tasks := featurePendingTasks(plan, feature, allProgress, cross)
 	if len(tasks) == 0 {
 		return FeatureResult{}, etcherr.Project("no actionable pending tasks in feature").
 			WithHint("all tasks may be completed or have unsatisfied external dependencies — run 'etch status' to check")
@@ -373,7 +476,7 @@ func AssembleFeature(rootDir string, plan *models.Plan, feature *models.Feature)
 	}
 
 	// Build combined context content.
-	content := buildFeatureTemplate(plan, feature, tasks, allProgress, sessionNum, progressPaths, rootDir)
+	content := buildFeatureTemplate(plan, feature, tasks, allProgress, cross, sessionNum, progressPaths, rootDir)
 
 	// Write context file.
 	ctxDir := filepath.Join(rootDir, contextDir)
@@ -397,7 +500,7 @@ func AssembleFeature(rootDir string, plan *models.Plan, feature *models.Feature)
 	}, nil
 }
 
-func buildFeatureTemplate(plan *models.Plan, feature *models.Feature, tasks []*models.Task, allProgress map[string][]models.SessionProgress, sessionNum int, progressPaths map[string]string, rootDir string) string {
+func buildFeatureTemplate(plan *models.Plan, feature *models.Feature, tasks []*models.Task, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup, sessionNum int, progressPaths map[string]string, rootDir string) string {
 	var b strings.Builder
 
 	// Header.
@@ -450,11 +553,8 @@ func buildFeatureTemplate(plan *models.Plan, feature *models.Feature, tasks []*m
 		if len(task.DependsOn) > 0 {
 			depParts := make([]string, len(task.DependsOn))
 			for j, dep := range task.DependsOn {
-				depID := extractTaskID(dep)
-				depTask := plan.TaskByID(depID)
-				if depTask != nil {
-					status := effectiveStatus(depTask, allProgress)
-					depParts[j] = fmt.Sprintf("%s (%s)", dep, status)
+				if target, _ := resolveDependency(plan, dep, allProgress, cross); target.task != nil {
+					depParts[j] = fmt.Sprintf("%s (%s)", dep, target.status)
 				} else {
 					depParts[j] = dep
 				}
@@ -508,7 +608,7 @@ func buildFeatureTemplate(plan *models.Plan, feature *models.Feature, tasks []*m
 		if len(priorSessions) > 0 {
 			b.WriteString("### Previous Sessions\n")
 			for _, s := range priorSessions {
-				b.WriteString(fmt.Sprintf("\n**Session %03d (%s, %s):**\n", s.SessionNumber, s.Started, s.Status))
+				b.WriteString(fmt.Sprintf("\n**Session %03d (%s, %s):**\n", s.SessionNumber, s.StartedLabel(), s.Status))
 				if len(s.ChangesMade) > 0 {
 					b.WriteString(fmt.Sprintf("Changes: %s\n", strings.Join(s.ChangesMade, ", ")))
 				}
@@ -610,7 +710,7 @@ func sessionNumberFromPath(path string) int {
 	return n
 }
 
-func buildTemplate(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress, sessionNum int, progressPath, rootDir string) string {
+func buildTemplate(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup, sessionNum int, progressPath, rootDir string) string {
 	var b strings.Builder
 
 	// Header.
@@ -649,11 +749,8 @@ func buildTemplate(plan *models.Plan, task *models.Task, allProgress map[string]
 	if len(task.DependsOn) > 0 {
 		depParts := make([]string, len(task.DependsOn))
 		for i, dep := range task.DependsOn {
-			depID := extractTaskID(dep)
-			depTask := plan.TaskByID(depID)
-			if depTask != nil {
-				status := effectiveStatus(depTask, allProgress)
-				depParts[i] = fmt.Sprintf("%s (%s)", dep, status)
+			if target, _ := resolveDependency(plan, dep, allProgress, cross); target.task != nil {
+				depParts[i] = fmt.Sprintf("%s (%s)", dep, target.status)
 			} else {
 				depParts[i] = dep
 			}
@@ -710,7 +807,7 @@ func buildTemplate(plan *models.Plan, task *models.Task, allProgress map[string]
 	if len(priorSessions) > 0 {
 		b.WriteString("### Previous Sessions\n")
 		for _, s := range priorSessions {
-			b.WriteString(fmt.Sprintf("\n**Session %03d (%s, %s):**\n", s.SessionNumber, s.Started, s.Status))
+			b.WriteString(fmt.Sprintf("\n**Session %03d (%s, %s):**\n", s.SessionNumber, s.StartedLabel(), s.Status))
 			if len(s.ChangesMade) > 0 {
 				b.WriteString(fmt.Sprintf("Changes: %s\n", strings.Join(s.ChangesMade, ", ")))
 			}
@@ -730,11 +827,11 @@ func buildTemplate(plan *models.Plan, task *models.Task, allProgress map[string]
 	}
 
 	// Completed prerequisites.
-	completedDeps := getCompletedPrereqs(plan, task, allProgress)
+	completedDeps := getCompletedPrereqs(plan, task, allProgress, cross)
 	if len(completedDeps) > 0 {
 		b.WriteString("### Completed Prerequisites\n")
 		for _, dep := range completedDeps {
-			b.WriteString(fmt.Sprintf("\n**Task %s (%s):**\n", dep.task.FullID(), dep.task.Title))
+			b.WriteString(fmt.Sprintf("\n**Task %s (%s):**\n", dep.ref, dep.task.Title))
 			if dep.summary != "" {
 				b.WriteString(dep.summary + "\n")
 			}
@@ -831,24 +928,20 @@ func splitSentences(text string) []string {
 }
 
 type completedDep struct {
+	ref     string
 	task    *models.Task
 	summary string
 }
 
-func getCompletedPrereqs(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress) []completedDep {
+func getCompletedPrereqs(plan *models.Plan, task *models.Task, allProgress map[string][]models.SessionProgress, cross *status.PlanLookup) []completedDep {
 	var deps []completedDep
 	for _, dep := range task.DependsOn {
-		depID := extractTaskID(dep)
-		depTask := plan.TaskByID(depID)
-		if depTask == nil {
-			continue
-		}
-		status := effectiveStatus(depTask, allProgress)
-		if status != models.StatusCompleted {
+		target, _ := resolveDependency(plan, dep, allProgress, cross)
+		if target.task == nil || target.status != models.StatusCompleted {
 			continue
 		}
-		summary := summarizeTask(depTask, allProgress)
-		deps = append(deps, completedDep{task: depTask, summary: summary})
+		summary := summarizeTask(target.task, target.progress)
+		deps = append(deps, completedDep{ref: target.ref, task: target.task, summary: summary})
 	}
 	return deps
 }
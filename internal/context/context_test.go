@@ -6,8 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gsigler/etch/internal/claim"
 	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/schedule"
 )
 
 // writePlanFile writes a plan markdown file into the temp dir's .etch/plans/.
@@ -221,6 +224,43 @@ func TestAssemble_WritesFiles(t *testing.T) {
 	}
 }
 
+func TestRender_WritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+	plan := plans[0]
+	task := plan.TaskByID("1.2")
+
+	content, err := Render(dir, plan, task)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(content, "Token refresh endpoint") {
+		t.Error("expected task title in rendered context")
+	}
+	if !strings.Contains(content, "auth-system--task-1.2--001.md") {
+		t.Error("expected rendered context to point at the first session file")
+	}
+	for _, sub := range []string{"context", "progress"} {
+		if _, err := os.Stat(filepath.Join(dir, ".etch", sub)); !os.IsNotExist(err) {
+			t.Errorf(".etch/%s should not be created", sub)
+		}
+	}
+
+	writeProgressFile(t, dir, "auth-system--task-1.2--002.md", "# Session: Task 1.2 — Token refresh endpoint\n")
+	content, err = Render(dir, plan, task)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(content, "auth-system--task-1.2--002.md") {
+		t.Error("expected rendered context to point at the latest session file")
+	}
+}
+
 func TestAssemble_TokenEstimate(t *testing.T) {
 	dir := t.TempDir()
 	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
@@ -260,7 +300,8 @@ func TestAutoSelect_PicksNextPending(t *testing.T) {
 	}
 
 	// Task 1.1 is completed. Tasks 1.2 and 2.1 depend on 1.1 (completed).
-	// Both should be eligible. Auto-select should pick the first one: 1.2.
+	// Both are eligible; 2.1 goes first because the large Task 2.2 waits
+	// on it.
 	plan, task, err := ResolveTask(plans, "", "", dir)
 	if err != nil {
 		t.Fatalf("ResolveTask: %v", err)
@@ -269,8 +310,28 @@ func TestAutoSelect_PicksNextPending(t *testing.T) {
 	if plan.Slug != "auth-system" {
 		t.Errorf("plan = %q, want auth-system", plan.Slug)
 	}
+	if task.FullID() != "2.1" {
+		t.Errorf("task = %q, want 2.1 (longest chain of work behind it)", task.FullID())
+	}
+}
+
+func TestAutoSelect_SkipsClaimed(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	if _, err := claim.Acquire(dir, "auth-system", "2.1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+	_, task, err := ResolveTask(plans, "", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
 	if task.FullID() != "1.2" {
-		t.Errorf("task = %q, want 1.2 (first pending with satisfied deps)", task.FullID())
+		t.Errorf("task = %q, want 1.2 (2.1 is claimed)", task.FullID())
 	}
 }
 
@@ -309,6 +370,115 @@ Do third.
 	}
 }
 
+const crossPlanConsumer = `# Plan: Billing
+
+## Feature 1: Invoices
+
+### Task 1.1: Invoice API [pending]
+**Depends on:** auth-system#1.2
+Needs token refresh.
+
+### Task 1.2: Invoice export [pending]
+Independent.
+`
+
+func TestAutoSelect_CrossPlanDepPending(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	writePlanFile(t, dir, "billing", crossPlanConsumer)
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+
+	// auth-system#1.2 is still pending, so billing 1.1 must be skipped.
+	_, task, err := ResolveTask(plans, "billing", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if task.FullID() != "1.2" {
+		t.Errorf("task = %q, want 1.2 (1.1 waits on auth-system#1.2)", task.FullID())
+	}
+}
+
+func TestAutoSelect_CrossPlanDepCompleted(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	writePlanFile(t, dir, "billing", crossPlanConsumer)
+	writeProgressFile(t, dir, "auth-system--task-1.2--001.md", `# Session: Task 1.2
+**Plan:** auth-system
+**Task:** 1.2
+**Session:** 001
+**Started:** 2026-02-14 09:00
+**Status:** completed
+
+## Changes Made
+- internal/api/refresh.go
+
+## Decisions & Notes
+Refresh tokens rotate on use.
+`)
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+	billing, _ := filterPlan(plans, "billing")
+
+	_, task, err := ResolveTask(plans, "billing", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if task.FullID() != "1.1" {
+		t.Errorf("task = %q, want 1.1 once auth-system#1.2 is completed", task.FullID())
+	}
+
+	result, err := Assemble(dir, billing, billing.TaskByID("1.1"))
+	if err != nil {
+		t.Fatalf("Assemble: %v", err)
+	}
+	content, _ := os.ReadFile(result.ContextPath)
+	ctx := string(content)
+	for _, want := range []string{
+		"**Depends on:** auth-system#1.2 (completed)",
+		"**Task auth-system#1.2 (Token refresh endpoint):**",
+		"Refresh tokens rotate on use.",
+	} {
+		if !strings.Contains(ctx, want) {
+			t.Errorf("context missing %q", want)
+		}
+	}
+}
+
+func TestAutoSelect_CrossPlanDepUnknownPlan(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "billing", strings.Replace(crossPlanConsumer, "auth-system#1.2", "missing-plan#1.2", 1))
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+
+	_, task, err := ResolveTask(plans, "", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if task.FullID() != "1.2" {
+		t.Errorf("task = %q, want 1.2 (unknown cross-plan dep is unmet)", task.FullID())
+	}
+}
+
+// filterPlan returns the plan with the given slug.
+func filterPlan(plans []*models.Plan, slug string) (*models.Plan, bool) {
+	for _, p := range plans {
+		if p.Slug == slug {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
 func TestAutoSelect_AllCompleted(t *testing.T) {
 	dir := t.TempDir()
 	planContent := `# Plan: All Done
@@ -1125,7 +1295,7 @@ func TestFeaturePendingTasks_AllPending(t *testing.T) {
 	feature := &plan.Features[1] // Feature 2: Login Endpoints
 	allProgress := map[string][]models.SessionProgress{}
 
-	tasks := featurePendingTasks(plan, feature, allProgress)
+	tasks := featurePendingTasks(plan, feature, allProgress, nil)
 
 	// Task 2.1 depends on 1.1 (completed in plan), so it should be included.
 	// Task 2.2 depends on 2.1, which is intra-feature, so it should also be included.
@@ -1157,7 +1327,7 @@ func TestFeaturePendingTasks_SomeCompleted(t *testing.T) {
 		"2.1": {{TaskID: "2.1", SessionNumber: 1, Status: "completed"}},
 	}
 
-	tasks := featurePendingTasks(plan, feature, allProgress)
+	tasks := featurePendingTasks(plan, feature, allProgress, nil)
 
 	// Only 2.2 should remain (2.1 completed).
 	if len(tasks) != 1 {
@@ -1185,7 +1355,7 @@ func TestFeaturePendingTasks_AllCompleted(t *testing.T) {
 		"1.2": {{TaskID: "1.2", SessionNumber: 1, Status: "completed"}},
 	}
 
-	tasks := featurePendingTasks(plan, feature, allProgress)
+	tasks := featurePendingTasks(plan, feature, allProgress, nil)
 
 	if len(tasks) != 0 {
 		t.Errorf("got %d tasks, want 0 (all completed)", len(tasks))
@@ -1221,7 +1391,7 @@ Build it.
 	feature := &plan.Features[1] // Feature 2
 	allProgress := map[string][]models.SessionProgress{}
 
-	tasks := featurePendingTasks(plan, feature, allProgress)
+	tasks := featurePendingTasks(plan, feature, allProgress, nil)
 
 	// 2.1 depends on 1.1 (pending, external), so should be blocked.
 	if len(tasks) != 0 {
@@ -1493,3 +1663,83 @@ Do second.
 		t.Errorf("task = %q, want 1.2 (1.1 completed via progress)", task.FullID())
 	}
 }
+
+func TestAutoSelect_PlanPriority(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	writePlanFile(t, dir, "rate-limiting", strings.Replace(singleFeaturePlan, "## Overview", "**Priority:** 1\n\n## Overview", 1))
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+
+	// Priority settles which plan goes first, so there's nothing to pick.
+	if needsPicker, _ := NeedsPlanPicker(plans, dir); needsPicker {
+		t.Error("plans with different priorities shouldn't need the picker")
+	}
+	plan, task, err := ResolveTask(plans, "", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if plan.Slug != "rate-limiting" || task.FullID() != "1.2" {
+		t.Errorf("got %s#%s, want rate-limiting#1.2", plan.Slug, task.FullID())
+	}
+
+	// A plan slug still limits the choice.
+	plan, task, err = ResolveTask(plans, "auth-system", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if plan.Slug != "auth-system" || task.FullID() != "2.1" {
+		t.Errorf("got %s#%s, want auth-system#2.1", plan.Slug, task.FullID())
+	}
+}
+
+func TestAutoSelect_OrderStrategy(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte("[scheduler]\nstrategy = \"order\"\n"), 0o644)
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+	_, task, err := ResolveTask(plans, "", "", dir)
+	if err != nil {
+		t.Fatalf("ResolveTask: %v", err)
+	}
+	if task.FullID() != "1.2" {
+		t.Errorf("task = %q, want 1.2 (first in plan order)", task.FullID())
+	}
+}
+
+func TestScheduleGraph_CrossPlanDeps(t *testing.T) {
+	dir := t.TempDir()
+	writePlanFile(t, dir, "auth-system", multiFeaturePlan)
+	writePlanFile(t, dir, "billing", crossPlanConsumer)
+
+	plans, err := DiscoverPlans(dir)
+	if err != nil {
+		t.Fatalf("DiscoverPlans: %v", err)
+	}
+	g := ScheduleGraph(dir, plans)
+
+	var refresh *schedule.Node
+	for _, n := range g.Nodes {
+		if n.Ref() == "auth-system#1.2" {
+			refresh = n
+		}
+	}
+	deps := g.Dependents()[refresh]
+	if len(deps) != 1 || deps[0].Ref() != "billing#1.1" {
+		t.Errorf("dependents of auth-system#1.2 = %v", deps)
+	}
+	var runnable []string
+	for _, n := range g.Runnable() {
+		runnable = append(runnable, n.Ref())
+	}
+	if got := strings.Join(runnable, " "); got != "auth-system#1.2 auth-system#2.1 billing#1.2" {
+		t.Errorf("runnable = %s", got)
+	}
+}
@@ -0,0 +1,128 @@
+package document
+
+import "strings"
+
+// Pos locates a line in the source.
+type Pos struct {
+	Line   int // 1-based line number
+	Offset int // byte offset of the line's first character
+}
+
+// Role says what a line means to etch.
+type Role int
+
+const (
+	Text                   Role = iota // not interpreted: blank lines, separators, unknown sections, stray prose
+	PlanHeading                        // # Plan: Title [status]
+	Priority                           // **Priority:** N
+	OverviewHeading                    // ## Overview
+	Overview                           // plan overview text
+	SectionHeading                     // any other ## heading, e.g. ## Architecture Decisions
+	FeatureHeading                     // ## Feature N: Title
+	FeatureOverviewHeading             // ### Overview
+	FeatureOverview                    // feature overview text
+	TaskHeading                        // ### Task N.M: Title [status]
+	Complexity                         // **Complexity:** ...
+	Files                              // **Files:** ...
+	DependsOn                          // **Depends on:** ...
+	Description                        // task description text
+	Comment                            // > 💬 first line of a review comment
+	CommentContinuation                // > further lines of a review comment
+	CriteriaHeading                    // **Acceptance Criteria:**
+	Criterion                          // - [ ] / - [x] acceptance criterion
+)
+
+// Line is one source line, what it means, and which part of the plan owns
+// it. Ownership follows the most recent heading: every line after a task
+// heading belongs to that task until the next heading.
+type Line struct {
+	Text    string // the line exactly as written, without its newline
+	Pos     Pos
+	Role    Role
+	Feature int // index into Plan.Features of the owning feature, or -1
+	Task    int // index into the feature's Tasks of the owning task, or -1
+	Item    int // index of the criterion or comment within its task, or -1
+}
+
+// Document is a lossless, line-level view of a plan file. The parser fills
+// in each line's role and owner; the serializer uses them to rewrite only
+// what changed and copy everything else through untouched.
+type Document struct {
+	Lines []Line
+	// FinalNewline records whether the source ended with a newline.
+	FinalNewline bool
+}
+
+// New splits src into lines. Every line starts as Text with no owner.
+func New(src string) *Document {
+	d := &Document{}
+	if src == "" {
+		return d
+	}
+	if strings.HasSuffix(src, "\n") {
+		d.FinalNewline = true
+		src = src[:len(src)-1]
+	}
+	offset := 0
+	for i, text := range strings.Split(src, "\n") {
+		d.Lines = append(d.Lines, Line{
+			Text:    text,
+			Pos:     Pos{Line: i + 1, Offset: offset},
+			Feature: -1,
+			Task:    -1,
+			Item:    -1,
+		})
+		offset += len(text) + 1
+	}
+	return d
+}
+
+// String reassembles the document's source exactly.
+func (d *Document) String() string {
+	return Join(d.textLines(), d.FinalNewline)
+}
+
+func (d *Document) textLines() []string {
+	lines := make([]string, len(d.Lines))
+	for i, l := range d.Lines {
+		lines[i] = l.Text
+	}
+	return lines
+}
+
+// Join joins lines with newlines, adding a final newline when requested.
+func Join(lines []string, finalNewline bool) string {
+	s := strings.Join(lines, "\n")
+	if finalNewline && len(lines) > 0 {
+		s += "\n"
+	}
+	return s
+}
+
+// Owned returns the indices of the lines owned by a task, or by a feature
+// when task is -1 (including the lines of its tasks), in source order.
+func (d *Document) Owned(feature, task int) []int {
+	var idx []int
+	for i, l := range d.Lines {
+		if l.Feature == feature && (task < 0 || l.Task == task) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// Find returns the index of the first line with role owned by the given
+// feature and task (-1 for either matches lines with no such owner), or -1.
+func (d *Document) Find(role Role, feature, task int) int {
+	for i, l := range d.Lines {
+		if l.Role == role && l.Feature == feature && l.Task == task {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsBlank reports whether the line is empty or whitespace.
+func (l Line) IsBlank() bool {
+	return strings.TrimSpace(l.Text) == ""
+}
@@ -0,0 +1,59 @@
+package document
+
+import "testing"
+
+func TestNew_Positions(t *testing.T) {
+	d := New("# Plan: X\n\nabc\n")
+
+	if len(d.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(d.Lines))
+	}
+	want := []Pos{{1, 0}, {2, 10}, {3, 11}}
+	for i, l := range d.Lines {
+		if l.Pos != want[i] {
+			t.Errorf("line %d: pos = %+v, want %+v", i, l.Pos, want[i])
+		}
+		if l.Role != Text || l.Feature != -1 || l.Task != -1 || l.Item != -1 {
+			t.Errorf("line %d: expected unowned text, got %+v", i, l)
+		}
+	}
+	if !d.FinalNewline {
+		t.Error("expected FinalNewline")
+	}
+}
+
+func TestString_RoundTrip(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"one",
+		"one\n",
+		"one\n\n",
+		"\n",
+		"a\r\nb\r\n",
+		"  trailing spaces  \n\tindented\n",
+	} {
+		if got := New(src).String(); got != src {
+			t.Errorf("String() = %q, want %q", got, src)
+		}
+	}
+}
+
+func TestOwnedAndFind(t *testing.T) {
+	d := New("a\nb\nc\nd")
+	d.Lines[1].Role, d.Lines[1].Feature, d.Lines[1].Task = TaskHeading, 0, 0
+	d.Lines[2].Feature, d.Lines[2].Task = 0, 0
+	d.Lines[3].Role, d.Lines[3].Feature, d.Lines[3].Task = TaskHeading, 0, 1
+
+	if got := d.Owned(0, 0); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Owned(0, 0) = %v, want [1 2]", got)
+	}
+	if got := d.Owned(0, -1); len(got) != 3 {
+		t.Errorf("Owned(0, -1) = %v, want 3 lines", got)
+	}
+	if got := d.Find(TaskHeading, 0, 1); got != 3 {
+		t.Errorf("Find = %d, want 3", got)
+	}
+	if got := d.Find(Criterion, 0, 0); got != -1 {
+		t.Errorf("Find = %d, want -1", got)
+	}
+}
@@ -0,0 +1,187 @@
+// Package events keeps the audit trail of changes etch makes to plans: an
+// append-only JSON Lines log at .etch/events.jsonl with one event per
+// status change, criterion tick, comment, priority change, replan, or
+// deletion.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gsigler/etch/internal/atomicfile"
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// Type names the kind of change an event records.
+type Type string
+
+const (
+	TaskStatus     Type = "task_status"
+	PlanStatus     Type = "plan_status"
+	Criterion      Type = "criterion"
+	CommentAdded   Type = "comment_added"
+	CommentDeleted Type = "comment_deleted"
+	Priority       Type = "priority"
+	Replan         Type = "replan"
+	PlanDeleted    Type = "plan_deleted"
+)
+
+// Event is one change to a plan. Subject says what within the task or plan
+// changed, such as a criterion's text; Before and After hold the old and
+// new values, left empty when there is none. Reason is the explanation
+// given for a replan or for recovering an abandoned session.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Type    Type      `json:"type"`
+	Plan    string    `json:"plan"`
+	Task    string    `json:"task,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Before  string    `json:"before,omitempty"`
+	After   string    `json:"after,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// ActorEnvVar overrides the actor recorded for changes. etch sets it to
+// "agent:<session>" for the agent sessions it launches.
+const ActorEnvVar = "ETCH_ACTOR"
+
+// Actor describes who is making changes from this process: the value of
+// ETCH_ACTOR, "agent:claude" inside a Claude Code session, or otherwise
+// "human:<username>".
+func Actor() string {
+	if a := os.Getenv(ActorEnvVar); a != "" {
+		return a
+	}
+	if os.Getenv("CLAUDECODE") != "" {
+		return "agent:claude"
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "human:" + u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return "human:" + name
+	}
+	return "human"
+}
+
+// SessionName identifies a task's session the way its progress file is
+// named, e.g. "auth--task-1.2--003".
+func SessionName(planSlug, taskID string, session int) string {
+	return fmt.Sprintf("%s--task-%s--%03d", planSlug, taskID, session)
+}
+
+// SessionActor is the actor for changes derived from a session's progress
+// file during status reconciliation.
+func SessionActor(planSlug, taskID string, session int) string {
+	return "session:" + SessionName(planSlug, taskID, session)
+}
+
+// AgentActor is the actor for an agent working in the given session.
+func AgentActor(planSlug, taskID string, session int) string {
+	return "agent:" + SessionName(planSlug, taskID, session)
+}
+
+// Path returns the event log's path in the project at rootDir.
+func Path(rootDir string) string {
+	return filepath.Join(rootDir, ".etch", "events.jsonl")
+}
+
+// Append adds e to the project's event log, stamping the time if unset.
+func Append(rootDir string, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC().Truncate(time.Second)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return etcherr.WrapIO("encoding event", err)
+	}
+
+	path := Path(rootDir)
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return etcherr.WrapIO("opening event log", err).WithHint("Check file permissions for " + path)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return etcherr.WrapIO("writing event log", err).WithHint("Check file permissions for " + path)
+	}
+	return nil
+}
+
+// Record appends e to the log of the project holding the plan file at
+// planPath, filling in the plan's slug. Plans that aren't in a project's
+// .etch/plans directory have no log, so nothing is recorded for them.
+func Record(planPath string, e Event) error {
+	plansDir := filepath.Dir(planPath)
+	etchDir := filepath.Dir(plansDir)
+	if filepath.Base(plansDir) != "plans" || filepath.Base(etchDir) != ".etch" {
+		return nil
+	}
+	if e.Plan == "" {
+		e.Plan = strings.TrimSuffix(filepath.Base(planPath), ".md")
+	}
+	return Append(filepath.Dir(etchDir), e)
+}
+
+// Filter selects events. Zero fields match everything.
+type Filter struct {
+	Plan  string
+	Task  string
+	Since time.Time
+}
+
+func (f Filter) match(e Event) bool {
+	return (f.Plan == "" || e.Plan == f.Plan) &&
+		(f.Task == "" || e.Task == f.Task) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since))
+}
+
+// Read returns the project's events matching f, oldest first. A missing
+// log has no events.
+func Read(rootDir string, f Filter) ([]Event, error) {
+	path := Path(rootDir)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, etcherr.WrapIO("opening event log", err)
+	}
+	defer file.Close()
+
+	var out []Event
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, etcherr.WrapParse(fmt.Sprintf("%s line %d", filepath.Base(path), n), err).
+				WithHint("Fix or remove the malformed line in " + path)
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, etcherr.WrapIO("reading event log", err)
+	}
+	return out, nil
+}
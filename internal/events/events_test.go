@@ -0,0 +1,94 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendRead_Filters(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".etch"), 0o755)
+	t0 := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	for _, e := range []Event{
+		{Time: t0, Actor: "human:a", Type: TaskStatus, Plan: "auth", Task: "1.1", Before: "pending", After: "in_progress"},
+		{Time: t0.Add(time.Hour), Actor: "agent:x", Type: Criterion, Plan: "auth", Task: "1.2", Subject: "works", Before: "unmet", After: "met"},
+		{Time: t0.Add(2 * time.Hour), Actor: "human:a", Type: Priority, Plan: "billing", After: "1"},
+	} {
+		if err := Append(dir, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Read(dir, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[1].Subject != "works" || !all[2].Time.Equal(t0.Add(2*time.Hour)) {
+		t.Fatalf("round trip = %+v", all)
+	}
+
+	for name, tc := range map[string]struct {
+		f    Filter
+		want int
+	}{
+		"plan":  {Filter{Plan: "auth"}, 2},
+		"task":  {Filter{Plan: "auth", Task: "1.2"}, 1},
+		"since": {Filter{Since: t0.Add(30 * time.Minute)}, 2},
+		"none":  {Filter{Plan: "nope"}, 0},
+	} {
+		got, _ := Read(dir, tc.f)
+		if len(got) != tc.want {
+			t.Errorf("%s: got %d events, want %d", name, len(got), tc.want)
+		}
+	}
+}
+
+func TestRead_MissingAndMalformed(t *testing.T) {
+	dir := t.TempDir()
+	if evs, err := Read(dir, Filter{}); err != nil || evs != nil {
+		t.Errorf("missing log: %v, %v", evs, err)
+	}
+
+	os.MkdirAll(filepath.Join(dir, ".etch"), 0o755)
+	os.WriteFile(Path(dir), []byte("{\"type\":\"priority\"}\nnot json\n"), 0o644)
+	if _, err := Read(dir, Filter{}); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+}
+
+func TestRecord_OnlyInsideProjects(t *testing.T) {
+	dir := t.TempDir()
+	plans := filepath.Join(dir, ".etch", "plans")
+	os.MkdirAll(plans, 0o755)
+
+	if err := Record(filepath.Join(plans, "auth.md"), Event{Actor: "human:a", Type: PlanStatus, After: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	evs, _ := Read(dir, Filter{})
+	if len(evs) != 1 || evs[0].Plan != "auth" || evs[0].Time.IsZero() {
+		t.Fatalf("events = %+v", evs)
+	}
+
+	loose := t.TempDir()
+	if err := Record(filepath.Join(loose, "plan.md"), Event{Type: PlanStatus}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(loose, ".etch")); !os.IsNotExist(err) {
+		t.Error("recording a plan outside a project should write nothing")
+	}
+}
+
+func TestActor(t *testing.T) {
+	t.Setenv(ActorEnvVar, "")
+	t.Setenv("CLAUDECODE", "1")
+	if got := Actor(); got != "agent:claude" {
+		t.Errorf("inside Claude Code: %q", got)
+	}
+	t.Setenv(ActorEnvVar, AgentActor("auth", "1.2", 3))
+	if got := Actor(); got != "agent:auth--task-1.2--003" {
+		t.Errorf("override: %q", got)
+	}
+}
@@ -0,0 +1,282 @@
+// Package forecast estimates how much work is left in a plan. Each task's
+// complexity is turned into an expected number of sessions, calibrated from
+// the sessions the project's completed tasks actually took, and the
+// project's recent pace turns sessions into calendar time.
+package forecast
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gsigler/etch/internal/graph"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// maxSessionDuration discards durations too long to be one sitting, such
+// as a progress file touched days after its session.
+const maxSessionDuration = 24 * time.Hour
+
+// DefaultSessions is the expected sessions per task before any history, in
+// line with the default complexity guide.
+var DefaultSessions = map[models.Complexity]float64{
+	models.ComplexitySmall:  1,
+	models.ComplexityMedium: 2,
+	models.ComplexityLarge:  3,
+}
+
+// complexities lists the calibrated complexities in display order.
+var complexities = []models.Complexity{models.ComplexitySmall, models.ComplexityMedium, models.ComplexityLarge}
+
+// Estimate is the expected sessions for a task of one complexity.
+type Estimate struct {
+	Sessions float64
+	Samples  int // completed tasks it was calibrated from
+}
+
+// Calibration is what the project's history says about its tasks.
+type Calibration struct {
+	Estimates map[models.Complexity]Estimate
+	// SessionDuration is the median length of a session, or 0 if unknown.
+	SessionDuration time.Duration
+	// SessionsPerDay is the project's pace, or 0 if there isn't enough
+	// history to tell.
+	SessionsPerDay float64
+}
+
+// Expected returns the sessions a task of complexity c is expected to take.
+// Tasks without a known complexity count as medium.
+func (c Calibration) Expected(cx models.Complexity) float64 {
+	if e, ok := c.Estimates[cx]; ok {
+		return e.Sessions
+	}
+	return c.Estimates[models.ComplexityMedium].Sessions
+}
+
+// Describe summarises the calibration in one line.
+func (c Calibration) Describe() string {
+	s := "Sessions per task:"
+	for i, cx := range complexities {
+		if i > 0 {
+			s += ","
+		}
+		e := c.Estimates[cx]
+		if e.Samples == 0 {
+			s += fmt.Sprintf(" %s %.1f (default)", cx, e.Sessions)
+		} else {
+			s += fmt.Sprintf(" %s %.1f (%d %s)", cx, e.Sessions, e.Samples, status.Plural(e.Samples, "task", "tasks"))
+		}
+	}
+	return s
+}
+
+// Session is one recorded session of a task.
+type Session struct {
+	Started  time.Time
+	Duration time.Duration // 0 if unknown
+}
+
+// TaskHistory is a task with the sessions it has had.
+type TaskHistory struct {
+	Complexity models.Complexity
+	Status     models.Status
+	Sessions   []Session
+}
+
+// History collects the session history of every task in plans. A session's
+// duration is the active time recorded when it ended or, for sessions
+// without an end time, the time from its start to the last write to its
+// progress file.
+func History(rootDir string, plans []*models.Plan) []TaskHistory {
+	var history []TaskHistory
+	for _, plan := range plans {
+		allProgress, _ := progress.ReadAll(rootDir, plan.Slug)
+		for i := range plan.Features {
+			for j := range plan.Features[i].Tasks {
+				task := &plan.Features[i].Tasks[j]
+				sessions := allProgress[task.FullID()]
+				h := TaskHistory{Complexity: task.Complexity, Status: status.Effective(task, sessions)}
+				for _, sp := range sessions {
+					if sp.Started.IsZero() {
+						continue
+					}
+					s := Session{Started: sp.Started}
+					if !sp.Ended.IsZero() {
+						s.Duration = sp.Active
+					} else if info, err := os.Stat(progress.SessionPath(rootDir, plan.Slug, task.FullID(), sp.SessionNumber)); err == nil {
+						// Sessions from before end times were recorded ran
+						// until the last write to their file.
+						if d := info.ModTime().Sub(sp.Started); d > 0 && d < maxSessionDuration {
+							s.Duration = d
+						}
+					}
+					h.Sessions = append(h.Sessions, s)
+				}
+				history = append(history, h)
+			}
+		}
+	}
+	return history
+}
+
+// Calibrate derives a calibration from history. Each complexity's estimate
+// is the mean sessions its completed tasks took, with the default counted
+// as one more task so that a single outlier can't swing it.
+func Calibrate(history []TaskHistory, now time.Time) Calibration {
+	cal := Calibration{Estimates: make(map[models.Complexity]Estimate)}
+
+	total := make(map[models.Complexity]int)
+	for _, h := range history {
+		if h.Status != models.StatusCompleted || len(h.Sessions) == 0 {
+			continue
+		}
+		if _, ok := DefaultSessions[h.Complexity]; !ok {
+			continue
+		}
+		e := cal.Estimates[h.Complexity]
+		e.Samples++
+		cal.Estimates[h.Complexity] = e
+		total[h.Complexity] += len(h.Sessions)
+	}
+	for _, cx := range complexities {
+		e := cal.Estimates[cx]
+		e.Sessions = (DefaultSessions[cx] + float64(total[cx])) / float64(e.Samples+1)
+		cal.Estimates[cx] = e
+	}
+
+	var durations []time.Duration
+	var first time.Time
+	count := 0
+	for _, h := range history {
+		for _, s := range h.Sessions {
+			if s.Duration > 0 {
+				durations = append(durations, s.Duration)
+			}
+			if first.IsZero() || s.Started.Before(first) {
+				first = s.Started
+			}
+			count++
+		}
+	}
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		cal.SessionDuration = durations[len(durations)/2]
+	}
+	// Pace needs a few sessions to mean anything; spans under a day count
+	// as a day.
+	if count >= 3 {
+		days := max(now.Sub(first).Hours()/24, 1)
+		cal.SessionsPerDay = float64(count) / days
+	}
+	return cal
+}
+
+// Task is the forecast for one task.
+type Task struct {
+	ID         string
+	Title      string
+	Status     models.Status
+	Complexity models.Complexity
+	Sessions   int     // sessions so far
+	Remaining  float64 // expected sessions still to go
+	Critical   bool
+}
+
+// Forecast is the estimate for the rest of a plan.
+type Forecast struct {
+	Title     string
+	Slug      string
+	Tasks     []Task
+	Remaining float64 // total expected sessions
+	// CriticalPath is the chain of unfinished tasks that takes the most
+	// sessions end to end, first task first.
+	CriticalPath     []string
+	CriticalSessions float64
+	// Work is Remaining at the median session length, or 0 if unknown.
+	Work time.Duration
+	// ETA is when the plan should be done at the project's pace, or zero
+	// if the pace is unknown.
+	ETA time.Time
+}
+
+// Plan forecasts the rest of plan from its progress and cal.
+func Plan(plan *models.Plan, allProgress map[string][]models.SessionProgress, cal Calibration, now time.Time) Forecast {
+	fc := Forecast{Title: plan.Title, Slug: plan.Slug}
+	index := make(map[string]int)
+
+	for i := range plan.Features {
+		for j := range plan.Features[i].Tasks {
+			task := &plan.Features[i].Tasks[j]
+			sessions := allProgress[task.FullID()]
+			t := Task{
+				ID:         task.FullID(),
+				Title:      task.Title,
+				Status:     status.Effective(task, sessions),
+				Complexity: task.Complexity,
+				Sessions:   len(sessions),
+			}
+			if t.Status != models.StatusCompleted {
+				// A task that has used up its estimate still needs some
+				// time to finish.
+				t.Remaining = max(cal.Expected(task.Complexity)-float64(t.Sessions), 0.5)
+			}
+			fc.Remaining += t.Remaining
+			index[t.ID] = len(fc.Tasks)
+			fc.Tasks = append(fc.Tasks, t)
+		}
+	}
+
+	fc.CriticalPath, fc.CriticalSessions = criticalPath(plan, fc.Tasks, index)
+	for _, id := range fc.CriticalPath {
+		fc.Tasks[index[id]].Critical = true
+	}
+
+	if cal.SessionDuration > 0 {
+		fc.Work = time.Duration(fc.Remaining * float64(cal.SessionDuration))
+	}
+	if cal.SessionsPerDay > 0 && fc.Remaining > 0 {
+		fc.ETA = now.Add(time.Duration(fc.Remaining / cal.SessionsPerDay * float64(24*time.Hour)))
+	}
+	return fc
+}
+
+// criticalPath returns the chain of unfinished tasks through DependsOn with
+// the most remaining sessions, and that number.
+func criticalPath(plan *models.Plan, tasks []Task, index map[string]int) ([]string, float64) {
+	singleFeature := len(plan.Features) == 1
+	deps := make(map[string][]string)
+	for i := range plan.Features {
+		for _, task := range plan.Features[i].Tasks {
+			for _, dep := range task.DependsOn {
+				if id := status.DependencyID(dep, singleFeature); id != "" && id != task.FullID() {
+					if _, ok := index[id]; ok {
+						deps[task.FullID()] = append(deps[task.FullID()], id)
+					}
+				}
+			}
+		}
+	}
+
+	var unfinished []string
+	for _, t := range tasks {
+		if t.Remaining > 0 {
+			unfinished = append(unfinished, t.ID)
+		}
+	}
+	// Chains are followed from the last task back through its dependencies.
+	path, sessions := graph.HeaviestChain(unfinished, func(id string) []string {
+		var next []string
+		for _, d := range deps[id] {
+			if tasks[index[d]].Remaining > 0 {
+				next = append(next, d)
+			}
+		}
+		return next
+	}, func(id string) float64 { return tasks[index[id]].Remaining })
+	slices.Reverse(path)
+	return path, sessions
+}
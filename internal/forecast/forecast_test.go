@@ -0,0 +1,112 @@
+package forecast
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+)
+
+func sessions(started time.Time, durations ...time.Duration) []Session {
+	var s []Session
+	for i, d := range durations {
+		s = append(s, Session{Started: started.Add(time.Duration(i) * 24 * time.Hour), Duration: d})
+	}
+	return s
+}
+
+func TestCalibrate(t *testing.T) {
+	now := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	start := now.Add(-10 * 24 * time.Hour)
+	history := []TaskHistory{
+		{Complexity: models.ComplexitySmall, Status: models.StatusCompleted, Sessions: sessions(start, time.Hour)},
+		{Complexity: models.ComplexitySmall, Status: models.StatusCompleted, Sessions: sessions(start, 30*time.Minute, 0)},
+		{Complexity: models.ComplexityLarge, Status: models.StatusCompleted, Sessions: sessions(start, 2*time.Hour, 2*time.Hour, 2*time.Hour, 2*time.Hour, 2*time.Hour)},
+		// Unfinished tasks and tasks without a known complexity don't
+		// calibrate estimates, but their sessions count toward pace.
+		{Complexity: models.ComplexitySmall, Status: models.StatusInProgress, Sessions: sessions(start, 0, 0, 0, 0)},
+		{Complexity: "huge", Status: models.StatusCompleted, Sessions: sessions(start, 0)},
+	}
+
+	cal := Calibrate(history, now)
+	for cx, want := range map[models.Complexity]Estimate{
+		models.ComplexitySmall:  {Sessions: (1 + 3) / 3.0, Samples: 2},
+		models.ComplexityMedium: {Sessions: 2, Samples: 0},
+		models.ComplexityLarge:  {Sessions: (3 + 5) / 2.0, Samples: 1},
+	} {
+		if got := cal.Estimates[cx]; got != want {
+			t.Errorf("%s estimate = %+v, want %+v", cx, got, want)
+		}
+	}
+	if cal.Expected("huge") != 2 {
+		t.Errorf("unknown complexity should count as medium, got %v", cal.Expected("huge"))
+	}
+	if cal.SessionDuration != 2*time.Hour {
+		t.Errorf("median session = %v, want 2h", cal.SessionDuration)
+	}
+	if cal.SessionsPerDay != 1.3 {
+		t.Errorf("pace = %v sessions/day, want 13 over 10 days", cal.SessionsPerDay)
+	}
+
+	if empty := Calibrate(nil, now); empty.SessionsPerDay != 0 || empty.SessionDuration != 0 || empty.Expected(models.ComplexityLarge) != 3 {
+		t.Errorf("calibration without history = %+v", empty)
+	}
+}
+
+const testPlan = `# Plan: Auth
+
+## Feature 1: Core
+
+### Task 1.1: Schema [completed]
+**Complexity:** small
+
+### Task 1.2: Tokens [pending]
+**Complexity:** large
+**Depends on:** Task 1.1
+
+### Task 1.3: Login [in_progress]
+**Complexity:** medium
+**Depends on:** Task 1.2
+
+### Task 1.4: Docs [pending]
+**Complexity:** small
+`
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "auth.md")
+	os.WriteFile(path, []byte(testPlan), 0o644)
+	plan, err := parser.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	cal := Calibrate(nil, now)
+	cal.SessionDuration = time.Hour
+	cal.SessionsPerDay = 2
+	progress := map[string][]models.SessionProgress{
+		"1.3": {{Status: "partial"}, {Status: "partial"}, {Status: "partial"}},
+	}
+
+	fc := Plan(plan, progress, cal, now)
+	// 1.2 large (3) + 1.3 over its estimate (0.5) + 1.4 small (1).
+	if fc.Remaining != 4.5 {
+		t.Errorf("remaining = %v, want 4.5", fc.Remaining)
+	}
+	if len(fc.CriticalPath) != 2 || fc.CriticalPath[0] != "1.2" || fc.CriticalPath[1] != "1.3" || fc.CriticalSessions != 3.5 {
+		t.Errorf("critical path = %v (%v sessions)", fc.CriticalPath, fc.CriticalSessions)
+	}
+	if !fc.Tasks[1].Critical || fc.Tasks[3].Critical || fc.Tasks[0].Remaining != 0 {
+		t.Errorf("tasks = %+v", fc.Tasks)
+	}
+	if fc.Work != 270*time.Minute {
+		t.Errorf("work = %v, want 4h30m", fc.Work)
+	}
+	if want := now.Add(54 * time.Hour); !fc.ETA.Equal(want) {
+		t.Errorf("ETA = %v, want %v", fc.ETA, want)
+	}
+}
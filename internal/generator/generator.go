@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/gsigler/etch/internal/atomicfile"
 	etcherr "github.com/gsigler/etch/internal/errors"
 )
 
@@ -17,8 +18,8 @@ func WritePlan(rootDir, slug, markdown string) (string, error) {
 	}
 
 	path := filepath.Join(plansDir, slug+".md")
-	if err := os.WriteFile(path, []byte(markdown), 0o644); err != nil {
-		return "", etcherr.WrapIO("writing plan file", err)
+	if err := atomicfile.Replace(path, []byte(markdown), 0o644); err != nil {
+		return "", err
 	}
 	return path, nil
 }
@@ -67,9 +67,10 @@ A plan markdown file follows this exact structure:
 - Every task MUST have a status tag: [pending]
 - Every task MUST have **Complexity:** (small, medium, or large)
 - Every task MUST have **Files:** listing specific files it will create or modify
-- Tasks MAY have **Depends on:** referencing other task IDs (e.g. "Task 1.1")
+- Tasks MAY have **Depends on:** referencing other task IDs (e.g. "Task 1.1"), or tasks in another plan as <plan-slug>#<task-id> (e.g. "auth-system#1.2")
 - Each task should have 3-5 acceptance criteria
 - Include at least one verification criterion per task (e.g., "Tests pass", "No regressions in existing tests")
+- When a criterion can be checked by a shell command, append it in backticks as verify: <command> (e.g. "Tests pass `+"`"+`verify: go test ./internal/auth/...`+"`"+`")
 - Every feature MUST end with a validation task that verifies the implementation works (e.g., writing tests, running the app, checking edge cases)
 - Use single-feature format when there is only one logical grouping
 - Use multi-feature format when work spans distinct areas
@@ -1,12 +1,16 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/gsigler/etch/internal/api"
+	"github.com/gsigler/etch/internal/atomicfile"
+	"github.com/gsigler/etch/internal/claude"
 	etcherr "github.com/gsigler/etch/internal/errors"
 	"github.com/gsigler/etch/internal/models"
 )
@@ -61,10 +65,46 @@ func BackupPlan(planPath, rootDir string) (string, error) {
 
 // ApplyRefinement writes the refined plan to disk, overwriting the original.
 func ApplyRefinement(planPath, newMarkdown string) error {
-	if err := os.WriteFile(planPath, []byte(newMarkdown), 0o644); err != nil {
-		return etcherr.WrapIO("writing refined plan", err)
+	return atomicfile.Replace(planPath, []byte(newMarkdown), 0o644)
+}
+
+// Refine sends the plan and its review comments to the Anthropic API and
+// returns the revised plan markdown. cb is called with each streamed text
+// delta so callers can show progress while the response arrives.
+func Refine(ctx context.Context, client *api.Client, planMarkdown, comments string, cb api.StreamCallback) (string, error) {
+	text, err := client.SendStream(ctx, buildRefineSystemPrompt(), buildRefineUserMessage(planMarkdown, comments), cb)
+	if err != nil {
+		return "", err
+	}
+	return cleanRefinedPlan(text)
+}
+
+// RefineWithClaude performs the same refinement as Refine through a
+// non-interactive Claude Code subprocess. It is the fallback used when no
+// API key is configured.
+func RefineWithClaude(ctx context.Context, planMarkdown, comments, workDir string, cb claude.OutputCallback) (string, error) {
+	prompt := buildRefineSystemPrompt() + "\n\n" + buildRefineUserMessage(planMarkdown, comments)
+	text, err := claude.Print(ctx, prompt, workDir, cb)
+	if err != nil {
+		return "", err
+	}
+	return cleanRefinedPlan(text)
+}
+
+// cleanRefinedPlan strips any preamble or surrounding code fence from a
+// model response so that only the plan document starting at "# Plan:" remains.
+func cleanRefinedPlan(text string) (string, error) {
+	idx := strings.Index(text, "# Plan:")
+	if idx < 0 {
+		return "", etcherr.API("refinement response did not contain a plan").
+			WithHint("the model must return a document starting with \"# Plan:\" — try again")
+	}
+	fenced := strings.Contains(text[:idx], "```")
+	text = strings.TrimRight(text[idx:], " \t\n")
+	if fenced {
+		text = strings.TrimSuffix(text, "```")
 	}
-	return nil
+	return strings.TrimRight(text, " \t\n") + "\n", nil
 }
 
 // GenerateDiff produces a unified-style colored diff between old and new text.
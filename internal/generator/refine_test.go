@@ -1,11 +1,17 @@
 package generator
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/gsigler/etch/internal/api"
 	"github.com/gsigler/etch/internal/models"
 )
 
@@ -265,3 +271,76 @@ func TestBuildRefineUserMessage(t *testing.T) {
 		t.Error("user message should have Review Comments section")
 	}
 }
+
+// --- Refinement tests ---
+
+func TestRefine_StreamsRevisedPlan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			System   string `json:"system"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !strings.Contains(req.System, "Plan Format Specification") {
+			t.Error("expected refine system prompt")
+		}
+		if len(req.Messages) != 1 || !strings.Contains(req.Messages[0].Content, "Fix this") {
+			t.Errorf("expected comments in user message, got %+v", req.Messages)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Here is the plan:\n\n", "# Plan: Revised\n", "\n## Overview\nBetter.\n"} {
+			data, _ := json.Marshal(map[string]interface{}{
+				"type":  "content_block_delta",
+				"delta": map[string]string{"type": "text_delta", "text": chunk},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer srv.Close()
+
+	client := api.NewClient("key", "model")
+	client.BaseURL = srv.URL
+
+	var chunks int
+	out, err := Refine(context.Background(), client, "# Plan: Test\n", "> 💬 Fix this\n", func(string) { chunks++ })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chunks != 3 {
+		t.Errorf("expected 3 streamed chunks, got %d", chunks)
+	}
+	want := "# Plan: Revised\n\n## Overview\nBetter.\n"
+	if out != want {
+		t.Errorf("refined plan = %q, want %q", out, want)
+	}
+}
+
+func TestCleanRefinedPlan_StripsFence(t *testing.T) {
+	out, err := cleanRefinedPlan("```markdown\n# Plan: Test\n\n### Task 1: A [pending]\n```\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "# Plan: Test\n\n### Task 1: A [pending]\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestCleanRefinedPlan_KeepsTrailingFenceInPlan(t *testing.T) {
+	in := "# Plan: Test\n\n### Task 1: A [pending]\n```go\nx := 1\n```\n"
+	out, err := cleanRefinedPlan(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != in {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestCleanRefinedPlan_NoPlan(t *testing.T) {
+	if _, err := cleanRefinedPlan("I could not do that."); err == nil {
+		t.Fatal("expected error when response has no plan heading")
+	}
+}
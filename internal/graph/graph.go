@@ -0,0 +1,413 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// Node is a single task in the dependency graph.
+type Node struct {
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`
+	Feature  int           `json:"feature"`
+	Status   models.Status `json:"status"`
+	Blocked  bool          `json:"blocked,omitempty"`
+	Weight   int           `json:"weight"`
+	Runnable bool          `json:"runnable,omitempty"`
+	Critical bool          `json:"critical,omitempty"`
+}
+
+// Edge points from a dependency to the task that depends on it.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Cluster groups the nodes belonging to one feature.
+type Cluster struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	Nodes  []string `json:"nodes"`
+}
+
+// Graph is the task dependency DAG for a single plan, annotated with
+// reconciled status, the critical path, and the runnable frontier.
+type Graph struct {
+	Title        string    `json:"title"`
+	Slug         string    `json:"slug"`
+	Clusters     []Cluster `json:"clusters"`
+	Nodes        []*Node   `json:"nodes"`
+	Edges        []Edge    `json:"edges"`
+	CriticalPath []string  `json:"critical_path"`
+	Runnable     []string  `json:"runnable"`
+
+	byID map[string]*Node
+}
+
+// Node returns the node with the given ID, or nil.
+func (g *Graph) Node(id string) *Node {
+	return g.byID[id]
+}
+
+// ComplexityWeight maps a task's complexity to a relative cost used when
+// computing the critical path. Unknown or missing complexity counts as medium.
+func ComplexityWeight(c models.Complexity) int {
+	switch c {
+	case models.ComplexitySmall:
+		return 1
+	case models.ComplexityLarge:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Build constructs the dependency graph for a plan. Task status comes from
+// ps (the reconciled status from status.Run); complexity comes from plan.
+func Build(plan *models.Plan, ps status.PlanStatus) *Graph {
+	g := &Graph{
+		Title: ps.Title,
+		Slug:  ps.Slug,
+		byID:  make(map[string]*Node),
+	}
+
+	singleFeature := len(ps.Features) == 1
+
+	for _, f := range ps.Features {
+		cl := Cluster{Number: f.Number, Title: f.Title}
+		for _, t := range f.Tasks {
+			weight := 2
+			if pt := plan.TaskByID(t.ID); pt != nil {
+				weight = ComplexityWeight(pt.Complexity)
+			}
+			n := &Node{
+				ID:      t.ID,
+				Title:   t.Title,
+				Feature: f.Number,
+				Status:  t.Status,
+				Blocked: t.IsBlocked,
+				Weight:  weight,
+			}
+			g.Nodes = append(g.Nodes, n)
+			g.byID[n.ID] = n
+			cl.Nodes = append(cl.Nodes, n.ID)
+		}
+		g.Clusters = append(g.Clusters, cl)
+	}
+
+	for _, f := range ps.Features {
+		for _, t := range f.Tasks {
+			for _, dep := range t.DependsOn {
+				depID := status.DependencyID(dep, singleFeature)
+				if depID == "" || depID == t.ID || g.byID[depID] == nil {
+					continue
+				}
+				g.Edges = append(g.Edges, Edge{From: depID, To: t.ID})
+			}
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if n.Status == models.StatusPending && !n.Blocked {
+			n.Runnable = true
+			g.Runnable = append(g.Runnable, n.ID)
+		}
+	}
+
+	g.CriticalPath = g.criticalPath()
+	for _, id := range g.CriticalPath {
+		g.byID[id].Critical = true
+	}
+
+	return g
+}
+
+// criticalPath returns the heaviest chain of unfinished tasks through the
+// dependency graph, ordered from the first task to start to the last.
+func (g *Graph) criticalPath() []string {
+	deps := make(map[string][]string)
+	for _, e := range g.Edges {
+		deps[e.To] = append(deps[e.To], e.From)
+	}
+
+	remaining := func(id string) bool {
+		return g.byID[id].Status != models.StatusCompleted
+	}
+	var unfinished []string
+	for _, n := range g.Nodes {
+		if remaining(n.ID) {
+			unfinished = append(unfinished, n.ID)
+		}
+	}
+
+	// Chains are followed from the last task back through its dependencies.
+	path, _ := HeaviestChain(unfinished, func(id string) []string {
+		var next []string
+		for _, d := range deps[id] {
+			if remaining(d) {
+				next = append(next, d)
+			}
+		}
+		return next
+	}, func(id string) int { return g.byID[id].Weight })
+	slices.Reverse(path)
+	return path
+}
+
+// Chains computes, for each of starts and every node reached from them
+// through next, the weight of the heaviest chain that begins at the node
+// and follows next, counting each node's weight once. link maps a node to
+// the one after it on that chain, if any. Cycles, which validate reports,
+// are cut rather than followed forever.
+func Chains[K comparable, W int | float64](starts []K, next func(K) []K, weight func(K) W) (cost map[K]W, link map[K]K) {
+	cost = make(map[K]W)
+	link = make(map[K]K)
+	visiting := make(map[K]bool)
+
+	var visit func(k K) W
+	visit = func(k K) W {
+		if c, ok := cost[k]; ok {
+			return c
+		}
+		if visiting[k] {
+			return 0
+		}
+		visiting[k] = true
+		var best W
+		for _, n := range next(k) {
+			if c := visit(n); c > best {
+				best = c
+				link[k] = n
+			}
+		}
+		visiting[k] = false
+		cost[k] = best + weight(k)
+		return cost[k]
+	}
+	for _, k := range starts {
+		visit(k)
+	}
+	return cost, link
+}
+
+// HeaviestChain returns the heaviest chain that Chains finds, starting from
+// the first of starts with the greatest cost, and its weight. It returns
+// nil if no chain has positive weight.
+func HeaviestChain[K comparable, W int | float64](starts []K, next func(K) []K, weight func(K) W) ([]K, W) {
+	cost, link := Chains(starts, next, weight)
+	var first K
+	var best W
+	found := false
+	for _, k := range starts {
+		if cost[k] > best {
+			first, best, found = k, cost[k], true
+		}
+	}
+	if !found {
+		return nil, best
+	}
+	chain := []K{first}
+	for k, ok := link[first]; ok; k, ok = link[k] {
+		chain = append(chain, k)
+	}
+	return chain, best
+}
+
+// isCriticalEdge reports whether e joins two consecutive critical-path tasks.
+func (g *Graph) isCriticalEdge(e Edge) bool {
+	for i := 0; i+1 < len(g.CriticalPath); i++ {
+		if g.CriticalPath[i] == e.From && g.CriticalPath[i+1] == e.To {
+			return true
+		}
+	}
+	return false
+}
+
+// displayStatus returns the status used for coloring: pending tasks whose
+// dependencies are unfinished are shown as blocked.
+func (n *Node) displayStatus() models.Status {
+	if n.Blocked {
+		return models.StatusBlocked
+	}
+	return n.Status
+}
+
+// statusColors maps a status to fill and font colors shared by DOT and Mermaid.
+var statusColors = map[models.Status][2]string{
+	models.StatusCompleted:  {"#2e7d32", "#ffffff"},
+	models.StatusInProgress: {"#f9a825", "#000000"},
+	models.StatusPending:    {"#e0e0e0", "#000000"},
+	models.StatusBlocked:    {"#ef6c00", "#ffffff"},
+	models.StatusFailed:     {"#c62828", "#ffffff"},
+}
+
+// DOT renders the graph in Graphviz DOT format.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("digraph %q {\n", g.Slug))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString(fmt.Sprintf("  label=%q;\n", g.Title))
+	b.WriteString("  labelloc=t;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	for _, cl := range g.Clusters {
+		b.WriteString(fmt.Sprintf("\n  subgraph cluster_%d {\n", cl.Number))
+		b.WriteString(fmt.Sprintf("    label=%q;\n", fmt.Sprintf("Feature %d: %s", cl.Number, cl.Title)))
+		b.WriteString("    style=dashed;\n")
+		for _, id := range cl.Nodes {
+			n := g.byID[id]
+			colors := statusColors[n.displayStatus()]
+			attrs := []string{
+				fmt.Sprintf("label=%q", fmt.Sprintf("%s %s\n%s", n.displayStatus().Icon(), n.ID, n.Title)),
+				fmt.Sprintf("fillcolor=%q", colors[0]),
+				fmt.Sprintf("fontcolor=%q", colors[1]),
+			}
+			if n.Critical {
+				attrs = append(attrs, `color="#d50000"`, "penwidth=3")
+			}
+			if n.Runnable {
+				attrs = append(attrs, "peripheries=2")
+			}
+			b.WriteString(fmt.Sprintf("    %q [%s];\n", n.ID, strings.Join(attrs, ", ")))
+		}
+		b.WriteString("  }\n")
+	}
+
+	if len(g.Edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, e := range g.Edges {
+		if g.isCriticalEdge(e) {
+			b.WriteString(fmt.Sprintf("  %q -> %q [color=\"#d50000\", penwidth=3];\n", e.From, e.To))
+		} else {
+			b.WriteString(fmt.Sprintf("  %q -> %q;\n", e.From, e.To))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidID converts a task ID like "1.3b" into a Mermaid-safe node ID.
+func mermaidID(id string) string {
+	return "t" + strings.ReplaceAll(id, ".", "_")
+}
+
+// mermaidLabel escapes text for use inside a quoted Mermaid label.
+func mermaidLabel(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+// Mermaid renders the graph as a Mermaid flowchart suitable for pasting into
+// Markdown (e.g. PR descriptions).
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for _, cl := range g.Clusters {
+		b.WriteString(fmt.Sprintf("  subgraph f%d[\"Feature %d: %s\"]\n", cl.Number, cl.Number, mermaidLabel(cl.Title)))
+		for _, id := range cl.Nodes {
+			n := g.byID[id]
+			b.WriteString(fmt.Sprintf("    %s[\"%s %s: %s\"]\n", mermaidID(n.ID), n.displayStatus().Icon(), n.ID, mermaidLabel(n.Title)))
+		}
+		b.WriteString("  end\n")
+	}
+
+	var criticalLinks []string
+	for i, e := range g.Edges {
+		b.WriteString(fmt.Sprintf("  %s --> %s\n", mermaidID(e.From), mermaidID(e.To)))
+		if g.isCriticalEdge(e) {
+			criticalLinks = append(criticalLinks, fmt.Sprintf("%d", i))
+		}
+	}
+
+	for _, s := range []models.Status{models.StatusCompleted, models.StatusInProgress, models.StatusPending, models.StatusBlocked, models.StatusFailed} {
+		colors := statusColors[s]
+		b.WriteString(fmt.Sprintf("  classDef %s fill:%s,color:%s\n", s, colors[0], colors[1]))
+	}
+	b.WriteString("  classDef critical stroke:#d50000,stroke-width:3px\n")
+	b.WriteString("  classDef runnable stroke-dasharray:5 3,stroke-width:2px\n")
+
+	byStatus := make(map[models.Status][]string)
+	var critical, runnable []string
+	for _, n := range g.Nodes {
+		byStatus[n.displayStatus()] = append(byStatus[n.displayStatus()], mermaidID(n.ID))
+		if n.Critical {
+			critical = append(critical, mermaidID(n.ID))
+		}
+		if n.Runnable {
+			runnable = append(runnable, mermaidID(n.ID))
+		}
+	}
+	for _, s := range []models.Status{models.StatusCompleted, models.StatusInProgress, models.StatusPending, models.StatusBlocked, models.StatusFailed} {
+		if ids := byStatus[s]; len(ids) > 0 {
+			b.WriteString(fmt.Sprintf("  class %s %s\n", strings.Join(ids, ","), s))
+		}
+	}
+	if len(runnable) > 0 {
+		b.WriteString(fmt.Sprintf("  class %s runnable\n", strings.Join(runnable, ",")))
+	}
+	if len(critical) > 0 {
+		b.WriteString(fmt.Sprintf("  class %s critical\n", strings.Join(critical, ",")))
+	}
+	if len(criticalLinks) > 0 {
+		b.WriteString(fmt.Sprintf("  linkStyle %s stroke:#d50000,stroke-width:3px\n", strings.Join(criticalLinks, ",")))
+	}
+
+	return b.String()
+}
+
+// ASCII renders the graph as an indented text listing for the terminal.
+func (g *Graph) ASCII() string {
+	deps := make(map[string][]string)
+	for _, e := range g.Edges {
+		deps[e.To] = append(deps[e.To], e.From)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s (%s)\n", g.Title, g.Slug))
+
+	for _, cl := range g.Clusters {
+		b.WriteString(fmt.Sprintf("\nFeature %d: %s\n", cl.Number, cl.Title))
+		for _, id := range cl.Nodes {
+			n := g.byID[id]
+			line := fmt.Sprintf("  %s %-6s %s", n.displayStatus().Icon(), n.ID, n.Title)
+			if d := deps[id]; len(d) > 0 {
+				line += "  ← " + strings.Join(d, ", ")
+			}
+			var tags []string
+			if n.Runnable {
+				tags = append(tags, "runnable")
+			}
+			if n.Critical {
+				tags = append(tags, "critical")
+			}
+			if len(tags) > 0 {
+				line += "  [" + strings.Join(tags, ", ") + "]"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	b.WriteString("\n")
+	if len(g.CriticalPath) > 0 {
+		weight := 0
+		for _, id := range g.CriticalPath {
+			weight += g.byID[id].Weight
+		}
+		b.WriteString(fmt.Sprintf("Critical path: %s (weight %d)\n", strings.Join(g.CriticalPath, " → "), weight))
+	} else {
+		b.WriteString("Critical path: none — all tasks completed\n")
+	}
+	if len(g.Runnable) > 0 {
+		b.WriteString(fmt.Sprintf("Runnable now:  %s\n", strings.Join(g.Runnable, ", ")))
+	} else {
+		b.WriteString("Runnable now:  none\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// testPlan builds a two-feature plan:
+//
+//	1.1 (done) → 1.2 (large) → 2.2
+//	1.1        → 2.1 (small)
+//	1.3 (no deps)
+func testPlan() (*models.Plan, status.PlanStatus) {
+	plan := &models.Plan{
+		Title: "Graph Test",
+		Slug:  "graph-test",
+		Features: []models.Feature{
+			{Number: 1, Title: "Core", Tasks: []models.Task{
+				{FeatureNumber: 1, TaskNumber: 1, Title: "Setup", Complexity: models.ComplexitySmall},
+				{FeatureNumber: 1, TaskNumber: 2, Title: "Engine", Complexity: models.ComplexityLarge, DependsOn: []string{"Task 1.1"}},
+				{FeatureNumber: 1, TaskNumber: 3, Title: "Docs \"v1\"", Complexity: models.ComplexitySmall},
+			}},
+			{Number: 2, Title: "Extras", Tasks: []models.Task{
+				{FeatureNumber: 2, TaskNumber: 1, Title: "Plugin", Complexity: models.ComplexitySmall, DependsOn: []string{"Task 1.1"}},
+				{FeatureNumber: 2, TaskNumber: 2, Title: "Polish", Complexity: models.ComplexityMedium, DependsOn: []string{"Task 1.2"}},
+			}},
+		},
+	}
+	ps := status.PlanStatus{
+		Title: "Graph Test",
+		Slug:  "graph-test",
+		Features: []status.FeatureStatus{
+			{Number: 1, Title: "Core", Tasks: []status.TaskStatus{
+				{ID: "1.1", Title: "Setup", Status: models.StatusCompleted},
+				{ID: "1.2", Title: "Engine", Status: models.StatusInProgress, DependsOn: []string{"Task 1.1"}},
+				{ID: "1.3", Title: "Docs \"v1\"", Status: models.StatusPending},
+			}},
+			{Number: 2, Title: "Extras", Tasks: []status.TaskStatus{
+				{ID: "2.1", Title: "Plugin", Status: models.StatusPending, DependsOn: []string{"Task 1.1"}},
+				{ID: "2.2", Title: "Polish", Status: models.StatusPending, DependsOn: []string{"Task 1.2"}, IsBlocked: true},
+			}},
+		},
+	}
+	return plan, ps
+}
+
+func TestBuild_EdgesAndClusters(t *testing.T) {
+	g := Build(testPlan())
+
+	if len(g.Clusters) != 2 || len(g.Clusters[0].Nodes) != 3 {
+		t.Fatalf("unexpected clusters: %+v", g.Clusters)
+	}
+	want := []Edge{{"1.1", "1.2"}, {"1.1", "2.1"}, {"1.2", "2.2"}}
+	if len(g.Edges) != len(want) {
+		t.Fatalf("edges = %+v, want %+v", g.Edges, want)
+	}
+	for i := range want {
+		if g.Edges[i] != want[i] {
+			t.Errorf("edge %d = %+v, want %+v", i, g.Edges[i], want[i])
+		}
+	}
+}
+
+func TestBuild_RunnableFrontier(t *testing.T) {
+	g := Build(testPlan())
+
+	if strings.Join(g.Runnable, ",") != "1.3,2.1" {
+		t.Errorf("runnable = %v, want [1.3 2.1]", g.Runnable)
+	}
+	if g.Node("2.2").Runnable {
+		t.Error("blocked task 2.2 should not be runnable")
+	}
+}
+
+func TestBuild_CriticalPathSkipsCompleted(t *testing.T) {
+	g := Build(testPlan())
+
+	// 1.2 (large=3) → 2.2 (medium=2) outweighs every other unfinished chain;
+	// completed 1.1 contributes nothing.
+	if strings.Join(g.CriticalPath, ",") != "1.2,2.2" {
+		t.Errorf("critical path = %v, want [1.2 2.2]", g.CriticalPath)
+	}
+	if !g.Node("1.2").Critical || g.Node("1.1").Critical {
+		t.Error("critical flags not set correctly")
+	}
+}
+
+func TestBuild_AllCompletedHasNoCriticalPath(t *testing.T) {
+	plan, ps := testPlan()
+	for fi := range ps.Features {
+		for ti := range ps.Features[fi].Tasks {
+			ps.Features[fi].Tasks[ti].Status = models.StatusCompleted
+			ps.Features[fi].Tasks[ti].IsBlocked = false
+		}
+	}
+	g := Build(plan, ps)
+	if len(g.CriticalPath) != 0 || len(g.Runnable) != 0 {
+		t.Errorf("expected empty path and frontier, got %v / %v", g.CriticalPath, g.Runnable)
+	}
+	if !strings.Contains(g.ASCII(), "all tasks completed") {
+		t.Errorf("expected completion note in ASCII output:\n%s", g.ASCII())
+	}
+}
+
+func TestBuild_CycleDoesNotHang(t *testing.T) {
+	plan, ps := testPlan()
+	ps.Features[0].Tasks[0].Status = models.StatusPending
+	ps.Features[0].Tasks[0].DependsOn = []string{"Task 2.2"}
+	g := Build(plan, ps)
+	if len(g.CriticalPath) == 0 {
+		t.Error("expected a critical path even with a cycle")
+	}
+}
+
+func TestBuild_SingleFeatureBareDeps(t *testing.T) {
+	plan := &models.Plan{Title: "Single", Slug: "single", Features: []models.Feature{
+		{Number: 1, Tasks: []models.Task{
+			{FeatureNumber: 1, TaskNumber: 1, Title: "A"},
+			{FeatureNumber: 1, TaskNumber: 2, Title: "B", DependsOn: []string{"Task 1"}},
+		}},
+	}}
+	ps := status.PlanStatus{Title: "Single", Slug: "single", Features: []status.FeatureStatus{
+		{Number: 1, Tasks: []status.TaskStatus{
+			{ID: "1.1", Title: "A", Status: models.StatusPending},
+			{ID: "1.2", Title: "B", Status: models.StatusPending, DependsOn: []string{"Task 1"}, IsBlocked: true},
+		}},
+	}}
+	g := Build(plan, ps)
+	if len(g.Edges) != 1 || g.Edges[0] != (Edge{"1.1", "1.2"}) {
+		t.Errorf("edges = %+v, want [1.1 → 1.2]", g.Edges)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	out := Build(testPlan()).DOT()
+
+	for _, want := range []string{
+		`digraph "graph-test" {`,
+		"subgraph cluster_1 {",
+		`label="Feature 2: Extras";`,
+		`"1.2" -> "2.2" [color="#d50000", penwidth=3];`,
+		`"1.1" -> "2.1";`,
+		"peripheries=2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	out := Build(testPlan()).Mermaid()
+
+	for _, want := range []string{
+		"flowchart LR",
+		`subgraph f1["Feature 1: Core"]`,
+		`t1_3["○ 1.3: Docs #quot;v1#quot;"]`,
+		"t1_2 --> t2_2",
+		"class t1_1 completed",
+		"class t2_2 blocked",
+		"class t1_3,t2_1 runnable",
+		"class t1_2,t2_2 critical",
+		"linkStyle 2 stroke:#d50000",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestASCII(t *testing.T) {
+	out := Build(testPlan()).ASCII()
+
+	for _, want := range []string{
+		"Feature 1: Core",
+		"2.2    Polish  ← 1.2  [critical]",
+		"2.1    Plugin  ← 1.1  [runnable]",
+		"Critical path: 1.2 → 2.2 (weight 5)",
+		"Runnable now:  1.3, 2.1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ASCII output missing %q:\n%s", want, out)
+		}
+	}
+}
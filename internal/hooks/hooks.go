@@ -0,0 +1,183 @@
+// Package hooks runs the shell commands configured under [hooks] when a
+// task or plan changes state. A hook gets the details of the change as
+// ETCH_* environment variables and as JSON on stdin. Hooks listed in
+// [hooks] veto stop the change when they fail; other failures are reported
+// and the change goes ahead. Once the change is saved, the same payload is
+// POSTed to any [[webhooks]].
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/models"
+)
+
+// Event names a lifecycle event; each matches a key under [hooks].
+type Event string
+
+const (
+	TaskStart    Event = "on_task_start"
+	TaskDone     Event = "on_task_done"
+	TaskBlocked  Event = "on_task_blocked"
+	TaskFailed   Event = "on_task_failed"
+	PlanComplete Event = "on_plan_complete"
+)
+
+// ForStatus returns the event for a task moving to status, or "" if moving
+// there has no hook.
+func ForStatus(status models.Status) Event {
+	switch status {
+	case models.StatusInProgress:
+		return TaskStart
+	case models.StatusCompleted:
+		return TaskDone
+	case models.StatusBlocked:
+		return TaskBlocked
+	case models.StatusFailed:
+		return TaskFailed
+	}
+	return ""
+}
+
+// Payload describes the change a hook is run for. It is the JSON a hook
+// reads on stdin.
+type Payload struct {
+	Event        Event  `json:"event"`
+	Root         string `json:"root"`
+	Plan         string `json:"plan"`
+	PlanTitle    string `json:"plan_title"`
+	PlanFile     string `json:"plan_file"`
+	Task         string `json:"task,omitempty"`
+	TaskTitle    string `json:"task_title,omitempty"`
+	Session      int    `json:"session,omitempty"`
+	ProgressFile string `json:"progress_file,omitempty"`
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Actor        string `json:"actor"`
+}
+
+// ForTask returns the payload for task moving to status.
+func ForTask(rootDir string, plan *models.Plan, task *models.Task, to models.Status) Payload {
+	p := ForPlan(rootDir, plan)
+	p.Event = ForStatus(to)
+	p.Task = task.FullID()
+	p.TaskTitle = task.Title
+	p.From = string(task.Status)
+	p.To = string(to)
+	return p
+}
+
+// ForPlan returns the payload for plan being completed.
+func ForPlan(rootDir string, plan *models.Plan) Payload {
+	return Payload{
+		Event:     PlanComplete,
+		Root:      rootDir,
+		Plan:      plan.Slug,
+		PlanTitle: plan.Title,
+		PlanFile:  plan.FilePath,
+		From:      string(plan.Status),
+		To:        string(models.StatusCompleted),
+		Actor:     events.Actor(),
+	}
+}
+
+// env returns the payload as ETCH_* environment variables.
+func (p Payload) env() []string {
+	vars := []string{
+		"ETCH_EVENT=" + string(p.Event),
+		"ETCH_ROOT=" + p.Root,
+		"ETCH_PLAN=" + p.Plan,
+		"ETCH_PLAN_TITLE=" + p.PlanTitle,
+		"ETCH_PLAN_FILE=" + p.PlanFile,
+		"ETCH_TASK=" + p.Task,
+		"ETCH_TASK_TITLE=" + p.TaskTitle,
+		"ETCH_PROGRESS_FILE=" + p.ProgressFile,
+		"ETCH_FROM_STATUS=" + p.From,
+		"ETCH_TO_STATUS=" + p.To,
+		"ETCH_REASON=" + p.Reason,
+		events.ActorEnvVar + "=" + p.Actor,
+	}
+	if p.Session > 0 {
+		vars = append(vars, "ETCH_SESSION="+strconv.Itoa(p.Session))
+	}
+	return vars
+}
+
+// Fire runs the hook for p.Event configured in the project at rootDir,
+// writing its output to stderr so it can't corrupt etch's own output.
+func Fire(rootDir string, p Payload) error {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+	return Run(cfg.Hooks, p, os.Stderr)
+}
+
+// Run runs the hook for p.Event from cfg, if one is configured, in p.Root
+// with its output sent to out. It returns an error only when the hook
+// failed and vetoes the change; other failures are reported to out.
+func Run(cfg config.HooksConfig, p Payload, out io.Writer) error {
+	name := string(p.Event)
+	command := cfg.Command(name)
+	if command == "" {
+		return nil
+	}
+
+	err := run(command, p, cfg.Timeout, out)
+	if err == nil {
+		return nil
+	}
+	if !cfg.Vetoes(name) {
+		fmt.Fprintf(out, "warning: %s hook failed: %v\n", name, err)
+		return nil
+	}
+	return etcherr.Project(fmt.Sprintf("%s hook failed, so the change was not made: %v", name, err)).
+		WithHint(fmt.Sprintf("fix what %q checks and try again, or remove %s from veto under [hooks] in .etch/config.toml", command, name))
+}
+
+func run(command string, p Payload, timeout time.Duration, out io.Writer) error {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = p.Root
+	cmd.Env = append(os.Environ(), p.env()...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = 5 * time.Second
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
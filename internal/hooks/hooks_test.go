@@ -0,0 +1,121 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+	"github.com/gsigler/etch/internal/models"
+)
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use POSIX shell commands")
+	}
+}
+
+func testPayload(t *testing.T) Payload {
+	plan := &models.Plan{Slug: "auth", Title: "Auth", FilePath: "/p/.etch/plans/auth.md"}
+	task := &models.Task{FeatureNumber: 1, TaskNumber: 2, Title: "Refresh", Status: models.StatusInProgress}
+	p := ForTask(t.TempDir(), plan, task, models.StatusCompleted)
+	p.Session = 3
+	p.Actor = "human:tester"
+	return p
+}
+
+func TestRun_PassesEnvAndJSON(t *testing.T) {
+	skipWithoutShell(t)
+	p := testPayload(t)
+	cfg := config.HooksConfig{
+		OnTaskDone: `cat > payload.json; echo "$ETCH_EVENT $ETCH_PLAN $ETCH_TASK $ETCH_FROM_STATUS>$ETCH_TO_STATUS $ETCH_SESSION $ETCH_ACTOR"`,
+	}
+
+	var out bytes.Buffer
+	if err := Run(cfg, p, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "on_task_done auth 1.2 in_progress>completed 3 human:tester" {
+		t.Errorf("hook env = %q", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.Root, "payload.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Payload
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("stdin wasn't JSON: %v\n%s", err, data)
+	}
+	if got != p {
+		t.Errorf("stdin payload = %+v, want %+v", got, p)
+	}
+}
+
+func TestRun_FailurePolicy(t *testing.T) {
+	skipWithoutShell(t)
+	p := testPayload(t)
+	cfg := config.HooksConfig{OnTaskDone: "echo tests failed; exit 2"}
+
+	var out bytes.Buffer
+	if err := Run(cfg, p, &out); err != nil {
+		t.Fatalf("a hook not in veto shouldn't block the change: %v", err)
+	}
+	if !strings.Contains(out.String(), "tests failed") || !strings.Contains(out.String(), "warning: on_task_done hook failed") {
+		t.Errorf("expected the hook's output and a warning, got %q", out.String())
+	}
+
+	cfg.Veto = []string{"on_task_done"}
+	err := Run(cfg, p, &out)
+	if err == nil || !strings.Contains(err.Error(), "on_task_done hook failed") {
+		t.Fatalf("expected a veto, got %v", err)
+	}
+
+	// Vetoes only apply to the hook that failed.
+	cfg.OnTaskBlocked = "exit 1"
+	p.Event = TaskBlocked
+	if err := Run(cfg, p, &out); err != nil {
+		t.Errorf("on_task_blocked isn't in veto: %v", err)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	skipWithoutShell(t)
+	cfg := config.HooksConfig{OnTaskDone: "exec sleep 5", Veto: []string{"on_task_done"}, Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	err := Run(cfg, testPayload(t), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 4*time.Second {
+		t.Errorf("hook wasn't killed at the timeout")
+	}
+}
+
+func TestRun_NoHookConfigured(t *testing.T) {
+	var out bytes.Buffer
+	if err := Run(config.HooksConfig{OnTaskStart: "exit 1"}, testPayload(t), &out); err != nil || out.Len() != 0 {
+		t.Errorf("unconfigured hook: err %v, output %q", err, out.String())
+	}
+}
+
+func TestForStatus(t *testing.T) {
+	for status, want := range map[models.Status]Event{
+		models.StatusInProgress: TaskStart,
+		models.StatusCompleted:  TaskDone,
+		models.StatusBlocked:    TaskBlocked,
+		models.StatusFailed:     TaskFailed,
+		models.StatusPending:    "",
+	} {
+		if got := ForStatus(status); got != want {
+			t.Errorf("ForStatus(%s) = %q, want %q", status, got, want)
+		}
+	}
+}
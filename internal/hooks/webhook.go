@@ -0,0 +1,145 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// request body, keyed with the webhook's secret.
+	SignatureHeader = "X-Etch-Signature"
+	// EventHeader carries the event name, e.g. "on_task_done".
+	EventHeader = "X-Etch-Event"
+	// DeliveryHeader carries an ID that stays the same across retries, so
+	// receivers can drop duplicates.
+	DeliveryHeader = "X-Etch-Delivery"
+)
+
+// retryBackoff is the wait before the first retry; it doubles after each.
+var retryBackoff = 1 * time.Second
+
+// delivery is the JSON body POSTed to a webhook.
+type delivery struct {
+	Payload
+	Time time.Time `json:"time"`
+}
+
+// Notify sends p to the webhooks configured in the project at rootDir,
+// reporting failures on stderr. It is called after a change is saved, so a
+// failed delivery never undoes it.
+func Notify(rootDir string, p Payload) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: webhooks not sent: %v\n", err)
+		return
+	}
+	Send(cfg.Webhooks, p, os.Stderr)
+}
+
+// Send POSTs p to each webhook that wants p.Event, retrying failed
+// deliveries with exponential backoff. Failures are reported to out.
+func Send(webhooks []config.Webhook, p Payload, out io.Writer) {
+	var body []byte
+	var id string
+	for _, w := range webhooks {
+		if !w.Wants(string(p.Event)) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(delivery{Payload: p, Time: time.Now().UTC().Truncate(time.Second)}); err != nil {
+				fmt.Fprintf(out, "warning: webhooks not sent: %v\n", err)
+				return
+			}
+			id = deliveryID()
+		}
+		if err := post(w, p.Event, id, body); err != nil {
+			fmt.Fprintf(out, "warning: %s webhook to %s failed: %v\n", p.Event, w.URL, err)
+		}
+	}
+}
+
+// post delivers body to w, retrying network errors, 429s, and 5xx
+// responses.
+func post(w config.Webhook, event Event, id string, body []byte) error {
+	retries := config.DefaultWebhookRetries
+	if w.Retries != nil {
+		retries = *w.Retries
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultWebhookTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	backoff := retryBackoff
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = postOnce(client, w, event, id, body); err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("%v (gave up after %d attempts)", err, retries+1)
+}
+
+// postOnce makes one delivery attempt and reports whether a failure is
+// worth retrying.
+func postOnce(client *http.Client, w config.Webhook, event Event, id string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "etch")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, id)
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the X-Etch-Signature value for body signed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
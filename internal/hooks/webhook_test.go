@@ -0,0 +1,124 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/config"
+)
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+// recorder is a webhook receiver that fails the first failures requests
+// with status code.
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	code     int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, body)
+	if len(rec.requests) <= rec.failures {
+		w.WriteHeader(rec.code)
+	}
+}
+
+func retries(n int) *int { return &n }
+
+func TestSend_SignsPayload(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	p := testPayload(t)
+	var out bytes.Buffer
+	Send([]config.Webhook{{URL: srv.URL, Secret: "s3cret"}}, p, &out)
+	if out.Len() != 0 {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+	if len(rec.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(rec.requests))
+	}
+
+	r, body := rec.requests[0], rec.bodies[0]
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get(EventHeader) != "on_task_done" {
+		t.Errorf("request = %s %v", r.Method, r.Header)
+	}
+	if got := r.Header.Get(SignatureHeader); got != Sign("s3cret", body) || !strings.HasPrefix(got, "sha256=") {
+		t.Errorf("signature = %q", got)
+	}
+	var got delivery
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("body wasn't JSON: %v\n%s", err, body)
+	}
+	if got.Payload != p || got.Time.IsZero() {
+		t.Errorf("body = %+v, want payload %+v", got, p)
+	}
+}
+
+func TestSend_RetriesWithBackoff(t *testing.T) {
+	rec := &recorder{failures: 2, code: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	var out bytes.Buffer
+	Send([]config.Webhook{{URL: srv.URL}}, testPayload(t), &out)
+	if len(rec.requests) != 3 || out.Len() != 0 {
+		t.Fatalf("got %d requests and output %q, want 3 and none", len(rec.requests), out.String())
+	}
+	if id := rec.requests[0].Header.Get(DeliveryHeader); id == "" || rec.requests[2].Header.Get(DeliveryHeader) != id {
+		t.Error("retries should keep the delivery ID")
+	}
+	if rec.requests[0].Header.Get(SignatureHeader) != "" {
+		t.Error("webhooks without a secret shouldn't be signed")
+	}
+
+	// Retries give up and warn.
+	rec = &recorder{failures: 10, code: http.StatusBadGateway}
+	srv2 := httptest.NewServer(rec)
+	defer srv2.Close()
+	Send([]config.Webhook{{URL: srv2.URL, Retries: retries(1)}}, testPayload(t), &out)
+	if len(rec.requests) != 2 || !strings.Contains(out.String(), "gave up after 2 attempts") {
+		t.Errorf("got %d requests and output %q", len(rec.requests), out.String())
+	}
+}
+
+func TestSend_ClientErrorsAreNotRetried(t *testing.T) {
+	rec := &recorder{failures: 10, code: http.StatusForbidden}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	var out bytes.Buffer
+	Send([]config.Webhook{{URL: srv.URL}}, testPayload(t), &out)
+	if len(rec.requests) != 1 || !strings.Contains(out.String(), "status 403") {
+		t.Errorf("got %d requests and output %q", len(rec.requests), out.String())
+	}
+}
+
+func TestSend_FiltersEvents(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	hooks := []config.Webhook{{URL: srv.URL, Events: []string{"on_plan_complete"}}}
+	Send(hooks, testPayload(t), io.Discard)
+	if len(rec.requests) != 0 {
+		t.Errorf("on_task_done was sent to a webhook that only wants on_plan_complete")
+	}
+}
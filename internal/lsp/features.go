@@ -0,0 +1,441 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/gsigler/etch/internal/document"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/validate"
+)
+
+// LSP diagnostic severities and completion item kinds.
+const (
+	severityError   = 1
+	severityWarning = 2
+
+	kindEnumMember = 20
+	kindReference  = 18
+)
+
+var (
+	dependsOnPrefixRe = regexp.MustCompile(`^\*\*Depends\s+on:\*\*\s*`)
+	taskHeadingRe     = regexp.MustCompile(`^###\s+Task\b`)
+)
+
+// Positions count lines from zero and columns in UTF-16 code units.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type hoverResult struct {
+	Contents markupContent `json:"contents"`
+	Range    *lspRange     `json:"range,omitempty"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type completionItem struct {
+	Label    string    `json:"label"`
+	Kind     int       `json:"kind"`
+	Detail   string    `json:"detail,omitempty"`
+	TextEdit *textEdit `json:"textEdit,omitempty"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+// planFile is a plan parsed for editor features.
+type planFile struct {
+	path string
+	plan *models.Plan
+	doc  *document.Document
+}
+
+func analyse(path, text string) *planFile {
+	plan, doc, err := parser.ParseDocument(text)
+	if err != nil {
+		// Diagnostics report what's wrong; other features find nothing.
+		plan, doc = &models.Plan{}, document.New(text)
+	}
+	plan.FilePath = path
+	plan.Slug = strings.TrimSuffix(filepath.Base(path), ".md")
+	return &planFile{path: path, plan: plan, doc: doc}
+}
+
+// rootDir returns the project root of a plan file in .etch/plans.
+func (pf *planFile) rootDir() string {
+	return filepath.Dir(filepath.Dir(filepath.Dir(pf.path)))
+}
+
+// line returns the text of line n without any carriage return, and whether
+// it exists.
+func (pf *planFile) line(n int) (string, bool) {
+	if n < 0 || n >= len(pf.doc.Lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(pf.doc.Lines[n].Text, "\r"), true
+}
+
+// taskAt returns the task whose heading is on line n.
+func (pf *planFile) taskAt(n int) *models.Task {
+	if n < 0 || n >= len(pf.doc.Lines) {
+		return nil
+	}
+	l := pf.doc.Lines[n]
+	if l.Role != document.TaskHeading || l.Feature < 0 || l.Task < 0 {
+		return nil
+	}
+	return &pf.plan.Features[l.Feature].Tasks[l.Task]
+}
+
+// taskLine returns the line of the heading of the task with the given ID,
+// or -1.
+func (pf *planFile) taskLine(id string) int {
+	for i := range pf.doc.Lines {
+		if t := pf.taskAt(i); t != nil && t.FullID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// diagnostics lints a plan's text with the plan linter.
+func diagnostics(path, text string) []diagnostic {
+	diags := []diagnostic{}
+	rep, err := validate.FileSource(path, strings.NewReader(text))
+	if err != nil {
+		return diags
+	}
+	lines := strings.Split(text, "\n")
+	for _, d := range rep.Diagnostics {
+		n := d.Line - 1
+		var line string
+		if n >= 0 && n < len(lines) {
+			line = strings.TrimSuffix(lines[n], "\r")
+		}
+		sev := severityError
+		if d.Severity == validate.SeverityWarning {
+			sev = severityWarning
+		}
+		diags = append(diags, diagnostic{
+			Range:    lineRange(n, line),
+			Severity: sev,
+			Code:     d.Code,
+			Source:   "etch",
+			Message:  d.Message,
+		})
+	}
+	return diags
+}
+
+// depRef is one comma-separated entry of a **Depends on:** line.
+type depRef struct {
+	raw        string
+	start, end int // byte offsets within the line
+}
+
+// depRefs splits a **Depends on:** line into its entries.
+func depRefs(line string) []depRef {
+	loc := dependsOnPrefixRe.FindStringIndex(line)
+	if loc == nil {
+		return nil
+	}
+	var refs []depRef
+	start := loc[1]
+	for start <= len(line) {
+		end := strings.IndexByte(line[start:], ',')
+		if end < 0 {
+			end = len(line)
+		} else {
+			end += start
+		}
+		entry := line[start:end]
+		trimmed := strings.TrimSpace(entry)
+		if trimmed != "" {
+			s := start + strings.Index(entry, trimmed)
+			refs = append(refs, depRef{raw: trimmed, start: s, end: s + len(trimmed)})
+		}
+		start = end + 1
+	}
+	return refs
+}
+
+// refAt returns the dependency entry under the cursor.
+func (pf *planFile) refAt(pos position) (depRef, bool) {
+	line, ok := pf.line(pos.Line)
+	if !ok {
+		return depRef{}, false
+	}
+	off := byteOffset(line, pos.Character)
+	for _, r := range depRefs(line) {
+		if off >= r.start && off <= r.end {
+			return r, true
+		}
+	}
+	return depRef{}, false
+}
+
+// load returns the plan file at path, as open in the editor or else as
+// saved on disk, or nil if there is none.
+func (s *Server) load(path string) *planFile {
+	text, open := s.docs[path]
+	if !open {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		text = string(data)
+	}
+	return analyse(path, text)
+}
+
+// resolve finds the plan file and task a dependency refers to.
+func (s *Server) resolve(pf *planFile, raw string) (*planFile, *models.Task) {
+	target, id := pf, ""
+	if slug, depID, ok := models.ParseCrossPlanDep(raw); ok {
+		target, id = s.load(filepath.Join(filepath.Dir(pf.path), slug+".md")), depID
+		if target == nil {
+			return nil, nil
+		}
+	} else {
+		id = status.DependencyID(raw, len(pf.plan.Features) == 1)
+	}
+	if id == "" {
+		return nil, nil
+	}
+	task := target.plan.ResolveTaskID(id)
+	if task == nil {
+		return nil, nil
+	}
+	return target, task
+}
+
+func (s *Server) definition(pf *planFile, pos position) any {
+	ref, ok := pf.refAt(pos)
+	if !ok {
+		return nil
+	}
+	target, task := s.resolve(pf, ref.raw)
+	if task == nil {
+		return nil
+	}
+	n := target.taskLine(task.FullID())
+	line, _ := target.line(n)
+	return location{URI: pathURI(target.path), Range: lineRange(n, line)}
+}
+
+func (s *Server) hover(pf *planFile, pos position) any {
+	line, ok := pf.line(pos.Line)
+	if !ok {
+		return nil
+	}
+	if task := pf.taskAt(pos.Line); task != nil {
+		r := lineRange(pos.Line, line)
+		return hoverResult{Contents: markdown(describe(pf, task)), Range: &r}
+	}
+	ref, ok := pf.refAt(pos)
+	if !ok {
+		return nil
+	}
+	target, task := s.resolve(pf, ref.raw)
+	if task == nil {
+		return nil
+	}
+	r := lspRange{
+		Start: position{pos.Line, utf16Col(line, ref.start)},
+		End:   position{pos.Line, utf16Col(line, ref.end)},
+	}
+	return hoverResult{Contents: markdown(describe(target, task)), Range: &r}
+}
+
+func markdown(s string) markupContent {
+	return markupContent{Kind: "markdown", Value: s}
+}
+
+// describe summarizes a task with its status reconciled against its
+// session files.
+func describe(pf *planFile, task *models.Task) string {
+	var b strings.Builder
+	id := task.FullID()
+	fmt.Fprintf(&b, "**Task %s: %s** (%s)\n\n", id, task.Title, pf.plan.Slug)
+
+	sessions, _ := progress.ReadAll(pf.rootDir(), pf.plan.Slug)
+	taskSessions := sessions[id]
+	st := status.Effective(task, taskSessions)
+	fmt.Fprintf(&b, "Status: `%s`", st)
+	if st != task.Status {
+		fmt.Fprintf(&b, " (plan says `%s`)", task.Status)
+	}
+	fmt.Fprintf(&b, " · %d session", len(taskSessions))
+	if len(taskSessions) != 1 {
+		b.WriteString("s")
+	}
+	if len(task.Criteria) > 0 {
+		met := 0
+		for _, c := range task.Criteria {
+			if c.IsMet {
+				met++
+			}
+		}
+		fmt.Fprintf(&b, " · criteria %d/%d", met, len(task.Criteria))
+	}
+	b.WriteString("\n")
+
+	if n := len(taskSessions); n > 0 {
+		latest := taskSessions[n-1]
+		fmt.Fprintf(&b, "\nLatest session %03d: %s, started %s\n", latest.SessionNumber, latest.Status, latest.StartedLabel())
+	}
+	if len(task.DependsOn) > 0 {
+		fmt.Fprintf(&b, "\nDepends on: %s\n", strings.Join(task.DependsOn, ", "))
+	}
+	return b.String()
+}
+
+func (s *Server) completion(pf *planFile, pos position) []completionItem {
+	items := []completionItem{}
+	line, ok := pf.line(pos.Line)
+	if !ok {
+		return items
+	}
+	off := byteOffset(line, pos.Character)
+	before := line[:off]
+
+	// Status tag in a task heading.
+	if taskHeadingRe.MatchString(line) {
+		open := strings.LastIndexByte(before, '[')
+		if open < 0 || strings.Contains(before[open:], "]") {
+			return items
+		}
+		r := lspRange{Start: position{pos.Line, utf16Col(line, open+1)}, End: pos}
+		for _, st := range []models.Status{
+			models.StatusPending, models.StatusInProgress, models.StatusCompleted,
+			models.StatusBlocked, models.StatusFailed,
+		} {
+			items = append(items, completionItem{
+				Label:    string(st),
+				Kind:     kindEnumMember,
+				Detail:   st.Icon(),
+				TextEdit: &textEdit{Range: r, NewText: string(st)},
+			})
+		}
+		return items
+	}
+
+	// Task reference in a **Depends on:** line.
+	loc := dependsOnPrefixRe.FindStringIndex(line)
+	if loc == nil || off < loc[1] {
+		return items
+	}
+	start := loc[1]
+	if i := strings.LastIndexByte(before, ','); i >= start {
+		start = i + 1
+	}
+	for start < off && before[start] == ' ' {
+		start++
+	}
+	r := lspRange{Start: position{pos.Line, utf16Col(line, start)}, End: pos}
+
+	if slug, _, found := strings.Cut(before[start:], "#"); found {
+		target := s.load(filepath.Join(filepath.Dir(pf.path), slug+".md"))
+		if target == nil {
+			return items
+		}
+		for _, t := range allTasks(target.plan) {
+			items = append(items, completionItem{
+				Label:    slug + "#" + t.FullID(),
+				Kind:     kindReference,
+				Detail:   t.Title,
+				TextEdit: &textEdit{Range: r, NewText: slug + "#" + t.FullID()},
+			})
+		}
+		return items
+	}
+
+	self := pf.doc.Lines[pos.Line]
+	for fi, f := range pf.plan.Features {
+		for ti, t := range f.Tasks {
+			if fi == self.Feature && ti == self.Task {
+				continue
+			}
+			label := "Task " + t.FullID()
+			items = append(items, completionItem{
+				Label:    label,
+				Kind:     kindReference,
+				Detail:   t.Title,
+				TextEdit: &textEdit{Range: r, NewText: label},
+			})
+		}
+	}
+	return items
+}
+
+func allTasks(plan *models.Plan) []*models.Task {
+	var tasks []*models.Task
+	for fi := range plan.Features {
+		for ti := range plan.Features[fi].Tasks {
+			tasks = append(tasks, &plan.Features[fi].Tasks[ti])
+		}
+	}
+	return tasks
+}
+
+// lineRange covers the whole of line n.
+func lineRange(n int, line string) lspRange {
+	if n < 0 {
+		n = 0
+	}
+	return lspRange{Start: position{n, 0}, End: position{n, utf16Col(line, len(line))}}
+}
+
+// utf16Col converts a byte offset within line to a UTF-16 column.
+func utf16Col(line string, off int) int {
+	n := 0
+	for _, r := range line[:off] {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// byteOffset converts a UTF-16 column within line to a byte offset.
+func byteOffset(line string, col int) int {
+	n := 0
+	for i, r := range line {
+		if n >= col {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	return len(line)
+}
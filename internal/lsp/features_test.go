@@ -0,0 +1,228 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validPlan = `# Plan: Auth
+
+## Feature 1: Tokens
+
+### Task 1.1: Token service [pending]
+**Complexity:** small
+
+- [ ] Tokens are signed
+
+### Task 1.2: Refresh endpoint [pending]
+**Complexity:** small
+**Depends on:** Task 1.1
+
+- [ ] Refresh works
+`
+
+const consumerPlan = `# Plan: Billing
+
+### Task 1: Charge — cards 💳 [pending]
+**Complexity:** small
+**Depends on:** auth#1.2, Task 2
+
+- [ ] Charges work
+
+### Task 2: Invoices [pending]
+**Complexity:** small
+
+- [ ] Invoices sent
+`
+
+func pos(line, char int) map[string]any {
+	return map[string]any{"line": line, "character": char}
+}
+
+func docPos(uri string, line, char int) map[string]any {
+	return map[string]any{"textDocument": map[string]any{"uri": uri}, "position": pos(line, char)}
+}
+
+// col returns the UTF-16 column of the first occurrence of sub in line.
+func col(line, sub string) int {
+	return utf16Col(line, strings.Index(line, sub))
+}
+
+func TestDiagnostics(t *testing.T) {
+	uris := writePlans(t, map[string]string{"auth": validPlan})
+	path, _ := uriPath(uris["auth"])
+
+	src := `# Plan: Auth
+
+## Feature 1: Tokens
+
+### Task 1.1: Token service [pending]
+**Complexity:** small
+- [ ] a
+
+### Task 1.1: Again [pending]
+**Complexity:** small
+**Depends on:** Task 1.9, other#1.1
+- [ ] b
+
+### Task one: Bad heading
+`
+	byCode := make(map[string]diagnostic)
+	for _, d := range diagnostics(path, src) {
+		byCode[d.Code] = d
+	}
+	for code, line := range map[string]int{
+		"duplicate-task":     8,
+		"unknown-dependency": 10,
+		"unknown-plan":       10,
+		"malformed-heading":  13,
+	} {
+		d, ok := byCode[code]
+		if !ok {
+			t.Errorf("missing %s diagnostic, got %+v", code, byCode)
+			continue
+		}
+		if d.Range.Start.Line != line || d.Source != "etch" {
+			t.Errorf("%s: range %+v, want line %d", code, d.Range, line)
+		}
+	}
+	if d := byCode["malformed-heading"]; d.Range.End.Character != len("### Task one: Bad heading") {
+		t.Errorf("expected the range to cover the line, got %+v", d.Range)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	uris := writePlans(t, map[string]string{"auth": validPlan, "billing": consumerPlan})
+	depLine := "**Depends on:** auth#1.2, Task 2"
+
+	s := newSession(t)
+	openDoc(s, uris["billing"], consumerPlan)
+	local := s.request("textDocument/definition", docPos(uris["billing"], 4, col(depLine, "Task 2")+2))
+	cross := s.request("textDocument/definition", docPos(uris["billing"], 4, col(depLine, "auth#")))
+	none := s.request("textDocument/definition", docPos(uris["billing"], 4, 2))
+	inAuth := s.request("textDocument/definition", docPos(uris["auth"], 10, 20))
+	s.run()
+
+	var loc location
+	s.response(local, &loc)
+	if loc.URI != uris["billing"] || loc.Range.Start.Line != 8 {
+		t.Errorf("Task 2: got %+v, want billing line 8", loc)
+	}
+
+	s.response(cross, &loc)
+	if loc.URI != uris["auth"] || loc.Range.Start.Line != 9 {
+		t.Errorf("auth#1.2: got %+v, want auth line 9", loc)
+	}
+
+	var missing any
+	s.response(none, &missing)
+	if missing != nil {
+		t.Errorf("cursor on the label: got %v, want null", missing)
+	}
+	// auth isn't open in the editor, so it has no features.
+	s.response(inAuth, &missing)
+	if missing != nil {
+		t.Errorf("unopened document: got %v, want null", missing)
+	}
+}
+
+func TestHover(t *testing.T) {
+	uris := writePlans(t, map[string]string{"auth": validPlan, "billing": consumerPlan})
+	authPath, _ := uriPath(uris["auth"])
+	root := filepath.Dir(filepath.Dir(filepath.Dir(authPath)))
+	os.MkdirAll(filepath.Join(root, ".etch", "progress"), 0o755)
+	os.WriteFile(filepath.Join(root, ".etch", "progress", "auth--task-1.2--001.md"), []byte(`# Session: Task 1.2
+**Plan:** auth
+**Task:** 1.2
+**Session:** 001
+**Started:** 2026-01-02 09:00
+**Status:** blocked
+`), 0o644)
+
+	depLine := "**Depends on:** auth#1.2, Task 2"
+	s := newSession(t)
+	openDoc(s, uris["billing"], consumerPlan)
+	onDep := s.request("textDocument/hover", docPos(uris["billing"], 4, col(depLine, "#1.2")))
+	onHeading := s.request("textDocument/hover", docPos(uris["billing"], 2, 5))
+	onText := s.request("textDocument/hover", docPos(uris["billing"], 6, 3))
+	s.run()
+
+	var h hoverResult
+	s.response(onDep, &h)
+	for _, want := range []string{"**Task 1.2: Refresh endpoint** (auth)", "Status: `blocked` (plan says `pending`)", "1 session", "Latest session 001: blocked", "Depends on: Task 1.1"} {
+		if !strings.Contains(h.Contents.Value, want) {
+			t.Errorf("dependency hover missing %q:\n%s", want, h.Contents.Value)
+		}
+	}
+	if h.Range == nil || h.Range.Start.Character != col(depLine, "auth#") || h.Range.End.Character != col(depLine, ", Task") {
+		t.Errorf("hover range = %+v", h.Range)
+	}
+
+	s.response(onHeading, &h)
+	if !strings.Contains(h.Contents.Value, "**Task 1.1: Charge — cards 💳** (billing)") || !strings.Contains(h.Contents.Value, "0 sessions · criteria 0/1") {
+		t.Errorf("heading hover:\n%s", h.Contents.Value)
+	}
+
+	var none any
+	s.response(onText, &none)
+	if none != nil {
+		t.Errorf("hover on text: got %v, want null", none)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	uris := writePlans(t, map[string]string{"auth": validPlan, "billing": consumerPlan})
+	edited := strings.Replace(consumerPlan, "**Depends on:** auth#1.2, Task 2", "**Depends on:** auth#1.2, auth#", 1)
+	edited = strings.Replace(edited, "### Task 2: Invoices [pending]", "### Task 2: Invoices [in", 1)
+	edited = strings.Replace(edited, "**Complexity:** small\n\n- [ ] Invoices", "**Complexity:** small\n**Depends on:** Ta\n- [ ] Invoices", 1)
+
+	s := newSession(t)
+	openDoc(s, uris["billing"], edited)
+	status := s.request("textDocument/completion", docPos(uris["billing"], 8, len("### Task 2: Invoices [in")))
+	cross := s.request("textDocument/completion", docPos(uris["billing"], 4, len("**Depends on:** auth#1.2, auth#")))
+	local := s.request("textDocument/completion", docPos(uris["billing"], 10, len("**Depends on:** Ta")))
+	none := s.request("textDocument/completion", docPos(uris["billing"], 0, 3))
+	s.run()
+
+	var items []completionItem
+	s.response(status, &items)
+	if len(items) != 5 || items[1].Label != "in_progress" {
+		t.Fatalf("status completion = %+v", items)
+	}
+	if r := items[1].TextEdit.Range; r.Start.Character != len("### Task 2: Invoices [") || r.End.Character != len("### Task 2: Invoices [in") {
+		t.Errorf("status edit range = %+v", r)
+	}
+
+	s.response(cross, &items)
+	if len(items) != 2 || items[0].Label != "auth#1.1" || items[1].Detail != "Refresh endpoint" {
+		t.Fatalf("cross-plan completion = %+v", items)
+	}
+	if r := items[0].TextEdit.Range; r.Start.Character != len("**Depends on:** auth#1.2, ") {
+		t.Errorf("cross-plan edit range = %+v", r)
+	}
+
+	s.response(local, &items)
+	if len(items) != 1 || items[0].Label != "Task 1.1" || items[0].TextEdit.NewText != "Task 1.1" {
+		t.Errorf("local completion should offer other tasks only, got %+v", items)
+	}
+
+	s.response(none, &items)
+	if len(items) != 0 {
+		t.Errorf("completion on the plan heading = %+v", items)
+	}
+}
+
+func TestUTF16Columns(t *testing.T) {
+	line := "a💳b—c"
+	if got := utf16Col(line, strings.Index(line, "b")); got != 3 {
+		t.Errorf("utf16Col = %d, want 3", got)
+	}
+	if got := byteOffset(line, 3); line[got:got+1] != "b" {
+		t.Errorf("byteOffset(3) = %d", got)
+	}
+	if got := byteOffset(line, 99); got != len(line) {
+		t.Errorf("byteOffset past end = %d, want %d", got, len(line))
+	}
+}
@@ -0,0 +1,294 @@
+// Package lsp implements a Language Server Protocol server for etch plan
+// files: diagnostics from the plan linter, go-to-definition and hover on
+// task references, and completion for task IDs and statuses.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInvalidRequest = -32600
+)
+
+// Server answers LSP requests over a single connection. Documents are
+// synced in full on every change.
+type Server struct {
+	// Version is reported to the client in serverInfo.
+	Version string
+
+	docs map[string]string // open documents by path
+	w    io.Writer
+}
+
+// NewServer returns a server with no open documents.
+func NewServer(version string) *Server {
+	return &Server{Version: version, docs: make(map[string]string)}
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve handles messages read from r, writing responses and diagnostics to
+// w, until the client sends exit, r is exhausted, or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	br := bufio.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := readMessage(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return etcherr.WrapIO("reading LSP message", err)
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.reply(json.RawMessage("null"), nil, &rpcError{codeParseError, "parse error: " + err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID == nil {
+			// Notifications get no response.
+			continue
+		}
+		if err := s.reply(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one message, returning the result of a request.
+func (s *Server) handle(msg message) (any, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   map[string]any{"openClose": true, "change": 1, "save": true},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{"[", " ", "#"}},
+			},
+			"serverInfo": map[string]string{"name": "etch", "version": s.Version},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err == nil {
+			s.open(p.TextDocument.URI, p.TextDocument.Text)
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   textDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err == nil && len(p.ContentChanges) > 0 {
+			s.open(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didSave":
+		// Other open plans may depend on the saved one.
+		for path := range s.docs {
+			s.publish(path)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument textDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err == nil {
+			if path, ok := uriPath(p.TextDocument.URI); ok {
+				delete(s.docs, path)
+				s.notify("textDocument/publishDiagnostics", publishParams{URI: p.TextDocument.URI, Diagnostics: []diagnostic{}})
+			}
+		}
+		return nil, nil
+	case "textDocument/definition", "textDocument/hover", "textDocument/completion":
+		var p positionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params: " + err.Error()}
+		}
+		pf := s.file(p.TextDocument.URI)
+		if pf == nil {
+			return nil, nil
+		}
+		switch msg.Method {
+		case "textDocument/definition":
+			return s.definition(pf, p.Position), nil
+		case "textDocument/hover":
+			return s.hover(pf, p.Position), nil
+		default:
+			return s.completion(pf, p.Position), nil
+		}
+	}
+	if msg.ID != nil && msg.Method != "" {
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + msg.Method}
+	}
+	if msg.ID != nil {
+		return nil, &rpcError{codeInvalidRequest, "invalid request"}
+	}
+	return nil, nil
+}
+
+type textDocument struct {
+	URI string `json:"uri"`
+}
+
+type positionParams struct {
+	TextDocument textDocument `json:"textDocument"`
+	Position     position     `json:"position"`
+}
+
+type publishParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// open records a document's text and publishes its diagnostics.
+func (s *Server) open(uri, text string) {
+	path, ok := uriPath(uri)
+	if !ok {
+		return
+	}
+	s.docs[path] = text
+	s.publish(path)
+}
+
+// publish sends diagnostics for an open plan file.
+func (s *Server) publish(path string) {
+	text, ok := s.docs[path]
+	if !ok || !isPlanPath(path) {
+		return
+	}
+	s.notify("textDocument/publishDiagnostics", publishParams{URI: pathURI(path), Diagnostics: diagnostics(path, text)})
+}
+
+// file returns the analysed plan for an open document, or nil when the
+// document isn't an open plan file.
+func (s *Server) file(uri string) *planFile {
+	path, ok := uriPath(uri)
+	if !ok || !isPlanPath(path) {
+		return nil
+	}
+	text, ok := s.docs[path]
+	if !ok {
+		return nil
+	}
+	return analyse(path, text)
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *rpcError) error {
+	msg := message{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return etcherr.WrapIO("encoding LSP response", err)
+		}
+		msg.Result = data
+	}
+	return writeMessage(s.w, msg)
+}
+
+func (s *Server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return etcherr.WrapIO("encoding LSP notification", err)
+	}
+	return writeMessage(s.w, message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// readMessage reads one Content-Length framed message body.
+func readMessage(br *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (len(header) == 0 && err == io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("missing or invalid Content-Length header")
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func writeMessage(w io.Writer, msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return etcherr.WrapIO("encoding LSP message", err)
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return etcherr.WrapIO("writing LSP message", err)
+	}
+	return nil
+}
+
+// uriPath converts a file:// URI to a path.
+func uriPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
+
+func pathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// isPlanPath reports whether path is a plan file in an etch project.
+func isPlanPath(path string) bool {
+	dir := filepath.Dir(path)
+	return strings.HasSuffix(path, ".md") &&
+		filepath.Base(dir) == "plans" &&
+		filepath.Base(filepath.Dir(dir)) == ".etch"
+}
@@ -0,0 +1,249 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// session drives a Server with a scripted client.
+type session struct {
+	t   *testing.T
+	in  bytes.Buffer
+	id  int
+	out []message
+}
+
+func newSession(t *testing.T) *session {
+	return &session{t: t}
+}
+
+func (s *session) send(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	fmt.Fprintf(&s.in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// request queues a request and returns its ID.
+func (s *session) request(method string, params any) int {
+	s.id++
+	s.send(map[string]any{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+	return s.id
+}
+
+func (s *session) notify(method string, params any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// run serves the queued messages and collects everything the server wrote.
+func (s *session) run() {
+	s.t.Helper()
+	var out bytes.Buffer
+	if err := NewServer("test").Serve(context.Background(), &s.in, &out); err != nil {
+		s.t.Fatalf("Serve: %v", err)
+	}
+	br := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(br)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.t.Fatalf("reading server output: %v", err)
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.t.Fatal(err)
+		}
+		s.out = append(s.out, msg)
+	}
+}
+
+// response decodes the result of request id into v, failing on an error.
+func (s *session) response(id int, v any) {
+	s.t.Helper()
+	for _, m := range s.out {
+		if string(m.ID) == fmt.Sprint(id) {
+			if m.Error != nil {
+				s.t.Fatalf("request %d: error %+v", id, m.Error)
+			}
+			if err := json.Unmarshal(m.Result, v); err != nil {
+				s.t.Fatalf("request %d: decoding %s: %v", id, m.Result, err)
+			}
+			return
+		}
+	}
+	s.t.Fatalf("no response to request %d", id)
+}
+
+// diagnostics returns the last diagnostics published for uri.
+func (s *session) diagnostics(uri string) []diagnostic {
+	s.t.Helper()
+	var got []diagnostic
+	found := false
+	for _, m := range s.out {
+		if m.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p publishParams
+		json.Unmarshal(m.Params, &p)
+		if p.URI == uri {
+			got, found = p.Diagnostics, true
+		}
+	}
+	if !found {
+		s.t.Fatalf("no diagnostics published for %s", uri)
+	}
+	return got
+}
+
+// writePlans creates an etch project with the given plans and returns the
+// URI of each plan by slug.
+func writePlans(t *testing.T, plans map[string]string) map[string]string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), ".etch", "plans")
+	os.MkdirAll(dir, 0o755)
+	uris := make(map[string]string)
+	for slug, content := range plans {
+		path := filepath.Join(dir, slug+".md")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		uris[slug] = pathURI(path)
+	}
+	return uris
+}
+
+func openDoc(s *session, uri, text string) {
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "markdown", "version": 1, "text": text},
+	})
+}
+
+func TestServe_Lifecycle(t *testing.T) {
+	s := newSession(t)
+	initID := s.request("initialize", map[string]any{"processId": nil, "capabilities": map[string]any{}})
+	s.notify("initialized", map[string]any{})
+	unknownID := s.request("workspace/symbol", map[string]any{"query": ""})
+	shutdownID := s.request("shutdown", nil)
+	s.notify("exit", nil)
+	s.request("initialize", nil) // after exit: never answered
+	s.run()
+
+	var init struct {
+		Capabilities struct {
+			DefinitionProvider bool `json:"definitionProvider"`
+			HoverProvider      bool `json:"hoverProvider"`
+			CompletionProvider any  `json:"completionProvider"`
+		} `json:"capabilities"`
+		ServerInfo struct{ Name string } `json:"serverInfo"`
+	}
+	s.response(initID, &init)
+	if !init.Capabilities.DefinitionProvider || !init.Capabilities.HoverProvider || init.Capabilities.CompletionProvider == nil {
+		t.Errorf("capabilities = %+v", init.Capabilities)
+	}
+	if init.ServerInfo.Name != "etch" {
+		t.Errorf("serverInfo = %+v", init.ServerInfo)
+	}
+
+	var shutdown any
+	s.response(shutdownID, &shutdown)
+	if shutdown != nil {
+		t.Errorf("shutdown result = %v, want null", shutdown)
+	}
+
+	for _, m := range s.out {
+		if string(m.ID) == fmt.Sprint(unknownID) {
+			if m.Error == nil || m.Error.Code != codeMethodNotFound {
+				t.Errorf("unknown method: %+v", m)
+			}
+		}
+	}
+	if len(s.out) != 3 {
+		t.Errorf("expected 3 responses, got %d", len(s.out))
+	}
+}
+
+func TestServe_DiagnosticsFollowEdits(t *testing.T) {
+	uris := writePlans(t, map[string]string{"auth": validPlan})
+	uri := uris["auth"]
+
+	s := newSession(t)
+	openDoc(s, uri, validPlan)
+	s.run()
+	if d := s.diagnostics(uri); len(d) != 0 {
+		t.Fatalf("valid plan: unexpected diagnostics %+v", d)
+	}
+
+	s = newSession(t)
+	openDoc(s, uri, validPlan)
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": strings.Replace(validPlan, "[pending]", "[done]", 1)}},
+	})
+	s.run()
+	d := s.diagnostics(uri)
+	if len(d) != 1 || d[0].Code != "unknown-status" || d[0].Severity != severityError {
+		t.Fatalf("expected unknown-status error, got %+v", d)
+	}
+
+	s = newSession(t)
+	openDoc(s, uri, "# Plan: X\n### Task 1: [pending]\n")
+	s.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	s.run()
+	if d := s.diagnostics(uri); len(d) != 0 {
+		t.Errorf("closing should clear diagnostics, got %+v", d)
+	}
+}
+
+func TestServe_IgnoresFilesOutsidePlans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "README.md")
+	s := newSession(t)
+	openDoc(s, pathURI(path), "# Not a plan\n")
+	hoverID := s.request("textDocument/hover", map[string]any{
+		"textDocument": map[string]any{"uri": pathURI(path)},
+		"position":     map[string]any{"line": 0, "character": 0},
+	})
+	s.run()
+
+	for _, m := range s.out {
+		if m.Method == "textDocument/publishDiagnostics" {
+			t.Errorf("unexpected diagnostics for a non-plan file: %s", m.Params)
+		}
+	}
+	var hover any
+	s.response(hoverID, &hover)
+	if hover != nil {
+		t.Errorf("hover = %v, want null", hover)
+	}
+}
+
+func TestReadMessage_InvalidHeader(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("Content-Type: x\r\n\r\n{}"))
+	if _, err := readMessage(br); err == nil {
+		t.Error("expected error for missing Content-Length")
+	}
+}
+
+func TestURIPath(t *testing.T) {
+	path := filepath.Join(string(filepath.Separator)+"tmp", "my project", ".etch", "plans", "a.md")
+	got, ok := uriPath(pathURI(path))
+	if !ok || got != path {
+		t.Errorf("round trip: got %q, %v", got, ok)
+	}
+	if !isPlanPath(got) {
+		t.Error("expected a plan path")
+	}
+	if _, ok := uriPath("untitled:Untitled-1"); ok {
+		t.Error("expected non-file URI to be rejected")
+	}
+}
@@ -0,0 +1,209 @@
+// Package mcp implements the subset of the Model Context Protocol that etch
+// needs to expose tools to agents: a server speaking newline-delimited
+// JSON-RPC 2.0 over stdio, with tools/list and tools/call.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// ProtocolVersion is the MCP revision the server implements.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is one tool offered to clients. Handler receives the call's
+// arguments as raw JSON and returns the text shown to the agent; an error
+// is reported to the agent as a failed call rather than a protocol error.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema Schema
+	Handler     func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Schema is a JSON Schema object describing a tool's arguments.
+type Schema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required,omitempty"`
+}
+
+// Property describes one argument in a Schema.
+type Property struct {
+	Type        string    `json:"type"`
+	Description string    `json:"description,omitempty"`
+	Items       *Property `json:"items,omitempty"`
+}
+
+// Server answers MCP requests with a fixed set of tools.
+type Server struct {
+	Name    string
+	Version string
+	Tools   []Tool
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type toolInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema Schema `json:"inputSchema"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w, one JSON message
+// per line, until r is exhausted or ctx is cancelled. Requests are handled
+// one at a time, in order.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp := s.handle(ctx, line)
+		if resp == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return etcherr.WrapIO("writing MCP response", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return etcherr.WrapIO("reading MCP request", err)
+	}
+	return nil
+}
+
+// handle answers one message. It returns nil for notifications.
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error: "+err.Error())
+	}
+	if req.ID == nil {
+		// Notifications, such as notifications/initialized, need no reply.
+		return nil
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	switch req.Method {
+	case "initialize":
+		return result(req.ID, map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+		})
+	case "ping":
+		return result(req.ID, map[string]any{})
+	case "tools/list":
+		tools := make([]toolInfo, len(s.Tools))
+		for i, t := range s.Tools {
+			tools[i] = toolInfo{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema}
+		}
+		return result(req.ID, map[string]any{"tools": tools})
+	case "tools/call":
+		return s.call(ctx, req)
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+func (s *Server) call(ctx context.Context, req request) *response {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, codeInvalidParams, "invalid params: "+err.Error())
+	}
+	for _, t := range s.Tools {
+		if t.Name != params.Name {
+			continue
+		}
+		args := params.Arguments
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		text, err := t.Handler(ctx, args)
+		if err != nil {
+			return result(req.ID, callResult{Content: []content{{Type: "text", Text: errorText(err)}}, IsError: true})
+		}
+		return result(req.ID, callResult{Content: []content{{Type: "text", Text: text}}})
+	}
+	return errorResponse(req.ID, codeInvalidParams, "unknown tool: "+params.Name)
+}
+
+// errorText renders err for an agent, including an etch error's hint.
+func errorText(err error) string {
+	var ee *etcherr.Error
+	if errors.As(err, &ee) && ee.Hint != "" {
+		return fmt.Sprintf("%s\nHint: %s", err, ee.Hint)
+	}
+	return err.Error()
+}
+
+func result(id json.RawMessage, v any) *response {
+	return &response{JSONRPC: "2.0", ID: id, Result: v}
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}}
+}
+
+// DecodeArgs unmarshals a tool's arguments into v, reporting bad input as
+// a usage error.
+func DecodeArgs(args json.RawMessage, v any) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return etcherr.Usage("invalid tool arguments: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+func testServer() *Server {
+	return &Server{
+		Name:    "etch",
+		Version: "test",
+		Tools: []Tool{
+			{
+				Name:        "echo",
+				Description: "Echo a message",
+				InputSchema: Schema{
+					Type:       "object",
+					Properties: map[string]Property{"message": {Type: "string"}},
+					Required:   []string{"message"},
+				},
+				Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+					var a struct{ Message string }
+					if err := DecodeArgs(args, &a); err != nil {
+						return "", err
+					}
+					if a.Message == "" {
+						return "", etcherr.Usage("message is required").WithHint("pass a message")
+					}
+					return a.Message, nil
+				},
+			},
+		},
+	}
+}
+
+// serve runs the server over the given request lines and returns the
+// decoded responses.
+func serve(t *testing.T, lines ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if err := testServer().Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var resps []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("decoding response: %v\n%s", err, out.String())
+		}
+		resps = append(resps, m)
+	}
+	return resps
+}
+
+func TestServe_Initialize(t *testing.T) {
+	resps := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	)
+	if len(resps) != 2 {
+		t.Fatalf("expected 2 responses (notification gets none), got %d: %v", len(resps), resps)
+	}
+	res := resps[0]["result"].(map[string]any)
+	if res["protocolVersion"] != ProtocolVersion {
+		t.Errorf("protocolVersion = %v", res["protocolVersion"])
+	}
+	if _, ok := res["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Error("expected tools capability")
+	}
+	if info := res["serverInfo"].(map[string]any); info["name"] != "etch" {
+		t.Errorf("serverInfo = %v", info)
+	}
+	if resps[1]["id"].(float64) != 2 {
+		t.Errorf("ping id = %v", resps[1]["id"])
+	}
+}
+
+func TestServe_ToolsList(t *testing.T) {
+	resps := serve(t, `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`)
+	tools := resps[0]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected 1 tool, got %d", len(tools))
+	}
+	tool := tools[0].(map[string]any)
+	if tool["name"] != "echo" {
+		t.Errorf("name = %v", tool["name"])
+	}
+	schema := tool["inputSchema"].(map[string]any)
+	if schema["type"] != "object" || schema["required"].([]any)[0] != "message" {
+		t.Errorf("inputSchema = %v", schema)
+	}
+}
+
+func TestServe_ToolsCall(t *testing.T) {
+	resps := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"message":3}}}`,
+	)
+
+	ok := resps[0]["result"].(map[string]any)
+	if ok["isError"] != nil {
+		t.Errorf("unexpected isError: %v", ok)
+	}
+	if text := ok["content"].([]any)[0].(map[string]any)["text"]; text != "hi" {
+		t.Errorf("text = %v", text)
+	}
+
+	failed := resps[1]["result"].(map[string]any)
+	if failed["isError"] != true {
+		t.Errorf("expected isError, got %v", failed)
+	}
+	text := failed["content"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.Contains(text, "message is required") || !strings.Contains(text, "Hint: pass a message") {
+		t.Errorf("error text = %q", text)
+	}
+
+	if resps[2]["result"].(map[string]any)["isError"] != true {
+		t.Error("expected badly typed arguments to fail the call")
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	resps := serve(t,
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"1.0","id":3,"method":"ping"}`,
+	)
+	want := []float64{codeParseError, codeMethodNotFound, codeInvalidParams, codeInvalidRequest}
+	if len(resps) != len(want) {
+		t.Fatalf("expected %d responses, got %d", len(want), len(resps))
+	}
+	for i, code := range want {
+		e, ok := resps[i]["error"].(map[string]any)
+		if !ok {
+			t.Errorf("response %d: expected error, got %v", i, resps[i])
+			continue
+		}
+		if e["code"] != code {
+			t.Errorf("response %d: code = %v, want %v", i, e["code"], code)
+		}
+	}
+	if resps[0]["id"] != nil {
+		t.Errorf("parse error id = %v, want null", resps[0]["id"])
+	}
+}
@@ -1,6 +1,12 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
 
 // Status represents the current state of a task.
 type Status string
@@ -73,6 +79,36 @@ func (p *Plan) TaskByID(id string) *Task {
 	return nil
 }
 
+// ResolveTaskID finds a task by its full ID (e.g. "1.2") or, in a
+// single-feature plan, by its bare task number (e.g. "2" → "1.2").
+func (p *Plan) ResolveTaskID(id string) *Task {
+	if task := p.TaskByID(id); task != nil {
+		return task
+	}
+	if len(p.Features) == 1 {
+		return p.TaskByID("1." + id)
+	}
+	return nil
+}
+
+// crossPlanDepRegex matches a dependency on a task in another plan, written
+// as "<plan-slug>#<task-id>" (e.g. "auth-system#1.2" or "auth-system#Task 2").
+// It is anchored to the start of the dependency, so a "word#N" mentioned in
+// a note after a same-plan dependency isn't taken for one.
+var crossPlanDepRegex = regexp.MustCompile(`^(?:Task\s+)?([a-z0-9][a-z0-9-]*)#\s*(?:Task\s+)?(\d+(?:\.\d+)?[a-z]?)\b`)
+
+// ParseCrossPlanDep splits a cross-plan dependency like "auth-system#1.2"
+// into the referenced plan slug and task ID. The task ID is returned as
+// written, so bare numbers should be resolved with Plan.ResolveTaskID.
+// ok is false for dependencies on tasks in the same plan.
+func ParseCrossPlanDep(dep string) (slug, taskID string, ok bool) {
+	m := crossPlanDepRegex.FindStringSubmatch(dep)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
 // Feature represents a group of related tasks within a plan.
 type Feature struct {
 	Number   int    `json:"number"`
@@ -101,10 +137,56 @@ func (t *Task) FullID() string {
 	return fmt.Sprintf("%d.%d%s", t.FeatureNumber, t.TaskNumber, t.Suffix)
 }
 
+// InScope reports whether a file path (relative to the project root) is
+// covered by the task's **Files:** list. Entries may name a file, a
+// directory, or a glob pattern; a trailing note such as "(new)" is ignored.
+// A task with no files listed has no scope to violate.
+func (t *Task) InScope(file string) bool {
+	if len(t.Files) == 0 {
+		return true
+	}
+	file = path.Clean(strings.TrimPrefix(file, "./"))
+	for _, entry := range t.Files {
+		entry = strings.Trim(entry, "` ")
+		if i := strings.Index(entry, " ("); i > 0 {
+			entry = strings.Trim(entry[:i], "` ")
+		}
+		entry = strings.TrimPrefix(entry, "./")
+		if entry == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(entry, "/**"); ok {
+			entry = prefix
+		}
+		entry = path.Clean(entry)
+		if file == entry || strings.HasPrefix(file, entry+"/") {
+			return true
+		}
+		if ok, _ := path.Match(entry, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Criterion represents a single acceptance criterion for a task.
 type Criterion struct {
 	Description string `json:"description"`
 	IsMet       bool   `json:"is_met"`
+	Verify      string `json:"verify,omitempty"` // shell command that checks the criterion
+}
+
+var verifyRegex = regexp.MustCompile("`verify:\\s*([^`]+)`")
+
+// VerifyCommand extracts the command from a criterion description written as
+// "Tests pass `verify: go test ./...`". It returns "" when the criterion has
+// no verify command.
+func VerifyCommand(description string) string {
+	m := verifyRegex.FindStringSubmatch(description)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
 }
 
 // SessionProgress tracks work done on a task in a single session.
@@ -112,11 +194,83 @@ type SessionProgress struct {
 	PlanSlug        string      `json:"plan_slug"`
 	TaskID          string      `json:"task_id"`
 	SessionNumber   int         `json:"session_number"`
-	Started         string      `json:"started"`
+	Started         time.Time   `json:"started,omitzero"`
+	Ended           time.Time   `json:"ended,omitzero"`
+	Resumed         time.Time   `json:"resumed,omitzero"`
+	Heartbeat       time.Time   `json:"heartbeat,omitzero"`
 	Status          string      `json:"status"`
+	Agent           string      `json:"agent,omitempty"`
+	Branch          string      `json:"branch,omitempty"`
+	BaseCommit      string      `json:"base_commit,omitempty"`
 	ChangesMade     []string    `json:"changes_made"`
 	CriteriaUpdates []Criterion `json:"criteria_updates"`
 	Decisions       string      `json:"decisions"`
 	Blockers        string      `json:"blockers"`
 	Next            string      `json:"next"`
+
+	// Active is the time worked in the session before it last started or
+	// resumed; see Duration.
+	Active time.Duration `json:"-"`
+	// Schema is the progress file format version, or 0 for files written
+	// before versioning.
+	Schema int `json:"schema"`
+}
+
+// SessionAbandoned is the status etch recover gives a session that stopped
+// without reporting an outcome. It says nothing about the task, whose
+// status after recovery is kept in the plan.
+const SessionAbandoned = "abandoned"
+
+// Running reports whether the session is in progress with its clock going.
+// Files from before schema 2 recorded when they were created rather than
+// when work started, so their sessions are never timed as running.
+func (s SessionProgress) Running() bool {
+	return s.Schema >= 2 && s.Status == string(StatusInProgress) && !s.Started.IsZero() && s.Ended.IsZero()
+}
+
+// StartedLabel returns when the session started in local time, for display.
+func (s SessionProgress) StartedLabel() string {
+	if s.Started.IsZero() {
+		return "not started"
+	}
+	return s.Started.Local().Format("2006-01-02 15:04")
+}
+
+// Duration returns how long the session has been worked on as of now: its
+// recorded active time, plus the time since it started or resumed if it is
+// still running.
+func (s SessionProgress) Duration(now time.Time) time.Duration {
+	d := s.Active
+	if s.Running() {
+		from := s.Started
+		if !s.Resumed.IsZero() {
+			from = s.Resumed
+		}
+		if now.After(from) {
+			d += now.Sub(from)
+		}
+	}
+	return d
+}
+
+// LastSeen returns the session's last sign of life: its latest heartbeat,
+// resume, or start.
+func (s SessionProgress) LastSeen() time.Time {
+	t := s.Started
+	for _, u := range []time.Time{s.Resumed, s.Heartbeat} {
+		if u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+// Stale reports whether the session is in progress but has shown no sign of
+// life for longer than after, as when its agent crashed mid-task. Sessions
+// without a heartbeat predate schema 3 and may record only when their file
+// was created, so they are never stale, as they are never Running before
+// schema 2.
+func (s SessionProgress) Stale(now time.Time, after time.Duration) bool {
+	return s.Schema >= 3 && !s.Heartbeat.IsZero() && s.Status == string(StatusInProgress) && s.Ended.IsZero() &&
+		now.Sub(s.LastSeen()) > after
 }
@@ -1,6 +1,9 @@
 package models
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestParseStatus(t *testing.T) {
 	tests := []struct {
@@ -79,6 +82,49 @@ func TestTaskFullID(t *testing.T) {
 	}
 }
 
+func TestTaskInScope(t *testing.T) {
+	task := &Task{Files: []string{"src/auth.ts", "`db/migrations/`", "internal/api/*.go", "docs/** (new)"}}
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"src/auth.ts", true},
+		{"./src/auth.ts", true},
+		{"src/auth.test.ts", false},
+		{"db/migrations/001.sql", true},
+		{"internal/api/client.go", true},
+		{"internal/api/sub/x.go", false},
+		{"docs/guide/intro.md", true},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		if got := task.InScope(tt.file); got != tt.want {
+			t.Errorf("InScope(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	if !(&Task{}).InScope("anything.go") {
+		t.Error("a task without files should accept every path")
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	tests := []struct {
+		desc string
+		want string
+	}{
+		{"Tests pass `verify: go test ./internal/auth/...`", "go test ./internal/auth/..."},
+		{"Builds `verify:make build` cleanly", "make build"},
+		{"Migration file created", ""},
+		{"Uses `verify` wording", ""},
+	}
+	for _, tt := range tests {
+		if got := VerifyCommand(tt.desc); got != tt.want {
+			t.Errorf("VerifyCommand(%q) = %q, want %q", tt.desc, got, tt.want)
+		}
+	}
+}
+
 func TestPlanTaskByID(t *testing.T) {
 	plan := &Plan{
 		Features: []Feature{
@@ -130,3 +176,90 @@ func TestPlanTaskByID(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCrossPlanDep(t *testing.T) {
+	tests := []struct {
+		input  string
+		slug   string
+		taskID string
+		ok     bool
+	}{
+		{"auth-system#1.2", "auth-system", "1.2", true},
+		{"Task auth-system#1.3b", "auth-system", "1.3b", true},
+		{"auth-system#Task 2", "auth-system", "2", true},
+		{"v2-api#3.1 (schema)", "v2-api", "3.1", true},
+		{"Task 1.2", "", "", false},
+		{"Task 1.2 (see issue#12)", "", "", false},
+		{"none", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			slug, taskID, ok := ParseCrossPlanDep(tt.input)
+			if slug != tt.slug || taskID != tt.taskID || ok != tt.ok {
+				t.Errorf("ParseCrossPlanDep(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.input, slug, taskID, ok, tt.slug, tt.taskID, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPlanResolveTaskID(t *testing.T) {
+	single := &Plan{Features: []Feature{{Number: 1, Tasks: []Task{{FeatureNumber: 1, TaskNumber: 2}}}}}
+	if single.ResolveTaskID("2") == nil || single.ResolveTaskID("1.2") == nil {
+		t.Error("single-feature plan should resolve both bare and full IDs")
+	}
+
+	multi := &Plan{Features: []Feature{
+		{Number: 1, Tasks: []Task{{FeatureNumber: 1, TaskNumber: 2}}},
+		{Number: 2, Tasks: []Task{{FeatureNumber: 2, TaskNumber: 1}}},
+	}}
+	if multi.ResolveTaskID("2") != nil {
+		t.Error("multi-feature plan should not resolve bare IDs")
+	}
+	if multi.ResolveTaskID("2.1") == nil {
+		t.Error("multi-feature plan should resolve full IDs")
+	}
+}
+
+func TestSessionProgressStale(t *testing.T) {
+	at := func(hm string) time.Time {
+		at, err := time.Parse("2006-01-02 15:04", "2026-03-01 "+hm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	s := SessionProgress{Status: "in_progress", Schema: 3, Started: at("09:00"), Heartbeat: at("09:40")}
+	if !s.LastSeen().Equal(at("09:40")) {
+		t.Errorf("LastSeen = %v, want the heartbeat", s.LastSeen())
+	}
+	if s.Stale(at("10:00"), 30*time.Minute) {
+		t.Error("a session with a recent heartbeat is not stale")
+	}
+	if !s.Stale(at("10:20"), 30*time.Minute) {
+		t.Error("a session idle past the threshold is stale")
+	}
+
+	s.Resumed = at("10:15")
+	if s.Stale(at("10:20"), 30*time.Minute) {
+		t.Error("resuming counts as a sign of life")
+	}
+
+	// Older files have no heartbeat, and their start may be when the file
+	// was created, long before anyone worked on it.
+	for _, old := range []SessionProgress{
+		{Status: "in_progress", Schema: 1, Started: at("09:00")},
+		{Status: "in_progress", Schema: 3, Started: at("09:00")},
+	} {
+		if old.Stale(at("12:00"), 30*time.Minute) {
+			t.Errorf("a session without a heartbeat is never stale: %+v", old)
+		}
+	}
+
+	for _, status := range []string{"completed", "failed", SessionAbandoned} {
+		s := SessionProgress{Status: status, Started: at("09:00")}
+		if s.Stale(at("12:00"), 30*time.Minute) {
+			t.Errorf("a %s session is never stale", status)
+		}
+	}
+}
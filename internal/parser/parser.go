@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gsigler/etch/internal/document"
 	"github.com/gsigler/etch/internal/models"
 )
 
@@ -82,7 +82,18 @@ func filepath(path string) string {
 // Parse reads plan markdown from r and returns a Plan struct.
 // It returns an error if no "# Plan:" heading is found.
 func Parse(r io.Reader) (*models.Plan, error) {
-	scanner := bufio.NewScanner(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	plan, _, err := ParseDocument(string(data))
+	return plan, err
+}
+
+// ParseDocument parses plan markdown and also returns the lossless document
+// it was read from, with each line's role and owner filled in.
+func ParseDocument(src string) (*models.Plan, *document.Document, error) {
+	doc := document.New(src)
 	plan := &models.Plan{}
 
 	cur := stateInit
@@ -94,10 +105,28 @@ func Parse(r io.Reader) (*models.Plan, error) {
 
 	inComment := false // tracking multi-line > 💬 comments
 
+	// Document lines accumulated into descBuf, and the lines that currently
+	// hold the plan and feature overviews (a later overview replaces them).
+	var descLines []int
+	var overviewLines []int
+	featureOverviewLines := make(map[int][]int)
+
+	// markText gives lines a text role, resetting any they held before.
+	markText := func(old, lines []int, role document.Role) {
+		for _, i := range old {
+			doc.Lines[i].Role = document.Text
+		}
+		for _, i := range lines {
+			doc.Lines[i].Role = role
+		}
+	}
+
 	// flush saves accumulated description text into the current section.
 	flush := func() {
 		text := strings.TrimSpace(descBuf.String())
+		lines := descLines
 		descBuf.Reset()
+		descLines = nil
 		inComment = false
 		if text == "" {
 			return
@@ -105,41 +134,56 @@ func Parse(r io.Reader) (*models.Plan, error) {
 		switch cur {
 		case stateOverview:
 			plan.Overview = text
+			markText(overviewLines, lines, document.Overview)
+			overviewLines = lines
 		case stateFeature:
 			// Content between feature heading and first task/overview — treat as feature overview.
 			if currentFeature != nil && currentFeature.Overview == "" {
 				currentFeature.Overview = text
+				fi := len(plan.Features) - 1
+				markText(nil, lines, document.FeatureOverview)
+				featureOverviewLines[fi] = lines
 			}
 		case stateFeatureOver:
 			if currentFeature != nil {
 				currentFeature.Overview = text
+				fi := len(plan.Features) - 1
+				markText(featureOverviewLines[fi], lines, document.FeatureOverview)
+				featureOverviewLines[fi] = lines
 			}
 		case stateTask:
 			if currentTask != nil {
 				currentTask.Description = text
+				markText(nil, lines, document.Description)
 			}
 		}
 	}
 
+	// accumulate adds line i to the current section's description text.
+	accumulate := func(i int, line string) {
+		descBuf.WriteString(line)
+		descBuf.WriteString("\n")
+		descLines = append(descLines, i)
+	}
+
 	inCodeFence := false
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for i := range doc.Lines {
+		ln := &doc.Lines[i]
+		line := strings.TrimSuffix(ln.Text, "\r")
 
 		// Track fenced code blocks — skip everything inside them.
 		if strings.HasPrefix(strings.TrimSpace(line), "```") {
 			inCodeFence = !inCodeFence
 			// Still accumulate code fence content into current section description.
 			if cur == stateTask || cur == stateOverview || cur == stateFeature || cur == stateFeatureOver {
-				descBuf.WriteString(line)
-				descBuf.WriteString("\n")
+				accumulate(i, line)
 			}
 			continue
 		}
 		if inCodeFence {
 			if cur == stateTask || cur == stateOverview || cur == stateFeature || cur == stateFeatureOver {
-				descBuf.WriteString(line)
-				descBuf.WriteString("\n")
+				accumulate(i, line)
 			}
 			continue
 		}
@@ -159,6 +203,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 				title = strings.TrimSpace(statusTagRe.ReplaceAllString(title, ""))
 			}
 			plan.Title = title
+			ln.Role = document.PlanHeading
 			cur = statePlanLevel
 			continue
 		}
@@ -168,9 +213,11 @@ func Parse(r io.Reader) (*models.Plan, error) {
 			flush()
 			// Only treat as plan overview if we haven't entered a feature yet.
 			if !sawFeatureHeading {
+				ln.Role = document.OverviewHeading
 				cur = stateOverview
 			} else {
 				// Feature-level content that happens to be "## Overview" — unlikely but handle.
+				ln.Role = document.SectionHeading
 				cur = stateOther
 			}
 			continue
@@ -188,6 +235,8 @@ func Parse(r io.Reader) (*models.Plan, error) {
 			plan.Features = append(plan.Features, f)
 			currentFeature = &plan.Features[len(plan.Features)-1]
 			currentTask = nil
+			ln.Role = document.FeatureHeading
+			ln.Feature = len(plan.Features) - 1
 			cur = stateFeature
 			continue
 		}
@@ -196,6 +245,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 		if overviewH3Re.MatchString(line) {
 			flush()
 			if currentFeature != nil {
+				ln.Role = document.FeatureOverviewHeading
 				cur = stateFeatureOver
 			}
 			continue
@@ -245,6 +295,9 @@ func Parse(r io.Reader) (*models.Plan, error) {
 			}
 			currentFeature.Tasks = append(currentFeature.Tasks, t)
 			currentTask = &currentFeature.Tasks[len(currentFeature.Tasks)-1]
+			ln.Role = document.TaskHeading
+			ln.Feature = len(plan.Features) - 1
+			ln.Task = len(currentFeature.Tasks) - 1
 			cur = stateTask
 			continue
 		}
@@ -253,6 +306,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 		if h2Re.MatchString(line) {
 			flush()
 			currentTask = nil
+			ln.Role = document.SectionHeading
 			cur = stateOther
 			continue
 		}
@@ -265,6 +319,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 			if m := priorityRe.FindStringSubmatch(line); m != nil {
 				if n, err := strconv.Atoi(m[1]); err == nil {
 					plan.Priority = n
+					ln.Role = document.Priority
 				}
 				continue
 			}
@@ -273,8 +328,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 		// Accumulate content into current section.
 		switch cur {
 		case stateOverview, stateFeature, stateFeatureOver:
-			descBuf.WriteString(line)
-			descBuf.WriteString("\n")
+			accumulate(i, line)
 		case stateTask:
 			if currentTask == nil {
 				break
@@ -282,6 +336,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 			// Try to extract task metadata before falling through to description.
 			if m := complexityRe.FindStringSubmatch(line); m != nil {
 				currentTask.Complexity = models.Complexity(strings.TrimSpace(m[1]))
+				ln.Role = document.Complexity
 				continue
 			}
 			if m := filesRe.FindStringSubmatch(line); m != nil {
@@ -291,6 +346,7 @@ func Parse(r io.Reader) (*models.Plan, error) {
 						currentTask.Files = append(currentTask.Files, f)
 					}
 				}
+				ln.Role = document.Files
 				continue
 			}
 			if m := dependsOnRe.FindStringSubmatch(line); m != nil {
@@ -300,22 +356,30 @@ func Parse(r io.Reader) (*models.Plan, error) {
 						currentTask.DependsOn = append(currentTask.DependsOn, d)
 					}
 				}
+				ln.Role = document.DependsOn
 				continue
 			}
 			if criteriaHeadingRe.MatchString(line) {
+				ln.Role = document.CriteriaHeading
 				continue
 			}
 			if m := criterionRe.FindStringSubmatch(line); m != nil {
 				inComment = false
+				desc := strings.TrimSpace(m[2])
 				currentTask.Criteria = append(currentTask.Criteria, models.Criterion{
-					Description: strings.TrimSpace(m[2]),
+					Description: desc,
 					IsMet:       m[1] == "x",
+					Verify:      models.VerifyCommand(desc),
 				})
+				ln.Role = document.Criterion
+				ln.Item = len(currentTask.Criteria) - 1
 				continue
 			}
 			if m := commentRe.FindStringSubmatch(line); m != nil {
 				inComment = true
 				currentTask.Comments = append(currentTask.Comments, strings.TrimSpace(m[1]))
+				ln.Role = document.Comment
+				ln.Item = len(currentTask.Comments) - 1
 				continue
 			}
 			// Multi-line comment continuation: > lines following a 💬 line.
@@ -323,27 +387,46 @@ func Parse(r io.Reader) (*models.Plan, error) {
 				if m := commentContRe.FindStringSubmatch(line); m != nil {
 					idx := len(currentTask.Comments) - 1
 					currentTask.Comments[idx] += "\n" + strings.TrimSpace(m[1])
+					ln.Role = document.CommentContinuation
+					ln.Item = idx
 					continue
 				}
 				inComment = false
 			}
-			descBuf.WriteString(line)
-			descBuf.WriteString("\n")
+			accumulate(i, line)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading plan: %w", err)
-	}
-
 	// Final flush.
 	flush()
 
 	if plan.Title == "" {
-		return nil, fmt.Errorf("invalid plan file: no '# Plan:' heading found")
+		return nil, nil, fmt.Errorf("invalid plan file: no '# Plan:' heading found")
 	}
 
-	return plan, nil
+	assignOwners(doc)
+	return plan, doc, nil
+}
+
+// assignOwners records which feature and task owns each line: the one
+// whose heading most recently preceded it. Plan-level sections and
+// unrecognized ## sections have no owner.
+func assignOwners(doc *document.Document) {
+	feature, task := -1, -1
+	for i := range doc.Lines {
+		ln := &doc.Lines[i]
+		switch ln.Role {
+		case document.PlanHeading, document.OverviewHeading, document.SectionHeading:
+			feature, task = -1, -1
+		case document.FeatureHeading:
+			feature, task = ln.Feature, -1
+		case document.TaskHeading:
+			feature, task = ln.Feature, ln.Task
+		case document.FeatureOverviewHeading:
+			task = -1
+		}
+		ln.Feature, ln.Task = feature, task
+	}
 }
 
 // atoi converts a string to int, returning 0 on failure.
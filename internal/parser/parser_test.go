@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gsigler/etch/internal/document"
 	"github.com/gsigler/etch/internal/models"
 )
 
@@ -763,3 +764,94 @@ Description.
 		})
 	}
 }
+
+func TestParse_CriterionVerifyCommand(t *testing.T) {
+	input := "# Plan: Verify\n\n## Feature 1: Core\n\n### Task 1.1: Auth [pending]\n\n" +
+		"**Acceptance Criteria:**\n" +
+		"- [ ] Tests pass `verify: go test ./internal/auth/...`\n" +
+		"- [x] Documented\n"
+
+	plan, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	criteria := plan.Features[0].Tasks[0].Criteria
+	if len(criteria) != 2 {
+		t.Fatalf("criteria count = %d, want 2", len(criteria))
+	}
+	if criteria[0].Verify != "go test ./internal/auth/..." {
+		t.Errorf("verify = %q", criteria[0].Verify)
+	}
+	if criteria[0].Description != "Tests pass `verify: go test ./internal/auth/...`" {
+		t.Errorf("description should keep the verify command, got %q", criteria[0].Description)
+	}
+	if criteria[1].Verify != "" {
+		t.Errorf("expected no verify command, got %q", criteria[1].Verify)
+	}
+}
+
+func TestParseDocument_RolesAndOwners(t *testing.T) {
+	input := `# Plan: Doc
+Stray note.
+
+## Notes
+Kept as text.
+
+## Feature 1: Core
+
+### Task 1.1: First [pending]
+**Complexity:** small
+
+Do it.
+
+> 💬 Check this.
+> Second line.
+
+**Acceptance Criteria:**
+- [ ] One
+- [x] Two
+`
+	_, doc, err := ParseDocument(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.String() != input {
+		t.Errorf("document does not reproduce its source")
+	}
+
+	want := []struct {
+		role                document.Role
+		feature, task, item int
+	}{
+		{document.PlanHeading, -1, -1, -1},
+		{document.Text, -1, -1, -1},
+		{document.Text, -1, -1, -1},
+		{document.SectionHeading, -1, -1, -1},
+		{document.Text, -1, -1, -1},
+		{document.Text, -1, -1, -1},
+		{document.FeatureHeading, 0, -1, -1},
+		{document.Text, 0, -1, -1},
+		{document.TaskHeading, 0, 0, -1},
+		{document.Complexity, 0, 0, -1},
+		{document.Description, 0, 0, -1},
+		{document.Description, 0, 0, -1},
+		{document.Description, 0, 0, -1},
+		{document.Comment, 0, 0, 0},
+		{document.CommentContinuation, 0, 0, 0},
+		{document.Description, 0, 0, -1},
+		{document.CriteriaHeading, 0, 0, -1},
+		{document.Criterion, 0, 0, 0},
+		{document.Criterion, 0, 0, 1},
+	}
+	if len(doc.Lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), len(doc.Lines))
+	}
+	for i, w := range want {
+		l := doc.Lines[i]
+		if l.Role != w.role || l.Feature != w.feature || l.Task != w.task || l.Item != w.item {
+			t.Errorf("line %d %q: got role=%d owner=(%d,%d) item=%d, want role=%d owner=(%d,%d) item=%d",
+				l.Pos.Line, l.Text, l.Role, l.Feature, l.Task, l.Item, w.role, w.feature, w.task, w.item)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package progress
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+)
+
+// SchemaVersion is the version of the progress file format this etch
+// writes. Files with a newer version are rejected rather than misread.
+//
+// Schema 3 adds heartbeat and the abandoned status. Schema 2 records times
+// as RFC 3339 with a time zone and adds resumed and active. Schema 1
+// recorded local times to the minute.
+const SchemaVersion = 3
+
+// TimeLayout is how progress files record times.
+const TimeLayout = time.RFC3339
+
+// schema1TimeLayouts are the local time formats of schema 1 and of files
+// written before frontmatter.
+var schema1TimeLayouts = []string{"2006-01-02 15:04", "2006-01-02"}
+
+const frontmatterDelim = "---"
+
+// statusPartial is what early versions of etch wrote for a session still in
+// progress. It is read as in_progress and rewritten as such on upgrade.
+const statusPartial = "partial"
+
+// Header is the YAML frontmatter at the top of a progress file. Everything
+// below it is markdown for humans, apart from the sections etch reads.
+type Header struct {
+	Schema  int
+	Plan    string
+	Task    string
+	Session int
+	Status  string
+	Started string
+	Ended   string
+	// Resumed is when work last resumed after the session stopped.
+	Resumed string
+	// Active is the time worked in the session before its latest start or
+	// resume, as a Go duration such as "1h20m0s".
+	Active string
+	// Heartbeat is when the session last showed it was alive, refreshed
+	// while an agent works so that crashed sessions can be told apart.
+	Heartbeat  string
+	Agent      string // backend that ran the session, e.g. "claude"
+	Branch     string
+	BaseCommit string
+}
+
+// field is one frontmatter key. Exactly one of str and num is set.
+type field struct {
+	key      string
+	required bool
+	str      func(h *Header) *string
+	num      func(h *Header) *int
+}
+
+// fields lists the frontmatter keys in the order they are written.
+var fields = []field{
+	{key: "schema", required: true, num: func(h *Header) *int { return &h.Schema }},
+	{key: "plan", required: true, str: func(h *Header) *string { return &h.Plan }},
+	{key: "task", required: true, str: func(h *Header) *string { return &h.Task }},
+	{key: "session", required: true, num: func(h *Header) *int { return &h.Session }},
+	{key: "status", required: true, str: func(h *Header) *string { return &h.Status }},
+	{key: "started", str: func(h *Header) *string { return &h.Started }},
+	{key: "ended", str: func(h *Header) *string { return &h.Ended }},
+	{key: "resumed", str: func(h *Header) *string { return &h.Resumed }},
+	{key: "active", str: func(h *Header) *string { return &h.Active }},
+	{key: "heartbeat", str: func(h *Header) *string { return &h.Heartbeat }},
+	{key: "agent", str: func(h *Header) *string { return &h.Agent }},
+	{key: "branch", str: func(h *Header) *string { return &h.Branch }},
+	{key: "base_commit", str: func(h *Header) *string { return &h.BaseCommit }},
+}
+
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// splitFrontmatter splits a progress file into its frontmatter lines and
+// the body after it. ok is false for files without frontmatter, which are
+// in the legacy **Field:** format.
+func splitFrontmatter(content string) (front []string, body string, ok bool, err error) {
+	first, rest, _ := strings.Cut(content, "\n")
+	if strings.TrimRight(first, "\r") != frontmatterDelim {
+		return nil, content, false, nil
+	}
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if strings.TrimRight(line, "\r") == frontmatterDelim {
+			return front, rest, true, nil
+		}
+		front = append(front, line)
+	}
+	return nil, "", true, fmt.Errorf("frontmatter is not closed with %q", frontmatterDelim)
+}
+
+// parseHeader parses frontmatter lines strictly: every key must be known
+// and appear once, values must be plain scalars, and required keys must be
+// present. Line numbers in errors count the opening "---" as line 1.
+func parseHeader(lines []string) (Header, error) {
+	var h Header
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		lineNo := i + 2
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return h, fmt.Errorf("line %d: nested values are not supported", lineNo)
+		}
+		key, raw, found := strings.Cut(line, ":")
+		if !found {
+			return h, fmt.Errorf("line %d: expected \"key: value\", got %q", lineNo, line)
+		}
+		f, ok := lookupField(key)
+		if !ok {
+			return h, fmt.Errorf("line %d: unknown key %q", lineNo, key)
+		}
+		if seen[key] {
+			return h, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+		seen[key] = true
+
+		value, err := parseScalar(strings.TrimSpace(raw))
+		if err != nil {
+			return h, fmt.Errorf("line %d: %s: %v", lineNo, key, err)
+		}
+		if f.num != nil {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return h, fmt.Errorf("line %d: %s must be a whole number, got %q", lineNo, key, value)
+			}
+			*f.num(&h) = n
+		} else {
+			*f.str(&h) = value
+		}
+	}
+
+	for _, f := range fields {
+		if f.required && !seen[f.key] {
+			return h, fmt.Errorf("missing required key %q", f.key)
+		}
+	}
+	return h, h.validate()
+}
+
+func (h Header) validate() error {
+	if h.Schema < 1 {
+		return fmt.Errorf("schema must be at least 1, got %d", h.Schema)
+	}
+	if h.Schema > SchemaVersion {
+		return fmt.Errorf("schema %d is newer than this version of etch supports (%d); upgrade etch", h.Schema, SchemaVersion)
+	}
+	if h.Plan == "" || h.Task == "" {
+		return fmt.Errorf("plan and task must not be empty")
+	}
+	if h.Session < 1 {
+		return fmt.Errorf("session must be at least 1, got %d", h.Session)
+	}
+	if string(models.ParseStatus(h.Status)) != h.Status && h.Status != models.SessionAbandoned && h.Status != statusPartial {
+		return fmt.Errorf("status %q is not one of pending, in_progress, completed, blocked, failed, abandoned", h.Status)
+	}
+	for key, v := range map[string]string{"started": h.Started, "ended": h.Ended, "resumed": h.Resumed, "heartbeat": h.Heartbeat} {
+		if _, err := parseTime(h.Schema, v); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	if h.Active != "" {
+		if d, err := time.ParseDuration(h.Active); err != nil || d < 0 {
+			return fmt.Errorf("active must be a duration such as \"1h20m0s\", got %q", h.Active)
+		}
+	}
+	return nil
+}
+
+// parseTime parses a time recorded under schema. Empty is the zero time.
+func parseTime(schema int, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if schema >= 2 {
+		t, err := time.Parse(TimeLayout, s)
+		if err != nil {
+			return t, fmt.Errorf("%q is not an RFC 3339 time such as \"2026-01-02T15:04:05-07:00\"", s)
+		}
+		return t, nil
+	}
+	for _, layout := range schema1TimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time such as \"2026-01-02 15:04\"", s)
+}
+
+func formatTime(t time.Time) string {
+	return t.Truncate(time.Second).Format(TimeLayout)
+}
+
+// upgrade converts a valid header from an older schema to the current one.
+// Schema 1 files have no active time, so a session that ended is counted as
+// active from start to end. Schema 2 files need no changes. The legacy
+// partial status becomes in_progress.
+func (h *Header) upgrade() {
+	if h.Schema < 2 {
+		h.upgradeTimes()
+	}
+	if h.Status == statusPartial {
+		h.Status = string(models.StatusInProgress)
+	}
+	h.Schema = SchemaVersion
+}
+
+// current reports whether the header needs no upgrade.
+func (h Header) current() bool {
+	return h.Schema == SchemaVersion && h.Status != statusPartial
+}
+
+func (h *Header) upgradeTimes() {
+	started, _ := parseTime(h.Schema, h.Started)
+	ended, _ := parseTime(h.Schema, h.Ended)
+	h.Started, h.Ended = "", ""
+	if !started.IsZero() {
+		h.Started = formatTime(started)
+	}
+	if !ended.IsZero() {
+		h.Ended = formatTime(ended)
+		if !started.IsZero() && ended.After(started) {
+			h.Active = ended.Sub(started).Round(time.Second).String()
+		}
+	}
+}
+
+// start records that work on the session began, or resumed after it
+// stopped, at t. A session already running only gets a heartbeat.
+func (h *Header) start(t time.Time) {
+	h.Heartbeat = formatTime(t)
+	switch {
+	case h.Started == "":
+		h.Started = formatTime(t)
+		h.Ended = ""
+	case h.Ended != "":
+		h.Resumed = formatTime(t)
+		h.Ended = ""
+	}
+}
+
+// stop records that work on the session stopped at t, adding the time
+// since it started or resumed to Active. A session already stopped keeps
+// its end time.
+func (h *Header) stop(t time.Time) {
+	if h.Ended != "" {
+		return
+	}
+	h.Ended = formatTime(t)
+	from := h.Started
+	if h.Resumed != "" {
+		from = h.Resumed
+	}
+	h.Resumed = ""
+	begin, err := parseTime(h.Schema, from)
+	if err != nil || begin.IsZero() || !t.After(begin) {
+		return
+	}
+	active, _ := time.ParseDuration(h.Active)
+	h.Active = (active + t.Sub(begin)).Round(time.Second).String()
+}
+
+// parseScalar returns the value of a YAML scalar: plain, double-quoted, or
+// single-quoted, optionally followed by a comment.
+func parseScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", fmt.Errorf("unterminated or invalid double-quoted string")
+		}
+		if err := trailingComment(s[len(quoted):]); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(s, "'"):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), trailingComment(s[i+1:])
+		}
+		return "", fmt.Errorf("unterminated single-quoted string")
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s != "" && (strings.ContainsRune("[]{}&*!|>%@`?,", rune(s[0])) || s == "-" || strings.HasPrefix(s, "- ")) {
+		return "", fmt.Errorf("only plain and quoted strings are supported, got %q", s)
+	}
+	return s, nil
+}
+
+func trailingComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after quoted string", rest)
+	}
+	return nil
+}
+
+// render returns the header as frontmatter, omitting empty optional keys.
+func (h Header) render() string {
+	var b strings.Builder
+	b.WriteString(frontmatterDelim + "\n")
+	for _, f := range fields {
+		if f.num != nil {
+			fmt.Fprintf(&b, "%s: %d\n", f.key, *f.num(&h))
+			continue
+		}
+		v := *f.str(&h)
+		if v == "" && !f.required {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", f.key, yamlString(v))
+	}
+	b.WriteString(frontmatterDelim + "\n")
+	return b.String()
+}
+
+// yamlString writes s plain when YAML would read it back as the same
+// string, and double-quoted otherwise, so "1.1" stays a string.
+func yamlString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_./-+", r)) {
+			return strconv.Quote(s)
+		}
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if strings.ContainsRune("-.+", rune(s[0])) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// fromLegacy builds a header from the **Field:** lines of a legacy progress
+// file, filling anything missing from its file name.
+func fromLegacy(meta map[string]string, filename string) Header {
+	h := Header{
+		Schema:     1, // legacy files record times as schema 1 does
+		Plan:       meta["plan"],
+		Task:       meta["task"],
+		Status:     meta["status"],
+		Started:    meta["started"],
+		Ended:      meta["ended"],
+		Agent:      meta["agent"],
+		Branch:     meta["branch"],
+		BaseCommit: meta["base_commit"],
+	}
+	fmt.Sscanf(meta["session"], "%d", &h.Session)
+
+	plan, task, session, ok := parseFilename(filename)
+	if ok {
+		if h.Plan == "" {
+			h.Plan = plan
+		}
+		if h.Task == "" {
+			h.Task = task
+		}
+		if h.Session == 0 {
+			h.Session = session
+		}
+	}
+	if h.Status == "" {
+		h.Status = string(models.StatusPending)
+	}
+	return h
+}
+
+// legacyKey maps a legacy header label such as "Base Commit" to its
+// frontmatter key, "base_commit".
+func legacyKey(label string) string {
+	return strings.ReplaceAll(strings.ToLower(label), " ", "_")
+}
+
+// legacyLabel maps a frontmatter key to its legacy header label.
+func legacyLabel(key string) string {
+	words := strings.Split(key, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// parseLegacyMeta returns a legacy **Label:** value line as a frontmatter
+// key and value.
+func parseLegacyMeta(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, "**") {
+		return "", "", false
+	}
+	label, value, found := strings.Cut(line[2:], ":**")
+	if !found || label == "" || strings.Contains(label, "*") {
+		return "", "", false
+	}
+	return legacyKey(label), strings.TrimSpace(value), true
+}
+
+// parseFilename splits <plan>--task-<id>--<NNN>.md into its parts.
+func parseFilename(name string) (plan, task string, session int, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".md"), "--")
+	if len(parts) < 3 || !strings.HasPrefix(parts[len(parts)-2], "task-") {
+		return "", "", 0, false
+	}
+	if _, err := fmt.Sscanf(parts[len(parts)-1], "%d", &session); err != nil {
+		return "", "", 0, false
+	}
+	plan = strings.Join(parts[:len(parts)-2], "--")
+	task = strings.TrimPrefix(parts[len(parts)-2], "task-")
+	return plan, task, session, true
+}
@@ -0,0 +1,172 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHeader_RoundTrip(t *testing.T) {
+	h := Header{
+		Schema:     SchemaVersion,
+		Plan:       "auth",
+		Task:       "1.1",
+		Session:    3,
+		Status:     "in_progress",
+		Started:    "2026-01-02T09:30:00Z",
+		Resumed:    "2026-01-02T11:00:00+01:00",
+		Active:     "45m0s",
+		Agent:      "claude",
+		Branch:     "etch/auth/task-1.1",
+		BaseCommit: "1234567",
+	}
+	front, body, ok, err := splitFrontmatter(h.render() + "\n# Session\n")
+	if err != nil || !ok {
+		t.Fatalf("splitFrontmatter: ok %v, err %v", ok, err)
+	}
+	if body != "\n# Session\n" {
+		t.Errorf("body = %q", body)
+	}
+	got, err := parseHeader(front)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != h {
+		t.Errorf("round trip = %+v, want %+v", got, h)
+	}
+}
+
+func TestParseHeader_Scalars(t *testing.T) {
+	front := []string{
+		"# written by hand",
+		"schema: 1",
+		"plan: 'it''s'",
+		`task: "1.3b" # quoted`,
+		"session: 2",
+		"",
+		"status: blocked  # waiting on review",
+		"agent:",
+	}
+	h, err := parseHeader(front)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Plan != "it's" || h.Task != "1.3b" || h.Session != 2 || h.Status != "blocked" || h.Agent != "" {
+		t.Errorf("parsed %+v", h)
+	}
+}
+
+func TestParseHeader_Strict(t *testing.T) {
+	valid := []string{"schema: 1", "plan: auth", `task: "1.1"`, "session: 1", "status: pending"}
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"unknown key", append(valid, "owner: me"), `line 7: unknown key "owner"`},
+		{"duplicate key", append(valid, "status: completed"), `line 7: duplicate key "status"`},
+		{"bold variant", append(valid, "**Status:** completed"), `unknown key "**Status"`},
+		{"missing key", valid[1:], `missing required key "schema"`},
+		{"not a number", []string{"schema: one", "plan: auth", "task: 1.1", "session: 1", "status: pending"}, "schema must be a whole number"},
+		{"newer schema", []string{"schema: 99", "plan: auth", "task: 1.1", "session: 1", "status: pending"}, "newer than this version of etch"},
+		{"bad status", []string{"schema: 1", "plan: auth", "task: 1.1", "session: 1", "status: done"}, `status "done" is not one of`},
+		{"schema 1 time", append(valid[1:], "schema: 2", "started: 2026-01-02 09:30"), "not an RFC 3339 time"},
+		{"bad time", append(valid, "ended: yesterday"), `ended: "yesterday" is not a time`},
+		{"bad active", append(valid[1:], "schema: 2", "active: 5 minutes"), "active must be a duration"},
+		{"bad heartbeat", append(valid[1:], "schema: 3", "heartbeat: now"), `heartbeat: "now" is not an RFC 3339 time`},
+		{"nested", append(valid, "  branch: x"), "nested values are not supported"},
+		{"no colon", append(valid, "branch"), `expected "key: value"`},
+		{"flow", append(valid, "agent: [claude]"), "only plain and quoted strings"},
+		{"unterminated", append(valid, `agent: "claude`), "unterminated"},
+		{"trailing text", append(valid, `agent: "claude" x`), "after quoted string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseHeader(tt.lines)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitFrontmatter(t *testing.T) {
+	if _, body, ok, err := splitFrontmatter("# Session\n**Task:** 1.1\n"); ok || err != nil || body != "# Session\n**Task:** 1.1\n" {
+		t.Errorf("legacy file: ok %v, err %v, body %q", ok, err, body)
+	}
+	if _, _, _, err := splitFrontmatter("---\nschema: 1\n# Session\n"); err == nil {
+		t.Error("expected an error for unclosed frontmatter")
+	}
+	front, body, ok, err := splitFrontmatter("---\r\nschema: 1\r\n---\r\nbody")
+	if !ok || err != nil || len(front) != 1 || body != "body" {
+		t.Errorf("CRLF file: front %q, body %q, ok %v, err %v", front, body, ok, err)
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	for in, want := range map[string]string{
+		"auth":               "auth",
+		"etch/auth/task-1.1": "etch/auth/task-1.1",
+		"1.1":                `"1.1"`,
+		"12":                 `"12"`,
+		"yes":                `"yes"`,
+		"2026-01-02 09:30":   `"2026-01-02 09:30"`,
+		"-x":                 `"-x"`,
+		"":                   `""`,
+	} {
+		if got := yamlString(in); got != want {
+			t.Errorf("yamlString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestParseFilename(t *testing.T) {
+	plan, task, session, ok := parseFilename("my--plan--task-1.3b--012.md")
+	if !ok || plan != "my--plan" || task != "1.3b" || session != 12 {
+		t.Errorf("parseFilename = %q %q %d %v", plan, task, session, ok)
+	}
+	if _, _, _, ok := parseFilename("notes.md"); ok {
+		t.Error("expected notes.md not to parse")
+	}
+}
+
+func TestHeader_Clock(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		tm, _ := time.Parse(time.RFC3339, "2026-01-02T"+hhmm+":00Z")
+		return tm
+	}
+	h := Header{Schema: SchemaVersion}
+
+	h.start(at("09:00"))
+	h.start(at("09:10")) // already running
+	h.stop(at("10:00"))
+	if h.Started != "2026-01-02T09:00:00Z" || h.Ended != "2026-01-02T10:00:00Z" || h.Active != "1h0m0s" {
+		t.Fatalf("after first stretch: %+v", h)
+	}
+
+	// Time spent blocked doesn't count.
+	h.start(at("13:00"))
+	if h.Resumed != "2026-01-02T13:00:00Z" || h.Ended != "" {
+		t.Fatalf("after resuming: %+v", h)
+	}
+	h.stop(at("13:30"))
+	h.stop(at("14:00")) // already stopped
+	if h.Active != "1h30m0s" || h.Resumed != "" || h.Ended != "2026-01-02T13:30:00Z" || h.Started != "2026-01-02T09:00:00Z" {
+		t.Errorf("after second stretch: %+v", h)
+	}
+}
+
+func TestHeader_UpgradeFromSchema1(t *testing.T) {
+	h := Header{Schema: 1, Plan: "auth", Task: "1.1", Session: 1, Status: "completed", Started: "2026-01-02 09:30", Ended: "2026-01-02 10:45"}
+	if err := h.validate(); err != nil {
+		t.Fatal(err)
+	}
+	h.upgrade()
+	if err := h.validate(); err != nil {
+		t.Fatalf("upgraded header invalid: %v", err)
+	}
+	started, _ := time.Parse(time.RFC3339, h.Started)
+	if h.Schema != SchemaVersion || !started.Equal(time.Date(2026, 1, 2, 9, 30, 0, 0, time.Local)) || h.Active != "1h15m0s" {
+		t.Errorf("upgraded = %+v", h)
+	}
+}
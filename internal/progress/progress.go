@@ -1,7 +1,7 @@
 package progress
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gsigler/etch/internal/atomicfile"
 	"github.com/gsigler/etch/internal/models"
 )
 
@@ -78,18 +79,25 @@ func nextSessionNumber(dir, planSlug, taskID string) int {
 	return max + 1
 }
 
+// SessionPath returns the path of a task's progress file for the given session.
+func SessionPath(rootDir, planSlug, taskID string, session int) string {
+	return filepath.Join(rootDir, progressDir, formatFilename(planSlug, taskID, session))
+}
+
 func formatFilename(planSlug, taskID string, session int) string {
 	return fmt.Sprintf("%s--task-%s--%03d.md", planSlug, taskID, session)
 }
 
 func renderTemplate(plan *models.Plan, task *models.Task, session int) string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("# Session: Task %s – %s\n", task.FullID(), task.Title))
-	b.WriteString(fmt.Sprintf("**Plan:** %s\n", plan.Slug))
-	b.WriteString(fmt.Sprintf("**Task:** %s\n", task.FullID()))
-	b.WriteString(fmt.Sprintf("**Session:** %03d\n", session))
-	b.WriteString(fmt.Sprintf("**Started:** %s\n", time.Now().Format("2006-01-02 15:04")))
-	b.WriteString("**Status:** pending\n")
+	b.WriteString(Header{
+		Schema:  SchemaVersion,
+		Plan:    plan.Slug,
+		Task:    task.FullID(),
+		Session: session,
+		Status:  string(models.StatusPending),
+	}.render())
+	b.WriteString(fmt.Sprintf("\n# Session: Task %s – %s\n", task.FullID(), task.Title))
 	b.WriteString("\n## Changes Made\n<!-- List files created or modified -->\n")
 	b.WriteString("\n## Acceptance Criteria Updates\n")
 	for _, c := range task.Criteria {
@@ -135,18 +143,56 @@ func ReadAll(rootDir, planSlug string) (map[string][]models.SessionProgress, err
 	return result, nil
 }
 
+// ReadSession parses a single progress file.
+func ReadSession(path, planSlug string) (models.SessionProgress, error) {
+	return parseProgressFile(path, planSlug)
+}
+
 func parseProgressFile(path, planSlug string) (models.SessionProgress, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return models.SessionProgress{}, err
 	}
-	defer f.Close()
 
 	sp := models.SessionProgress{PlanSlug: planSlug}
+	front, body, ok, err := splitFrontmatter(string(data))
+	if err != nil {
+		return sp, err
+	}
+	if ok {
+		h, err := parseHeader(front)
+		if err != nil {
+			return sp, err
+		}
+		if h.Plan != planSlug {
+			return sp, fmt.Errorf("belongs to plan %q, not %q", h.Plan, planSlug)
+		}
+		sp.Schema = h.Schema
+		h.upgrade()
+		sp.TaskID = h.Task
+		sp.SessionNumber = h.Session
+		sp.Status = h.Status
+		sp.Started, _ = parseTime(h.Schema, h.Started)
+		sp.Ended, _ = parseTime(h.Schema, h.Ended)
+		sp.Resumed, _ = parseTime(h.Schema, h.Resumed)
+		sp.Active, _ = time.ParseDuration(h.Active)
+		sp.Heartbeat, _ = parseTime(h.Schema, h.Heartbeat)
+		sp.Agent = h.Agent
+		sp.Branch = h.Branch
+		sp.BaseCommit = h.BaseCommit
+	}
+	parseBody(&sp, body, !ok)
 
-	scanner := bufio.NewScanner(f)
-	var currentSection string
+	if sp.TaskID == "" {
+		return sp, fmt.Errorf("missing task ID")
+	}
+	return sp, nil
+}
 
+// parseBody reads the markdown sections of a progress file into sp. Legacy
+// files keep their metadata in **Field:** lines, which are read too.
+func parseBody(sp *models.SessionProgress, body string, legacy bool) {
+	var currentSection string
 	var sectionText strings.Builder
 
 	flushSection := func() {
@@ -168,26 +214,31 @@ func parseProgressFile(path, planSlug string) (models.SessionProgress, error) {
 		sectionText.Reset()
 	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Parse metadata lines.
-		if strings.HasPrefix(line, "**Task:**") {
-			sp.TaskID = strings.TrimSpace(strings.TrimPrefix(line, "**Task:**"))
-			continue
-		}
-		if strings.HasPrefix(line, "**Session:**") {
-			val := strings.TrimSpace(strings.TrimPrefix(line, "**Session:**"))
-			fmt.Sscanf(val, "%d", &sp.SessionNumber)
-			continue
-		}
-		if strings.HasPrefix(line, "**Status:**") {
-			sp.Status = strings.TrimSpace(strings.TrimPrefix(line, "**Status:**"))
-			continue
-		}
-		if strings.HasPrefix(line, "**Started:**") {
-			sp.Started = strings.TrimSpace(strings.TrimPrefix(line, "**Started:**"))
-			continue
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if legacy && currentSection == "" {
+			if key, value, ok := parseLegacyMeta(line); ok {
+				switch key {
+				case "task":
+					sp.TaskID = value
+				case "session":
+					fmt.Sscanf(value, "%d", &sp.SessionNumber)
+				case "status":
+					sp.Status = value
+				case "started":
+					sp.Started, _ = parseTime(0, value)
+				case "ended":
+					sp.Ended, _ = parseTime(0, value)
+				case "agent":
+					sp.Agent = value
+				case "branch":
+					sp.Branch = value
+				case "base_commit":
+					sp.BaseCommit = value
+				}
+				continue
+			}
 		}
 
 		// Detect section headers.
@@ -203,12 +254,6 @@ func parseProgressFile(path, planSlug string) (models.SessionProgress, error) {
 		}
 	}
 	flushSection()
-
-	if sp.TaskID == "" {
-		return sp, fmt.Errorf("missing task ID")
-	}
-
-	return sp, scanner.Err()
 }
 
 func parseListItems(text string) []string {
@@ -232,14 +277,18 @@ func parseCriteria(text string) []models.Criterion {
 	for _, line := range strings.Split(text, "\n") {
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "- [x] ") {
+			desc := strings.TrimPrefix(line, "- [x] ")
 			criteria = append(criteria, models.Criterion{
-				Description: strings.TrimPrefix(line, "- [x] "),
+				Description: desc,
 				IsMet:       true,
+				Verify:      models.VerifyCommand(desc),
 			})
 		} else if strings.HasPrefix(line, "- [ ] ") {
+			desc := strings.TrimPrefix(line, "- [ ] ")
 			criteria = append(criteria, models.Criterion{
-				Description: strings.TrimPrefix(line, "- [ ] "),
+				Description: desc,
 				IsMet:       false,
+				Verify:      models.VerifyCommand(desc),
 			})
 		}
 	}
@@ -285,6 +334,12 @@ func FindLatestSessionPath(rootDir, planSlug, taskID string) (string, int, error
 // AppendToSection appends a line of content to a named section (e.g. "Changes Made")
 // in a progress file. This is a surgical line-based edit that preserves existing content.
 func AppendToSection(path, sectionName, content string) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading progress file: %w", err)
@@ -327,12 +382,18 @@ func AppendToSection(path, sectionName, content string) error {
 	newLines = append(newLines, content)
 	newLines = append(newLines, lines[insertIdx:]...)
 
-	return os.WriteFile(path, []byte(strings.Join(newLines, "\n")), 0644)
+	return atomicfile.WriteFile(path, []byte(strings.Join(newLines, "\n")), 0644)
 }
 
 // UpdateCriterion marks a criterion as checked in a progress file's
 // "Acceptance Criteria Updates" section. It matches by exact criterion description.
 func UpdateCriterion(path, criterionText string) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading progress file: %w", err)
@@ -357,31 +418,308 @@ func UpdateCriterion(path, criterionText string) error {
 		return fmt.Errorf("criterion %q not found in progress file", criterionText)
 	}
 
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	return atomicfile.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
 }
 
-// UpdateStatus surgically replaces the **Status:** line in a progress file.
-func UpdateStatus(path string, newStatus string) error {
+// SetCriterion ticks or unticks a criterion in a progress file's
+// "Acceptance Criteria Updates" section, matching by exact description.
+// Unlike UpdateCriterion, a criterion already in the requested state is not
+// an error.
+func SetCriterion(path, criterionText string, met bool) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading progress file: %w", err)
 	}
 
+	check := "- [ ] "
+	if met {
+		check = "- [x] "
+	}
 	lines := strings.Split(string(data), "\n")
 	found := false
 	for i, line := range lines {
-		if strings.HasPrefix(line, "**Status:**") {
-			lines[i] = "**Status:** " + newStatus
-			found = true
+		trimmed := strings.TrimSpace(line)
+		for _, prefix := range []string{"- [ ] ", "- [x] "} {
+			if strings.HasPrefix(trimmed, prefix) && strings.TrimPrefix(trimmed, prefix) == criterionText {
+				lines[i] = strings.Replace(line, prefix, check, 1)
+				found = true
+			}
+		}
+		if found {
 			break
 		}
 	}
 
 	if !found {
-		return fmt.Errorf("no **Status:** line found in %s", filepath.Base(path))
+		return fmt.Errorf("criterion %q not found in progress file", criterionText)
+	}
+
+	return atomicfile.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// UpdateStatus sets a progress file's status. Moving to in_progress starts
+// or resumes the session's clock; moving to completed, blocked, or failed
+// stops it.
+func UpdateStatus(path string, newStatus string) error {
+	return rewriteHeader(path, func(h *Header) error {
+		h.Status = newStatus
+		switch models.Status(newStatus) {
+		case models.StatusInProgress:
+			h.start(time.Now())
+		case models.StatusCompleted, models.StatusBlocked, models.StatusFailed:
+			h.stop(time.Now())
+		}
+		return nil
+	}, legacyStatus(path, newStatus))
+}
+
+// legacyStatus sets the **Status:** line of a legacy progress file.
+func legacyStatus(path, newStatus string) func(lines []string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		for i, line := range lines {
+			if strings.HasPrefix(line, "**Status:**") {
+				lines[i] = "**Status:** " + newStatus
+				return lines, nil
+			}
+		}
+		return nil, fmt.Errorf("no **Status:** line found in %s", filepath.Base(path))
 	}
+}
 
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+// SetField sets an optional frontmatter key, such as "branch" or
+// "base_commit", in a progress file. Legacy files get the matching
+// **Field:** line instead, added after the last header line if missing.
+func SetField(path, key, value string) error {
+	f, ok := lookupField(key)
+	if !ok || f.str == nil || f.required {
+		return fmt.Errorf("%q is not a progress field that can be set", key)
+	}
+	return rewriteHeader(path, func(h *Header) error {
+		*f.str(h) = value
+		return nil
+	}, func(lines []string) ([]string, error) {
+		prefix := "**" + legacyLabel(key) + ":**"
+		last := -1
+		for i, line := range lines {
+			if strings.HasPrefix(line, "## ") {
+				break
+			}
+			if strings.HasPrefix(line, prefix) {
+				lines[i] = prefix + " " + value
+				return lines, nil
+			}
+			if _, _, ok := parseLegacyMeta(line); ok {
+				last = i
+			}
+		}
+		if last < 0 {
+			return nil, fmt.Errorf("no header found in %s", filepath.Base(path))
+		}
+
+		newLines := make([]string, 0, len(lines)+1)
+		newLines = append(newLines, lines[:last+1]...)
+		newLines = append(newLines, prefix+" "+value)
+		newLines = append(newLines, lines[last+1:]...)
+		return newLines, nil
+	})
+}
+
+// RecordStart starts or resumes the clock of the session at path, for
+// callers such as etch run that know when work begins regardless of status.
+// Legacy files are left alone; run etch migrate to time them.
+func RecordStart(path string) error {
+	return rewriteHeader(path, func(h *Header) error {
+		h.start(time.Now())
+		return nil
+	}, unchanged)
+}
+
+// RecordEnd stops the clock of the session at path, if it is running.
+func RecordEnd(path string) error {
+	return rewriteHeader(path, func(h *Header) error {
+		h.stop(time.Now())
+		return nil
+	}, unchanged)
+}
+
+// Heartbeat records that the session at path is still alive. Legacy files
+// are left alone.
+func Heartbeat(path string) error {
+	return rewriteHeader(path, func(h *Header) error {
+		h.Heartbeat = formatTime(time.Now())
+		return nil
+	}, unchanged)
+}
+
+// HeartbeatInterval is how often KeepAlive refreshes a session's heartbeat.
+const HeartbeatInterval = time.Minute
+
+// KeepAlive refreshes the heartbeat of the session at path every interval
+// until the returned function is called, for wrappers such as etch run that
+// supervise an agent. Failed refreshes are ignored: a missed heartbeat only
+// makes the session look idle.
+func KeepAlive(path string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				Heartbeat(path)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// Abandon marks the session at path abandoned. Its clock stops at
+// lastSeen, its last sign of life, so the time since isn't counted as
+// worked.
+func Abandon(path string, lastSeen time.Time) error {
+	return rewriteHeader(path, func(h *Header) error {
+		h.stop(lastSeen)
+		h.Status = models.SessionAbandoned
+		return nil
+	}, legacyStatus(path, models.SessionAbandoned))
+}
+
+func unchanged(lines []string) ([]string, error) {
+	return lines, nil
+}
+
+// rewriteHeader applies edit to the frontmatter of the progress file at
+// path, or legacy to its lines if the file predates frontmatter.
+func rewriteHeader(path string, edit func(h *Header) error, legacy func(lines []string) ([]string, error)) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading progress file: %w", err)
+	}
+
+	front, body, ok, err := splitFrontmatter(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if !ok {
+		lines, err := legacy(strings.Split(string(data), "\n"))
+		if err != nil {
+			return err
+		}
+		return atomicfile.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	}
+
+	h, err := parseHeader(front)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	h.upgrade()
+	if err := edit(&h); err != nil {
+		return err
+	}
+	if err := h.validate(); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return atomicfile.WriteFile(path, []byte(h.render()+body), 0644)
+}
+
+// Migrate upgrades the progress file at path to the current schema in
+// place, moving a legacy file's **Field:** lines into frontmatter and
+// leaving the rest of the markdown as it was. It reports whether the file
+// changed.
+func Migrate(path string) (bool, error) {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("reading progress file: %w", err)
+	}
+
+	front, rest, ok, err := splitFrontmatter(string(data))
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		h, err := parseHeader(front)
+		if err != nil || h.current() {
+			return false, err
+		}
+		h.upgrade()
+		return true, atomicfile.WriteFile(path, []byte(h.render()+rest), 0644)
+	}
+
+	meta := make(map[string]string)
+	var body []string
+	inHeader := true
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "## ") {
+			inHeader = false
+		}
+		if inHeader {
+			if key, value, ok := parseLegacyMeta(line); ok {
+				if _, known := lookupField(key); known {
+					meta[key] = value
+					continue
+				}
+			}
+		}
+		body = append(body, line)
+	}
+
+	h := fromLegacy(meta, filepath.Base(path))
+	if err := h.validate(); err != nil {
+		return false, err
+	}
+	h.upgrade()
+	content := h.render() + "\n" + strings.TrimLeft(strings.Join(body, "\n"), "\n")
+	return true, atomicfile.WriteFile(path, []byte(content), 0644)
+}
+
+// MigrateAll upgrades every progress file in the project at rootDir and
+// returns the paths of the files it changed. Files that can't be upgraded
+// are left alone and reported together in the error.
+func MigrateAll(rootDir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(rootDir, progressDir, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("globbing progress files: %w", err)
+	}
+	sort.Strings(matches)
+
+	var migrated []string
+	var errs []error
+	for _, path := range matches {
+		changed, err := Migrate(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		if changed {
+			migrated = append(migrated, path)
+		}
+	}
+	return migrated, errors.Join(errs...)
 }
 
 func stripComments(text string) string {
@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gsigler/etch/internal/models"
 )
@@ -123,12 +125,18 @@ func TestWriteSession_TemplateContent(t *testing.T) {
 	}
 	content := string(data)
 
+	if !strings.HasPrefix(content, "---\nschema: 3\n") {
+		t.Errorf("template should open with versioned frontmatter, got:\n%s", content)
+	}
+	if strings.Contains(content, "started:") {
+		t.Error("a new session shouldn't be started until work begins")
+	}
 	checks := []string{
 		"# Session: Task 1.1 – Database Schema",
-		"**Plan:** auth-system",
-		"**Task:** 1.1",
-		"**Session:** 001",
-		"**Status:** pending",
+		"plan: auth-system\n",
+		"task: \"1.1\"\n",
+		"session: 1\n",
+		"status: pending\n",
 		"## Changes Made",
 		"## Acceptance Criteria Updates",
 		"- [ ] Migration file creates users table",
@@ -200,8 +208,8 @@ Run integration tests.
 	if sp.Status != "completed" {
 		t.Errorf("Status = %q, want %q", sp.Status, "completed")
 	}
-	if sp.Started != "2026-02-16 09:30" {
-		t.Errorf("Started = %q, want %q", sp.Started, "2026-02-16 09:30")
+	if want := time.Date(2026, 2, 16, 9, 30, 0, 0, time.Local); !sp.Started.Equal(want) {
+		t.Errorf("Started = %v, want %v", sp.Started, want)
 	}
 	if len(sp.ChangesMade) != 2 {
 		t.Errorf("ChangesMade len = %d, want 2", len(sp.ChangesMade))
@@ -531,6 +539,45 @@ func TestAppendToSection_AppendsMultiple(t *testing.T) {
 	}
 }
 
+func TestAppendToSection_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.md")
+	initial := "---\nschema: 1\nplan: myplan\ntask: \"1.1\"\nsession: 1\nstatus: pending\n---\n\n## Changes Made\n\n## Decisions & Notes\n\n## Next\n"
+	os.WriteFile(path, []byte(initial), 0o644)
+
+	const writers = 40
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := AppendToSection(path, "Changes Made", fmt.Sprintf("- change %d", i)); err != nil {
+				t.Error(err)
+			}
+			if err := AppendToSection(path, "Decisions & Notes", fmt.Sprintf("- note %d", i)); err != nil {
+				t.Error(err)
+			}
+			if err := SetField(path, "agent", fmt.Sprintf("writer%d", i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	for i := 0; i < writers; i++ {
+		for _, want := range []string{fmt.Sprintf("- change %d\n", i), fmt.Sprintf("- note %d\n", i)} {
+			if !strings.Contains(content, want) {
+				t.Errorf("lost update %q", strings.TrimSpace(want))
+			}
+		}
+	}
+	if sp, err := parseProgressFile(path, "myplan"); err != nil || !strings.HasPrefix(sp.Agent, "writer") {
+		t.Errorf("frontmatter damaged by concurrent writes: agent %q, err %v", sp.Agent, err)
+	}
+}
+
 func TestAppendToSection_MissingSection(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "test.md")
@@ -656,3 +703,391 @@ func TestUpdateCriterion_FileNotFound(t *testing.T) {
 		t.Fatal("expected error for missing file")
 	}
 }
+
+func TestSetField_LegacyAddsAfterHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.md")
+	initial := "# Session\n**Plan:** myplan\n**Task:** 1.1\n**Session:** 001\n**Status:** pending\n\n## Changes Made\n"
+	os.WriteFile(path, []byte(initial), 0o644)
+
+	if err := SetField(path, "branch", "etch/myplan/task-1.1"); err != nil {
+		t.Fatalf("SetField() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "**Status:** pending\n**Branch:** etch/myplan/task-1.1\n\n## Changes Made") {
+		t.Errorf("expected branch after status line, got:\n%s", data)
+	}
+
+	sp, err := parseProgressFile(path, "myplan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Branch != "etch/myplan/task-1.1" {
+		t.Errorf("Branch = %q", sp.Branch)
+	}
+}
+
+func TestSetField_LegacyReplacesExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.md")
+	os.WriteFile(path, []byte("**Task:** 1.1\n**Branch:** old\n\n## Changes Made\n"), 0o644)
+
+	if err := SetField(path, "branch", "new"); err != nil {
+		t.Fatalf("SetField() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "old") || strings.Count(string(data), "**Branch:**") != 1 {
+		t.Errorf("expected branch to be replaced, got:\n%s", data)
+	}
+}
+
+func TestSetField_LegacyNoHeader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.md")
+	os.WriteFile(path, []byte("## Changes Made\n"), 0o644)
+
+	if err := SetField(path, "branch", "x"); err == nil {
+		t.Error("expected error for file without header")
+	}
+}
+
+func TestSetCriterion_TicksAndUnticks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.md")
+	os.WriteFile(path, []byte("**Task:** 1.1\n\n## Acceptance Criteria Updates\n- [ ] Tests pass `verify: true`\n- [x] Other\n"), 0o644)
+
+	if err := SetCriterion(path, "Tests pass `verify: true`", true); err != nil {
+		t.Fatalf("SetCriterion() error: %v", err)
+	}
+	if err := SetCriterion(path, "Other", false); err != nil {
+		t.Fatalf("SetCriterion() error: %v", err)
+	}
+	// Already in the requested state.
+	if err := SetCriterion(path, "Other", false); err != nil {
+		t.Fatalf("SetCriterion() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "**Task:** 1.1\n\n## Acceptance Criteria Updates\n- [x] Tests pass `verify: true`\n- [ ] Other\n" {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+
+	sp, err := ReadSession(path, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.CriteriaUpdates) != 2 || sp.CriteriaUpdates[0].Verify != "true" || !sp.CriteriaUpdates[0].IsMet {
+		t.Errorf("expected verify command parsed from progress criteria, got %+v", sp.CriteriaUpdates)
+	}
+
+	if err := SetCriterion(path, "Missing", true); err == nil {
+		t.Error("expected error for unknown criterion")
+	}
+}
+
+// --- Frontmatter and migration tests ---
+
+func TestReadAll_Frontmatter(t *testing.T) {
+	dir := t.TempDir()
+	progDir := filepath.Join(dir, progressDir)
+	os.MkdirAll(progDir, 0o755)
+
+	content := `---
+schema: 1
+plan: myplan
+task: "1.2"
+session: 2
+status: completed
+started: "2026-02-16 09:30"
+ended: "2026-02-16 10:15"
+agent: claude
+base_commit: abc123
+---
+
+# Session: Task 1.2 – Login
+**Status:** blocked
+
+## Changes Made
+- Added login handler
+
+## Acceptance Criteria Updates
+- [x] Login works
+`
+	os.WriteFile(filepath.Join(progDir, "myplan--task-1.2--002.md"), []byte(content), 0o644)
+	// A malformed header is skipped, not guessed at.
+	os.WriteFile(filepath.Join(progDir, "myplan--task-1.3--001.md"), []byte("---\nschema: 1\nplan: myplan\ntask: 1.3\n---\n"), 0o644)
+
+	result, err := ReadAll(dir, "myplan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || len(result["1.2"]) != 1 {
+		t.Fatalf("expected only task 1.2, got %v", result)
+	}
+	sp := result["1.2"][0]
+	if sp.Schema != 1 || sp.SessionNumber != 2 || sp.Agent != "claude" || sp.BaseCommit != "abc123" {
+		t.Errorf("header not read: %+v", sp)
+	}
+	// Schema 1 times are local, and a finished session counts as active
+	// from start to end.
+	if want := time.Date(2026, 2, 16, 10, 15, 0, 0, time.Local); !sp.Ended.Equal(want) || sp.Active != 45*time.Minute {
+		t.Errorf("ended %v, active %v; want %v and 45m", sp.Ended, sp.Active, want)
+	}
+	if sp.Status != "completed" {
+		t.Errorf("Status = %q; **Status:** lines in the body must be ignored", sp.Status)
+	}
+	if len(sp.ChangesMade) != 1 || len(sp.CriteriaUpdates) != 1 || !sp.CriteriaUpdates[0].IsMet {
+		t.Errorf("body not read: %+v", sp)
+	}
+}
+
+func TestUpdateStatus_Frontmatter(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteSession(dir, testPlan(), testTask(1, 1, "", "Schema", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UpdateStatus(path, "completed"); err != nil {
+		t.Fatal(err)
+	}
+	sp, err := ReadSession(path, "auth-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Status != "completed" || sp.Ended.IsZero() {
+		t.Errorf("after completing: status %q, ended %v", sp.Status, sp.Ended)
+	}
+
+	if err := UpdateStatus(path, "in_progress"); err != nil {
+		t.Fatal(err)
+	}
+	if sp, _ := ReadSession(path, "auth-system"); !sp.Ended.IsZero() || sp.Started.IsZero() || !sp.Running() {
+		t.Errorf("restarting should clear ended and run the clock: %+v", sp)
+	}
+
+	if err := UpdateStatus(path, "done"); err == nil {
+		t.Error("expected an error for an invalid status")
+	}
+}
+
+func TestHeartbeatAndAbandon(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteSession(dir, testPlan(), testTask(1, 1, "", "Schema", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateStatus(path, "in_progress"); err != nil {
+		t.Fatal(err)
+	}
+	sp, _ := ReadSession(path, "auth-system")
+	if sp.Heartbeat.IsZero() || !sp.LastSeen().Equal(sp.Heartbeat) {
+		t.Fatalf("starting should record a heartbeat: %+v", sp)
+	}
+
+	// Backdate the session so the heartbeat has something to move.
+	data, _ := os.ReadFile(path)
+	started := sp.Started.Format(TimeLayout)
+	old := sp.Started.Add(-2 * time.Hour).Format(TimeLayout)
+	os.WriteFile(path, []byte(strings.ReplaceAll(string(data), started, old)), 0o644)
+	if err := Heartbeat(path); err != nil {
+		t.Fatal(err)
+	}
+	sp, _ = ReadSession(path, "auth-system")
+	if !sp.Heartbeat.After(sp.Started) || sp.Stale(time.Now(), 30*time.Minute) {
+		t.Errorf("a fresh heartbeat should keep the session alive: %+v", sp)
+	}
+
+	lastSeen := sp.Started.Add(45 * time.Minute)
+	if err := Abandon(path, lastSeen); err != nil {
+		t.Fatal(err)
+	}
+	sp, err = ReadSession(path, "auth-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Status != models.SessionAbandoned || !sp.Ended.Equal(lastSeen.Truncate(time.Second)) || sp.Active != 45*time.Minute || sp.Running() {
+		t.Errorf("after abandoning: status %q, ended %v, active %v", sp.Status, sp.Ended, sp.Active)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteSession(dir, testPlan(), testTask(1, 1, "", "Schema", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := KeepAlive(path, 10*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if sp, _ := ReadSession(path, "auth-system"); !sp.Heartbeat.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no heartbeat recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+
+	before, _ := os.ReadFile(path)
+	time.Sleep(50 * time.Millisecond)
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("heartbeats continued after stop")
+	}
+}
+
+func TestSetField_Frontmatter(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteSession(dir, testPlan(), testTask(1, 1, "", "Schema", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetField(path, "branch", "etch/auth-system/task-1.1"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "branch: etch/auth-system/task-1.1\n---\n") {
+		t.Errorf("branch not in frontmatter:\n%s", data)
+	}
+	for _, key := range []string{"status", "owner"} {
+		if err := SetField(path, key, "x"); err == nil {
+			t.Errorf("SetField(%q) should be refused", key)
+		}
+	}
+}
+
+func TestMigrate_LegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	progDir := filepath.Join(dir, progressDir)
+	os.MkdirAll(progDir, 0o755)
+	path := filepath.Join(progDir, "auth--task-1.1--003.md")
+	legacy := `# Session: Task 1.1 – Schema
+**Plan:** auth
+**Task:** 1.1
+**Started:** 2026-02-16 09:30
+**Status:** in_progress
+**Branch:** etch/auth/task-1.1
+**Base Commit:** abc123
+
+## Changes Made
+- Created migration
+
+## Acceptance Criteria Updates
+- [x] Table exists
+`
+	os.WriteFile(path, []byte(legacy), 0o644)
+	before, err := ReadSession(path, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := Migrate(path)
+	if err != nil || !changed {
+		t.Fatalf("Migrate: changed %v, err %v", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	started := time.Date(2026, 2, 16, 9, 30, 0, 0, time.Local).Format(time.RFC3339)
+	want := `---
+schema: 3
+plan: auth
+task: "1.1"
+session: 3
+status: in_progress
+started: "` + started + `"
+branch: etch/auth/task-1.1
+base_commit: abc123
+---
+
+# Session: Task 1.1 – Schema
+
+## Changes Made
+- Created migration
+
+## Acceptance Criteria Updates
+- [x] Table exists
+`
+	if string(data) != want {
+		t.Errorf("migrated file:\n%s\nwant:\n%s", data, want)
+	}
+
+	after, err := ReadSession(path, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the schema, and the session number taken from the file name,
+	// should differ.
+	if !after.Started.Equal(before.Started) {
+		t.Errorf("started moved from %v to %v", before.Started, after.Started)
+	}
+	after.Schema = 0
+	after.Started = before.Started
+	before.SessionNumber = 3
+	if fmt.Sprintf("%+v", after) != fmt.Sprintf("%+v", before) {
+		t.Errorf("migration changed what is read:\nbefore %+v\nafter  %+v", before, after)
+	}
+
+	if changed, err := Migrate(path); err != nil || changed {
+		t.Errorf("second Migrate: changed %v, err %v", changed, err)
+	}
+}
+
+func TestMigrateAll(t *testing.T) {
+	dir := t.TempDir()
+	progDir := filepath.Join(dir, progressDir)
+	os.MkdirAll(progDir, 0o755)
+	os.WriteFile(filepath.Join(progDir, "a--task-1.1--001.md"), []byte("# Session\n**Task:** 1.1\n**Status:** completed\n"), 0o644)
+	os.WriteFile(filepath.Join(progDir, "a--task-1.2--001.md"), []byte("# Session\n**Task:** 1.2\n**Status:** done\n"), 0o644)
+	current, err := WriteSession(dir, &models.Plan{Slug: "a"}, testTask(1, 3, "", "Three", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := MigrateAll(dir)
+	if len(migrated) != 1 || filepath.Base(migrated[0]) != "a--task-1.1--001.md" {
+		t.Errorf("migrated = %v", migrated)
+	}
+	if err == nil || !strings.Contains(err.Error(), `a--task-1.2--001.md: status "done"`) {
+		t.Errorf("expected the invalid file to be reported, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(progDir, "a--task-1.2--001.md")); !strings.HasPrefix(string(data), "# Session") {
+		t.Errorf("a file that can't be migrated should be left alone:\n%s", data)
+	}
+	if _, err := ReadSession(current, "a"); err != nil {
+		t.Errorf("current file damaged: %v", err)
+	}
+}
+
+func TestMigrate_PartialStatus(t *testing.T) {
+	dir := t.TempDir()
+	progDir := filepath.Join(dir, progressDir)
+	os.MkdirAll(progDir, 0o755)
+	legacy := filepath.Join(progDir, "a--task-1.1--001.md")
+	os.WriteFile(legacy, []byte("# Session\n**Task:** 1.1\n**Status:** partial\n"), 0o644)
+	front := filepath.Join(progDir, "a--task-1.2--001.md")
+	os.WriteFile(front, []byte("---\nschema: 1\nplan: a\ntask: \"1.2\"\nsession: 1\nstatus: partial\n---\n\n# Session\n"), 0o644)
+
+	// Early etch wrote "partial" for sessions in progress. Neither file is
+	// skipped; status reconciles partial as in_progress.
+	all, err := ReadAll(dir, "a")
+	if err != nil || len(all["1.1"]) != 1 || len(all["1.2"]) != 1 {
+		t.Fatalf("ReadAll = %+v, %v", all, err)
+	}
+	if s := all["1.2"][0].Status; s != string(models.StatusInProgress) {
+		t.Errorf("frontmatter partial read as %q", s)
+	}
+
+	migrated, err := MigrateAll(dir)
+	if err != nil || len(migrated) != 2 {
+		t.Fatalf("MigrateAll = %v, %v", migrated, err)
+	}
+	for _, path := range migrated {
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), "status: in_progress\n") {
+			t.Errorf("%s not normalized:\n%s", filepath.Base(path), data)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package schedule ranks the tasks that are ready to run, across plans, so
+// etch run and etch next can pick the most useful one. The ranking strategy
+// is pluggable: a Scheduler is chosen by [scheduler] strategy in config.
+package schedule
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gsigler/etch/internal/config"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/graph"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// Node is a task in the cross-plan dependency graph.
+type Node struct {
+	Plan   *models.Plan
+	Task   *models.Task
+	Status models.Status // reconciled from progress files
+	// Deps are the tasks this one depends on, in this or other plans.
+	Deps []*Node
+	// Runnable is set for pending tasks whose dependencies are completed
+	// and that no one else has claimed.
+	Runnable bool
+}
+
+// Ref returns the node's plan-qualified ID, e.g. "auth#1.2".
+func (n *Node) Ref() string {
+	return n.Plan.Slug + "#" + n.Task.FullID()
+}
+
+// Graph is the dependency graph of every task a scheduler can see. Nodes
+// are kept in plan order, which breaks ties between equal scores.
+type Graph struct {
+	Nodes []*Node
+}
+
+// Runnable returns the nodes that are ready to run, in plan order.
+func (g *Graph) Runnable() []*Node {
+	var nodes []*Node
+	for _, n := range g.Nodes {
+		if n.Runnable {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Dependents returns, for each node, the unfinished tasks that depend on it
+// directly.
+func (g *Graph) Dependents() map[*Node][]*Node {
+	dependents := make(map[*Node][]*Node)
+	for _, n := range g.Nodes {
+		if n.Status == models.StatusCompleted {
+			continue
+		}
+		for _, d := range n.Deps {
+			dependents[d] = append(dependents[d], n)
+		}
+	}
+	return dependents
+}
+
+// Tail returns the weight of the heaviest chain of unfinished tasks that
+// starts at each node, counting complexity as graph.ComplexityWeight does.
+// A runnable task with a long tail holds up everything behind it.
+func (g *Graph) Tail() map[*Node]int {
+	dependents := g.Dependents()
+	var unfinished []*Node
+	for _, n := range g.Nodes {
+		if n.Status != models.StatusCompleted {
+			unfinished = append(unfinished, n)
+		}
+	}
+	tail, _ := graph.Chains(unfinished, func(n *Node) []*Node { return dependents[n] },
+		func(n *Node) int { return graph.ComplexityWeight(n.Task.Complexity) })
+	return tail
+}
+
+// Factor is one reason a task was ranked where it was.
+type Factor struct {
+	Name   string  // e.g. "priority"
+	Detail string  // e.g. "plan priority 1"
+	Score  float64 // the factor's weighted contribution
+}
+
+// Ranked is a runnable task with its score.
+type Ranked struct {
+	*Node
+	Score   float64
+	Factors []Factor
+}
+
+// Scheduler orders the runnable tasks in a graph, best first.
+type Scheduler interface {
+	Rank(g *Graph) []Ranked
+}
+
+// New returns the scheduler selected in cfg.
+func New(cfg config.SchedulerConfig) (Scheduler, error) {
+	switch cfg.Strategy {
+	case "", "weighted":
+		return Weighted{Weights: cfg.Weights}, nil
+	case "order":
+		return Order{}, nil
+	}
+	return nil, etcherr.Config(fmt.Sprintf("unknown scheduler strategy %q", cfg.Strategy)).
+		WithHint(`set strategy under [scheduler] in .etch/config.toml to "weighted" or "order"`)
+}
+
+// Order runs tasks in plan file order, ignoring everything else.
+type Order struct{}
+
+// Rank implements Scheduler.
+func (Order) Rank(g *Graph) []Ranked {
+	var ranked []Ranked
+	for _, n := range g.Runnable() {
+		ranked = append(ranked, Ranked{Node: n, Factors: []Factor{{Name: "order", Detail: "plan order"}}})
+	}
+	return ranked
+}
+
+// Weighted scores each runnable task on plan priority, the length of the
+// chain of work behind it, how many tasks depend on it, and how small it
+// is, and ranks by the weighted sum.
+type Weighted struct {
+	Weights config.SchedulerWeights
+}
+
+// Rank implements Scheduler.
+func (w Weighted) Rank(g *Graph) []Ranked {
+	runnable := g.Runnable()
+	dependents := g.Dependents()
+	tail := g.Tail()
+
+	maxTail, maxFanOut := 0, 0
+	for _, n := range runnable {
+		maxTail = max(maxTail, tail[n])
+		maxFanOut = max(maxFanOut, len(dependents[n]))
+	}
+
+	ranked := make([]Ranked, 0, len(runnable))
+	for _, n := range runnable {
+		r := Ranked{Node: n}
+		add := func(name, detail string, weight, value float64) {
+			score := weight * value
+			r.Score += score
+			r.Factors = append(r.Factors, Factor{Name: name, Detail: detail, Score: score})
+		}
+
+		if p := n.Plan.Priority; p > 0 {
+			add("priority", fmt.Sprintf("plan priority %d", p), w.Weights.Priority, 1/float64(p))
+		} else {
+			add("priority", "no plan priority", w.Weights.Priority, 0)
+		}
+		add("critical_path", fmt.Sprintf("critical path weight %d", tail[n]), w.Weights.CriticalPath, ratio(tail[n], maxTail))
+		add("fan_out", fmt.Sprintf("%d %s on it", len(dependents[n]), status.Plural(len(dependents[n]), "task depends", "tasks depend")), w.Weights.FanOut, ratio(len(dependents[n]), maxFanOut))
+		add("complexity", complexityDetail(n.Task.Complexity), w.Weights.Complexity, smallness(n.Task.Complexity))
+
+		ranked = append(ranked, r)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+func ratio(n, max int) float64 {
+	if max == 0 {
+		return 0
+	}
+	return float64(n) / float64(max)
+}
+
+// smallness scores small tasks highest, so quick wins go first when
+// everything else is equal.
+func smallness(c models.Complexity) float64 {
+	switch c {
+	case models.ComplexitySmall:
+		return 1
+	case models.ComplexityLarge:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+func complexityDetail(c models.Complexity) string {
+	if c == "" {
+		return "complexity not set"
+	}
+	return string(c) + " complexity"
+}
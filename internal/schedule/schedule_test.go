@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/config"
+	"github.com/gsigler/etch/internal/models"
+)
+
+// build returns a graph of tasks named "slug#id", with plan priorities,
+// complexities, and deps ("task → its dependencies"). Pending tasks whose
+// deps are all done are runnable.
+func build(plans map[string]int, tasks []string, complexity map[string]models.Complexity, deps map[string][]string, done ...string) *Graph {
+	g := &Graph{}
+	byRef := make(map[string]*Node)
+	planBySlug := make(map[string]*models.Plan)
+	for _, ref := range tasks {
+		slug, id, _ := strings.Cut(ref, "#")
+		p := planBySlug[slug]
+		if p == nil {
+			p = &models.Plan{Slug: slug, Priority: plans[slug]}
+			planBySlug[slug] = p
+		}
+		var fn, tn int
+		fmt.Sscanf(id, "%d.%d", &fn, &tn)
+		n := &Node{Plan: p, Task: &models.Task{FeatureNumber: fn, TaskNumber: tn, Complexity: complexity[ref]}, Status: models.StatusPending}
+		byRef[ref] = n
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, ref := range done {
+		byRef[ref].Status = models.StatusCompleted
+	}
+	for ref, ds := range deps {
+		for _, d := range ds {
+			byRef[ref].Deps = append(byRef[ref].Deps, byRef[d])
+		}
+	}
+	for _, n := range g.Nodes {
+		n.Runnable = n.Status == models.StatusPending
+		for _, d := range n.Deps {
+			if d.Status != models.StatusCompleted {
+				n.Runnable = false
+			}
+		}
+	}
+	return g
+}
+
+func refs(ranked []Ranked) string {
+	var s []string
+	for _, r := range ranked {
+		s = append(s, r.Ref())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestWeighted_Rank(t *testing.T) {
+	tasks := []string{"a#1.1", "a#1.2", "a#1.3", "a#1.4", "b#1.1"}
+	deps := map[string][]string{"a#1.3": {"a#1.2"}, "a#1.4": {"a#1.2"}}
+	complexity := map[string]models.Complexity{"a#1.1": models.ComplexitySmall, "a#1.2": models.ComplexityLarge}
+	w := Weighted{Weights: config.DefaultSchedulerWeights}
+
+	// 1.2 has two tasks and the longest chain behind it; b#1.1 outweighs
+	// the smaller a#1.1 because unset complexity counts as medium.
+	if got := refs(w.Rank(build(nil, tasks, complexity, deps))); got != "a#1.2 b#1.1 a#1.1" {
+		t.Errorf("rank = %s", got)
+	}
+
+	// Plan priority outweighs the rest.
+	ranked := w.Rank(build(map[string]int{"b": 1}, tasks, complexity, deps))
+	if got := refs(ranked); got != "b#1.1 a#1.2 a#1.1" {
+		t.Errorf("rank with priority = %s", got)
+	}
+	var details []string
+	for _, f := range ranked[1].Factors {
+		details = append(details, f.Detail)
+	}
+	if got := strings.Join(details, ", "); got != "no plan priority, critical path weight 5, 2 tasks depend on it, large complexity" {
+		t.Errorf("factors = %s", got)
+	}
+
+	// Equal scores keep plan order.
+	w = Weighted{}
+	if got := refs(w.Rank(build(nil, tasks, complexity, deps))); got != "a#1.1 a#1.2 b#1.1" {
+		t.Errorf("rank with no weights = %s", got)
+	}
+}
+
+func TestTail_IgnoresCompletedAndCycles(t *testing.T) {
+	tasks := []string{"a#1.1", "a#1.2", "a#1.3"}
+	deps := map[string][]string{"a#1.2": {"a#1.1", "a#1.3"}, "a#1.3": {"a#1.2"}}
+	g := build(nil, tasks, nil, deps, "a#1.1")
+	tail := g.Tail()
+	if tail[g.Nodes[0]] != 0 {
+		t.Errorf("completed tasks have no tail, got %d", tail[g.Nodes[0]])
+	}
+	if tail[g.Nodes[1]] == 0 || tail[g.Nodes[2]] == 0 {
+		t.Errorf("cycle tails = %v", tail)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(config.SchedulerConfig{Strategy: "order"}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := s.(Order); !ok {
+		t.Errorf("order strategy = %T", s)
+	}
+	if _, err := New(config.SchedulerConfig{Strategy: "random"}); err == nil || !strings.Contains(err.Error(), "random") {
+		t.Errorf("expected an error for an unknown strategy, got %v", err)
+	}
+}
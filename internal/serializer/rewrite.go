@@ -0,0 +1,531 @@
+package serializer
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gsigler/etch/internal/document"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+)
+
+var (
+	statusTagRe = regexp.MustCompile(`\[(\w+)\]\s*$`)
+	metaLabelRe = regexp.MustCompile(`^\*\*[^*]+:\*\*\s*`)
+	separatorRe = regexp.MustCompile(`^---+\s*$`)
+	checkboxRe  = regexp.MustCompile(`^-\s+\[[ x]\]`)
+)
+
+// Rewrite renders plan as an edit of src, the markdown it was parsed from.
+// Lines whose meaning didn't change are copied through byte-for-byte, so
+// unknown sections, stray prose, and formatting survive. Tasks and features
+// are matched by ID (in source order when an ID repeats); new ones are rendered the way Serialize would and
+// placed after their predecessors.
+//
+// A plan written in single-feature form that gains a second feature can't
+// be expressed as an edit; it is rendered from scratch with Serialize.
+func Rewrite(src string, plan *models.Plan) (string, error) {
+	old, doc, err := parser.ParseDocument(src)
+	if err != nil {
+		return "", err
+	}
+
+	implicit := len(old.Features) > 0 && doc.Find(document.FeatureHeading, 0, -1) < 0
+	if implicit && len(old.Features) == 1 && len(plan.Features) > 1 {
+		return Serialize(plan), nil
+	}
+
+	r := &rewriter{doc: doc, edits: make([]edit, len(doc.Lines)), implicit: implicit}
+	r.plan(old, plan)
+	r.features(old, plan)
+	return r.render(), nil
+}
+
+// edit describes what happens to one source line.
+type edit struct {
+	drop    bool
+	replace []string // when non-nil, emitted instead of the line
+	before  []string
+	after   []string
+}
+
+type rewriter struct {
+	doc      *document.Document
+	edits    []edit
+	implicit bool // single-feature plan without a "## Feature" heading
+}
+
+func (r *rewriter) render() string {
+	var out []string
+	for i, l := range r.doc.Lines {
+		e := r.edits[i]
+		out = append(out, e.before...)
+		switch {
+		case e.drop:
+		case e.replace != nil:
+			out = append(out, e.replace...)
+		default:
+			out = append(out, l.Text)
+		}
+		out = append(out, e.after...)
+	}
+	return document.Join(out, r.doc.FinalNewline)
+}
+
+func (r *rewriter) replace(i int, lines ...string) {
+	r.edits[i].replace = lines
+}
+
+func (r *rewriter) drop(idx ...int) {
+	for _, i := range idx {
+		r.edits[i].drop = true
+	}
+}
+
+func (r *rewriter) insertAfter(i int, lines ...string) {
+	r.edits[i].after = append(r.edits[i].after, lines...)
+}
+
+func (r *rewriter) insertBefore(i int, lines ...string) {
+	r.edits[i].before = append(r.edits[i].before, lines...)
+}
+
+// lines returns the indices of the lines with role owned by the given
+// feature and task (-1 for none).
+func (r *rewriter) lines(role document.Role, feature, task int) []int {
+	var idx []int
+	for i, l := range r.doc.Lines {
+		if l.Role == role && l.Feature == feature && l.Task == task {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// item returns the lines of the criterion or comment at index item.
+func (r *rewriter) item(roles []document.Role, feature, task, item int) []int {
+	var idx []int
+	for i, l := range r.doc.Lines {
+		if slices.Contains(roles, l.Role) && l.Feature == feature && l.Task == task && l.Item == item {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// contentEnd returns the last non-blank line etch interprets within the
+// given lines, ignoring trailing blanks and separators, or -1.
+func (r *rewriter) contentEnd(idx []int) int {
+	for k := len(idx) - 1; k >= 0; k-- {
+		l := r.doc.Lines[idx[k]]
+		if l.Role != document.Text && !l.IsBlank() {
+			return idx[k]
+		}
+	}
+	return -1
+}
+
+// dropSpan drops lines from..to, plus the blank lines (and, when
+// separators is set, "---" rules) directly above it.
+func (r *rewriter) dropSpan(from, to int, separators bool) {
+	for i := from; i <= to; i++ {
+		r.drop(i)
+	}
+	for i := from - 1; i >= 0 && !r.edits[i].drop; i-- {
+		l := r.doc.Lines[i]
+		if !l.IsBlank() && !(separators && separatorRe.MatchString(l.Text)) {
+			break
+		}
+		if len(r.edits[i].after) > 0 {
+			break
+		}
+		r.drop(i)
+	}
+}
+
+// replaceText swaps the text held by lines (which may include surrounding
+// blank lines) for text, inserting it after anchor with a blank line above
+// when there was none.
+func (r *rewriter) replaceText(lines []int, text string, anchor int, prefix ...string) {
+	var body []int
+	for _, i := range lines {
+		if !r.doc.Lines[i].IsBlank() {
+			body = append(body, i)
+		}
+	}
+	switch {
+	case len(body) > 0 && text == "":
+		for i := body[0]; i <= body[len(body)-1]; i++ {
+			if slices.Contains(lines, i) {
+				r.drop(i)
+			}
+		}
+	case len(body) > 0:
+		r.replace(body[0], strings.Split(text, "\n")...)
+		for i := body[0] + 1; i <= body[len(body)-1]; i++ {
+			if slices.Contains(lines, i) {
+				r.drop(i)
+			}
+		}
+	case text != "":
+		ins := append(prefix, strings.Split(text, "\n")...)
+		r.insertAfter(anchor, ins...)
+	}
+}
+
+func (r *rewriter) plan(old, plan *models.Plan) {
+	heading := r.doc.Find(document.PlanHeading, -1, -1)
+	if old.Title != plan.Title || old.Status != plan.Status {
+		r.replace(heading, retag(r.doc.Lines[heading].Text, "# Plan: ", old.Title, plan.Title, plan.Status))
+	}
+
+	anchor := heading
+	if prio := r.lines(document.Priority, -1, -1); old.Priority != plan.Priority {
+		switch {
+		case plan.Priority == 0:
+			r.drop(prio...)
+		case len(prio) > 0:
+			r.replace(prio[len(prio)-1], priorityLine(plan.Priority))
+		default:
+			r.insertAfter(heading, priorityLine(plan.Priority))
+		}
+	} else if len(prio) > 0 {
+		anchor = prio[len(prio)-1]
+	}
+
+	if old.Overview != plan.Overview {
+		if oh := r.doc.Find(document.OverviewHeading, -1, -1); oh >= 0 {
+			r.replaceText(r.lines(document.Overview, -1, -1), plan.Overview, oh, "")
+		} else {
+			r.replaceText(nil, plan.Overview, anchor, "", "## Overview", "")
+		}
+	}
+}
+
+func (r *rewriter) features(old, plan *models.Plan) {
+	oldIdx := make(positions[int])
+	for i, f := range old.Features {
+		oldIdx.add(f.Number, i)
+	}
+	kept := make(map[int]bool)
+
+	prevEnd := -1 // content end of the last feature already placed
+	var pending [][]string
+	for _, f := range plan.Features {
+		oi, ok := oldIdx.take(f.Number)
+		if !ok {
+			pending = append(pending, r.renderFeature(f))
+			continue
+		}
+		kept[oi] = true
+		if len(pending) > 0 {
+			r.placeBefore(r.featureStart(oi), pending)
+			pending = nil
+		}
+		r.feature(oi, &old.Features[oi], f)
+		prevEnd = r.contentEnd(r.doc.Owned(oi, -1))
+	}
+	if len(pending) > 0 {
+		if prevEnd < 0 {
+			prevEnd = r.contentEnd(indices(len(r.doc.Lines)))
+		}
+		for _, block := range pending {
+			r.insertAfter(prevEnd, block...)
+		}
+	}
+
+	for oi := range old.Features {
+		if kept[oi] {
+			continue
+		}
+		owned := r.doc.Owned(oi, -1)
+		if end := r.contentEnd(owned); end >= 0 {
+			r.dropSpan(owned[0], end, true)
+		}
+	}
+}
+
+// featureStart returns the first line of feature oi's block, including the
+// separator above its heading.
+func (r *rewriter) featureStart(oi int) int {
+	start := r.doc.Owned(oi, -1)[0]
+	for i := start - 1; i >= 0; i-- {
+		l := r.doc.Lines[i]
+		if !l.IsBlank() && !separatorRe.MatchString(l.Text) {
+			break
+		}
+		if separatorRe.MatchString(l.Text) {
+			start = i
+		}
+	}
+	return start
+}
+
+// placeBefore inserts rendered blocks (each starting with a blank line)
+// before line i, moving the blank line to the end of each block.
+func (r *rewriter) placeBefore(i int, blocks [][]string) {
+	for _, block := range blocks {
+		r.insertBefore(i, append(slices.Clone(block[1:]), "")...)
+	}
+}
+
+func (r *rewriter) renderFeature(f models.Feature) []string {
+	var b strings.Builder
+	writeFeatureHeader(&b, f)
+	for _, t := range f.Tasks {
+		writeTask(&b, t, false)
+	}
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func (r *rewriter) renderTask(t models.Task) []string {
+	var b strings.Builder
+	writeTask(&b, t, r.implicit)
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func (r *rewriter) feature(fi int, old *models.Feature, f models.Feature) {
+	heading := r.doc.Find(document.FeatureHeading, fi, -1)
+	if heading >= 0 && (old.Title != f.Title || old.Number != f.Number) {
+		r.replace(heading, featureHeading(f))
+	}
+
+	if heading >= 0 && old.Overview != f.Overview {
+		lines := r.lines(document.FeatureOverview, fi, -1)
+		if oh := r.doc.Find(document.FeatureOverviewHeading, fi, -1); oh >= 0 {
+			r.replaceText(lines, f.Overview, oh)
+		} else {
+			r.replaceText(lines, f.Overview, heading, "", "### Overview")
+		}
+	}
+
+	oldIdx := make(positions[string])
+	for i, t := range old.Tasks {
+		oldIdx.add(t.FullID(), i)
+	}
+	kept := make(map[int]bool)
+
+	prevEnd := -1
+	var pending [][]string
+	for _, t := range f.Tasks {
+		ti, ok := oldIdx.take(t.FullID())
+		if !ok {
+			pending = append(pending, r.renderTask(t))
+			continue
+		}
+		kept[ti] = true
+		if len(pending) > 0 {
+			r.placeBefore(r.doc.Find(document.TaskHeading, fi, ti), pending)
+			pending = nil
+		}
+		r.task(fi, ti, &old.Tasks[ti], &t)
+		prevEnd = r.contentEnd(r.doc.Owned(fi, ti))
+	}
+	if len(pending) > 0 {
+		if prevEnd < 0 {
+			prevEnd = r.contentEnd(r.doc.Owned(fi, -1))
+		}
+		for _, block := range pending {
+			r.insertAfter(prevEnd, block...)
+		}
+	}
+
+	for ti := range old.Tasks {
+		if kept[ti] {
+			continue
+		}
+		owned := r.doc.Owned(fi, ti)
+		r.dropSpan(owned[0], r.contentEnd(owned), false)
+	}
+}
+
+func (r *rewriter) task(fi, ti int, old, t *models.Task) {
+	heading := r.doc.Find(document.TaskHeading, fi, ti)
+	if old.Title != t.Title || old.Status != t.Status {
+		r.replace(heading, retag(r.doc.Lines[heading].Text, taskHeadingPrefix(*t, r.implicit), old.Title, t.Title, t.Status))
+	}
+
+	// Metadata fields, in the order Serialize writes them.
+	metaEnd := heading
+	fields := []struct {
+		role     document.Role
+		label    string
+		old, new string
+	}{
+		{document.Complexity, complexityLabel, string(old.Complexity), string(t.Complexity)},
+		{document.Files, filesLabel, strings.Join(old.Files, ", "), strings.Join(t.Files, ", ")},
+		{document.DependsOn, dependsOnLabel, strings.Join(old.DependsOn, ", "), strings.Join(t.DependsOn, ", ")},
+	}
+	for _, fd := range fields {
+		lines := r.lines(fd.role, fi, ti)
+		if fd.old != fd.new {
+			switch {
+			case fd.new == "":
+				r.drop(lines...)
+			case len(lines) > 0:
+				label := metaLabelRe.FindString(r.doc.Lines[lines[0]].Text)
+				r.replace(lines[0], strings.TrimRight(label, " \t")+" "+fd.new)
+				r.drop(lines[1:]...)
+			default:
+				r.insertAfter(metaEnd, fd.label+fd.new)
+			}
+		}
+		if len(lines) > 0 && lines[len(lines)-1] > metaEnd {
+			metaEnd = lines[len(lines)-1]
+		}
+	}
+
+	if old.Description != t.Description {
+		r.replaceText(r.lines(document.Description, fi, ti), t.Description, metaEnd, "")
+	}
+
+	r.comments(fi, ti, old, t)
+	r.criteria(fi, ti, old, t)
+}
+
+func (r *rewriter) comments(fi, ti int, old, t *models.Task) {
+	roles := []document.Role{document.Comment, document.CommentContinuation}
+	last := -1
+	for k, c := range old.Comments {
+		lines := r.item(roles, fi, ti, k)
+		if len(lines) == 0 {
+			continue
+		}
+		last = lines[len(lines)-1]
+		switch {
+		case k >= len(t.Comments):
+			r.drop(lines...)
+			if next := last + 1; next < len(r.doc.Lines) && r.doc.Lines[next].IsBlank() {
+				r.drop(next)
+			}
+		case c != t.Comments[k]:
+			r.replace(lines[0], commentLines(t.Comments[k])...)
+			r.drop(lines[1:]...)
+		}
+	}
+	if len(t.Comments) <= len(old.Comments) {
+		return
+	}
+
+	var added []string
+	for _, c := range t.Comments[len(old.Comments):] {
+		added = append(added, "")
+		added = append(added, commentLines(c)...)
+	}
+	switch {
+	case last >= 0:
+		r.insertAfter(last, added...)
+	case r.doc.Find(document.CriteriaHeading, fi, ti) >= 0:
+		r.insertBefore(r.doc.Find(document.CriteriaHeading, fi, ti), append(added[1:], "")...)
+	default:
+		r.insertAfter(r.contentEnd(r.doc.Owned(fi, ti)), added...)
+	}
+}
+
+func (r *rewriter) criteria(fi, ti int, old, t *models.Task) {
+	roles := []document.Role{document.Criterion}
+	last := -1
+	for k, c := range old.Criteria {
+		lines := r.item(roles, fi, ti, k)
+		if len(lines) == 0 {
+			continue
+		}
+		i := lines[0]
+		last = i
+		switch {
+		case k >= len(t.Criteria):
+			r.drop(i)
+		case c.Description != t.Criteria[k].Description:
+			r.replace(i, criterionLine(t.Criteria[k]))
+		case c.IsMet != t.Criteria[k].IsMet:
+			r.replace(i, tick(r.doc.Lines[i].Text, t.Criteria[k].IsMet))
+		}
+	}
+
+	heading := r.doc.Find(document.CriteriaHeading, fi, ti)
+	if len(t.Criteria) == 0 && len(old.Criteria) > 0 && heading >= 0 {
+		r.dropSpan(heading, heading, false)
+	}
+	if len(t.Criteria) <= len(old.Criteria) {
+		return
+	}
+
+	var added []string
+	for _, c := range t.Criteria[len(old.Criteria):] {
+		added = append(added, criterionLine(c))
+	}
+	switch {
+	case last >= 0:
+		r.insertAfter(last, added...)
+	case heading >= 0:
+		r.insertAfter(heading, added...)
+	default:
+		r.insertAfter(r.contentEnd(r.doc.Owned(fi, ti)), append([]string{"", criteriaHeading}, added...)...)
+	}
+}
+
+// retag rewrites a heading line for a new title and status, keeping the
+// line's own spelling (prefix, spacing) where it can.
+func retag(text, prefix, oldTitle, title string, status models.Status) string {
+	head, tagged := text, false
+	if loc := statusTagRe.FindStringIndex(text); loc != nil {
+		head, tagged = text[:loc[0]], true
+	}
+	if oldTitle != title {
+		trimmed := strings.TrimRight(head, " \t")
+		if !strings.HasSuffix(trimmed, oldTitle) {
+			return planHeading(prefix, title, status)
+		}
+		head = strings.TrimSuffix(trimmed, oldTitle) + title + head[len(trimmed):]
+	}
+	switch {
+	case status == "":
+		return strings.TrimRight(head, " \t")
+	case tagged:
+		return head + "[" + string(status) + "]"
+	default:
+		return strings.TrimRight(head, " \t") + " [" + string(status) + "]"
+	}
+}
+
+// tick sets the checkbox of a criterion line, leaving the rest untouched.
+func tick(text string, met bool) string {
+	loc := checkboxRe.FindStringIndex(text)
+	if loc == nil {
+		return text
+	}
+	mark := " "
+	if met {
+		mark = "x"
+	}
+	return text[:loc[1]-2] + mark + text[loc[1]-1:]
+}
+
+// positions maps a feature number or task ID to the indices of the old
+// plan's features or tasks that have it, in source order. Matching takes
+// them in turn, so items sharing an ID (which validate reports) each keep
+// their own lines instead of one replacing the other.
+type positions[K comparable] map[K][]int
+
+func (p positions[K]) add(k K, i int) {
+	p[k] = append(p[k], i)
+}
+
+// take returns the first unmatched index for k.
+func (p positions[K]) take(k K) (int, bool) {
+	idx := p[k]
+	if len(idx) == 0 {
+		return 0, false
+	}
+	p[k] = idx[1:]
+	return idx[0], true
+}
+
+func indices(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
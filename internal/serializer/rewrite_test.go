@@ -0,0 +1,317 @@
+package serializer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+)
+
+// formatExamples returns the plan examples from the etch-plan skill, plus
+// the project's own plans. Fragments without a plan heading get one.
+func formatExamples(t testing.TB) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "skill", "etch-plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples := make(map[string]string)
+	var block []string
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case !inBlock && line == "```markdown":
+			inBlock, block = true, nil
+		case inBlock && line == "```":
+			inBlock = false
+			src := strings.Join(block, "\n") + "\n"
+			if !strings.HasPrefix(src, "# Plan:") {
+				src = "# Plan: Example\n\n" + src
+			}
+			examples["etch-plan.md#"+string(rune('0'+len(examples)))] = src
+		case inBlock:
+			block = append(block, line)
+		}
+	}
+	if len(examples) == 0 {
+		t.Fatal("no markdown examples found in etch-plan.md")
+	}
+
+	plans, _ := filepath.Glob(filepath.Join("..", "..", ".etch", "plans", "*.md"))
+	for _, p := range plans {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		examples[filepath.Base(p)] = string(data)
+	}
+	return examples
+}
+
+func TestRewrite_IdentityOnFormatExamples(t *testing.T) {
+	for name, src := range formatExamples(t) {
+		t.Run(name, func(t *testing.T) {
+			plan, err := parser.Parse(strings.NewReader(src))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got, err := Rewrite(src, plan)
+			if err != nil {
+				t.Fatalf("Rewrite: %v", err)
+			}
+			if got != src {
+				t.Errorf("parse→rewrite is not identity.\ngot:\n%s\nwant:\n%s", got, src)
+			}
+		})
+	}
+}
+
+// TestRewrite_StatusChangesAreLocal checks, for every task in every example,
+// that changing its status touches only its heading line and reparses to
+// the changed plan.
+func TestRewrite_StatusChangesAreLocal(t *testing.T) {
+	for name, src := range formatExamples(t) {
+		plan, err := parser.Parse(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		for fi := range plan.Features {
+			for ti := range plan.Features[fi].Tasks {
+				want, _ := parser.Parse(strings.NewReader(src))
+				task := &want.Features[fi].Tasks[ti]
+				task.Status = models.StatusBlocked
+
+				got, err := Rewrite(src, want)
+				if err != nil {
+					t.Fatalf("%s: Rewrite: %v", name, err)
+				}
+				assertReparses(t, got, want)
+				if n := changedLines(src, got); n != 1 {
+					t.Errorf("%s task %s: %d lines changed, want 1", name, task.FullID(), n)
+				}
+			}
+		}
+	}
+}
+
+func FuzzRewrite_Identity(f *testing.F) {
+	for _, src := range formatExamples(f) {
+		f.Add(src)
+	}
+	f.Add("# Plan:0\n### Task 0:0\n## Feature 0:0")
+	f.Add("# Plan: X\r\n\r\n### Task 1: A [pending]\r\n- [ ] c\r\n")
+	f.Fuzz(func(t *testing.T, src string) {
+		plan, err := parser.Parse(strings.NewReader(src))
+		if err != nil {
+			return
+		}
+		got, err := Rewrite(src, plan)
+		if err != nil {
+			t.Fatalf("Rewrite: %v", err)
+		}
+		if got != src {
+			t.Errorf("parse→rewrite is not identity.\ngot:  %q\nwant: %q", got, src)
+		}
+	})
+}
+
+const customPlan = `# Plan: Custom Sections
+**Priority:** 2
+
+Plan-level note that etch doesn't model.
+
+## Overview
+
+The overview.
+
+## Architecture Decisions
+
+- Use SQLite.
+- Keep the API *small*.
+
+---
+
+## Feature 1: Core
+
+### Task 1.1: First [pending]
+**Complexity:** small
+**Files in Scope:** a.go
+
+Do the first thing.
+
+**Acceptance Criteria:**
+- [ ] One
+- [ ] Two
+
+Trailing notes after the criteria.
+
+### Task 1.2: Second [pending]
+**Depends on:** Task 1.1
+
+Do the second thing.
+
+---
+
+## Feature 2: Extras
+
+### Task 2.1: Third [pending]
+Third description.
+
+## Appendix
+
+Raw notes   with   odd   spacing.
+`
+
+func TestRewrite_PreservesUnknownContent(t *testing.T) {
+	plan, err := parser.Parse(strings.NewReader(customPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core := &plan.Features[0]
+	core.Tasks[0].Status = models.StatusCompleted
+	core.Tasks[0].Criteria[1].IsMet = true
+	core.Tasks[0].Complexity = models.Complexity("medium")
+	core.Tasks[1].Comments = []string{"Needs a test."}
+	core.Tasks = append(core.Tasks, models.Task{
+		FeatureNumber: 1, TaskNumber: 3, Title: "New task", Status: models.StatusPending,
+		Description: "Added later.",
+		Criteria:    []models.Criterion{{Description: "Works"}},
+	})
+	plan.Features = plan.Features[:1]
+	plan.Features = append(plan.Features, models.Feature{
+		Number: 3, Title: "Later",
+		Tasks: []models.Task{{FeatureNumber: 3, TaskNumber: 1, Title: "Later task", Status: models.StatusPending}},
+	})
+
+	got, err := Rewrite(customPlan, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReparses(t, got, plan)
+
+	for _, kept := range []string{
+		"Plan-level note that etch doesn't model.\n",
+		"## Architecture Decisions\n\n- Use SQLite.\n- Keep the API *small*.\n",
+		"**Files in Scope:** a.go\n",
+		"Trailing notes after the criteria.\n",
+		"## Appendix\n\nRaw notes   with   odd   spacing.\n",
+	} {
+		assertContains(t, got, kept)
+	}
+	assertContains(t, got, "### Task 1.1: First [completed]\n**Complexity:** medium\n")
+	assertContains(t, got, "- [ ] One\n- [x] Two\n")
+	assertContains(t, got, "Do the second thing.\n\n> 💬 Needs a test.\n")
+	assertContains(t, got, "### Task 1.3: New task [pending]\n\nAdded later.\n\n**Acceptance Criteria:**\n- [ ] Works\n")
+	assertContains(t, got, "---\n\n## Feature 3: Later\n\n### Task 3.1: Later task [pending]\n")
+	assertNotContains(t, got, "Feature 2: Extras")
+	assertNotContains(t, got, "Third description.")
+}
+
+func TestRewrite_RemoveTask(t *testing.T) {
+	plan, err := parser.Parse(strings.NewReader(customPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Features[0].Tasks = plan.Features[0].Tasks[1:]
+
+	got, err := Rewrite(customPlan, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReparses(t, got, plan)
+	assertContains(t, got, "## Feature 1: Core\n\n### Task 1.2: Second [pending]\n")
+	assertNotContains(t, got, "Trailing notes")
+}
+
+func TestRewrite_DuplicateTaskIDs(t *testing.T) {
+	src := "# Plan: Dupes\n\n### Task 1: A [pending]\nFirst.\n\n### Task 1: B [pending]\nSecond.\n"
+	path := filepath.Join(t.TempDir(), "dupes.md")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each task keeps its own lines; the second isn't taken for the first.
+	if err := UpdateTaskStatus(path, "1.1", models.StatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := strings.Replace(src, "Task 1: A [pending]", "Task 1: A [completed]", 1)
+	if string(data) != want {
+		t.Errorf("unexpected rewrite.\ngot:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestRewrite_PlanFields(t *testing.T) {
+	plan, err := parser.Parse(strings.NewReader(customPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Title = "Renamed"
+	plan.Status = models.StatusCompleted
+	plan.Priority = 0
+	plan.Overview = "A new overview.\n\nTwo paragraphs."
+
+	got, err := Rewrite(customPlan, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReparses(t, got, plan)
+	want := strings.Replace(customPlan, "# Plan: Custom Sections\n**Priority:** 2\n", "# Plan: Renamed [completed]\n", 1)
+	want = strings.Replace(want, "The overview.", "A new overview.\n\nTwo paragraphs.", 1)
+	if got != want {
+		t.Errorf("unexpected rewrite.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRewrite_SingleFeatureAddsShortTask(t *testing.T) {
+	src := "# Plan: Small\n\n### Task 1: Only [pending]\nDo it.\n"
+	plan, err := parser.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Features[0].Tasks = append(plan.Features[0].Tasks, models.Task{
+		FeatureNumber: 1, TaskNumber: 2, Title: "Next", Status: models.StatusPending,
+	})
+
+	got, err := Rewrite(src, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := src + "\n### Task 2: Next [pending]\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// assertReparses checks that md parses back to want.
+func assertReparses(t *testing.T, md string, want *models.Plan) {
+	t.Helper()
+	got, err := parser.Parse(strings.NewReader(md))
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, md)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rewritten plan does not reparse to the edited plan.\ngot:  %+v\nwant: %+v\nmarkdown:\n%s", got, want, md)
+	}
+}
+
+// changedLines counts lines that differ between two texts of equal length
+// in lines, or returns -1 when the line counts differ.
+func changedLines(a, b string) int {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(al) != len(bl) {
+		return -1
+	}
+	n := 0
+	for i := range al {
+		if al[i] != bl[i] {
+			n++
+		}
+	}
+	return n
+}
@@ -3,30 +3,31 @@ package serializer
 import (
 	"fmt"
 	"os"
-	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/gsigler/etch/internal/atomicfile"
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
 	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
 )
 
 // Serialize converts a Plan struct into its markdown representation.
 // For single-feature plans (one feature whose title matches the plan title),
 // it omits the "## Feature N:" heading and uses "### Task N:" format.
+//
+// Serialize renders from scratch; to rewrite an existing plan file without
+// losing content etch doesn't model, use Rewrite.
 func Serialize(plan *models.Plan) string {
 	var b strings.Builder
 
-	b.WriteString("# Plan: ")
-	b.WriteString(plan.Title)
-	if plan.Status != "" {
-		b.WriteString(" [")
-		b.WriteString(string(plan.Status))
-		b.WriteString("]")
-	}
+	b.WriteString(planHeading("# Plan: ", plan.Title, plan.Status))
 	b.WriteString("\n")
 
 	if plan.Priority > 0 {
-		b.WriteString(fmt.Sprintf("**Priority:** %d\n", plan.Priority))
+		b.WriteString(priorityLine(plan.Priority))
+		b.WriteString("\n")
 	}
 
 	if plan.Overview != "" {
@@ -37,282 +38,279 @@ func Serialize(plan *models.Plan) string {
 
 	singleFeature := len(plan.Features) == 1
 
-	for i, f := range plan.Features {
+	for _, f := range plan.Features {
 		if !singleFeature {
-			b.WriteString("\n---\n")
-			b.WriteString("\n## Feature ")
-			b.WriteString(fmt.Sprintf("%d", f.Number))
-			b.WriteString(": ")
-			b.WriteString(f.Title)
-			b.WriteString("\n")
-
-			if f.Overview != "" {
-				b.WriteString("\n### Overview\n")
-				b.WriteString(f.Overview)
-				b.WriteString("\n")
-			}
+			writeFeatureHeader(&b, f)
 		}
-
 		for _, task := range f.Tasks {
-			b.WriteString("\n")
-			b.WriteString("### Task ")
-			if singleFeature {
-				b.WriteString(fmt.Sprintf("%d%s", task.TaskNumber, task.Suffix))
-			} else {
-				b.WriteString(task.FullID())
-			}
-			b.WriteString(": ")
-			b.WriteString(task.Title)
-			if task.Status != "" {
-				b.WriteString(" [")
-				b.WriteString(string(task.Status))
-				b.WriteString("]")
-			}
-			b.WriteString("\n")
-
-			if task.Complexity != "" {
-				b.WriteString("**Complexity:** ")
-				b.WriteString(string(task.Complexity))
-				b.WriteString("\n")
-			}
-			if len(task.Files) > 0 {
-				b.WriteString("**Files:** ")
-				b.WriteString(strings.Join(task.Files, ", "))
-				b.WriteString("\n")
-			}
-			if len(task.DependsOn) > 0 {
-				b.WriteString("**Depends on:** ")
-				b.WriteString(strings.Join(task.DependsOn, ", "))
-				b.WriteString("\n")
-			}
-
-			if task.Description != "" {
-				b.WriteString("\n")
-				b.WriteString(task.Description)
-				b.WriteString("\n")
-			}
-
-			for _, comment := range task.Comments {
-				b.WriteString("\n")
-				lines := strings.Split(comment, "\n")
-				for k, line := range lines {
-					if k == 0 {
-						b.WriteString("> 💬 ")
-					} else {
-						b.WriteString("> ")
-					}
-					b.WriteString(line)
-					b.WriteString("\n")
-				}
-			}
-
-			if len(task.Criteria) > 0 {
-				b.WriteString("\n**Acceptance Criteria:**\n")
-				for _, c := range task.Criteria {
-					if c.IsMet {
-						b.WriteString("- [x] ")
-					} else {
-						b.WriteString("- [ ] ")
-					}
-					b.WriteString(c.Description)
-					b.WriteString("\n")
-				}
-			}
+			writeTask(&b, task, singleFeature)
 		}
-
-		_ = i // used for loop indexing
 	}
 
 	return b.String()
 }
 
-var (
-	taskLineRe      = regexp.MustCompile(`^(### Task \d+(?:\.\d+[a-z]?)?:\s*.+?)\s*\[(\w+)\]\s*$`)
-	criterionLineRe = regexp.MustCompile(`^(- \[)([ x])(\] .+)$`)
-)
+// writeFeatureHeader writes a feature's separator, heading, and overview.
+func writeFeatureHeader(b *strings.Builder, f models.Feature) {
+	b.WriteString("\n---\n")
+	b.WriteString("\n")
+	b.WriteString(featureHeading(f))
+	b.WriteString("\n")
 
-// UpdateTaskStatus reads a plan file, changes the status tag on the specified
-// task, and writes the file back. It preserves all other content exactly.
-func UpdateTaskStatus(path string, taskID string, newStatus models.Status) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("reading plan file: %w", err)
+	if f.Overview != "" {
+		b.WriteString("\n### Overview\n")
+		b.WriteString(f.Overview)
+		b.WriteString("\n")
 	}
+}
 
-	lines := strings.Split(string(data), "\n")
-	found := false
+// writeTask writes a task, starting with the blank line before its heading.
+func writeTask(b *strings.Builder, task models.Task, singleFeature bool) {
+	b.WriteString("\n")
+	b.WriteString(planHeading(taskHeadingPrefix(task, singleFeature), task.Title, task.Status))
+	b.WriteString("\n")
 
-	taskPatterns := TaskIDPatterns(taskID)
+	if task.Complexity != "" {
+		b.WriteString(complexityLabel)
+		b.WriteString(string(task.Complexity))
+		b.WriteString("\n")
+	}
+	if len(task.Files) > 0 {
+		b.WriteString(filesLabel)
+		b.WriteString(strings.Join(task.Files, ", "))
+		b.WriteString("\n")
+	}
+	if len(task.DependsOn) > 0 {
+		b.WriteString(dependsOnLabel)
+		b.WriteString(strings.Join(task.DependsOn, ", "))
+		b.WriteString("\n")
+	}
 
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		matched := false
-		for _, pat := range taskPatterns {
-			if strings.HasPrefix(trimmed, pat) {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			continue
+	if task.Description != "" {
+		b.WriteString("\n")
+		b.WriteString(task.Description)
+		b.WriteString("\n")
+	}
+
+	for _, comment := range task.Comments {
+		b.WriteString("\n")
+		for _, line := range commentLines(comment) {
+			b.WriteString(line)
+			b.WriteString("\n")
 		}
-		if m := taskLineRe.FindStringSubmatchIndex(line); m != nil {
-			lines[i] = line[:m[4]] + string(newStatus) + line[m[5]:]
-			found = true
-			break
+	}
+
+	if len(task.Criteria) > 0 {
+		b.WriteString("\n")
+		b.WriteString(criteriaHeading)
+		b.WriteString("\n")
+		for _, c := range task.Criteria {
+			b.WriteString(criterionLine(c))
+			b.WriteString("\n")
 		}
 	}
+}
 
-	if !found {
-		return fmt.Errorf("task %s not found in %s", taskID, path)
+const (
+	complexityLabel = "**Complexity:** "
+	filesLabel      = "**Files:** "
+	dependsOnLabel  = "**Depends on:** "
+	criteriaHeading = "**Acceptance Criteria:**"
+)
+
+// planHeading renders a heading: prefix, title, and an optional status tag.
+func planHeading(prefix, title string, status models.Status) string {
+	s := prefix + title
+	if status != "" {
+		s += " [" + string(status) + "]"
 	}
+	return s
+}
 
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+func featureHeading(f models.Feature) string {
+	return fmt.Sprintf("## Feature %d: %s", f.Number, f.Title)
 }
 
-// UpdateCriterion reads a plan file, finds the specified task's acceptance
-// criteria by text match, and flips the checkbox. It preserves all other
-// content exactly.
-func UpdateCriterion(path string, taskID string, criterionText string, met bool) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("reading plan file: %w", err)
+func taskHeadingPrefix(task models.Task, singleFeature bool) string {
+	if singleFeature {
+		return fmt.Sprintf("### Task %d%s: ", task.TaskNumber, task.Suffix)
 	}
+	return "### Task " + task.FullID() + ": "
+}
 
-	lines := strings.Split(string(data), "\n")
-
-	taskPatterns := TaskIDPatterns(taskID)
-	inTask := false
-	found := false
+func priorityLine(priority int) string {
+	return fmt.Sprintf("**Priority:** %d", priority)
+}
 
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
+func criterionLine(c models.Criterion) string {
+	if c.IsMet {
+		return "- [x] " + c.Description
+	}
+	return "- [ ] " + c.Description
+}
 
-		if !inTask {
-			for _, pat := range taskPatterns {
-				if strings.HasPrefix(trimmed, pat) {
-					inTask = true
-					break
-				}
-			}
-			if inTask {
-				continue
-			}
+// commentLines renders a review comment as a blockquote.
+func commentLines(comment string) []string {
+	var lines []string
+	for k, line := range strings.Split(comment, "\n") {
+		if k == 0 {
+			lines = append(lines, "> 💬 "+line)
+		} else {
+			lines = append(lines, "> "+line)
 		}
+	}
+	return lines
+}
 
-		if inTask && (strings.HasPrefix(trimmed, "### ") || strings.HasPrefix(trimmed, "## ")) {
-			break
-		}
+// UpdateTaskStatus reads a plan file, changes the status tag on the specified
+// task, and writes the file back. It preserves all other content exactly.
+func UpdateTaskStatus(path string, taskID string, newStatus models.Status) error {
+	return UpdateTaskStatusAs(path, taskID, newStatus, events.Actor())
+}
+
+// UpdateTaskStatusAs is UpdateTaskStatus recording actor as the author of
+// the change in the event log.
+func UpdateTaskStatusAs(path string, taskID string, newStatus models.Status, actor string) error {
+	return updateTaskStatus(path, taskID, newStatus, actor, "")
+}
+
+// UpdateTaskStatusBecause is UpdateTaskStatus recording why the status
+// changed in the event log.
+func UpdateTaskStatusBecause(path string, taskID string, newStatus models.Status, reason string) error {
+	return updateTaskStatus(path, taskID, newStatus, events.Actor(), reason)
+}
 
-		if !inTask {
-			continue
+func updateTaskStatus(path string, taskID string, newStatus models.Status, actor, reason string) error {
+	return update(path, func(plan *models.Plan) (*events.Event, error) {
+		task := plan.TaskByID(taskID)
+		if task == nil {
+			return nil, fmt.Errorf("task %s not found in %s", taskID, path)
 		}
+		e := &events.Event{Actor: actor, Type: events.TaskStatus, Task: task.FullID(), Before: string(task.Status), After: string(newStatus), Reason: reason}
+		task.Status = newStatus
+		return e, nil
+	})
+}
 
-		if m := criterionLineRe.FindStringSubmatch(line); m != nil {
-			desc := strings.TrimSpace(m[3][2:])
-			if desc == criterionText {
-				check := " "
-				if met {
-					check = "x"
+// UpdateCriterion reads a plan file, finds the specified task's acceptance
+// criteria by text match, and flips the checkbox. It preserves all other
+// content exactly.
+func UpdateCriterion(path string, taskID string, criterionText string, met bool) error {
+	return UpdateCriterionAs(path, taskID, criterionText, met, events.Actor())
+}
+
+// UpdateCriterionAs is UpdateCriterion recording actor as the author of the
+// change in the event log.
+func UpdateCriterionAs(path string, taskID string, criterionText string, met bool, actor string) error {
+	return update(path, func(plan *models.Plan) (*events.Event, error) {
+		if task := plan.TaskByID(taskID); task != nil {
+			for i := range task.Criteria {
+				if task.Criteria[i].Description == criterionText {
+					e := &events.Event{Actor: actor, Type: events.Criterion, Task: task.FullID(), Subject: criterionText,
+						Before: criterionState(task.Criteria[i].IsMet), After: criterionState(met)}
+					task.Criteria[i].IsMet = met
+					return e, nil
 				}
-				lines[i] = m[1] + check + m[3]
-				found = true
-				break
 			}
 		}
-	}
+		return nil, fmt.Errorf("criterion %q not found in task %s", criterionText, taskID)
+	})
+}
 
-	if !found {
-		return fmt.Errorf("criterion %q not found in task %s", criterionText, taskID)
+func criterionState(met bool) string {
+	if met {
+		return "met"
 	}
-
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	return "unmet"
 }
 
-var planHeadingRe = regexp.MustCompile(`^(#\s+Plan:\s*.+?)(?:\s*\[(\w+)\])?\s*$`)
-
 // UpdatePlanStatus reads a plan file, updates or adds the status tag on the
 // plan heading line (e.g. "# Plan: Title [completed]"), and writes the file back.
 func UpdatePlanStatus(path string, newStatus models.Status) error {
-	data, err := os.ReadFile(path)
+	return update(path, func(plan *models.Plan) (*events.Event, error) {
+		e := &events.Event{Actor: events.Actor(), Type: events.PlanStatus, Before: string(plan.Status), After: string(newStatus)}
+		plan.Status = newStatus
+		return e, nil
+	})
+}
+
+// UpdatePlanPriority reads a plan file, updates/inserts/removes the priority
+// metadata line, and writes the file back. It preserves all other content exactly.
+func UpdatePlanPriority(path string, newPriority int) error {
+	unlock, err := atomicfile.Lock(path)
 	if err != nil {
-		return fmt.Errorf("reading plan file: %w", err)
+		return err
 	}
+	defer unlock()
 
-	lines := strings.Split(string(data), "\n")
-	found := false
-
-	for i, line := range lines {
-		if m := planHeadingRe.FindStringSubmatchIndex(line); m != nil {
-			// m[2]:m[3] is the title part (group 1), m[4]:m[5] is the status (group 2, may be -1)
-			title := line[m[2]:m[3]]
-			lines[i] = title + " [" + string(newStatus) + "]"
-			found = true
-			break
-		}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return etcherr.WrapIO("reading plan file", err).WithHint("Check that the plan file exists at " + path)
 	}
-
-	if !found {
-		return fmt.Errorf("plan heading not found in %s", path)
+	plan, err := parser.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return etcherr.IO("no # Plan: heading found in " + path).WithHint("Ensure the file is a valid etch plan")
 	}
+	e := events.Event{Actor: events.Actor(), Type: events.Priority, Before: priorityValue(plan.Priority), After: priorityValue(newPriority)}
+	plan.Priority = newPriority
+	out, err := Rewrite(string(data), plan)
+	if err != nil {
+		return etcherr.WrapIO("rewriting plan file", err)
+	}
+	if err := atomicfile.WriteFile(path, []byte(out), 0644); err != nil {
+		return err
+	}
+	return record(path, &e)
+}
 
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+// priorityValue renders a priority for the event log; 0 means unset.
+func priorityValue(p int) string {
+	if p <= 0 {
+		return ""
+	}
+	return strconv.Itoa(p)
 }
 
-var priorityLineRe = regexp.MustCompile(`^\*\*Priority:\*\*\s*\d+\s*$`)
+// update parses the plan file at path, applies fn to the plan, and rewrites
+// the file with Rewrite so untouched content is preserved exactly. The plan
+// stays locked from read to write so concurrent updates aren't lost. The
+// event fn returns is logged if it changed anything.
+func update(path string, fn func(plan *models.Plan) (*events.Event, error)) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-// UpdatePlanPriority reads a plan file, updates/inserts/removes the priority
-// metadata line, and writes the file back. It preserves all other content exactly.
-func UpdatePlanPriority(path string, newPriority int) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return etcherr.WrapIO("reading plan file", err).WithHint("Check that the plan file exists at " + path)
+		return fmt.Errorf("reading plan file: %w", err)
 	}
-
-	lines := strings.Split(string(data), "\n")
-
-	// Find the # Plan: heading line.
-	planIdx := -1
-	for i, line := range lines {
-		if strings.HasPrefix(line, "# Plan:") {
-			planIdx = i
-			break
-		}
+	plan, err := parser.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return err
 	}
-	if planIdx < 0 {
-		return etcherr.IO("no # Plan: heading found in " + path).WithHint("Ensure the file is a valid etch plan")
+	e, err := fn(plan)
+	if err != nil {
+		return err
 	}
-
-	// Find existing priority line between plan heading and next ## heading.
-	priorityIdx := -1
-	nextSectionIdx := len(lines)
-	for i := planIdx + 1; i < len(lines); i++ {
-		if strings.HasPrefix(lines[i], "## ") {
-			nextSectionIdx = i
-			break
-		}
-		if priorityLineRe.MatchString(lines[i]) {
-			priorityIdx = i
-		}
+	out, err := Rewrite(string(data), plan)
+	if err != nil {
+		return err
 	}
-
-	if priorityIdx >= 0 && newPriority > 0 {
-		// Replace existing line.
-		lines[priorityIdx] = fmt.Sprintf("**Priority:** %d", newPriority)
-	} else if priorityIdx < 0 && newPriority > 0 {
-		// Insert after plan heading.
-		newLine := fmt.Sprintf("**Priority:** %d", newPriority)
-		insertAt := planIdx + 1
-		lines = append(lines[:insertAt], append([]string{newLine}, lines[insertAt:]...)...)
-		_ = nextSectionIdx // not needed after insert
-	} else if priorityIdx >= 0 && newPriority == 0 {
-		// Remove the priority line.
-		lines = append(lines[:priorityIdx], lines[priorityIdx+1:]...)
+	if err := atomicfile.WriteFile(path, []byte(out), 0644); err != nil {
+		return err
 	}
-	// If priorityIdx < 0 && newPriority == 0, nothing to do.
+	return record(path, e)
+}
 
-	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
-		return etcherr.WrapIO("writing plan file", err).WithHint("Check file permissions for " + path)
+// record logs e for the plan at path unless it's a no-op.
+func record(path string, e *events.Event) error {
+	if e == nil || e.Before == e.After {
+		return nil
+	}
+	if err := events.Record(path, *e); err != nil {
+		return etcherr.WrapIO("recording plan change", err)
 	}
 	return nil
 }
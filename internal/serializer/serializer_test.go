@@ -1,9 +1,13 @@
 package serializer
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gsigler/etch/internal/models"
@@ -831,3 +835,111 @@ func assertNotContains(t *testing.T, s, substr string) {
 		t.Errorf("output should not contain %q\nfull output:\n%s", substr, s)
 	}
 }
+
+// concurrentPlan returns a plan with tasks 1.1–1.<tasks>, each with
+// criteria "c<task>-1" to "c<task>-<criteria>", all unchecked.
+func concurrentPlan(tasks, criteria int) string {
+	var b strings.Builder
+	b.WriteString("# Plan: Concurrency\n\n## Feature 1: Core\n")
+	for i := 1; i <= tasks; i++ {
+		fmt.Fprintf(&b, "\n### Task 1.%d: Task %d [pending]\n**Complexity:** small\n\n", i, i)
+		for j := 1; j <= criteria; j++ {
+			fmt.Fprintf(&b, "- [ ] c%d-%d\n", i, j)
+		}
+	}
+	return b.String()
+}
+
+// checkTask ticks every criterion of a task and marks it in progress, one
+// write per change.
+func checkTask(path string, task, criteria int) error {
+	id := fmt.Sprintf("1.%d", task)
+	for j := 1; j <= criteria; j++ {
+		if err := UpdateCriterion(path, id, fmt.Sprintf("c%d-%d", task, j), true); err != nil {
+			return err
+		}
+	}
+	return UpdateTaskStatus(path, id, models.StatusInProgress)
+}
+
+// TestHelperProcess is run as a child by TestConcurrentUpdates.
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv("SERIALIZER_PLAN")
+	if path == "" {
+		t.Skip("helper process")
+	}
+	task, _ := strconv.Atoi(os.Getenv("SERIALIZER_TASK"))
+	criteria, _ := strconv.Atoi(os.Getenv("SERIALIZER_CRITERIA"))
+	if err := checkTask(path, task, criteria); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	const procs, goroutines, criteria = 4, 12, 8
+	tasks := goroutines
+	if !testing.Short() {
+		tasks += procs
+	}
+	path := filepath.Join(t.TempDir(), "plan.md")
+	os.WriteFile(path, []byte(concurrentPlan(tasks, criteria)), 0644)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := checkTask(path, i, criteria); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for i := goroutines + 1; i <= tasks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+			cmd.Env = append(os.Environ(),
+				"SERIALIZER_PLAN="+path,
+				"SERIALIZER_TASK="+strconv.Itoa(i),
+				"SERIALIZER_CRITERIA="+strconv.Itoa(criteria))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("helper process: %v\n%s", err, out)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for p := 1; p <= 20; p++ {
+			if err := UpdatePlanPriority(path, p); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	plan, err := parser.ParseFile(path)
+	if err != nil {
+		t.Fatalf("plan no longer parses: %v", err)
+	}
+	if plan.Priority != 20 {
+		t.Errorf("priority = %d, want 20", plan.Priority)
+	}
+	got := 0
+	for _, task := range plan.Features[0].Tasks {
+		got++
+		if task.Status != models.StatusInProgress {
+			t.Errorf("task %s: status %s, want in_progress", task.FullID(), task.Status)
+		}
+		for _, c := range task.Criteria {
+			if !c.IsMet {
+				t.Errorf("task %s: %q lost its check", task.FullID(), c.Description)
+			}
+		}
+	}
+	if got != tasks {
+		t.Errorf("plan has %d tasks, want %d", got, tasks)
+	}
+}
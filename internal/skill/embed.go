@@ -1,9 +1,24 @@
 package skill
 
-import _ "embed"
+import (
+	_ "embed"
+	"strings"
+)
 
 //go:embed etch-plan.md
 var Content string
 
 //go:embed etch.md
 var EtchContent string
+
+// Prompt turns a skill into a plain prompt for agents that can't invoke
+// Claude Code skills: it drops the frontmatter and substitutes args for
+// $ARGUMENTS.
+func Prompt(content, args string) string {
+	if rest, ok := strings.CutPrefix(content, "---\n"); ok {
+		if _, body, ok := strings.Cut(rest, "\n---\n"); ok {
+			content = body
+		}
+	}
+	return strings.TrimSpace(strings.ReplaceAll(content, "$ARGUMENTS", args)) + "\n"
+}
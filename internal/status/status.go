@@ -1,6 +1,7 @@
 package status
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,8 +9,12 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/gsigler/etch/internal/config"
 	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/hooks"
 	"github.com/gsigler/etch/internal/models"
 	"github.com/gsigler/etch/internal/parser"
 	"github.com/gsigler/etch/internal/progress"
@@ -18,14 +23,15 @@ import (
 
 // PlanStatus holds the reconciled status for a single plan.
 type PlanStatus struct {
-	Title          string          `json:"title"`
-	Slug           string          `json:"slug"`
-	FilePath       string          `json:"file_path"`
-	Priority       int             `json:"priority"`
-	PlanCompleted  bool            `json:"plan_completed"`
-	Features       []FeatureStatus `json:"features"`
-	CompletedTasks int             `json:"completed_tasks"`
-	TotalTasks     int             `json:"total_tasks"`
+	Title           string          `json:"title"`
+	Slug            string          `json:"slug"`
+	FilePath        string          `json:"file_path"`
+	Priority        int             `json:"priority"`
+	PlanCompleted   bool            `json:"plan_completed"`
+	Features        []FeatureStatus `json:"features"`
+	CompletedTasks  int             `json:"completed_tasks"`
+	TotalTasks      int             `json:"total_tasks"`
+	DurationSeconds int64           `json:"duration_seconds"`
 }
 
 // IsActive returns true unless the plan is fully completed (all tasks done).
@@ -44,40 +50,130 @@ func (ps PlanStatus) Percentage() int {
 
 // FeatureStatus holds status summary for a feature.
 type FeatureStatus struct {
-	Number         int          `json:"number"`
-	Title          string       `json:"title"`
-	Tasks          []TaskStatus `json:"tasks"`
-	CompletedTasks int          `json:"completed_tasks"`
-	TotalTasks     int          `json:"total_tasks"`
+	Number          int          `json:"number"`
+	Title           string       `json:"title"`
+	Tasks           []TaskStatus `json:"tasks"`
+	CompletedTasks  int          `json:"completed_tasks"`
+	TotalTasks      int          `json:"total_tasks"`
+	DurationSeconds int64        `json:"duration_seconds"`
 }
 
 // TaskStatus holds reconciled status for a single task.
 type TaskStatus struct {
-	ID            string             `json:"id"`
-	Title         string             `json:"title"`
-	Status        models.Status      `json:"status"`
-	DependsOn     []string           `json:"depends_on,omitempty"`
-	IsBlocked     bool               `json:"is_blocked,omitempty"`
-	SessionCount  int                `json:"session_count"`
-	LastOutcome   string             `json:"last_outcome,omitempty"`
-	Criteria      []models.Criterion `json:"criteria,omitempty"`
-	LastDecisions string             `json:"last_decisions,omitempty"`
-	LastNext      string             `json:"last_next,omitempty"`
-}
-
-// Run reads all plans (or a specific one), reconciles progress, updates plan files, and returns status.
+	ID              string             `json:"id"`
+	Title           string             `json:"title"`
+	Status          models.Status      `json:"status"`
+	DependsOn       []string           `json:"depends_on,omitempty"`
+	IsBlocked       bool               `json:"is_blocked,omitempty"`
+	SessionCount    int                `json:"session_count"`
+	LastOutcome     string             `json:"last_outcome,omitempty"`
+	Criteria        []models.Criterion `json:"criteria,omitempty"`
+	LastDecisions   string             `json:"last_decisions,omitempty"`
+	LastNext        string             `json:"last_next,omitempty"`
+	Branch          string             `json:"branch,omitempty"`
+	Sessions        []SessionTime      `json:"sessions,omitempty"`
+	DurationSeconds int64              `json:"duration_seconds"`
+	// Stale is set when the latest session is in progress but has had no
+	// heartbeat since LastSeen for longer than stale_after under [status].
+	Stale    bool      `json:"stale,omitempty"`
+	LastSeen time.Time `json:"last_seen,omitzero"`
+}
+
+// SessionTime is when one of a task's sessions ran and how long it was
+// worked on.
+type SessionTime struct {
+	Session         int       `json:"session"`
+	Agent           string    `json:"agent,omitempty"`
+	Started         time.Time `json:"started,omitzero"`
+	Ended           time.Time `json:"ended,omitzero"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Running         bool      `json:"running,omitempty"`
+}
+
+// now is the clock that running sessions are timed against.
+var now = time.Now
+
+// Change is one line that syncing rewrites in a plan file. Before is empty
+// for an inserted line and After is empty for a removed one.
+type Change struct {
+	Plan   string
+	File   string
+	Line   int // 1-based line number in the file before the change
+	Before string
+	After  string
+}
+
+// mode says what a status run does to plan files.
+type mode int
+
+const (
+	readOnly mode = iota // report status, change nothing
+	preview              // report the changes syncing would make
+	write                // make those changes
+)
+
+// Run reads all plans (or a specific one), reconciles them with their
+// progress files, and returns their status. Plan files are not changed
+// unless auto_sync is set under [status] in config, in which case Run
+// syncs like Sync.
 func Run(rootDir string, planFilter string) ([]PlanStatus, error) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	m := readOnly
+	if cfg.Status.AutoSync {
+		m = write
+	}
+	plans, _, err := run(rootDir, planFilter, cfg, m)
+	return plans, err
+}
+
+// Sync reconciles plans like Run and writes the result to the plan files:
+// task statuses, checked criteria, and plan completion. Each task and plan
+// transition runs its hook first and its webhooks after, and every change
+// is recorded in the event log. With dryRun, nothing is written and no
+// hooks run; the changes are only reported.
+func Sync(rootDir string, planFilter string, dryRun bool) ([]PlanStatus, []Change, error) {
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := write
+	if dryRun {
+		m = preview
+	}
+	return run(rootDir, planFilter, cfg, m)
+}
+
+func run(rootDir, planFilter string, cfg config.Config, m mode) ([]PlanStatus, []Change, error) {
 	plansDir := filepath.Join(rootDir, ".etch", "plans")
 
 	entries, err := os.ReadDir(plansDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, nil, nil
+		}
+		return nil, nil, etcherr.WrapIO("reading plans directory", err)
+	}
+
+	var s *syncer
+	if m == write {
+		// Hooks for transitions found here write to stderr, which keeps
+		// stdout clean for --json and the MCP server.
+		s = &syncer{
+			fire: func(p hooks.Payload) error {
+				return hooks.Run(cfg.Hooks, p, os.Stderr)
+			},
+			notify: func(p hooks.Payload) {
+				hooks.Send(cfg.Webhooks, p, os.Stderr)
+			},
 		}
-		return nil, etcherr.WrapIO("reading plans directory", err)
 	}
 
 	var results []PlanStatus
+	var changes []Change
+	external := NewPlanLookup(rootDir)
 
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
@@ -90,31 +186,101 @@ func Run(rootDir string, planFilter string) ([]PlanStatus, error) {
 		}
 
 		planPath := filepath.Join(plansDir, entry.Name())
-		plan, err := parser.ParseFile(planPath)
+		before, err := os.ReadFile(planPath)
 		if err != nil {
-			return nil, etcherr.WrapParse(fmt.Sprintf("parsing plan %s", entry.Name()), err)
+			return nil, nil, etcherr.WrapIO(fmt.Sprintf("reading plan %s", entry.Name()), err)
 		}
+		plan, err := parser.Parse(bytes.NewReader(before))
+		if err != nil {
+			return nil, nil, etcherr.WrapParse(fmt.Sprintf("parsing plan %s", entry.Name()), err)
+		}
+		plan.FilePath = planPath
+		plan.Slug = slug
 
 		progressMap, err := progress.ReadAll(rootDir, plan.Slug)
 		if err != nil {
-			return nil, etcherr.WrapIO(fmt.Sprintf("reading progress for %s", plan.Slug), err)
+			return nil, nil, etcherr.WrapIO(fmt.Sprintf("reading progress for %s", plan.Slug), err)
 		}
 
-		ps, err := reconcile(plan, progressMap)
+		ps, err := reconcile(rootDir, plan, progressMap, s, now(), cfg.Status.StaleAfter)
 		if err != nil {
-			return nil, etcherr.WrapIO(fmt.Sprintf("reconciling %s", plan.Slug), err)
+			return nil, nil, etcherr.WrapIO(fmt.Sprintf("reconciling %s", plan.Slug), err)
 		}
-
+		resolveCrossPlanBlocked(&ps, external)
 		results = append(results, ps)
+
+		// Report what changed, or for a preview, what would.
+		var after []byte
+		switch m {
+		case preview:
+			out, err := serializer.Rewrite(string(before), plan)
+			if err != nil {
+				return nil, nil, etcherr.WrapIO(fmt.Sprintf("rendering %s", plan.Slug), err)
+			}
+			after = []byte(out)
+		case write:
+			if after, err = os.ReadFile(planPath); err != nil {
+				return nil, nil, etcherr.WrapIO(fmt.Sprintf("reading plan %s", entry.Name()), err)
+			}
+		}
+		if m != readOnly {
+			changes = append(changes, diffLines(plan.Slug, planPath, string(before), string(after))...)
+		}
+	}
+
+	return results, changes, nil
+}
+
+// syncer writes reconciled changes to plan files. Each status change runs
+// its hook through fire first, and a hook that vetoes leaves the old status
+// in place; saved changes are passed to notify. A nil syncer writes
+// nothing and runs no hooks.
+type syncer struct {
+	fire   func(hooks.Payload) error
+	notify func(hooks.Payload)
+}
+
+// allow runs p's hook and reports whether the change may go ahead.
+func (s *syncer) allow(p hooks.Payload) bool {
+	return s == nil || !vetoed(s.fire, p)
+}
+
+func (s *syncer) taskStatus(plan *models.Plan, task *models.Task, status models.Status, actor string, p hooks.Payload) error {
+	if s == nil {
+		return nil
 	}
+	if err := serializer.UpdateTaskStatusAs(plan.FilePath, task.FullID(), status, actor); err != nil {
+		return etcherr.WrapIO(fmt.Sprintf("updating task %s status", task.FullID()), err)
+	}
+	s.notify(p)
+	return nil
+}
 
-	return results, nil
+func (s *syncer) criterion(plan *models.Plan, task *models.Task, text, actor string) error {
+	if s == nil {
+		return nil
+	}
+	if err := serializer.UpdateCriterionAs(plan.FilePath, task.FullID(), text, true, actor); err != nil {
+		return etcherr.WrapIO(fmt.Sprintf("updating criterion for task %s", task.FullID()), err)
+	}
+	return nil
 }
 
-// reconcile merges progress data into plan status and updates the plan file if needed.
-func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgress) (PlanStatus, error) {
-	changed := false
+func (s *syncer) planCompleted(plan *models.Plan, p hooks.Payload) error {
+	if s == nil {
+		return nil
+	}
+	if err := serializer.UpdatePlanStatus(plan.FilePath, models.StatusCompleted); err != nil {
+		return etcherr.WrapIO("updating plan completion status", err)
+	}
+	s.notify(p)
+	return nil
+}
 
+// reconcile merges progress data into plan status as of at, updating plan
+// in memory and, through s, in its file. Sessions idle for longer than
+// staleAfter are flagged.
+func reconcile(rootDir string, plan *models.Plan, progressMap map[string][]models.SessionProgress, s *syncer, at time.Time, staleAfter time.Duration) (PlanStatus, error) {
 	ps := PlanStatus{
 		Title:    plan.Title,
 		Slug:     plan.Slug,
@@ -145,18 +311,22 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 
 			if len(sessions) > 0 {
 				latest := sessions[len(sessions)-1]
-				newStatus := mapProgressStatus(latest.Status)
+				newStatus := Effective(task, sessions)
 
-				if newStatus != task.Status {
-					ts.Status = newStatus
-					if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), newStatus); err != nil {
-						return ps, etcherr.WrapIO(fmt.Sprintf("updating task %s status", task.FullID()), err)
+				if p := taskPayload(rootDir, plan, task, newStatus, latest); newStatus != task.Status && s.allow(p) {
+					actor := events.SessionActor(plan.Slug, task.FullID(), latest.SessionNumber)
+					if err := s.taskStatus(plan, task, newStatus, actor, p); err != nil {
+						return ps, err
 					}
+					ts.Status = newStatus
 					task.Status = newStatus
-					changed = true
 				}
 
 				ts.LastOutcome = latest.Status
+				if latest.Stale(at, staleAfter) {
+					ts.Stale = true
+					ts.LastSeen = latest.LastSeen()
+				}
 
 				// Merge criteria: if any session marks [x], plan gets [x].
 				for _, sess := range sessions {
@@ -166,12 +336,12 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 						}
 						for k := range task.Criteria {
 							if task.Criteria[k].Description == cu.Description && !task.Criteria[k].IsMet {
+								actor := events.SessionActor(plan.Slug, task.FullID(), sess.SessionNumber)
+								if err := s.criterion(plan, task, cu.Description, actor); err != nil {
+									return ps, err
+								}
 								task.Criteria[k].IsMet = true
 								ts.Criteria = task.Criteria
-								if err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), cu.Description, true); err != nil {
-									return ps, etcherr.WrapIO(fmt.Sprintf("updating criterion for task %s", task.FullID()), err)
-								}
-								changed = true
 							}
 						}
 					}
@@ -179,11 +349,33 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 
 				ts.LastDecisions = latest.Decisions
 				ts.LastNext = latest.Next
+
+				// The most recent session run in a worktree names the
+				// branch holding the task's work.
+				for _, sess := range sessions {
+					if sess.Branch != "" {
+						ts.Branch = sess.Branch
+					}
+				}
+
+				for _, sess := range sessions {
+					st := SessionTime{
+						Session:         sess.SessionNumber,
+						Agent:           sess.Agent,
+						Started:         sess.Started,
+						Ended:           sess.Ended,
+						DurationSeconds: int64(sess.Duration(at) / time.Second),
+						Running:         sess.Running(),
+					}
+					ts.Sessions = append(ts.Sessions, st)
+					ts.DurationSeconds += st.DurationSeconds
+				}
 			}
 
 			if ts.Status == models.StatusCompleted {
 				fs.CompletedTasks++
 			}
+			fs.DurationSeconds += ts.DurationSeconds
 			fs.Tasks = append(fs.Tasks, ts)
 		}
 
@@ -194,6 +386,7 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 	for _, f := range ps.Features {
 		ps.CompletedTasks += f.CompletedTasks
 		ps.TotalTasks += f.TotalTasks
+		ps.DurationSeconds += f.DurationSeconds
 	}
 
 	// Resolve blocked status: a pending task is blocked if any dependency is not completed.
@@ -203,9 +396,13 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 	if ps.CompletedTasks == ps.TotalTasks && ps.TotalTasks > 0 {
 		ps.PlanCompleted = true
 		if plan.Status != models.StatusCompleted {
-			plan.Status = models.StatusCompleted
-			if err := serializer.UpdatePlanStatus(plan.FilePath, models.StatusCompleted); err != nil {
-				return ps, etcherr.WrapIO("updating plan completion status", err)
+			if p := hooks.ForPlan(rootDir, plan); s.allow(p) {
+				if err := s.planCompleted(plan, p); err != nil {
+					return ps, err
+				}
+				plan.Status = models.StatusCompleted
+			} else {
+				ps.PlanCompleted = false
 			}
 		}
 	} else if plan.Status == models.StatusCompleted {
@@ -213,10 +410,71 @@ func reconcile(plan *models.Plan, progressMap map[string][]models.SessionProgres
 		ps.PlanCompleted = true
 	}
 
-	_ = changed // tracking for potential future use
 	return ps, nil
 }
 
+// diffLines returns the lines that differ between before and after. The
+// rewrites syncing makes change lines in place, so only a differing middle
+// section is compared line by line; any extra lines on either side are
+// reported as removed or inserted.
+func diffLines(slug, file, before, after string) []Change {
+	if before == after {
+		return nil
+	}
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// Trim the common prefix and suffix.
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	var changes []Change
+	for i := 0; start+i < endA || start+i < endB; i++ {
+		c := Change{Plan: slug, File: file, Line: start + i + 1}
+		if start+i < endA {
+			c.Before = a[start+i]
+		} else {
+			c.Line = endA + 1
+		}
+		if start+i < endB {
+			c.After = b[start+i]
+		}
+		if c.Before != c.After {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// taskPayload describes task moving to status because of session.
+func taskPayload(rootDir string, plan *models.Plan, task *models.Task, status models.Status, session models.SessionProgress) hooks.Payload {
+	p := hooks.ForTask(rootDir, plan, task, status)
+	p.Session = session.SessionNumber
+	p.ProgressFile = progress.SessionPath(rootDir, plan.Slug, task.FullID(), session.SessionNumber)
+	p.Actor = events.SessionActor(plan.Slug, task.FullID(), session.SessionNumber)
+	return p
+}
+
+// vetoed runs a hook and reports whether it cancelled the change, printing
+// why to stderr.
+func vetoed(fire func(hooks.Payload) error, p hooks.Payload) bool {
+	if p.Event == "" {
+		return false
+	}
+	if err := fire(p); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return true
+	}
+	return false
+}
+
 // depIDRegex extracts a task ID like "1.2" or "1.3b" from a dependency string like "Task 1.2".
 var depIDRegex = regexp.MustCompile(`(\d+\.\d+[a-z]?)`)
 
@@ -243,11 +501,7 @@ func resolveBlocked(ps *PlanStatus) {
 				continue
 			}
 			for _, dep := range t.DependsOn {
-				depID := extractDepID(dep)
-				// For single-feature plans, deps may be bare numbers like "Task 2".
-				if depID == "" && singleFeature {
-					depID = extractBareDepID(dep)
-				}
+				depID := DependencyID(dep, singleFeature)
 				if depID == "" {
 					continue
 				}
@@ -260,6 +514,101 @@ func resolveBlocked(ps *PlanStatus) {
 	}
 }
 
+// resolveCrossPlanBlocked marks pending tasks as blocked if any cross-plan
+// dependency (e.g. "auth-system#1.2") is not completed or does not exist.
+func resolveCrossPlanBlocked(ps *PlanStatus, lookup *PlanLookup) {
+	for i := range ps.Features {
+		for j := range ps.Features[i].Tasks {
+			t := &ps.Features[i].Tasks[j]
+			if t.Status != models.StatusPending || t.IsBlocked {
+				continue
+			}
+			for _, dep := range t.DependsOn {
+				slug, taskID, ok := models.ParseCrossPlanDep(dep)
+				if !ok {
+					continue
+				}
+				if s, found := lookup.status(slug, taskID); !found || s != models.StatusCompleted {
+					t.IsBlocked = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// PlanLookup loads plans referenced by cross-plan dependencies, along with
+// their progress, caching each plan after the first lookup. Plans are read
+// without being reconciled, so looking one up never writes to its file.
+type PlanLookup struct {
+	rootDir  string
+	plans    map[string]*models.Plan
+	progress map[string]map[string][]models.SessionProgress
+}
+
+// NewPlanLookup returns a lookup for cross-plan dependencies, seeded with
+// plans that have already been parsed.
+func NewPlanLookup(rootDir string, known ...*models.Plan) *PlanLookup {
+	l := &PlanLookup{
+		rootDir:  rootDir,
+		plans:    make(map[string]*models.Plan),
+		progress: make(map[string]map[string][]models.SessionProgress),
+	}
+	for _, p := range known {
+		l.plans[p.Slug] = p
+	}
+	return l
+}
+
+// Load returns the plan with the given slug and its progress. The plan is
+// nil if it does not exist or cannot be parsed. A nil lookup finds nothing.
+func (l *PlanLookup) Load(slug string) (*models.Plan, map[string][]models.SessionProgress) {
+	if l == nil {
+		return nil, nil
+	}
+	plan, seen := l.plans[slug]
+	if !seen {
+		plan, _ = parser.ParseFile(filepath.Join(l.rootDir, ".etch", "plans", slug+".md"))
+		l.plans[slug] = plan
+	}
+	if plan == nil {
+		return nil, nil
+	}
+	if _, ok := l.progress[slug]; !ok {
+		l.progress[slug], _ = progress.ReadAll(l.rootDir, slug)
+	}
+	return plan, l.progress[slug]
+}
+
+// status returns the effective status of a task in another plan: the latest
+// progress session's status if there is one, otherwise the plan file's.
+func (l *PlanLookup) status(slug, taskID string) (models.Status, bool) {
+	plan, sessions := l.Load(slug)
+	if plan == nil {
+		return "", false
+	}
+	task := plan.ResolveTaskID(taskID)
+	if task == nil {
+		return "", false
+	}
+	return Effective(task, sessions[task.FullID()]), true
+}
+
+// DependencyID resolves a dependency string like "Task 1.2" to a task ID.
+// For single-feature plans, bare numbers like "Task 2" resolve to "1.2".
+// Returns "" if the string contains no recognizable task reference, or if it
+// refers to a task in another plan (see models.ParseCrossPlanDep).
+func DependencyID(dep string, singleFeature bool) string {
+	if _, _, ok := models.ParseCrossPlanDep(dep); ok {
+		return ""
+	}
+	depID := extractDepID(dep)
+	if depID == "" && singleFeature {
+		depID = extractBareDepID(dep)
+	}
+	return depID
+}
+
 // extractDepID pulls a task ID from a dependency string like "Task 1.2".
 func extractDepID(dep string) string {
 	m := depIDRegex.FindString(dep)
@@ -276,6 +625,17 @@ func extractBareDepID(dep string) string {
 	return "1." + m[1]
 }
 
+// Effective returns a task's status reconciled with its sessions: the
+// outcome of the latest session when there is one, otherwise the status in
+// the plan. An abandoned session has no outcome, so the plan's status,
+// set when it was recovered, stands. Unlike Run, it writes nothing.
+func Effective(task *models.Task, sessions []models.SessionProgress) models.Status {
+	if len(sessions) == 0 || sessions[len(sessions)-1].Status == models.SessionAbandoned {
+		return task.Status
+	}
+	return mapProgressStatus(sessions[len(sessions)-1].Status)
+}
+
 // mapProgressStatus converts a progress file status string to a plan Status.
 func mapProgressStatus(progressStatus string) models.Status {
 	switch progressStatus {
@@ -338,12 +698,12 @@ func FormatSummary(plans []PlanStatus) string {
 			planIcon = "✓"
 		}
 		b.WriteString(fmt.Sprintf("%s %s %s  %s\n", planIcon, priorityTag, p.Title, progressBar(pct)))
-		b.WriteString(fmt.Sprintf("  slug: %s\n", p.Slug))
+		b.WriteString(fmt.Sprintf("  slug: %s%s\n", p.Slug, timeSuffix(p.DurationSeconds)))
 
 		for _, f := range p.Features {
 			icon := featureIcon(f)
-			b.WriteString(fmt.Sprintf("   %s Feature %d: %s [%d/%d tasks]\n",
-				icon, f.Number, f.Title, f.CompletedTasks, f.TotalTasks))
+			b.WriteString(fmt.Sprintf("   %s Feature %d: %s [%d/%d tasks]%s\n",
+				icon, f.Number, f.Title, f.CompletedTasks, f.TotalTasks, timeSuffix(f.DurationSeconds)))
 
 			for _, t := range f.Tasks {
 				icon := taskIcon(t)
@@ -351,6 +711,13 @@ func FormatSummary(plans []PlanStatus) string {
 				if t.SessionCount > 0 && t.Status != models.StatusCompleted {
 					line += fmt.Sprintf(" (%d sessions, last: %s)", t.SessionCount, t.LastOutcome)
 				}
+				line += timeSuffix(t.DurationSeconds)
+				if t.Stale {
+					line += " ⚠ stale"
+				}
+				if t.Branch != "" {
+					line += " ⎇ " + t.Branch
+				}
 				b.WriteString(line + "\n")
 			}
 		}
@@ -371,12 +738,12 @@ func FormatDetailed(ps PlanStatus) string {
 		planIcon = "✓"
 	}
 	b.WriteString(fmt.Sprintf("%s %s %s  %s\n", planIcon, priorityTag, ps.Title, progressBar(pct)))
-	b.WriteString(fmt.Sprintf("  slug: %s\n\n", ps.Slug))
+	b.WriteString(fmt.Sprintf("  slug: %s%s\n\n", ps.Slug, timeSuffix(ps.DurationSeconds)))
 
 	for _, f := range ps.Features {
 		icon := featureIcon(f)
-		b.WriteString(fmt.Sprintf("%s Feature %d: %s [%d/%d tasks]\n",
-			icon, f.Number, f.Title, f.CompletedTasks, f.TotalTasks))
+		b.WriteString(fmt.Sprintf("%s Feature %d: %s [%d/%d tasks]%s\n",
+			icon, f.Number, f.Title, f.CompletedTasks, f.TotalTasks, timeSuffix(f.DurationSeconds)))
 
 		for _, t := range f.Tasks {
 			icon := taskIcon(t)
@@ -384,7 +751,15 @@ func FormatDetailed(ps PlanStatus) string {
 			if t.SessionCount > 0 {
 				b.WriteString(fmt.Sprintf(" (%d sessions, last: %s)", t.SessionCount, t.LastOutcome))
 			}
+			b.WriteString(timeSuffix(t.DurationSeconds))
 			b.WriteString("\n")
+			if line := sessionTimes(t.Sessions); line != "" {
+				b.WriteString("    Time: " + line + "\n")
+			}
+			if t.Stale {
+				b.WriteString(fmt.Sprintf("    Stale: no activity since %s; run 'etch recover %s -t %s' if its agent is gone\n",
+					t.LastSeen.Local().Format("2006-01-02 15:04"), ps.Slug, t.ID))
+			}
 
 			if t.IsBlocked && len(t.DependsOn) > 0 {
 				b.WriteString(fmt.Sprintf("    Waiting on: %s\n", strings.Join(t.DependsOn, ", ")))
@@ -406,6 +781,9 @@ func FormatDetailed(ps PlanStatus) string {
 			if t.LastNext != "" {
 				b.WriteString(fmt.Sprintf("    Next: %s\n", t.LastNext))
 			}
+			if t.Branch != "" {
+				b.WriteString(fmt.Sprintf("    Branch: %s\n", t.Branch))
+			}
 		}
 		b.WriteString("\n")
 	}
@@ -421,6 +799,53 @@ func FormatJSON(plans []PlanStatus) (string, error) {
 	return string(data), nil
 }
 
+// timeSuffix formats a total time worked for the end of a status line, or
+// returns "" if nothing has been timed.
+func timeSuffix(seconds int64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return " · " + FormatDuration(time.Duration(seconds)*time.Second)
+}
+
+// sessionTimes lists each timed session's duration, e.g.
+// "001 45m, 002 20m (running)".
+func sessionTimes(sessions []SessionTime) string {
+	var parts []string
+	for _, st := range sessions {
+		if st.DurationSeconds == 0 && !st.Running {
+			continue
+		}
+		part := fmt.Sprintf("%03d %s", st.Session, FormatDuration(time.Duration(st.DurationSeconds)*time.Second))
+		if st.Running {
+			part += " (running)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Plural returns one if n is 1, and many otherwise.
+func Plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// FormatDuration formats a time worked to the minute, e.g. "1h20m", or
+// "<1m" for less than a minute.
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	s := strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
 // featureIcon returns a status icon based on task completion.
 func featureIcon(f FeatureStatus) string {
 	if f.CompletedTasks == f.TotalTasks && f.TotalTasks > 0 {
@@ -2,11 +2,18 @@ package status
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gsigler/etch/internal/events"
+	"github.com/gsigler/etch/internal/hooks"
 	"github.com/gsigler/etch/internal/models"
 )
 
@@ -141,7 +148,7 @@ func TestReconcileCompletedStatus(t *testing.T) {
 		"- [x] Indexes added",
 	})
 
-	plans, err := Run(root, "")
+	plans, _, err := Sync(root, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -172,6 +179,174 @@ func TestReconcileCompletedStatus(t *testing.T) {
 	}
 }
 
+func TestRunIsReadOnly(t *testing.T) {
+	root := t.TempDir()
+	path := writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 1, "completed", []string{"- [x] Indexes added"})
+
+	plans, err := Run(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := findTask(plans[0], "1.1"); task.Status != models.StatusCompleted || !task.Criteria[1].IsMet {
+		t.Errorf("status should still be reconciled in memory: %+v", task)
+	}
+	if data, _ := os.ReadFile(path); string(data) != testPlan {
+		t.Errorf("Run changed the plan file:\n%s", data)
+	}
+	if evs, _ := events.Read(root, events.Filter{}); len(evs) != 0 {
+		t.Errorf("Run recorded events: %+v", evs)
+	}
+
+	// auto_sync restores writing on every read.
+	os.WriteFile(filepath.Join(root, ".etch", "config.toml"), []byte("[status]\nauto_sync = true\n"), 0o644)
+	if _, err := Run(root, ""); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "### Task 1.1: Schema [completed]") {
+		t.Errorf("auto_sync didn't write the plan file:\n%s", data)
+	}
+}
+
+func TestSyncReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	path := writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 1, "completed", []string{"- [x] Indexes added"})
+
+	_, preview, err := Sync(root, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != testPlan {
+		t.Errorf("a dry run changed the plan file:\n%s", data)
+	}
+	want := []Change{
+		{Plan: "auth", File: path, Line: 13, Before: "### Task 1.1: Schema [pending]", After: "### Task 1.1: Schema [completed]"},
+		{Plan: "auth", File: path, Line: 21, Before: "- [ ] Indexes added", After: "- [x] Indexes added"},
+	}
+	if len(preview) != len(want) {
+		t.Fatalf("dry run changes = %+v", preview)
+	}
+	for i := range want {
+		if preview[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, preview[i], want[i])
+		}
+	}
+
+	_, changes, err := Sync(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != len(preview) || changes[0] != preview[0] || changes[1] != preview[1] {
+		t.Errorf("sync changes %+v differ from the dry run %+v", changes, preview)
+	}
+	if _, changes, _ = Sync(root, "", false); len(changes) != 0 {
+		t.Errorf("a second sync should change nothing, got %+v", changes)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	changes := diffLines("p", "f", "a\nb\nc\n", "a\nB\nc\nd\n")
+	want := []Change{
+		{Plan: "p", File: "f", Line: 2, Before: "b", After: "B"},
+		{Plan: "p", File: "f", Line: 4, After: "d"},
+	}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("diffLines = %+v, want %+v", changes, want)
+	}
+	if changes := diffLines("p", "f", "a\nb\n", "a\n"); len(changes) != 1 || changes[0].Line != 2 || changes[0].Before != "b" || changes[0].After != "" {
+		t.Errorf("removed line = %+v", changes)
+	}
+}
+
+func TestReconcileRecordsSessionEvents(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 2, "completed", []string{"- [x] Indexes added"})
+
+	if _, _, err := Sync(root, "", false); err != nil {
+		t.Fatal(err)
+	}
+	// A second run has nothing left to change.
+	if _, _, err := Sync(root, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	evs, err := events.Read(root, events.Filter{Plan: "auth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %+v", evs)
+	}
+	for _, e := range evs {
+		if e.Actor != "session:auth--task-1.1--002" || e.Task != "1.1" {
+			t.Errorf("event = %+v", e)
+		}
+	}
+	if evs[0].Type != events.TaskStatus || evs[0].After != "completed" || evs[1].Subject != "Indexes added" {
+		t.Errorf("events = %+v", evs)
+	}
+}
+
+func TestReconcileVetoedByHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use POSIX shell commands")
+	}
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 1, "completed", []string{"- [x] Indexes added"})
+	os.WriteFile(filepath.Join(root, ".etch", "config.toml"), []byte("[hooks]\non_task_done = \"test -f ok\"\nveto = [\"on_task_done\"]\n"), 0o644)
+
+	plans, _, err := Sync(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := findTask(plans[0], "1.1"); task.Status != models.StatusPending {
+		t.Errorf("vetoed task status = %s, want pending", task.Status)
+	}
+
+	// Once the hook passes, the next run applies the change.
+	os.WriteFile(filepath.Join(root, "ok"), nil, 0o644)
+	plans, _, err = Sync(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := findTask(plans[0], "1.1"); task.Status != models.StatusCompleted {
+		t.Errorf("task status = %s, want completed", task.Status)
+	}
+}
+
+func TestReconcileSendsWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var got []hooks.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p hooks.Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 2, "completed", nil)
+	os.WriteFile(filepath.Join(root, ".etch", "config.toml"), []byte("[[webhooks]]\nurl = \""+srv.URL+"\"\n"), 0o644)
+
+	for range 2 {
+		if _, _, err := Sync(root, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one webhook for the one change, got %+v", got)
+	}
+	if p := got[0]; p.Event != hooks.TaskDone || p.Task != "1.1" || p.Session != 2 || p.From != "pending" || p.To != "completed" {
+		t.Errorf("payload = %+v", p)
+	}
+}
+
 func TestReconcilePartialStatus(t *testing.T) {
 	root := t.TempDir()
 	writePlanFile(t, root, "auth", testPlan)
@@ -181,7 +356,7 @@ func TestReconcilePartialStatus(t *testing.T) {
 		"- [ ] Expiry works",
 	})
 
-	plans, err := Run(root, "")
+	plans, _, err := Sync(root, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -266,7 +441,7 @@ func TestCriteriaMergingAcrossSessions(t *testing.T) {
 		"- [x] Indexes added",
 	})
 
-	plans, err := Run(root, "")
+	plans, _, err := Sync(root, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -674,7 +849,7 @@ func TestPlanFilePreservesOtherContent(t *testing.T) {
 		"- [x] Migration file created",
 	})
 
-	_, err := Run(root, "")
+	_, _, err := Sync(root, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -771,6 +946,17 @@ func TestMapProgressStatus(t *testing.T) {
 	}
 }
 
+func TestEffective(t *testing.T) {
+	task := &models.Task{Status: models.StatusPending}
+	if got := Effective(task, nil); got != models.StatusPending {
+		t.Errorf("no sessions: got %s, want plan status", got)
+	}
+	sessions := []models.SessionProgress{{Status: "completed"}, {Status: "partial"}}
+	if got := Effective(task, sessions); got != models.StatusInProgress {
+		t.Errorf("got %s, want the latest session's status", got)
+	}
+}
+
 // findTask finds a task by ID in a PlanStatus.
 func findTask(ps PlanStatus, id string) *TaskStatus {
 	for _, f := range ps.Features {
@@ -1281,7 +1467,7 @@ Do second thing.
 	writeProgressFile(t, root, "small-plan", "1.1", 1, "completed", []string{"- [x] Done"})
 	writeProgressFile(t, root, "small-plan", "1.2", 1, "completed", []string{"- [x] Done"})
 
-	plans, err := Run(root, "small-plan")
+	plans, _, err := Sync(root, "small-plan", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1435,3 +1621,254 @@ Do setup.
 		t.Errorf("expected priority 2, got %d", plans[0].Priority)
 	}
 }
+
+const crossPlanConsumer = `# Plan: Billing
+
+## Feature 1: Invoices
+
+### Task 1.1: Invoice API [pending]
+**Depends on:** auth#1.2
+
+**Acceptance Criteria:**
+- [ ] Works
+
+### Task 1.2: Export [pending]
+**Depends on:** missing#1.1
+
+**Acceptance Criteria:**
+- [ ] Works
+
+### Task 1.3: Report [pending]
+**Depends on:** auth#2.1, Task 1.1
+
+**Acceptance Criteria:**
+- [ ] Works
+`
+
+func TestRunCrossPlanDependencies(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writePlanFile(t, root, "billing", crossPlanConsumer)
+	writeProgressFile(t, root, "auth", "2.1", 1, "completed", nil)
+
+	plans, err := Run(root, "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("expected 1 plan, got %d", len(plans))
+	}
+
+	// auth#1.2 is pending in the other plan.
+	if !findTask(plans[0], "1.1").IsBlocked {
+		t.Error("task 1.1 should be blocked on auth#1.2")
+	}
+	// Unknown plans never satisfy a dependency.
+	if !findTask(plans[0], "1.2").IsBlocked {
+		t.Error("task 1.2 should be blocked on a missing plan")
+	}
+	// auth#2.1 is completed via progress, but same-plan Task 1.1 is pending.
+	if !findTask(plans[0], "1.3").IsBlocked {
+		t.Error("task 1.3 should still be blocked on Task 1.1")
+	}
+
+	// Looking up the other plan must not reconcile (write) it.
+	data, _ := os.ReadFile(filepath.Join(root, ".etch", "plans", "auth.md"))
+	if strings.Contains(string(data), "Registration [completed]") {
+		t.Error("cross-plan lookup should not rewrite the referenced plan")
+	}
+}
+
+func TestRunCrossPlanDependencySatisfied(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writePlanFile(t, root, "billing", crossPlanConsumer)
+	writeProgressFile(t, root, "auth", "1.2", 1, "completed", nil)
+
+	plans, err := Run(root, "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findTask(plans[0], "1.1").IsBlocked {
+		t.Error("task 1.1 should be unblocked once auth#1.2 is completed")
+	}
+}
+
+func TestDependencyIDIgnoresCrossPlan(t *testing.T) {
+	if got := DependencyID("auth-system#1.2", false); got != "" {
+		t.Errorf("DependencyID(cross-plan) = %q, want empty", got)
+	}
+	if got := DependencyID("Task 1.2", false); got != "1.2" {
+		t.Errorf("DependencyID(Task 1.2) = %q, want 1.2", got)
+	}
+}
+
+func TestRunReportsWorktreeBranch(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+	writeProgressFile(t, root, "auth", "1.1", 1, "in_progress", nil)
+
+	// Record the branch the way 'etch run --worktree' does.
+	path := filepath.Join(root, ".etch", "progress", "auth--task-1.1--001.md")
+	data, _ := os.ReadFile(path)
+	content := strings.Replace(string(data), "**Status:** in_progress\n", "**Status:** in_progress\n**Branch:** etch/auth/task-1.1\n", 1)
+	os.WriteFile(path, []byte(content), 0o644)
+	writeProgressFile(t, root, "auth", "1.1", 2, "in_progress", nil)
+
+	plans, err := Run(root, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := plans[0].Features[0].Tasks[0]
+	if ts.Branch != "etch/auth/task-1.1" {
+		t.Errorf("Branch = %q, want etch/auth/task-1.1", ts.Branch)
+	}
+	if plans[0].Features[0].Tasks[1].Branch != "" {
+		t.Error("expected no branch for a task never run in a worktree")
+	}
+
+	if out := FormatDetailed(plans[0]); !strings.Contains(out, "Branch: etch/auth/task-1.1") {
+		t.Errorf("expected branch in detailed view, got:\n%s", out)
+	}
+	if out := FormatSummary(plans); !strings.Contains(out, "⎇ etch/auth/task-1.1") {
+		t.Errorf("expected branch in summary view, got:\n%s", out)
+	}
+}
+
+func TestRunTimesSessions(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+
+	dir := filepath.Join(root, ".etch", "progress")
+	os.MkdirAll(dir, 0o755)
+	write := func(name, header string) {
+		t.Helper()
+		content := "---\nschema: 2\nplan: auth\n" + header + "---\n\n# Session\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("auth--task-1.1--001.md", "task: \"1.1\"\nsession: 1\nstatus: blocked\nstarted: 2026-03-01T09:00:00Z\nended: 2026-03-01T09:45:00Z\nactive: 45m0s\n")
+	write("auth--task-1.1--002.md", "task: \"1.1\"\nsession: 2\nstatus: in_progress\nstarted: 2026-03-01T10:00:00Z\n")
+	// Sessions from before schema 2 have no clock and are never timed.
+	writeProgressFile(t, root, "auth", "1.2", 1, "in_progress", nil)
+
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2026, 3, 1, 10, 20, 0, 0, time.UTC) }
+
+	plans, err := Run(root, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := plans[0]
+	ts := ps.Features[0].Tasks[0]
+	if ts.DurationSeconds != 65*60 {
+		t.Errorf("task DurationSeconds = %d, want %d", ts.DurationSeconds, 65*60)
+	}
+	if len(ts.Sessions) != 2 || ts.Sessions[0].Running || !ts.Sessions[1].Running {
+		t.Fatalf("unexpected sessions: %+v", ts.Sessions)
+	}
+	if ts.Sessions[1].DurationSeconds != 20*60 {
+		t.Errorf("running session DurationSeconds = %d, want %d", ts.Sessions[1].DurationSeconds, 20*60)
+	}
+	if got := ps.Features[0].Tasks[1].DurationSeconds; got != 0 {
+		t.Errorf("untimed task DurationSeconds = %d, want 0", got)
+	}
+	if ps.Features[0].DurationSeconds != 65*60 || ps.DurationSeconds != 65*60 {
+		t.Errorf("feature/plan DurationSeconds = %d/%d, want %d", ps.Features[0].DurationSeconds, ps.DurationSeconds, 65*60)
+	}
+
+	detailed := FormatDetailed(ps)
+	for _, want := range []string{"slug: auth · 1h5m", "Time: 001 45m, 002 20m (running)"} {
+		if !strings.Contains(detailed, want) {
+			t.Errorf("expected %q in detailed view, got:\n%s", want, detailed)
+		}
+	}
+	if strings.Count(detailed, "Time:") != 1 {
+		t.Errorf("expected only the timed task to list session times, got:\n%s", detailed)
+	}
+	if out := FormatSummary(plans); !strings.Contains(out, "Token Management [0/2 tasks] · 1h5m") {
+		t.Errorf("expected feature time in summary view, got:\n%s", out)
+	}
+
+	out, err := FormatJSON(plans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"duration_seconds": 3900`) || !strings.Contains(out, `"running": true`) {
+		t.Errorf("expected durations in JSON, got:\n%s", out)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{20 * time.Second, "<1m"},
+		{45 * time.Minute, "45m"},
+		{90*time.Minute + 20*time.Second, "1h30m"},
+		{2 * time.Hour, "2h"},
+		{26*time.Hour + 5*time.Minute, "26h5m"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRunFlagsStaleSessions(t *testing.T) {
+	root := t.TempDir()
+	writePlanFile(t, root, "auth", testPlan)
+
+	dir := filepath.Join(root, ".etch", "progress")
+	os.MkdirAll(dir, 0o755)
+	write := func(name, header string) {
+		t.Helper()
+		content := "---\nschema: 3\nplan: auth\n" + header + "---\n\n# Session\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("auth--task-1.1--001.md", "task: \"1.1\"\nsession: 1\nstatus: in_progress\nstarted: 2026-03-01T09:00:00Z\nheartbeat: 2026-03-01T09:20:00Z\n")
+	write("auth--task-1.2--001.md", "task: \"1.2\"\nsession: 1\nstatus: in_progress\nstarted: 2026-03-01T09:00:00Z\nheartbeat: 2026-03-01T09:55:00Z\n")
+	// An abandoned session leaves the plan's status in place.
+	write("auth--task-2.1--001.md", "task: \"2.1\"\nsession: 1\nstatus: abandoned\nstarted: 2026-03-01T08:00:00Z\nended: 2026-03-01T08:30:00Z\nactive: 30m0s\n")
+
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC) }
+
+	plans, err := Run(root, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := plans[0]
+	stale, fresh := ps.Features[0].Tasks[0], ps.Features[0].Tasks[1]
+	if !stale.Stale || !stale.LastSeen.Equal(time.Date(2026, 3, 1, 9, 20, 0, 0, time.UTC)) {
+		t.Errorf("task 1.1: Stale %v, LastSeen %v", stale.Stale, stale.LastSeen)
+	}
+	if fresh.Stale {
+		t.Error("task 1.2 had a heartbeat within stale_after and should not be stale")
+	}
+	if got := ps.Features[1].Tasks[0]; got.Status != models.StatusPending || got.LastOutcome != "abandoned" {
+		t.Errorf("task 2.1: status %s, last outcome %q", got.Status, got.LastOutcome)
+	}
+
+	if out := FormatSummary(plans); strings.Count(out, "⚠ stale") != 1 {
+		t.Errorf("expected one stale task in summary view, got:\n%s", out)
+	}
+	if out := FormatDetailed(ps); !strings.Contains(out, "Stale: no activity since") || !strings.Contains(out, "etch recover auth -t 1.1") {
+		t.Errorf("expected stale hint in detailed view, got:\n%s", out)
+	}
+
+	// The threshold comes from [status] stale_after.
+	os.WriteFile(filepath.Join(root, ".etch", "config.toml"), []byte("[status]\nstale_after = \"1h\"\n"), 0o644)
+	plans, err = Run(root, "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plans[0].Features[0].Tasks[0].Stale {
+		t.Error("task 1.1 should not be stale with stale_after = 1h")
+	}
+}
@@ -0,0 +1,182 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// Launcher runs one session for a task and returns when it ends. A non-nil
+// error means the session itself failed (for example a non-zero exit),
+// unless it wraps ErrSkip.
+type Launcher func(ctx context.Context, taskID string) error
+
+// ErrSkip is wrapped by a Launcher's error when the task can't be started
+// after all, for example because someone else claimed it. The task is
+// passed over rather than counted as failed.
+var ErrSkip = errors.New("task skipped")
+
+// Skip marks err, explaining why a task can't be started, as wrapping ErrSkip.
+func Skip(err error) error {
+	return skipError{err}
+}
+
+type skipError struct{ error }
+
+func (e skipError) Is(target error) bool { return target == ErrSkip }
+func (e skipError) Unwrap() error        { return e.error }
+
+// Reconciler returns the plan's current status, reconciling progress files
+// into the plan (typically a wrapper around status.Run).
+type Reconciler func() (status.PlanStatus, error)
+
+// Options configures a swarm run.
+type Options struct {
+	Jobs      int        // maximum concurrent sessions (at least 1)
+	Launch    Launcher   // starts a session for a task
+	Reconcile Reconciler // reloads plan status after each session
+	Out       io.Writer  // receives one line per scheduling event; may be nil
+}
+
+// Outcome records how one task's session ended.
+type Outcome struct {
+	TaskID   string
+	Status   models.Status // reconciled task status after the session
+	Err      error
+	Duration time.Duration
+}
+
+// Result summarizes a swarm run.
+type Result struct {
+	Outcomes []Outcome
+	Failed   *Outcome // first task that did not complete, if any
+	PlanDone bool
+	Final    status.PlanStatus
+}
+
+// Run launches sessions for every runnable task, up to opts.Jobs at a time,
+// reconciling status after each session ends and scheduling tasks that
+// become unblocked. It stops scheduling once a task fails (sessions already
+// running are allowed to finish) or when nothing is left to run.
+//
+// A session succeeds only if it exits cleanly and its task is reconciled as
+// completed. The returned error is reserved for reconciliation failures;
+// task failures are reported in Result.Failed.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	logf := func(format string, args ...any) {
+		if opts.Out != nil {
+			fmt.Fprintf(opts.Out, format+"\n", args...)
+		}
+	}
+
+	var res Result
+	ps, err := opts.Reconcile()
+	if err != nil {
+		return res, err
+	}
+
+	launched := make(map[string]bool)
+	done := make(chan Outcome)
+	running := 0
+	stopping := false
+
+	for {
+		if !stopping && ctx.Err() == nil {
+			for _, id := range Frontier(ps) {
+				if running >= jobs {
+					break
+				}
+				if launched[id] {
+					continue
+				}
+				launched[id] = true
+				running++
+				logf("▶ started task %s", id)
+				go func(id string) {
+					start := time.Now()
+					err := opts.Launch(ctx, id)
+					done <- Outcome{TaskID: id, Err: err, Duration: time.Since(start)}
+				}(id)
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		o := <-done
+		running--
+		if errors.Is(o.Err, ErrSkip) {
+			logf("– skipped task %s: %v", o.TaskID, o.Err)
+			continue
+		}
+
+		next, err := opts.Reconcile()
+		if err != nil {
+			// Let running sessions finish before reporting the error.
+			for ; running > 0; running-- {
+				res.Outcomes = append(res.Outcomes, <-done)
+			}
+			return res, err
+		}
+		ps = next
+
+		o.Status = taskStatus(ps, o.TaskID)
+		if o.Err == nil && o.Status != models.StatusCompleted {
+			o.Err = fmt.Errorf("session ended with task %s %s", o.TaskID, o.Status)
+		}
+		res.Outcomes = append(res.Outcomes, o)
+
+		if o.Err != nil {
+			logf("✗ task %s failed after %s: %v", o.TaskID, o.Duration.Round(time.Second), o.Err)
+			if res.Failed == nil {
+				failed := o
+				res.Failed = &failed
+				stopping = true
+				if running > 0 {
+					logf("  waiting for %d running session(s) to finish", running)
+				}
+			}
+		} else {
+			logf("✓ task %s completed in %s", o.TaskID, o.Duration.Round(time.Second))
+		}
+	}
+
+	res.Final = ps
+	res.PlanDone = ps.TotalTasks > 0 && ps.CompletedTasks == ps.TotalTasks
+	return res, nil
+}
+
+// Frontier returns the IDs of tasks that can start now: pending tasks whose
+// dependencies are all completed, in plan order.
+func Frontier(ps status.PlanStatus) []string {
+	var ids []string
+	for _, f := range ps.Features {
+		for _, t := range f.Tasks {
+			if t.Status == models.StatusPending && !t.IsBlocked {
+				ids = append(ids, t.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func taskStatus(ps status.PlanStatus, id string) models.Status {
+	for _, f := range ps.Features {
+		for _, t := range f.Tasks {
+			if t.ID == id {
+				return t.Status
+			}
+		}
+	}
+	return models.StatusPending
+}
@@ -0,0 +1,213 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/status"
+)
+
+// fakePlan simulates a plan whose tasks complete when their session runs.
+type fakePlan struct {
+	mu       sync.Mutex
+	order    []string
+	deps     map[string][]string
+	statuses map[string]models.Status
+	outcome  map[string]models.Status // status a session leaves behind (default completed)
+	exitErr  map[string]error
+
+	running, maxRunning int
+	started             []string
+}
+
+func newFakePlan(order []string, deps map[string][]string) *fakePlan {
+	p := &fakePlan{
+		order:    order,
+		deps:     deps,
+		statuses: make(map[string]models.Status),
+		outcome:  make(map[string]models.Status),
+		exitErr:  make(map[string]error),
+	}
+	for _, id := range order {
+		p.statuses[id] = models.StatusPending
+	}
+	return p
+}
+
+func (p *fakePlan) launch(ctx context.Context, id string) error {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.maxRunning {
+		p.maxRunning = p.running
+	}
+	p.started = append(p.started, id)
+	p.statuses[id] = models.StatusInProgress
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	if s, ok := p.outcome[id]; ok {
+		p.statuses[id] = s
+	} else {
+		p.statuses[id] = models.StatusCompleted
+	}
+	return p.exitErr[id]
+}
+
+func (p *fakePlan) reconcile() (status.PlanStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ps := status.PlanStatus{Slug: "fake", TotalTasks: len(p.order)}
+	fs := status.FeatureStatus{Number: 1}
+	for _, id := range p.order {
+		ts := status.TaskStatus{ID: id, Status: p.statuses[id]}
+		if ts.Status == models.StatusCompleted {
+			ps.CompletedTasks++
+		}
+		if ts.Status == models.StatusPending {
+			for _, d := range p.deps[id] {
+				if p.statuses[d] != models.StatusCompleted {
+					ts.IsBlocked = true
+				}
+			}
+		}
+		fs.Tasks = append(fs.Tasks, ts)
+	}
+	ps.Features = []status.FeatureStatus{fs}
+	return ps, nil
+}
+
+func (p *fakePlan) options(jobs int) Options {
+	return Options{Jobs: jobs, Launch: p.launch, Reconcile: p.reconcile}
+}
+
+func TestRun_CompletesPlanRespectingDeps(t *testing.T) {
+	p := newFakePlan([]string{"1.1", "1.2", "1.3", "2.1"}, map[string][]string{
+		"1.3": {"1.1", "1.2"},
+		"2.1": {"1.3"},
+	})
+
+	res, err := Run(context.Background(), p.options(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.PlanDone || res.Failed != nil {
+		t.Fatalf("expected plan done without failures, got %+v", res)
+	}
+	if len(res.Outcomes) != 4 {
+		t.Errorf("expected 4 outcomes, got %d", len(res.Outcomes))
+	}
+	if p.maxRunning != 2 {
+		t.Errorf("expected 2 concurrent sessions, got %d", p.maxRunning)
+	}
+	got := strings.Join(p.started, ",")
+	if !strings.HasSuffix(got, "1.3,2.1") {
+		t.Errorf("dependent tasks launched out of order: %s", got)
+	}
+}
+
+func TestRun_RespectsJobLimit(t *testing.T) {
+	p := newFakePlan([]string{"1.1", "1.2", "1.3", "1.4"}, nil)
+
+	if _, err := Run(context.Background(), p.options(1)); err != nil {
+		t.Fatal(err)
+	}
+	if p.maxRunning != 1 {
+		t.Errorf("expected at most 1 concurrent session, got %d", p.maxRunning)
+	}
+}
+
+func TestRun_StopsSchedulingAfterFailure(t *testing.T) {
+	p := newFakePlan([]string{"1.1", "1.2", "1.3"}, map[string][]string{
+		"1.2": {"1.1"},
+		"1.3": {"1.1"},
+	})
+	p.exitErr["1.1"] = errors.New("exit 1")
+	p.outcome["1.1"] = models.StatusFailed
+
+	res, err := Run(context.Background(), p.options(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed == nil || res.Failed.TaskID != "1.1" || res.Failed.Status != models.StatusFailed {
+		t.Fatalf("expected 1.1 to fail, got %+v", res.Failed)
+	}
+	if len(p.started) != 1 || res.PlanDone {
+		t.Errorf("expected no further tasks after failure, started %v", p.started)
+	}
+}
+
+func TestRun_CleanExitWithoutCompletionIsFailure(t *testing.T) {
+	p := newFakePlan([]string{"1.1"}, nil)
+	p.outcome["1.1"] = models.StatusInProgress
+
+	res, err := Run(context.Background(), p.options(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed == nil || !strings.Contains(res.Failed.Err.Error(), "in_progress") {
+		t.Fatalf("expected failure for unfinished task, got %+v", res.Failed)
+	}
+}
+
+func TestRun_SkippedTaskIsNotFailure(t *testing.T) {
+	p := newFakePlan([]string{"1.1", "1.2", "1.3"}, map[string][]string{"1.3": {"1.1"}})
+	opts := p.options(2)
+	opts.Launch = func(ctx context.Context, id string) error {
+		if id == "1.1" {
+			return Skip(errors.New("claimed elsewhere"))
+		}
+		return p.launch(ctx, id)
+	}
+
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed != nil {
+		t.Fatalf("expected no failure, got %+v", res.Failed)
+	}
+	if len(res.Outcomes) != 1 || res.Outcomes[0].TaskID != "1.2" || res.PlanDone {
+		t.Errorf("expected only 1.2 to run, got %+v", res.Outcomes)
+	}
+}
+
+func TestRun_ReconcileError(t *testing.T) {
+	calls := 0
+	opts := Options{
+		Jobs:   1,
+		Launch: func(ctx context.Context, id string) error { return nil },
+		Reconcile: func() (status.PlanStatus, error) {
+			calls++
+			if calls > 1 {
+				return status.PlanStatus{}, errors.New("boom")
+			}
+			return status.PlanStatus{Features: []status.FeatureStatus{{Tasks: []status.TaskStatus{
+				{ID: "1.1", Status: models.StatusPending},
+			}}}}, nil
+		},
+	}
+	if _, err := Run(context.Background(), opts); err == nil {
+		t.Fatal("expected reconcile error")
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	ps := status.PlanStatus{Features: []status.FeatureStatus{{Tasks: []status.TaskStatus{
+		{ID: "1.1", Status: models.StatusCompleted},
+		{ID: "1.2", Status: models.StatusPending},
+		{ID: "1.3", Status: models.StatusPending, IsBlocked: true},
+		{ID: "1.4", Status: models.StatusInProgress},
+	}}}}
+	if got := Frontier(ps); len(got) != 1 || got[0] != "1.2" {
+		t.Errorf("Frontier = %v, want [1.2]", got)
+	}
+}
@@ -0,0 +1,242 @@
+// Package transition moves tasks between statuses. Every way of changing a
+// task's status — etch progress, the MCP server and the web API — goes
+// through it, so they all follow the same rules: a task someone else has
+// claimed can't be started, a task whose verify commands fail can't be
+// completed, and hooks may veto a change and are told about it once it is
+// saved.
+package transition
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gsigler/etch/internal/claim"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/hooks"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/serializer"
+	"github.com/gsigler/etch/internal/verify"
+)
+
+// Start marks a task in progress in the plan file and its latest session
+// file, creating a session if there is none. It refuses a task someone else
+// has claimed. It returns the session number.
+func Start(rootDir string, plan *models.Plan, task *models.Task) (int, error) {
+	if held, ok := claim.Others(rootDir, time.Now())[claim.Ref(plan.Slug, task.FullID())]; ok {
+		return 0, held.HeldError()
+	}
+
+	notify, err := Fire(rootDir, plan, task, models.StatusInProgress, "")
+	if err != nil {
+		return 0, err
+	}
+
+	// Update task status in the plan file.
+	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusInProgress); err != nil {
+		return 0, etcherr.WrapIO("updating task status", err).
+			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
+	}
+
+	// Find or create session progress file.
+	allProgress, err := progress.ReadAll(rootDir, plan.Slug)
+	if err != nil {
+		return 0, etcherr.WrapIO("reading progress files", err)
+	}
+
+	var sessionNum int
+	var progressPath string
+
+	sessions := allProgress[task.FullID()]
+	if len(sessions) > 0 && sessions[len(sessions)-1].Status != models.SessionAbandoned {
+		// Reuse the latest session file.
+		latest := sessions[len(sessions)-1]
+		sessionNum = latest.SessionNumber
+		progressPath = progress.SessionPath(rootDir, plan.Slug, task.FullID(), sessionNum)
+	} else {
+		// Create a new session file.
+		progressPath, err = progress.WriteSession(rootDir, plan, task)
+		if err != nil {
+			return 0, etcherr.WrapIO("creating progress file", err)
+		}
+		// Extract session number from filename.
+		sessionNum = extractSessionNumber(progressPath)
+	}
+
+	// Update the status line in the progress file.
+	if err := progress.UpdateStatus(progressPath, "in_progress"); err != nil {
+		return 0, etcherr.WrapIO("updating progress file status", err)
+	}
+	notify()
+	return sessionNum, nil
+}
+
+var sessionNumRe = regexp.MustCompile(`--(\d{3})\.md$`)
+
+func extractSessionNumber(path string) int {
+	m := sessionNumRe.FindStringSubmatch(path)
+	if m == nil {
+		return 1
+	}
+	var n int
+	fmt.Sscanf(m[1], "%d", &n)
+	return n
+}
+
+// Complete runs the task's verify commands, writing their results to out,
+// and marks it completed if they pass. It returns the criteria still
+// unchecked.
+func Complete(ctx context.Context, rootDir string, plan *models.Plan, task *models.Task, out io.Writer) ([]string, error) {
+	// Criteria with verify commands must pass before the task can complete.
+	if verify.Verifiable(task) {
+		fmt.Fprintf(out, "Verifying Task %s\n", task.FullID())
+		results, err := Verify(ctx, rootDir, plan, task, VerifyTimeout)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(out, FormatVerifyResults(results))
+		if err := VerifyFailures(task, results); err != nil {
+			return nil, err
+		}
+	}
+
+	notify, err := Fire(rootDir, plan, task, models.StatusCompleted, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Update plan file status to completed.
+	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), models.StatusCompleted); err != nil {
+		return nil, etcherr.WrapIO("updating task status", err).
+			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
+	}
+
+	// Update progress file status to completed.
+	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
+	if err == nil {
+		if err := progress.UpdateStatus(sessionPath, "completed"); err != nil {
+			return nil, etcherr.WrapIO("updating progress file status", err)
+		}
+	}
+	notify()
+
+	var unchecked []string
+	for _, c := range task.Criteria {
+		if !c.IsMet {
+			unchecked = append(unchecked, c.Description)
+		}
+	}
+	return unchecked, nil
+}
+
+// Stop marks a task blocked or failed in the plan and its latest session
+// file, and records the reason under Blockers.
+func Stop(rootDir string, plan *models.Plan, task *models.Task, status models.Status, reason string) error {
+	notify, err := Fire(rootDir, plan, task, status, reason)
+	if err != nil {
+		return err
+	}
+
+	// Update plan file status.
+	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), status); err != nil {
+		return etcherr.WrapIO("updating task status", err).
+			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
+	}
+
+	sessionPath, err := LatestSession(rootDir, plan, task)
+	if err != nil {
+		return err
+	}
+
+	// Update progress file status.
+	if err := progress.UpdateStatus(sessionPath, string(status)); err != nil {
+		return etcherr.WrapIO("updating progress file status", err)
+	}
+
+	// Append reason to Blockers section.
+	entry := fmt.Sprintf("- %s", reason)
+	if err := progress.AppendToSection(sessionPath, "Blockers", entry); err != nil {
+		return etcherr.WrapIO("appending to blockers section", err)
+	}
+	notify()
+	return nil
+}
+
+// Set moves a task to any status, following the same rules as Start,
+// Complete and Stop; verify output is written to out. Unlike Stop, it
+// accepts a blocked or failed task without a session, changing only the
+// plan, and records a reason only when one is given. Moving back to pending
+// runs no hooks, as with etch recover.
+func Set(ctx context.Context, rootDir string, plan *models.Plan, task *models.Task, status models.Status, reason string, out io.Writer) error {
+	switch status {
+	case models.StatusInProgress:
+		_, err := Start(rootDir, plan, task)
+		return err
+	case models.StatusCompleted:
+		_, err := Complete(ctx, rootDir, plan, task, out)
+		return err
+	}
+
+	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
+	if err == nil && reason != "" && status != models.StatusPending {
+		return Stop(rootDir, plan, task, status, reason)
+	}
+
+	notify := func() {}
+	if status != models.StatusPending {
+		if notify, err = Fire(rootDir, plan, task, status, reason); err != nil {
+			return err
+		}
+	}
+	if err := serializer.UpdateTaskStatus(plan.FilePath, task.FullID(), status); err != nil {
+		return etcherr.WrapIO("updating task status", err).
+			WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
+	}
+	// Status is reconciled from the latest session, so it must agree or
+	// the change would be undone on the next read.
+	if sessionPath != "" {
+		if err := progress.UpdateStatus(sessionPath, string(status)); err != nil {
+			return etcherr.WrapIO("updating progress file status", err)
+		}
+	}
+	notify()
+	return nil
+}
+
+// Fire runs the hook for task moving to status, if it isn't there already.
+// An error means the hook vetoed the change. Once the change is saved, the
+// caller calls notify to send it to webhooks.
+func Fire(rootDir string, plan *models.Plan, task *models.Task, status models.Status, reason string) (notify func(), err error) {
+	if task.Status == status {
+		return func() {}, nil
+	}
+	p := hooks.ForTask(rootDir, plan, task, status)
+	p.Reason = reason
+	if path, session, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID()); err == nil {
+		p.Session, p.ProgressFile = session, path
+	}
+	if err := hooks.Fire(rootDir, p); err != nil {
+		return nil, err
+	}
+	return func() { hooks.Notify(rootDir, p) }, nil
+}
+
+// LatestSession returns the task's latest session file, or an error telling
+// the caller to start one.
+func LatestSession(rootDir string, plan *models.Plan, task *models.Task) (string, error) {
+	sessionPath, _, err := progress.FindLatestSessionPath(rootDir, plan.Slug, task.FullID())
+	if err != nil {
+		return "", etcherr.WrapIO("finding session file", err).
+			WithHint(fmt.Sprintf("run 'etch progress start -p %s -t %s' first to create a session", plan.Slug, task.FullID()))
+	}
+	return sessionPath, nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
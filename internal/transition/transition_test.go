@@ -0,0 +1,89 @@
+package transition
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+	"github.com/gsigler/etch/internal/progress"
+)
+
+const testPlan = `# Plan: Test Plan
+
+### Task 1: Do the thing [pending]
+**Complexity:** small
+
+**Acceptance Criteria:**
+- [ ] Thing works
+`
+
+// setTask moves task 1.1 of the test plan to status with Set, re-reading
+// the plan first as each etch command would.
+func setTask(t *testing.T, dir string, status models.Status, reason string) error {
+	t.Helper()
+	plan, err := parser.ParseFile(filepath.Join(dir, ".etch", "plans", "test-plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Slug = "test-plan"
+	return Set(context.Background(), dir, plan, plan.TaskByID("1.1"), status, reason, io.Discard)
+}
+
+func TestSet(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use POSIX shell commands")
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".etch", "plans"), 0o755)
+	planPath := filepath.Join(dir, ".etch", "plans", "test-plan.md")
+	os.WriteFile(planPath, []byte(testPlan), 0o644)
+	os.WriteFile(filepath.Join(dir, ".etch", "config.toml"), []byte(`
+[hooks]
+on_task_failed = "exit 1"
+veto = ["on_task_failed"]
+`), 0o644)
+
+	latest := func() models.SessionProgress {
+		t.Helper()
+		all, err := progress.ReadAll(dir, "test-plan")
+		if err != nil || len(all["1.1"]) == 0 {
+			t.Fatalf("no session for task 1.1: %v", err)
+		}
+		return all["1.1"][len(all["1.1"])-1]
+	}
+
+	if err := setTask(t, dir, models.StatusInProgress, ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := latest(); s.Status != "in_progress" {
+		t.Errorf("session status = %q, want in_progress", s.Status)
+	}
+
+	if err := setTask(t, dir, models.StatusFailed, "broken"); err == nil || !strings.Contains(err.Error(), "on_task_failed hook failed") {
+		t.Fatalf("expected the on_task_failed veto, got %v", err)
+	}
+	if data, _ := os.ReadFile(planPath); !strings.Contains(string(data), "[in_progress]") {
+		t.Errorf("a vetoed task should stay in progress:\n%s", data)
+	}
+
+	if err := setTask(t, dir, models.StatusBlocked, "waiting on API keys"); err != nil {
+		t.Fatal(err)
+	}
+	if s := latest(); s.Status != "blocked" || !strings.Contains(s.Blockers, "waiting on API keys") {
+		t.Errorf("session = %+v, want blocked with the reason under Blockers", s)
+	}
+
+	if err := setTask(t, dir, models.StatusPending, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(planPath)
+	if s := latest(); s.Status != "pending" || !strings.Contains(string(data), "[pending]") {
+		t.Errorf("expected the plan and session pending, session %q:\n%s", s.Status, data)
+	}
+}
@@ -0,0 +1,109 @@
+package transition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/serializer"
+	"github.com/gsigler/etch/internal/verify"
+	"github.com/gsigler/etch/internal/worktree"
+)
+
+// VerifyTimeout bounds each criterion's verify command.
+const VerifyTimeout = 10 * time.Minute
+
+// Verify runs the task's verify commands and records each outcome in the
+// plan file and the task's progress files. Commands run in the task's
+// worktree when it has one, so they check the task's own branch.
+//
+// A passing criterion is ticked in the latest session; a failing one is
+// unticked in every session, since reconciliation ticks the plan when any
+// session has the criterion ticked.
+func Verify(ctx context.Context, rootDir string, plan *models.Plan, task *models.Task, timeout time.Duration) ([]verify.Result, error) {
+	dir := rootDir
+	if wt := worktree.Path(rootDir, plan.Slug, task.FullID()); dirExists(wt) {
+		dir = wt
+	}
+	results := verify.Run(ctx, task, dir, timeout)
+
+	sessions, err := progress.ReadAll(rootDir, plan.Slug)
+	if err != nil {
+		return nil, etcherr.WrapIO("reading progress files", err)
+	}
+	var sessionPaths []string
+	for _, s := range sessions[task.FullID()] {
+		sessionPaths = append(sessionPaths, progress.SessionPath(rootDir, plan.Slug, task.FullID(), s.SessionNumber))
+	}
+
+	for _, r := range results {
+		desc := r.Criterion.Description
+		if err := serializer.UpdateCriterion(plan.FilePath, task.FullID(), desc, r.Passed); err != nil {
+			return nil, etcherr.WrapIO("updating criterion", err).
+				WithHint(fmt.Sprintf("could not update task %s in plan file", task.FullID()))
+		}
+		for i := range task.Criteria {
+			if task.Criteria[i].Description == desc {
+				task.Criteria[i].IsMet = r.Passed
+			}
+		}
+
+		// Best-effort: older sessions may predate the criterion.
+		if r.Passed {
+			if n := len(sessionPaths); n > 0 {
+				progress.SetCriterion(sessionPaths[n-1], desc, true)
+			}
+			continue
+		}
+		for _, p := range sessionPaths {
+			progress.SetCriterion(p, desc, false)
+		}
+	}
+	return results, nil
+}
+
+// VerifyFailures returns an error naming the failed criteria, or nil when
+// every verify command passed.
+func VerifyFailures(task *models.Task, results []verify.Result) error {
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return etcherr.Project(fmt.Sprintf("%d of %d verifiable criteria failed for task %s", failed, len(results), task.FullID())).
+		WithHint(fmt.Sprintf("fix the failures and re-run 'etch verify -t %s'", task.FullID()))
+}
+
+// verifyOutputLines is how much of a failed command's output is shown.
+const verifyOutputLines = 10
+
+// FormatVerifyResults renders one line per verify command, with the tail of
+// the output of any that failed.
+func FormatVerifyResults(results []verify.Result) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.Passed {
+			fmt.Fprintf(&b, "  ✓ %s (%s)\n", r.Criterion.Description, r.Duration.Round(time.Millisecond))
+			continue
+		}
+		fmt.Fprintf(&b, "  ✗ %s (%v)\n", r.Criterion.Description, r.Err)
+		lines := strings.Split(strings.TrimRight(r.Output, "\n"), "\n")
+		if len(lines) > verifyOutputLines {
+			lines = lines[len(lines)-verifyOutputLines:]
+		}
+		for _, line := range lines {
+			if line != "" {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
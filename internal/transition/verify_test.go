@@ -0,0 +1,28 @@
+package transition
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/verify"
+)
+
+func TestFormatVerifyResults(t *testing.T) {
+	var output strings.Builder
+	for i := 1; i <= 15; i++ {
+		output.WriteString("line " + string(rune('a'+i)) + "\n")
+	}
+	out := FormatVerifyResults([]verify.Result{
+		{Criterion: models.Criterion{Description: "ok"}, Passed: true, Duration: 1500 * time.Millisecond},
+		{Criterion: models.Criterion{Description: "bad"}, Err: os.ErrDeadlineExceeded, Output: output.String()},
+	})
+	if !strings.Contains(out, "✓ ok (1.5s)") || !strings.Contains(out, "✗ bad") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if strings.Contains(out, "line b\n") || !strings.Contains(out, "line p") {
+		t.Errorf("expected only the last %d output lines:\n%s", verifyOutputLines, out)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/gsigler/etch/internal/atomicfile"
+	"github.com/gsigler/etch/internal/events"
 	"github.com/gsigler/etch/internal/serializer"
 )
 
@@ -16,6 +18,12 @@ var planCommentContRe = regexp.MustCompile(`^>\s*(.+)$`)
 // after the task heading section identified by taskID. Multi-line comments
 // have continuation lines prefixed with `> `.
 func AddComment(path string, taskID string, comment string) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading plan file: %w", err)
@@ -71,12 +79,21 @@ func AddComment(path string, taskID string, comment string) error {
 	result = append(result, insertion...)
 	result = append(result, lines[insertIdx:]...)
 
-	return os.WriteFile(path, []byte(strings.Join(result, "\n")), 0644)
+	if err := atomicfile.WriteFile(path, []byte(strings.Join(result, "\n")), 0644); err != nil {
+		return err
+	}
+	return events.Record(path, events.Event{Actor: events.Actor(), Type: events.CommentAdded, Task: taskID, After: comment})
 }
 
 // DeleteComment removes a comment from the plan file. It matches by the
 // comment text content within the specified task section.
 func DeleteComment(path string, taskID string, commentText string) error {
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading plan file: %w", err)
@@ -150,7 +167,10 @@ func DeleteComment(path string, taskID string, commentText string) error {
 	result = append(result, lines[:deleteStart]...)
 	result = append(result, lines[deleteEnd:]...)
 
-	return os.WriteFile(path, []byte(strings.Join(result, "\n")), 0644)
+	if err := atomicfile.WriteFile(path, []byte(strings.Join(result, "\n")), 0644); err != nil {
+		return err
+	}
+	return events.Record(path, events.Event{Actor: events.Actor(), Type: events.CommentDeleted, Task: taskID, Before: commentText})
 }
 
 // buildCommentLines formats a comment string as markdown blockquote lines.
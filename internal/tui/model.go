@@ -69,6 +69,9 @@ type Model struct {
 	newPlanContent   string
 	backupPath       string
 	applyCommentCount int
+	refineCh         <-chan tea.Msg // active refinement stream, nil when idle
+	refineCancel     func()         // stops the active refinement
+	streamed         string         // output streamed so far by the refine function
 }
 
 // New creates a new TUI model for the given plan.
@@ -103,6 +106,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case editorResultMsg:
 		return m.handleEditorResult(msg)
 
+	case refinementTokenMsg:
+		return m.handleRefinementToken(msg)
+
 	case refinementResultMsg:
 		return m.handleRefinementResult(msg)
 
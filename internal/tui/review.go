@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -9,13 +10,17 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/lipgloss"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gsigler/etch/internal/atomicfile"
 	"github.com/gsigler/etch/internal/models"
 )
 
 // RefineFunc is the function signature for plan refinement.
 // It takes the current plan content and review comments, and returns the
-// refined plan content.
-type RefineFunc func(planContent string, comments []string) (string, error)
+// refined plan content. onToken is called with each chunk of streamed output
+// so the loading view can show progress; it may be called from any goroutine.
+// ctx is cancelled when the user abandons the refinement, and the function
+// should then stop its request or subprocess and return.
+type RefineFunc func(ctx context.Context, planContent string, comments []string, onToken func(string)) (string, error)
 
 // Option configures the TUI model.
 type Option func(*Model)
@@ -27,10 +32,17 @@ func WithRefineFunc(fn RefineFunc) Option {
 
 // refinementResultMsg carries the result of an async refinement call.
 type refinementResultMsg struct {
+	ch         <-chan tea.Msg
 	newContent string
 	err        error
 }
 
+// refinementTokenMsg carries a chunk of streamed refinement output.
+type refinementTokenMsg struct {
+	ch   <-chan tea.Msg
+	text string
+}
+
 // collectComments gathers all review comments from the plan, prefixed with
 // their task ID for context.
 func collectComments(plan *models.Plan) []string {
@@ -64,17 +76,43 @@ func restoreBackup(backupPath, origPath string) error {
 	if err != nil {
 		return fmt.Errorf("reading backup: %w", err)
 	}
-	if err := os.WriteFile(origPath, data, 0644); err != nil {
+	if err := atomicfile.Replace(origPath, data, 0644); err != nil {
 		return fmt.Errorf("restoring backup: %w", err)
 	}
 	return nil
 }
 
-// startRefinement fires an async command that calls the refine function.
-func startRefinement(refineFn RefineFunc, planContent string, comments []string) tea.Cmd {
+// startRefinement runs the refine function in the background and returns the
+// channel its streamed tokens and final result are delivered on. Once ctx is
+// cancelled nothing more is sent, so the goroutine never blocks on a channel
+// that is no longer read. The channel is closed when the goroutine ends.
+func startRefinement(ctx context.Context, refineFn RefineFunc, planContent string, comments []string) <-chan tea.Msg {
+	ch := make(chan tea.Msg, 64)
+	send := func(msg tea.Msg) {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(ch)
+		newContent, err := refineFn(ctx, planContent, comments, func(text string) {
+			send(refinementTokenMsg{ch: ch, text: text})
+		})
+		send(refinementResultMsg{ch: ch, newContent: newContent, err: err})
+	}()
+	return ch
+}
+
+// waitForRefinement returns a command that blocks until the next message
+// arrives on a refinement channel, or it is closed.
+func waitForRefinement(ch <-chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		newContent, err := refineFn(planContent, comments)
-		return refinementResultMsg{newContent: newContent, err: err}
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
 	}
 }
 
@@ -130,11 +168,15 @@ func (m Model) updateApplyConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Switch to loading mode with spinner.
 		m.mode = modeLoading
 		m.spinner = newSpinner()
+		m.streamed = ""
 
 		comments := collectComments(m.plan)
+		ctx, cancel := context.WithCancel(context.Background())
+		m.refineCancel = cancel
+		m.refineCh = startRefinement(ctx, m.refineFn, m.oldPlanContent, comments)
 		return m, tea.Batch(
 			m.spinner.Tick,
-			startRefinement(m.refineFn, m.oldPlanContent, comments),
+			waitForRefinement(m.refineCh),
 		)
 	default:
 		m.mode = modeNormal
@@ -143,11 +185,21 @@ func (m Model) updateApplyConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// handleRefinementToken appends streamed output to the loading view. Tokens
+// from a cancelled refinement are drained until its channel closes.
+func (m Model) handleRefinementToken(msg refinementTokenMsg) (tea.Model, tea.Cmd) {
+	if msg.ch == m.refineCh && m.mode == modeLoading {
+		m.streamed += msg.text
+	}
+	return m, waitForRefinement(msg.ch)
+}
+
 // handleRefinementResult processes the async refinement response.
 func (m Model) handleRefinementResult(msg refinementResultMsg) (tea.Model, tea.Cmd) {
-	if m.mode != modeLoading {
+	if msg.ch != m.refineCh || m.mode != modeLoading {
 		return m, nil
 	}
+	m.stopRefinement()
 	if msg.err != nil {
 		m.mode = modeNormal
 		m.statusMsg = "Refinement error: " + msg.err.Error()
@@ -164,10 +216,21 @@ func (m Model) handleRefinementResult(msg refinementResultMsg) (tea.Model, tea.C
 	return m, nil
 }
 
+// stopRefinement cancels the active refinement, if any, and forgets its
+// stream.
+func (m *Model) stopRefinement() {
+	if m.refineCancel != nil {
+		m.refineCancel()
+		m.refineCancel = nil
+	}
+	m.refineCh = nil
+}
+
 // updateLoadingKey handles keypresses while the spinner is showing.
 func (m Model) updateLoadingKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if msg.String() == "esc" {
 		m.mode = modeNormal
+		m.stopRefinement()
 		m.statusMsg = "Refinement cancelled"
 		if m.backupPath != "" {
 			os.Remove(m.backupPath)
@@ -185,7 +248,7 @@ func (m Model) updateDiff(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
 		// Accept: write new content to plan file.
-		if err := os.WriteFile(m.planPath, []byte(m.newPlanContent), 0644); err != nil {
+		if err := atomicfile.Replace(m.planPath, []byte(m.newPlanContent), 0644); err != nil {
 			m.statusMsg = "Error writing plan: " + err.Error()
 			m.mode = modeNormal
 			return m, nil
@@ -239,6 +302,7 @@ func (m *Model) cleanupRefinement() {
 	m.backupPath = ""
 	m.oldPlanContent = ""
 	m.newPlanContent = ""
+	m.streamed = ""
 	m.diffLines = nil
 	m.diffOffset = 0
 }
@@ -281,19 +345,48 @@ func (m Model) viewLoading() string {
 	b.WriteByte('\n')
 
 	viewH := m.height - 2
-	topPad := viewH / 2
-	for i := 0; i < topPad; i++ {
-		b.WriteByte('\n')
-	}
 
 	spinnerText := m.spinner.View() + " Sending comments for AI refinement..."
+	if m.streamed != "" {
+		spinnerText = m.spinner.View() + fmt.Sprintf(" Receiving refined plan (%d chars)...", len(m.streamed))
+	}
 	pad := (m.width - lipgloss.Width(spinnerText)) / 2
 	if pad < 0 {
 		pad = 0
 	}
+
+	// Show the tail of the streamed output below the spinner once tokens arrive.
+	var tail []string
+	if m.streamed != "" && viewH > 3 {
+		tail = strings.Split(strings.TrimRight(m.streamed, "\n"), "\n")
+		if limit := viewH - 3; len(tail) > limit {
+			tail = tail[len(tail)-limit:]
+		}
+	}
+
+	topPad := (viewH - 2 - len(tail)) / 2
+	if topPad < 0 {
+		topPad = 0
+	}
+	written := 0
+	for ; written < topPad; written++ {
+		b.WriteByte('\n')
+	}
 	b.WriteString(strings.Repeat(" ", pad) + spinnerText + "\n")
+	written++
+	if len(tail) > 0 {
+		b.WriteByte('\n')
+		written++
+		for _, line := range tail {
+			if lipgloss.Width(line) > m.width-2 && m.width > 2 {
+				line = truncateRunes(line, m.width-2)
+			}
+			b.WriteString(hintStyle.Render(" "+line) + "\n")
+			written++
+		}
+	}
 
-	for i := topPad + 1; i < viewH; i++ {
+	for ; written < viewH; written++ {
 		b.WriteByte('\n')
 	}
 
@@ -302,6 +395,15 @@ func (m Model) viewLoading() string {
 	return b.String()
 }
 
+// truncateRunes shortens s to at most n runes.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
 // viewDiff renders the diff view with accept/reject controls.
 func (m Model) viewDiff() string {
 	var b strings.Builder
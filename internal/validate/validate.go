@@ -0,0 +1,495 @@
+package validate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/parser"
+)
+
+// Severity classifies a diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic codes reported by the linter.
+const (
+	CodeNoPlanHeading     = "no-plan-heading"
+	CodeMalformedHeading  = "malformed-heading"
+	CodeUnknownStatus     = "unknown-status"
+	CodeMissingStatus     = "missing-status"
+	CodeDuplicateTask     = "duplicate-task"
+	CodeDuplicateFeature  = "duplicate-feature"
+	CodeUnknownDependency = "unknown-dependency"
+	CodeUnknownPlan       = "unknown-plan"
+	CodeBadDependency     = "bad-dependency"
+	CodeDependencyCycle   = "dependency-cycle"
+	CodeNoCriteria        = "no-criteria"
+	CodeMissingComplexity = "missing-complexity"
+	CodeUnknownComplexity = "unknown-complexity"
+)
+
+// Diagnostic is a single problem found in a plan file.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	TaskID   string   `json:"task_id,omitempty"`
+}
+
+// Report holds all diagnostics for one plan file.
+type Report struct {
+	Slug        string       `json:"slug"`
+	FilePath    string       `json:"file_path"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+
+	// crossDeps are dependencies on other plans, checked by File against
+	// the sibling plan files.
+	crossDeps []crossDep
+}
+
+// crossDep is a dependency like "auth-system#1.2" on a task in another plan.
+type crossDep struct {
+	taskID string
+	slug   string
+	depID  string
+	line   int
+}
+
+// Errors returns the number of error-severity diagnostics.
+func (r Report) Errors() int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if d.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Warnings returns the number of warning-severity diagnostics.
+func (r Report) Warnings() int {
+	return len(r.Diagnostics) - r.Errors()
+}
+
+var (
+	planHeadingRe    = regexp.MustCompile(`^#\s+Plan:\s*(.+)$`)
+	featureHeadingRe = regexp.MustCompile(`^##\s+Feature\s+(\d+):\s*(.+)$`)
+	featurePrefixRe  = regexp.MustCompile(`^##\s+Feature\b`)
+	taskHeadingRe    = regexp.MustCompile(`^###\s+Task\s+(\d+)(?:\.(\d+)([a-z])?)?:\s*(.+)$`)
+	taskPrefixRe     = regexp.MustCompile(`^###\s+Task\b`)
+	statusTagRe      = regexp.MustCompile(`\[(\w+)\]\s*$`)
+	h2Re             = regexp.MustCompile(`^##\s+`)
+	complexityRe     = regexp.MustCompile(`^\*\*Complexity:\*\*\s*(.+)$`)
+	dependsOnRe      = regexp.MustCompile(`^\*\*Depends\s+on:\*\*\s*(.+)$`)
+	criterionRe      = regexp.MustCompile(`^-\s+\[([ x])\]\s+(.+)$`)
+
+	// depIDRe matches a full task ID like "1.2" or "1.3b" in a dependency.
+	depIDRe = regexp.MustCompile(`\b(\d+\.\d+[a-z]?)\b`)
+	// depBareIDRe matches a bare task number like "2" in a single-feature dependency.
+	depBareIDRe = regexp.MustCompile(`\b(\d+[a-z]?)\b`)
+)
+
+// taskInfo records what the linter saw for a single task.
+type taskInfo struct {
+	id         string
+	line       int
+	complexity string
+	hasComplex bool
+	deps       []depRef
+	criteria   int
+}
+
+// depRef is one comma-separated entry from a **Depends on:** line.
+type depRef struct {
+	raw  string
+	line int
+}
+
+// File validates the plan file at path.
+func File(path string) (Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Report{}, fmt.Errorf("opening plan file: %w", err)
+	}
+	defer f.Close()
+	return FileSource(path, f)
+}
+
+// FileSource validates plan markdown read from src as the plan file at path,
+// such as an editor's unsaved copy of it. Cross-plan dependencies are checked
+// against the plan files beside path.
+func FileSource(path string, src io.Reader) (Report, error) {
+	r, err := Source(src)
+	if err != nil {
+		return Report{}, err
+	}
+	r.FilePath = path
+	r.Slug = strings.TrimSuffix(filepath.Base(path), ".md")
+	checkCrossPlanDeps(&r, filepath.Dir(path))
+	return r, nil
+}
+
+// checkCrossPlanDeps reports cross-plan dependencies whose plan or task does
+// not exist among the plan files in plansDir.
+func checkCrossPlanDeps(r *Report, plansDir string) {
+	if len(r.crossDeps) == 0 {
+		return
+	}
+	plans := make(map[string]*models.Plan)
+	for _, d := range r.crossDeps {
+		plan, seen := plans[d.slug]
+		if !seen {
+			plan, _ = parser.ParseFile(filepath.Join(plansDir, d.slug+".md"))
+			plans[d.slug] = plan
+		}
+		ref := d.slug + "#" + d.depID
+		switch {
+		case plan == nil:
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Line:     d.line,
+				Severity: SeverityError,
+				Code:     CodeUnknownPlan,
+				Message:  fmt.Sprintf("task %s depends on %s, but plan %q does not exist or cannot be parsed", d.taskID, ref, d.slug),
+				TaskID:   d.taskID,
+			})
+		case plan.ResolveTaskID(d.depID) == nil:
+			r.Diagnostics = append(r.Diagnostics, Diagnostic{
+				Line:     d.line,
+				Severity: SeverityError,
+				Code:     CodeUnknownDependency,
+				Message:  fmt.Sprintf("task %s depends on unknown task %s", d.taskID, ref),
+				TaskID:   d.taskID,
+			})
+		}
+	}
+	sort.SliceStable(r.Diagnostics, func(i, j int) bool {
+		return r.Diagnostics[i].Line < r.Diagnostics[j].Line
+	})
+}
+
+// Source validates plan markdown read from r.
+func Source(r io.Reader) (Report, error) {
+	var rep Report
+	add := func(line int, sev Severity, code, taskID, format string, args ...any) {
+		rep.Diagnostics = append(rep.Diagnostics, Diagnostic{
+			Line:     line,
+			Severity: sev,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+			TaskID:   taskID,
+		})
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	inFence := false
+	sawPlan := false
+	sawFeature := false
+	inTaskSection := false
+
+	var tasks []*taskInfo
+	taskByID := make(map[string]*taskInfo)
+	featureLines := make(map[string]int)
+	var cur *taskInfo
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if planHeadingRe.MatchString(line) {
+			sawPlan = true
+			continue
+		}
+
+		if featurePrefixRe.MatchString(line) {
+			cur = nil
+			inTaskSection = false
+			m := featureHeadingRe.FindStringSubmatch(line)
+			if m == nil {
+				add(lineNum, SeverityError, CodeMalformedHeading, "",
+					"malformed feature heading %q (expected \"## Feature N: Title\")", strings.TrimSpace(line))
+				continue
+			}
+			sawFeature = true
+			if prev, ok := featureLines[m[1]]; ok {
+				add(lineNum, SeverityError, CodeDuplicateFeature, "",
+					"duplicate feature number %s (first defined on line %d)", m[1], prev)
+			} else {
+				featureLines[m[1]] = lineNum
+			}
+			continue
+		}
+
+		if taskPrefixRe.MatchString(line) {
+			m := taskHeadingRe.FindStringSubmatch(line)
+			if m == nil {
+				cur = nil
+				inTaskSection = false
+				add(lineNum, SeverityError, CodeMalformedHeading, "",
+					"malformed task heading %q (expected \"### Task N.M: Title [status]\")", strings.TrimSpace(line))
+				continue
+			}
+
+			id := taskIDFromHeading(m)
+			title := strings.TrimSpace(m[4])
+			if sm := statusTagRe.FindStringSubmatch(title); sm != nil {
+				if !isKnownStatus(sm[1]) {
+					add(lineNum, SeverityError, CodeUnknownStatus, id,
+						"task %s has unknown status [%s] (expected one of %s)", id, sm[1], strings.Join(statusNames(), ", "))
+				}
+			} else {
+				add(lineNum, SeverityWarning, CodeMissingStatus, id,
+					"task %s has no status tag (treated as [pending])", id)
+			}
+
+			t := &taskInfo{id: id, line: lineNum}
+			if prev, ok := taskByID[id]; ok {
+				add(lineNum, SeverityError, CodeDuplicateTask, id,
+					"duplicate task ID %s (first defined on line %d)", id, prev.line)
+			} else {
+				taskByID[id] = t
+			}
+			tasks = append(tasks, t)
+			cur = t
+			inTaskSection = true
+			continue
+		}
+
+		if h2Re.MatchString(line) {
+			cur = nil
+			inTaskSection = false
+			continue
+		}
+
+		if !inTaskSection || cur == nil {
+			continue
+		}
+
+		if m := complexityRe.FindStringSubmatch(line); m != nil {
+			cur.hasComplex = true
+			cur.complexity = strings.TrimSpace(m[1])
+			continue
+		}
+		if m := dependsOnRe.FindStringSubmatch(line); m != nil {
+			for _, d := range strings.Split(m[1], ",") {
+				d = strings.TrimSpace(d)
+				if d != "" {
+					cur.deps = append(cur.deps, depRef{raw: d, line: lineNum})
+				}
+			}
+			continue
+		}
+		if criterionRe.MatchString(line) {
+			cur.criteria++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return rep, fmt.Errorf("reading plan: %w", err)
+	}
+
+	if !sawPlan {
+		add(1, SeverityError, CodeNoPlanHeading, "", "no '# Plan:' heading found")
+	}
+
+	// Per-task checks.
+	graph := make(map[string][]string)
+	for _, t := range tasks {
+		if !t.hasComplex {
+			add(t.line, SeverityWarning, CodeMissingComplexity, t.id,
+				"task %s has no **Complexity:** line", t.id)
+		} else if !isKnownComplexity(t.complexity) {
+			add(t.line, SeverityWarning, CodeUnknownComplexity, t.id,
+				"task %s has unknown complexity %q (expected small, medium, or large)", t.id, t.complexity)
+		}
+		if t.criteria == 0 {
+			add(t.line, SeverityWarning, CodeNoCriteria, t.id,
+				"task %s has no acceptance criteria", t.id)
+		}
+
+		for _, d := range t.deps {
+			if slug, depID, ok := models.ParseCrossPlanDep(d.raw); ok {
+				rep.crossDeps = append(rep.crossDeps, crossDep{taskID: t.id, slug: slug, depID: depID, line: d.line})
+				continue
+			}
+			depID, ok := resolveDep(d.raw, sawFeature)
+			if !ok {
+				if !isNoneDep(d.raw) {
+					add(d.line, SeverityWarning, CodeBadDependency, t.id,
+						"task %s has unrecognized dependency %q", t.id, d.raw)
+				}
+				continue
+			}
+			if _, exists := taskByID[depID]; !exists {
+				add(d.line, SeverityError, CodeUnknownDependency, t.id,
+					"task %s depends on unknown task %s", t.id, depID)
+				continue
+			}
+			graph[t.id] = append(graph[t.id], depID)
+		}
+	}
+
+	for _, cycle := range findCycles(tasks, graph) {
+		first := taskByID[cycle[0]]
+		add(first.line, SeverityError, CodeDependencyCycle, first.id,
+			"dependency cycle: %s", strings.Join(cycle, " → "))
+	}
+
+	sort.SliceStable(rep.Diagnostics, func(i, j int) bool {
+		return rep.Diagnostics[i].Line < rep.Diagnostics[j].Line
+	})
+	return rep, nil
+}
+
+// taskIDFromHeading builds a full task ID from a task heading match. Single
+// feature plans use "### Task N:" which maps to "1.N".
+func taskIDFromHeading(m []string) string {
+	if m[2] == "" {
+		return "1." + m[1]
+	}
+	return m[1] + "." + m[2] + m[3]
+}
+
+// resolveDep extracts a task ID from a dependency string like "Task 1.2".
+// Bare numbers ("Task 2") are only accepted in single-feature plans.
+func resolveDep(dep string, multiFeature bool) (string, bool) {
+	if m := depIDRe.FindStringSubmatch(dep); m != nil {
+		return m[1], true
+	}
+	if !multiFeature {
+		if m := depBareIDRe.FindStringSubmatch(dep); m != nil {
+			return "1." + m[1], true
+		}
+	}
+	return "", false
+}
+
+// isNoneDep reports whether a dependency entry is a placeholder like "none".
+func isNoneDep(dep string) bool {
+	d := strings.ToLower(strings.Trim(dep, " ()—-_*"))
+	return d == "" || strings.HasPrefix(d, "none") || d == "n/a"
+}
+
+func isKnownStatus(s string) bool {
+	for _, name := range statusNames() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func statusNames() []string {
+	return []string{
+		string(models.StatusPending),
+		string(models.StatusInProgress),
+		string(models.StatusCompleted),
+		string(models.StatusBlocked),
+		string(models.StatusFailed),
+	}
+}
+
+func isKnownComplexity(c string) bool {
+	switch models.Complexity(strings.ToLower(c)) {
+	case models.ComplexitySmall, models.ComplexityMedium, models.ComplexityLarge:
+		return true
+	}
+	return false
+}
+
+// findCycles returns each dependency cycle once, as a path of task IDs that
+// starts and ends with the same task.
+func findCycles(tasks []*taskInfo, graph map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range graph[id] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycle := append([]string{}, stack[i:]...)
+						cycles = append(cycles, append(cycle, dep))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, t := range tasks {
+		if state[t.id] == unvisited {
+			visit(t.id)
+		}
+	}
+	return cycles
+}
+
+// FormatText renders reports as "path:line: severity: message" lines followed
+// by a one-line summary per plan.
+func FormatText(reports []Report, rootDir string) string {
+	var b strings.Builder
+	for _, r := range reports {
+		path := r.FilePath
+		if rel, err := filepath.Rel(rootDir, path); err == nil {
+			path = rel
+		}
+		for _, d := range r.Diagnostics {
+			b.WriteString(fmt.Sprintf("%s:%d: %s: %s [%s]\n", path, d.Line, d.Severity, d.Message, d.Code))
+		}
+		if len(r.Diagnostics) == 0 {
+			b.WriteString(fmt.Sprintf("✓ %s: no problems found\n", r.Slug))
+		} else {
+			b.WriteString(fmt.Sprintf("✗ %s: %d error(s), %d warning(s)\n", r.Slug, r.Errors(), r.Warnings()))
+		}
+	}
+	return b.String()
+}
+
+// FormatJSON renders reports as JSON.
+func FormatJSON(reports []Report) (string, error) {
+	for i := range reports {
+		if reports[i].Diagnostics == nil {
+			reports[i].Diagnostics = []Diagnostic{}
+		}
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
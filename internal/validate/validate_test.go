@@ -0,0 +1,309 @@
+package validate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validPlan = `# Plan: Valid
+
+## Overview
+A valid plan.
+
+---
+
+## Feature 1: Core
+
+### Task 1.1: First [pending]
+**Complexity:** small
+**Files:** a.go
+**Depends on:** none
+
+Do it.
+
+**Acceptance Criteria:**
+- [ ] Works
+
+### Task 1.2: Second [completed]
+**Complexity:** medium
+**Depends on:** Task 1.1
+
+**Acceptance Criteria:**
+- [x] Works
+
+---
+
+## Feature 2: More
+
+### Task 2.1: Third [in_progress]
+**Complexity:** large
+**Depends on:** Task 1.1, Task 1.2
+
+**Acceptance Criteria:**
+- [ ] Works
+`
+
+func validateString(t *testing.T, content string) Report {
+	t.Helper()
+	r, err := Source(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
+
+// findCode returns the first diagnostic with the given code, or nil.
+func findCode(r Report, code string) *Diagnostic {
+	for i := range r.Diagnostics {
+		if r.Diagnostics[i].Code == code {
+			return &r.Diagnostics[i]
+		}
+	}
+	return nil
+}
+
+func TestSource_ValidPlan(t *testing.T) {
+	r := validateString(t, validPlan)
+	if len(r.Diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_SingleFeatureBareDeps(t *testing.T) {
+	r := validateString(t, `# Plan: Single
+
+### Task 1: A [pending]
+**Complexity:** small
+
+**Acceptance Criteria:**
+- [ ] ok
+
+### Task 2: B [pending]
+**Complexity:** small
+**Depends on:** Task 1
+
+**Acceptance Criteria:**
+- [ ] ok
+`)
+	if len(r.Diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_DanglingDependency(t *testing.T) {
+	r := validateString(t, strings.Replace(validPlan, "**Depends on:** Task 1.1\n", "**Depends on:** Task 3.4\n", 1))
+	d := findCode(r, CodeUnknownDependency)
+	if d == nil {
+		t.Fatalf("expected unknown-dependency diagnostic, got %+v", r.Diagnostics)
+	}
+	if d.Severity != SeverityError || d.TaskID != "1.2" || d.Line != 22 {
+		t.Errorf("unexpected diagnostic: %+v", d)
+	}
+}
+
+func TestSource_DuplicateTaskID(t *testing.T) {
+	r := validateString(t, strings.Replace(validPlan, "### Task 2.1: Third", "### Task 1.2: Third", 1))
+	d := findCode(r, CodeDuplicateTask)
+	if d == nil {
+		t.Fatalf("expected duplicate-task diagnostic, got %+v", r.Diagnostics)
+	}
+	if !strings.Contains(d.Message, "line 20") {
+		t.Errorf("expected message to reference first definition, got %q", d.Message)
+	}
+}
+
+func TestSource_DuplicateFeature(t *testing.T) {
+	r := validateString(t, strings.Replace(validPlan, "## Feature 2: More", "## Feature 1: More", 1))
+	if findCode(r, CodeDuplicateFeature) == nil {
+		t.Fatalf("expected duplicate-feature diagnostic, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_Cycle(t *testing.T) {
+	content := strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** Task 2.1", 1)
+	r := validateString(t, content)
+	d := findCode(r, CodeDependencyCycle)
+	if d == nil {
+		t.Fatalf("expected dependency-cycle diagnostic, got %+v", r.Diagnostics)
+	}
+	if !strings.Contains(d.Message, "1.1 → 2.1 → 1.1") {
+		t.Errorf("unexpected cycle message: %q", d.Message)
+	}
+}
+
+func TestSource_SelfDependency(t *testing.T) {
+	r := validateString(t, strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** Task 1.1", 1))
+	if findCode(r, CodeDependencyCycle) == nil {
+		t.Fatalf("expected dependency-cycle diagnostic, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_UnknownStatusAndComplexity(t *testing.T) {
+	content := strings.Replace(validPlan, "First [pending]", "First [done]", 1)
+	content = strings.Replace(content, "**Complexity:** small", "**Complexity:** huge", 1)
+	r := validateString(t, content)
+
+	if d := findCode(r, CodeUnknownStatus); d == nil || d.Severity != SeverityError || d.Line != 10 {
+		t.Errorf("expected unknown-status error on line 10, got %+v", d)
+	}
+	if d := findCode(r, CodeUnknownComplexity); d == nil || d.Severity != SeverityWarning {
+		t.Errorf("expected unknown-complexity warning, got %+v", d)
+	}
+}
+
+func TestSource_MissingStatusCriteriaComplexity(t *testing.T) {
+	r := validateString(t, `# Plan: Sparse
+
+### Task 1: Bare
+Just text.
+`)
+	for _, code := range []string{CodeMissingStatus, CodeMissingComplexity, CodeNoCriteria} {
+		if d := findCode(r, code); d == nil || d.Severity != SeverityWarning {
+			t.Errorf("expected %s warning, got %+v", code, d)
+		}
+	}
+	if r.Errors() != 0 {
+		t.Errorf("expected no errors, got %d", r.Errors())
+	}
+}
+
+func TestSource_MalformedHeadings(t *testing.T) {
+	r := validateString(t, `# Plan: Bad
+
+## Feature: No number
+
+### Task one: Words [pending]
+`)
+	count := 0
+	for _, d := range r.Diagnostics {
+		if d.Code == CodeMalformedHeading {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected 2 malformed-heading diagnostics, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_NoPlanHeading(t *testing.T) {
+	r := validateString(t, "just some text\n")
+	if findCode(r, CodeNoPlanHeading) == nil {
+		t.Fatalf("expected no-plan-heading diagnostic, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_IgnoresCodeFences(t *testing.T) {
+	content := strings.Replace(validPlan, "Do it.\n", "Do it.\n\n```\n### Task 9: Fake [bogus]\n```\n", 1)
+	r := validateString(t, content)
+	if len(r.Diagnostics) != 0 {
+		t.Fatalf("expected fenced content to be ignored, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_UnrecognizedDependency(t *testing.T) {
+	r := validateString(t, strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** the database work", 1))
+	if d := findCode(r, CodeBadDependency); d == nil || d.Severity != SeverityWarning {
+		t.Fatalf("expected bad-dependency warning, got %+v", r.Diagnostics)
+	}
+}
+
+func TestFile_SetsSlugAndPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my-plan.md")
+	os.WriteFile(path, []byte(validPlan), 0o644)
+
+	r, err := File(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Slug != "my-plan" || r.FilePath != path {
+		t.Errorf("unexpected report identity: %+v", r)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	r := Report{
+		Slug:     "p",
+		FilePath: "/root/.etch/plans/p.md",
+		Diagnostics: []Diagnostic{
+			{Line: 4, Severity: SeverityError, Code: CodeDuplicateTask, Message: "duplicate"},
+		},
+	}
+	out := FormatText([]Report{r}, "/root")
+	if !strings.Contains(out, ".etch/plans/p.md:4: error: duplicate [duplicate-task]") {
+		t.Errorf("unexpected text output:\n%s", out)
+	}
+	if !strings.Contains(out, "1 error(s), 0 warning(s)") {
+		t.Errorf("expected summary line, got:\n%s", out)
+	}
+}
+
+func TestFormatJSON_EmptyDiagnosticsIsArray(t *testing.T) {
+	out, err := FormatJSON([]Report{{Slug: "p"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if _, ok := decoded[0]["diagnostics"].([]any); !ok {
+		t.Errorf("expected diagnostics to be an array, got %v", decoded[0]["diagnostics"])
+	}
+}
+
+func TestFile_CrossPlanDependencies(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "auth.md"), []byte(validPlan), 0o644)
+
+	content := strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** auth#2.1, auth#9.9, billing#1.1", 1)
+	path := filepath.Join(dir, "consumer.md")
+	os.WriteFile(path, []byte(content), 0o644)
+
+	r, err := File(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := findCode(r, CodeUnknownDependency)
+	if d == nil || !strings.Contains(d.Message, "auth#9.9") || d.Line != 13 {
+		t.Errorf("expected unknown-dependency for auth#9.9 on line 13, got %+v", r.Diagnostics)
+	}
+	d = findCode(r, CodeUnknownPlan)
+	if d == nil || !strings.Contains(d.Message, `plan "billing"`) {
+		t.Errorf("expected unknown-plan for billing, got %+v", r.Diagnostics)
+	}
+	if r.Errors() != 2 {
+		t.Errorf("expected exactly 2 errors (auth#2.1 exists), got %+v", r.Diagnostics)
+	}
+}
+
+func TestFileSource_UsesGivenContent(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "auth.md"), []byte(validPlan), 0o644)
+	path := filepath.Join(dir, "consumer.md")
+	os.WriteFile(path, []byte(validPlan), 0o644)
+
+	// The unsaved content differs from what's on disk.
+	content := strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** auth#9.9", 1)
+	r, err := FileSource(path, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Slug != "consumer" || r.FilePath != path {
+		t.Errorf("unexpected report identity: %+v", r)
+	}
+	if d := findCode(r, CodeUnknownDependency); d == nil || !strings.Contains(d.Message, "auth#9.9") {
+		t.Errorf("expected unknown-dependency for auth#9.9, got %+v", r.Diagnostics)
+	}
+}
+
+func TestSource_CrossPlanDepNotCheckedLocally(t *testing.T) {
+	// "other#1.1" must not be mistaken for a self-dependency on local task 1.1.
+	r := validateString(t, strings.Replace(validPlan, "**Depends on:** none", "**Depends on:** other#1.1", 1))
+	if len(r.Diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics without sibling plans, got %+v", r.Diagnostics)
+	}
+}
@@ -0,0 +1,80 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+)
+
+// Result is the outcome of one criterion's verify command.
+type Result struct {
+	Criterion models.Criterion
+	Passed    bool
+	Output    string // combined stdout and stderr
+	Duration  time.Duration
+	Err       error // why the command failed; nil when it passed
+}
+
+// Verifiable reports whether any of the task's criteria has a verify command.
+func Verifiable(task *models.Task) bool {
+	for _, c := range task.Criteria {
+		if c.Verify != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the verify command of each of the task's verifiable criteria in
+// dir, one at a time and in plan order. A command passes when it exits zero
+// within timeout (0 for no limit). Criteria without a verify command are
+// skipped.
+func Run(ctx context.Context, task *models.Task, dir string, timeout time.Duration) []Result {
+	var results []Result
+	for _, c := range task.Criteria {
+		if c.Verify == "" {
+			continue
+		}
+		results = append(results, runOne(ctx, c, dir, timeout))
+	}
+	return results
+}
+
+func runOne(ctx context.Context, c models.Criterion, dir string, timeout time.Duration) Result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Verify)
+	cmd.Dir = dir
+	cmd.WaitDelay = 5 * time.Second
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+	res := Result{Criterion: c, Output: out.String(), Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Passed = true
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.Err = fmt.Errorf("timed out after %s", timeout)
+	case ctx.Err() != nil:
+		res.Err = ctx.Err()
+	case errors.As(err, &exitErr):
+		res.Err = fmt.Errorf("exit status %d", exitErr.ExitCode())
+	default:
+		res.Err = err
+	}
+	return res
+}
@@ -0,0 +1,67 @@
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/models"
+)
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("verify commands require a POSIX shell")
+	}
+}
+
+func TestRun(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "marker"), []byte("x"), 0o644)
+
+	task := &models.Task{Criteria: []models.Criterion{
+		{Description: "Marker exists", Verify: "test -f marker"},
+		{Description: "Written by hand"},
+		{Description: "Fails", Verify: "echo broken; exit 3"},
+	}}
+
+	results := Run(context.Background(), task, dir, time.Minute)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].Passed || results[0].Err != nil {
+		t.Errorf("expected marker check to pass (runs in dir), got %+v", results[0])
+	}
+	if results[1].Passed || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "exit status 3") {
+		t.Errorf("expected exit status 3, got %+v", results[1])
+	}
+	if !strings.Contains(results[1].Output, "broken") {
+		t.Errorf("expected output captured, got %q", results[1].Output)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	skipWithoutShell(t)
+	task := &models.Task{Criteria: []models.Criterion{
+		{Description: "Slow", Verify: "exec sleep 5"},
+	}}
+
+	results := Run(context.Background(), task, t.TempDir(), 50*time.Millisecond)
+	if results[0].Passed || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "timed out") {
+		t.Errorf("expected timeout, got %+v", results[0])
+	}
+}
+
+func TestVerifiable(t *testing.T) {
+	if Verifiable(&models.Task{Criteria: []models.Criterion{{Description: "By hand"}}}) {
+		t.Error("expected task without verify commands not to be verifiable")
+	}
+	if !Verifiable(&models.Task{Criteria: []models.Criterion{{Description: "x", Verify: "true"}}}) {
+		t.Error("expected task with a verify command to be verifiable")
+	}
+}
@@ -0,0 +1,169 @@
+"use strict";
+
+const STATUSES = ["pending", "in_progress", "blocked", "completed", "failed"];
+
+async function api(path, options = {}) {
+  if (options.body !== undefined) {
+    options.headers = { "Content-Type": "application/json" };
+    options.body = JSON.stringify(options.body);
+  }
+  const res = await fetch("/api" + path, options);
+  const data = await res.json();
+  if (!res.ok) {
+    throw new Error(data.hint ? `${data.error} (${data.hint})` : data.error);
+  }
+  return data;
+}
+
+// el builds an element. Text is always set with textContent, never parsed
+// as HTML, since plans and comments are user-written.
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs)) {
+    if (k === "class") node.className = v;
+    else if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else node.setAttribute(k, v);
+  }
+  for (const c of children) {
+    if (c == null) continue;
+    node.append(typeof c === "string" ? document.createTextNode(c) : c);
+  }
+  return node;
+}
+
+function showError(err) {
+  const box = document.getElementById("error");
+  box.textContent = err ? err.message : "";
+  box.hidden = !err;
+}
+
+function bar(done, total) {
+  const pct = total ? Math.round((done / total) * 100) : 0;
+  return el("div", { class: "bar" }, el("span", { style: `width:${pct}%` }));
+}
+
+async function loadPlans(active) {
+  const plans = await api("/plans");
+  const list = document.getElementById("plans");
+  list.replaceChildren(...plans.map((p) => el("li", {},
+    el("a", { href: `#/plan/${encodeURIComponent(p.slug)}`, class: p.slug === active ? "active" : "" },
+      p.title,
+      el("div", { class: "muted" }, `${p.completed_tasks}/${p.total_tasks} tasks`),
+      bar(p.completed_tasks, p.total_tasks)))));
+  if (plans.length === 0) {
+    list.replaceChildren(el("li", { class: "muted" }, "No plans yet. Run etch plan to create one."));
+  }
+}
+
+async function loadPlan(slug) {
+  const [detail, sessions] = await Promise.all([
+    api(`/plans/${encodeURIComponent(slug)}`),
+    api(`/plans/${encodeURIComponent(slug)}/sessions`),
+  ]);
+  const { plan, status } = detail;
+  const statusByID = {};
+  for (const f of status.features || []) {
+    for (const t of f.tasks || []) statusByID[t.id] = t;
+  }
+
+  const main = document.getElementById("plan");
+  main.replaceChildren(
+    el("h2", {}, plan.title),
+    el("p", { class: "muted" }, `${status.completed_tasks}/${status.total_tasks} tasks completed`),
+    bar(status.completed_tasks, status.total_tasks),
+    plan.overview ? el("p", { class: "overview" }, plan.overview) : null,
+    ...plan.features.map((f) => el("section", { class: "feature" },
+      el("h3", {}, plan.features.length > 1 ? `Feature ${f.number}: ${f.title}` : "Tasks"),
+      ...f.tasks.map((t) => renderTask(slug, t, statusByID, sessions)))),
+  );
+}
+
+function taskID(t) {
+  return `${t.feature_number}.${t.task_number}${t.suffix || ""}`;
+}
+
+function renderTask(slug, task, statusByID, sessions) {
+  const id = taskID(task);
+  const st = statusByID[id] || { status: task.status };
+  const base = `/plans/${encodeURIComponent(slug)}/tasks/${encodeURIComponent(id)}`;
+
+  const select = el("select", {
+    "aria-label": `Status of task ${id}`,
+    onchange: async (e) => {
+      try {
+        await api(`${base}/status`, { method: "PUT", body: { status: e.target.value } });
+        showError(null);
+        route();
+      } catch (err) {
+        showError(err);
+      }
+    },
+  }, ...STATUSES.map((s) => {
+    const opt = el("option", { value: s }, s.replace("_", " "));
+    opt.selected = s === st.status;
+    return opt;
+  }));
+
+  const textarea = el("textarea", { placeholder: "Add a review comment", required: "" });
+  const form = el("form", {
+    onsubmit: async (e) => {
+      e.preventDefault();
+      try {
+        await api(`${base}/comments`, { method: "POST", body: { text: textarea.value } });
+        showError(null);
+        route();
+      } catch (err) {
+        showError(err);
+      }
+    },
+  }, textarea, el("button", { type: "submit" }, "Comment"));
+
+  const history = sessions[id] || [];
+  const deps = task.depends_on || [];
+
+  return el("article", { class: "task" },
+    el("div", { class: "task-head" },
+      el("strong", {}, `${id} ${task.title}`),
+      el("span", { class: `badge ${st.is_blocked ? "blocked" : st.status}` }, st.is_blocked ? "waiting on dependencies" : st.status.replace("_", " ")),
+      select),
+    el("div", { class: "muted" },
+      [task.complexity && `complexity: ${task.complexity}`, deps.length && `depends on: ${deps.join(", ")}`, st.branch && `branch: ${st.branch}`]
+        .filter(Boolean).join(" · ")),
+    task.description ? el("div", { class: "description" }, task.description) : null,
+    (task.criteria || []).length
+      ? el("ul", { class: "criteria" }, ...task.criteria.map((c) => el("li", { class: c.is_met ? "met" : "" }, c.description)))
+      : null,
+    ...(task.comments || []).map((c) => el("div", { class: "comment" }, "💬 " + c)),
+    history.length
+      ? el("details", {},
+        el("summary", {}, `${history.length} session${history.length === 1 ? "" : "s"}`),
+        ...history.map(renderSession))
+      : null,
+    form,
+  );
+}
+
+function renderSession(s) {
+  const section = (title, text) => (text ? el("div", {}, el("strong", {}, title), el("pre", {}, text)) : null);
+  return el("div", { class: "session" },
+    el("div", {}, el("strong", {}, `Session ${String(s.session_number).padStart(3, "0")}`), ` · ${s.status} · ${s.started}`),
+    section("Changes", (s.changes_made || []).join("\n")),
+    section("Decisions", s.decisions),
+    section("Blockers", s.blockers),
+    section("Next", s.next));
+}
+
+async function route() {
+  const m = location.hash.match(/^#\/plan\/(.+)$/);
+  const slug = m ? decodeURIComponent(m[1]) : null;
+  try {
+    await loadPlans(slug);
+    if (slug) await loadPlan(slug);
+    else document.getElementById("plan").replaceChildren(el("p", { class: "muted" }, "Select a plan."));
+  } catch (err) {
+    showError(err);
+  }
+}
+
+window.addEventListener("hashchange", route);
+route();
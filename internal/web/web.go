@@ -0,0 +1,307 @@
+// Package web serves etch's plans, reconciled status and session history
+// as a JSON API, along with an embedded dashboard that uses it.
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	etchcontext "github.com/gsigler/etch/internal/context"
+	etcherr "github.com/gsigler/etch/internal/errors"
+	"github.com/gsigler/etch/internal/models"
+	"github.com/gsigler/etch/internal/progress"
+	"github.com/gsigler/etch/internal/status"
+	"github.com/gsigler/etch/internal/transition"
+	"github.com/gsigler/etch/internal/tui"
+)
+
+//go:embed static
+var static embed.FS
+
+// maxBody bounds the size of request bodies.
+const maxBody = 1 << 20
+
+// Server answers API and dashboard requests for the project at RootDir.
+type Server struct {
+	RootDir string
+
+	// mu serializes requests, since writes edit plan files and reads may
+	// sync status into them when auto_sync is set.
+	mu sync.Mutex
+}
+
+// New returns a Server for the project at rootDir.
+func New(rootDir string) *Server {
+	return &Server{RootDir: rootDir}
+}
+
+// PlanDetail is the response for a single plan: its parsed content and
+// reconciled status.
+type PlanDetail struct {
+	Plan   *models.Plan      `json:"plan"`
+	Status status.PlanStatus `json:"status"`
+}
+
+// TaskDetail is the response for a single task.
+type TaskDetail struct {
+	Task     *models.Task             `json:"task"`
+	Status   status.TaskStatus        `json:"status"`
+	Sessions []models.SessionProgress `json:"sessions"`
+}
+
+// Handler returns the server's routes:
+//
+//	GET  /api/plans                              reconciled status of every plan
+//	GET  /api/plans/{slug}                       one plan and its status
+//	GET  /api/plans/{slug}/sessions              session history by task ID
+//	GET  /api/plans/{slug}/tasks/{id}            one task, its status and sessions
+//	POST /api/plans/{slug}/tasks/{id}/comments   add a review comment {"text": "..."}
+//	PUT  /api/plans/{slug}/tasks/{id}/status     set the task status {"status": "...", "reason": "..."}
+//	GET  /                                       the dashboard
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/plans", s.handle(s.listPlans))
+	mux.HandleFunc("GET /api/plans/{slug}", s.handle(s.getPlan))
+	mux.HandleFunc("GET /api/plans/{slug}/sessions", s.handle(s.getSessions))
+	mux.HandleFunc("GET /api/plans/{slug}/tasks/{id}", s.handle(s.getTask))
+	mux.HandleFunc("POST /api/plans/{slug}/tasks/{id}/comments", s.handle(s.addComment))
+	mux.HandleFunc("PUT /api/plans/{slug}/tasks/{id}/status", s.handle(s.setStatus))
+
+	assets, _ := fs.Sub(static, "static")
+	mux.Handle("GET /", http.FileServerFS(assets))
+	return mux
+}
+
+// handle adapts an API handler: it serializes requests, and writes the
+// result or error as JSON.
+func (s *Server) handle(fn func(r *http.Request) (int, any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		code, v, err := fn(r)
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, code, err)
+			return
+		}
+		writeJSON(w, code, v)
+	}
+}
+
+func (s *Server) listPlans(r *http.Request) (int, any, error) {
+	plans, err := status.Run(s.RootDir, "")
+	if err != nil {
+		return 0, nil, err
+	}
+	status.SortPlanStatuses(plans)
+	if plans == nil {
+		plans = []status.PlanStatus{}
+	}
+	return http.StatusOK, plans, nil
+}
+
+func (s *Server) getPlan(r *http.Request) (int, any, error) {
+	plan, ps, err := s.plan(r.PathValue("slug"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, PlanDetail{Plan: plan, Status: ps}, nil
+}
+
+func (s *Server) getSessions(r *http.Request) (int, any, error) {
+	plan, _, err := s.plan(r.PathValue("slug"))
+	if err != nil {
+		return 0, nil, err
+	}
+	sessions, err := progress.ReadAll(s.RootDir, plan.Slug)
+	if err != nil {
+		return 0, nil, etcherr.WrapIO("reading progress files", err)
+	}
+	return http.StatusOK, sessions, nil
+}
+
+func (s *Server) getTask(r *http.Request) (int, any, error) {
+	plan, ps, err := s.plan(r.PathValue("slug"))
+	if err != nil {
+		return 0, nil, err
+	}
+	task, err := findTask(plan, r.PathValue("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	sessions, err := progress.ReadAll(s.RootDir, plan.Slug)
+	if err != nil {
+		return 0, nil, etcherr.WrapIO("reading progress files", err)
+	}
+	detail := TaskDetail{Task: task, Sessions: sessions[task.FullID()]}
+	if detail.Sessions == nil {
+		detail.Sessions = []models.SessionProgress{}
+	}
+	for _, f := range ps.Features {
+		for _, ts := range f.Tasks {
+			if ts.ID == task.FullID() {
+				detail.Status = ts
+			}
+		}
+	}
+	return http.StatusOK, detail, nil
+}
+
+func (s *Server) addComment(r *http.Request) (int, any, error) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		return 0, nil, err
+	}
+	text := strings.TrimSpace(body.Text)
+	if text == "" {
+		return 0, nil, etcherr.Usage("comment text is required")
+	}
+
+	plan, task, err := s.task(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := tui.AddComment(plan.FilePath, task.FullID(), text); err != nil {
+		return 0, nil, etcherr.WrapIO("adding comment", err)
+	}
+	return s.taskResponse(r, http.StatusCreated)
+}
+
+func (s *Server) setStatus(r *http.Request) (int, any, error) {
+	var body struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		return 0, nil, err
+	}
+	st := models.Status(body.Status)
+	if models.ParseStatus(body.Status) != st {
+		return 0, nil, etcherr.Usage(fmt.Sprintf("unknown status %q", body.Status)).
+			WithHint("use pending, in_progress, blocked, completed, or failed")
+	}
+
+	plan, task, err := s.task(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	// A claim, failing verify commands, or a hook veto turn the change
+	// down; report that as a conflict rather than as not found.
+	if err := transition.Set(r.Context(), s.RootDir, plan, task, st, strings.TrimSpace(body.Reason), io.Discard); err != nil {
+		return http.StatusConflict, nil, err
+	}
+	return s.taskResponse(r, http.StatusOK)
+}
+
+// taskResponse re-reads the task after a change.
+func (s *Server) taskResponse(r *http.Request, code int) (int, any, error) {
+	_, v, err := s.getTask(r)
+	return code, v, err
+}
+
+// plan reports a plan's status, reconciled with its progress files, along
+// with the plan itself. Status is only written back to the plan file when
+// auto_sync is set, in which case the plan is read after the sync.
+func (s *Server) plan(slug string) (*models.Plan, status.PlanStatus, error) {
+	statuses, err := status.Run(s.RootDir, slug)
+	if err != nil {
+		return nil, status.PlanStatus{}, err
+	}
+	if len(statuses) != 1 {
+		return nil, status.PlanStatus{}, planNotFound(slug)
+	}
+	plan, err := s.findPlan(slug)
+	if err != nil {
+		return nil, status.PlanStatus{}, err
+	}
+	return plan, statuses[0], nil
+}
+
+// task looks up the plan and task named in the request path.
+func (s *Server) task(r *http.Request) (*models.Plan, *models.Task, error) {
+	plan, err := s.findPlan(r.PathValue("slug"))
+	if err != nil {
+		return nil, nil, err
+	}
+	task, err := findTask(plan, r.PathValue("id"))
+	return plan, task, err
+}
+
+func (s *Server) findPlan(slug string) (*models.Plan, error) {
+	plans, err := etchcontext.DiscoverPlans(s.RootDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plans {
+		if p.Slug == slug {
+			return p, nil
+		}
+	}
+	return nil, planNotFound(slug)
+}
+
+func planNotFound(slug string) error {
+	return etcherr.Project(fmt.Sprintf("no plan found with slug %q", slug)).
+		WithHint("GET /api/plans lists the available plans")
+}
+
+func findTask(plan *models.Plan, id string) (*models.Task, error) {
+	if task := plan.ResolveTaskID(id); task != nil {
+		return task, nil
+	}
+	return nil, etcherr.Project(fmt.Sprintf("task %q not found in plan %s", id, plan.Slug))
+}
+
+// decodeBody reads a JSON request body into v. Requiring the JSON content
+// type keeps plain cross-site form posts from changing plans.
+func decodeBody(r *http.Request, v any) error {
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		return etcherr.Usage("request body must be JSON").WithHint("set Content-Type: application/json")
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBody))
+	if err := dec.Decode(v); err != nil {
+		return etcherr.Usage("invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError reports err as {"error": ..., "hint": ...}, with a status
+// code chosen by its category. Project errors are reported with
+// projectCode, or as not found when it is 0.
+func writeError(w http.ResponseWriter, projectCode int, err error) {
+	code := http.StatusInternalServerError
+	body := map[string]string{"error": err.Error()}
+	var ee *etcherr.Error
+	if errors.As(err, &ee) {
+		switch ee.Category {
+		case etcherr.CatUsage:
+			code = http.StatusBadRequest
+		case etcherr.CatProject:
+			code = http.StatusNotFound
+			if projectCode != 0 {
+				code = projectCode
+			}
+		}
+		if ee.Hint != "" {
+			body["hint"] = ee.Hint
+		}
+	}
+	writeJSON(w, code, body)
+}
@@ -0,0 +1,304 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsigler/etch/internal/claim"
+)
+
+const testPlan = `# Plan: Auth System
+
+## Overview
+
+Add authentication.
+
+## Feature 1: Tokens
+
+### Task 1.1: Token service [pending]
+**Complexity:** small
+
+Sign and verify tokens.
+
+**Acceptance Criteria:**
+- [ ] Tokens are signed
+
+### Task 1.2: Refresh endpoint [pending]
+**Depends on:** Task 1.1
+
+Refresh tokens.
+`
+
+const testSession = `# Session: Task 1.1 — Token service
+**Plan:** auth-system
+**Task:** 1.1
+**Session:** 001
+**Started:** 2026-01-01 10:00
+**Status:** in_progress
+
+## Changes Made
+- Added signer
+
+## Acceptance Criteria Updates
+- [ ] Tokens are signed
+
+## Decisions & Notes
+
+## Blockers
+
+## Next
+`
+
+func setup(t *testing.T) (string, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".etch/plans/auth-system.md":                   testPlan,
+		".etch/progress/auth-system--task-1.1--001.md": testSession,
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(New(dir).Handler())
+	t.Cleanup(srv.Close)
+	return dir, srv
+}
+
+// do sends a request and decodes the JSON response into out, returning the
+// status code.
+func do(t *testing.T, method, url, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, url, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestListPlans(t *testing.T) {
+	_, srv := setup(t)
+
+	var plans []struct {
+		Slug       string `json:"slug"`
+		TotalTasks int    `json:"total_tasks"`
+	}
+	if code := do(t, "GET", srv.URL+"/api/plans", "", &plans); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(plans) != 1 || plans[0].Slug != "auth-system" || plans[0].TotalTasks != 2 {
+		t.Errorf("plans = %+v", plans)
+	}
+}
+
+func TestGetPlan(t *testing.T) {
+	_, srv := setup(t)
+
+	var detail PlanDetail
+	if code := do(t, "GET", srv.URL+"/api/plans/auth-system", "", &detail); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if detail.Plan.Title != "Auth System" || detail.Plan.Overview != "Add authentication." {
+		t.Errorf("plan = %+v", detail.Plan)
+	}
+	tasks := detail.Status.Features[0].Tasks
+	if tasks[0].Status != "in_progress" {
+		t.Errorf("1.1 status = %s, want in_progress reconciled from its session", tasks[0].Status)
+	}
+	if !tasks[1].IsBlocked {
+		t.Error("1.2 should be blocked on 1.1")
+	}
+
+	var errBody map[string]string
+	if code := do(t, "GET", srv.URL+"/api/plans/nope", "", &errBody); code != http.StatusNotFound {
+		t.Errorf("unknown plan: status %d", code)
+	}
+	if !strings.Contains(errBody["error"], `"nope"`) || errBody["hint"] == "" {
+		t.Errorf("error body = %v", errBody)
+	}
+}
+
+func TestGetSessionsAndTask(t *testing.T) {
+	_, srv := setup(t)
+
+	var sessions map[string][]struct {
+		SessionNumber int      `json:"session_number"`
+		ChangesMade   []string `json:"changes_made"`
+	}
+	if code := do(t, "GET", srv.URL+"/api/plans/auth-system/sessions", "", &sessions); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if s := sessions["1.1"]; len(s) != 1 || s[0].ChangesMade[0] != "Added signer" {
+		t.Errorf("sessions = %+v", sessions)
+	}
+
+	var task TaskDetail
+	if code := do(t, "GET", srv.URL+"/api/plans/auth-system/tasks/1.1", "", &task); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if task.Task.Title != "Token service" || len(task.Sessions) != 1 || task.Status.ID != "1.1" {
+		t.Errorf("task = %+v", task)
+	}
+
+	if code := do(t, "GET", srv.URL+"/api/plans/auth-system/tasks/9.9", "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown task: status %d", code)
+	}
+}
+
+func TestAddComment(t *testing.T) {
+	dir, srv := setup(t)
+
+	var task TaskDetail
+	code := do(t, "POST", srv.URL+"/api/plans/auth-system/tasks/1.2/comments", `{"text":"Rotate refresh tokens too."}`, &task)
+	if code != http.StatusCreated {
+		t.Fatalf("status %d", code)
+	}
+	if len(task.Task.Comments) != 1 || task.Task.Comments[0] != "Rotate refresh tokens too." {
+		t.Errorf("comments = %v", task.Task.Comments)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "auth-system.md"))
+	if !strings.Contains(string(data), "> 💬 Rotate refresh tokens too.") {
+		t.Error("comment should be written to the plan file")
+	}
+
+	if code := do(t, "POST", srv.URL+"/api/plans/auth-system/tasks/1.2/comments", `{"text":"  "}`, nil); code != http.StatusBadRequest {
+		t.Errorf("empty comment: status %d", code)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	dir, srv := setup(t)
+
+	var task TaskDetail
+	if code := do(t, "PUT", srv.URL+"/api/plans/auth-system/tasks/1.1/status", `{"status":"completed"}`, &task); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if task.Task.Status != "completed" || task.Status.Status != "completed" {
+		t.Errorf("task = %+v", task)
+	}
+
+	// The change survives reconciling against the task's session.
+	session, _ := os.ReadFile(filepath.Join(dir, ".etch", "progress", "auth-system--task-1.1--001.md"))
+	if !strings.Contains(string(session), "**Status:** completed") {
+		t.Error("latest session status should be updated")
+	}
+	var detail PlanDetail
+	do(t, "GET", srv.URL+"/api/plans/auth-system", "", &detail)
+	if got := detail.Status.Features[0].Tasks[1].IsBlocked; got {
+		t.Error("1.2 should be unblocked once 1.1 is completed")
+	}
+
+	// Tasks without sessions are updated in the plan only.
+	if code := do(t, "PUT", srv.URL+"/api/plans/auth-system/tasks/1.2/status", `{"status":"blocked"}`, &task); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if task.Task.Status != "blocked" {
+		t.Errorf("1.2 status = %s", task.Task.Status)
+	}
+
+	if code := do(t, "PUT", srv.URL+"/api/plans/auth-system/tasks/1.1/status", `{"status":"done"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown status: status %d", code)
+	}
+}
+
+func TestSetStatus_FailingVerify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("verify commands run in a POSIX shell")
+	}
+	dir, srv := setup(t)
+	planPath := filepath.Join(dir, ".etch", "plans", "auth-system.md")
+	plan := strings.Replace(testPlan, "- [ ] Tokens are signed", "- [ ] Tokens are signed `verify: false`", 1)
+	os.WriteFile(planPath, []byte(plan), 0o644)
+
+	var body map[string]string
+	if code := do(t, "PUT", srv.URL+"/api/plans/auth-system/tasks/1.1/status", `{"status":"completed"}`, &body); code != http.StatusConflict {
+		t.Fatalf("status %d, want %d", code, http.StatusConflict)
+	}
+	if !strings.Contains(body["error"], "1 of 1 verifiable criteria failed") {
+		t.Errorf("error = %q", body["error"])
+	}
+	data, _ := os.ReadFile(planPath)
+	if !strings.Contains(string(data), "Token service [pending]") {
+		t.Errorf("task completed despite failing verify:\n%s", data)
+	}
+}
+
+func TestSetStatus_ClaimedTask(t *testing.T) {
+	dir, srv := setup(t)
+	if _, err := claim.Acquire(dir, "auth-system", "1.2", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]string
+	if code := do(t, "PUT", srv.URL+"/api/plans/auth-system/tasks/1.2/status", `{"status":"in_progress"}`, &body); code != http.StatusConflict {
+		t.Fatalf("status %d, want %d", code, http.StatusConflict)
+	}
+	if !strings.Contains(body["error"], "task auth-system#1.2 is claimed") {
+		t.Errorf("error = %q", body["error"])
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".etch", "plans", "auth-system.md"))
+	if !strings.Contains(string(data), "Refresh endpoint [pending]") {
+		t.Errorf("claimed task started:\n%s", data)
+	}
+}
+
+func TestWritesRequireJSON(t *testing.T) {
+	_, srv := setup(t)
+
+	resp, err := http.Post(srv.URL+"/api/plans/auth-system/tasks/1.1/comments", "application/x-www-form-urlencoded", strings.NewReader("text=hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("form post: status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	if code := do(t, "DELETE", srv.URL+"/api/plans/auth-system", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status %d", code)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	_, srv := setup(t)
+
+	for path, want := range map[string]string{
+		"/":          "<title>etch</title>",
+		"/app.js":    "/api",
+		"/style.css": ".task",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), want) {
+			t.Errorf("GET %s: status %d, body missing %q", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package worktree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	etcherr "github.com/gsigler/etch/internal/errors"
+)
+
+// Dir is where etch creates task worktrees, relative to the project root.
+const Dir = ".etch/worktrees"
+
+// BranchName returns the branch used for a task's worktree, e.g.
+// "etch/auth-system/task-1.2".
+func BranchName(planSlug, taskID string) string {
+	return fmt.Sprintf("etch/%s/task-%s", planSlug, taskID)
+}
+
+// Path returns the worktree directory for a task.
+func Path(rootDir, planSlug, taskID string) string {
+	return filepath.Join(rootDir, Dir, fmt.Sprintf("%s--task-%s", planSlug, taskID))
+}
+
+// Add creates a worktree at path on a new branch started from base (a
+// branch name, commit, or "HEAD").
+func Add(rootDir, path, branch, base string) error {
+	if _, err := os.Stat(path); err == nil {
+		return etcherr.Project(fmt.Sprintf("worktree already exists: %s", path)).
+			WithHint(fmt.Sprintf("remove it with 'git worktree remove %s' and 'git branch -D %s'", path, branch))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return etcherr.WrapIO("creating worktree directory", err)
+	}
+	_, err := git(rootDir, "worktree", "add", "-b", branch, path, base)
+	return err
+}
+
+// Ensure makes path a worktree on branch, reusing what earlier sessions left
+// behind: an existing worktree directory is used as is, and an existing
+// branch is checked out again. Otherwise it behaves like Add. It reports
+// whether the worktree was created.
+func Ensure(rootDir, path, branch, base string) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if BranchExists(rootDir, branch) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return false, etcherr.WrapIO("creating worktree directory", err)
+		}
+		_, err := git(rootDir, "worktree", "add", path, branch)
+		return err == nil, err
+	}
+	if err := Add(rootDir, path, branch, base); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BranchExists reports whether the repository at dir has a local branch
+// with the given name.
+func BranchExists(dir, branch string) bool {
+	_, err := git(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// Merge merges branch into the worktree at path. On conflict the merge is
+// aborted so the worktree is left clean.
+func Merge(path, branch string) error {
+	if _, err := git(path, "merge", "--no-edit", branch); err != nil {
+		git(path, "merge", "--abort")
+		return err
+	}
+	return nil
+}
+
+// CommitFiles stages the given files in the work tree at path, including
+// deletions and new files, and commits only them with message. Anything
+// else that is modified or staged is left alone, as are listed files with
+// nothing to commit. It reports whether a commit was made.
+func CommitFiles(path, message string, files []string) (bool, error) {
+	dirty, err := ChangedFiles(path, "HEAD")
+	if err != nil {
+		return false, err
+	}
+	pathspec := []string{"--"}
+	for _, f := range files {
+		if slices.Contains(dirty, f) {
+			pathspec = append(pathspec, f)
+		}
+	}
+	if len(pathspec) == 1 {
+		return false, nil
+	}
+	if _, err := git(path, append([]string{"add", "-A"}, pathspec...)...); err != nil {
+		return false, err
+	}
+	out, err := git(path, append([]string{"diff", "--cached", "--name-only"}, pathspec...)...)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(out) == "" {
+		return false, nil
+	}
+	if _, err := git(path, append([]string{"commit", "-q", "-m", message}, pathspec...)...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Snapshot records the uncommitted files in a work tree and their contents,
+// so that changes made afterwards can be told apart from edits that were
+// already there.
+type Snapshot map[string]string // path → content hash, "" if deleted
+
+// TakeSnapshot records the files in the work tree at dir that differ from
+// HEAD, as ChangedFiles lists them.
+func TakeSnapshot(dir string) (Snapshot, error) {
+	files, err := ChangedFiles(dir, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	s := make(Snapshot, len(files))
+	for _, f := range files {
+		s[f] = contentHash(dir, f)
+	}
+	return s, nil
+}
+
+// Touched reports whether file in the work tree at dir changed after the
+// snapshot was taken: it was clean then, or its content differs now.
+func (s Snapshot) Touched(dir, file string) bool {
+	hash, dirty := s[file]
+	return !dirty || hash != contentHash(dir, file)
+}
+
+func contentHash(dir, file string) string {
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Head returns the commit checked out in the work tree at dir.
+func Head(dir string) (string, error) {
+	out, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ChangedFiles lists the files in the work tree at dir that differ from
+// base: changes committed since base, uncommitted edits, and new untracked
+// files. Paths are relative to dir and sorted.
+func ChangedFiles(dir, base string) ([]string, error) {
+	diff, err := git(dir, "diff", "--name-only", "--relative", base)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(diff+untracked, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !seen[line] {
+			seen[line] = true
+			files = append(files, line)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// IsRepo reports whether dir is inside a git work tree.
+func IsRepo(dir string) bool {
+	out, err := git(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// git runs a git subcommand in dir and returns its stdout.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", etcherr.WrapIO(fmt.Sprintf("git %s failed", args[0]), err).
+			WithHint(msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,201 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository with one commit in a temp dir.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not on PATH")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return dir
+}
+
+// commitAll commits every change in the work tree at dir.
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", message}} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+}
+
+func TestBranchNameAndPath(t *testing.T) {
+	if got := BranchName("auth-system", "1.2"); got != "etch/auth-system/task-1.2" {
+		t.Errorf("BranchName = %q", got)
+	}
+	if got := Path("/root", "auth-system", "1.2"); got != filepath.Join("/root", ".etch", "worktrees", "auth-system--task-1.2") {
+		t.Errorf("Path = %q", got)
+	}
+}
+
+func TestAddCommitAndMerge(t *testing.T) {
+	root := initRepo(t)
+	if !IsRepo(root) {
+		t.Fatal("expected temp dir to be a git repo")
+	}
+
+	a := Path(root, "p", "1.1")
+	if err := Add(root, a, BranchName("p", "1.1"), "HEAD"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	os.WriteFile(filepath.Join(a, "a.txt"), []byte("a\n"), 0o644)
+	committed, err := CommitFiles(a, "task 1.1", []string{"a.txt"})
+	if err != nil || !committed {
+		t.Fatalf("CommitFiles = %v, %v", committed, err)
+	}
+
+	// A clean worktree commits nothing.
+	if committed, err := CommitFiles(a, "again", []string{"a.txt"}); err != nil || committed {
+		t.Errorf("CommitFiles on clean tree = %v, %v", committed, err)
+	}
+
+	// A dependent worktree started from 1.1's branch sees its work, and can
+	// merge in another branch.
+	b := Path(root, "p", "1.2")
+	if err := Add(root, b, BranchName("p", "1.2"), BranchName("p", "1.1")); err != nil {
+		t.Fatalf("Add from branch: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b, "a.txt")); err != nil {
+		t.Errorf("expected a.txt in dependent worktree: %v", err)
+	}
+
+	c := Path(root, "p", "1.3")
+	Add(root, c, BranchName("p", "1.3"), "HEAD")
+	os.WriteFile(filepath.Join(c, "c.txt"), []byte("c\n"), 0o644)
+	commitAll(t, c, "task 1.3")
+	if err := Merge(b, BranchName("p", "1.3")); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b, "c.txt")); err != nil {
+		t.Errorf("expected merged c.txt: %v", err)
+	}
+}
+
+func TestAddExistingPath(t *testing.T) {
+	root := initRepo(t)
+	path := Path(root, "p", "1.1")
+	Add(root, path, BranchName("p", "1.1"), "HEAD")
+
+	err := Add(root, path, BranchName("p", "1.1"), "HEAD")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already-exists error, got %v", err)
+	}
+}
+
+func TestIsRepo_NotARepo(t *testing.T) {
+	if IsRepo(t.TempDir()) {
+		t.Error("expected plain temp dir not to be a git repo")
+	}
+}
+
+func TestEnsureReusesWorktreeAndBranch(t *testing.T) {
+	root := initRepo(t)
+	path := Path(root, "p", "1.1")
+	branch := BranchName("p", "1.1")
+
+	created, err := Ensure(root, path, branch, "HEAD")
+	if err != nil || !created {
+		t.Fatalf("first Ensure = %v, %v", created, err)
+	}
+	os.WriteFile(filepath.Join(path, "a.txt"), []byte("a\n"), 0o644)
+	commitAll(t, path, "work")
+
+	// A second session reuses the existing worktree.
+	created, err = Ensure(root, path, branch, "HEAD")
+	if err != nil || created {
+		t.Fatalf("second Ensure = %v, %v", created, err)
+	}
+
+	// With the worktree removed, the branch's work is checked out again.
+	if _, err := git(root, "worktree", "remove", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Ensure(root, path, branch, "HEAD"); err != nil {
+		t.Fatalf("Ensure after removal: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "a.txt")); err != nil {
+		t.Error("expected branch work to be restored in the worktree")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	root := initRepo(t)
+	os.WriteFile(filepath.Join(root, "tracked.txt"), []byte("a\n"), 0o644)
+	commitAll(t, root, "add tracked")
+	base, err := Head(root)
+	if err != nil || base == "" {
+		t.Fatalf("Head = %q, %v", base, err)
+	}
+
+	// One committed change, one uncommitted edit, one new file.
+	os.WriteFile(filepath.Join(root, "committed.txt"), []byte("c\n"), 0o644)
+	commitAll(t, root, "commit")
+	os.WriteFile(filepath.Join(root, "tracked.txt"), []byte("b\n"), 0o644)
+	os.MkdirAll(filepath.Join(root, "pkg"), 0o755)
+	os.WriteFile(filepath.Join(root, "pkg", "new.go"), []byte("package pkg\n"), 0o644)
+
+	files, err := ChangedFiles(root, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, ","); got != "committed.txt,pkg/new.go,tracked.txt" {
+		t.Errorf("ChangedFiles = %s", got)
+	}
+}
+
+func TestSnapshotAndCommitFiles(t *testing.T) {
+	root := initRepo(t)
+	os.WriteFile(filepath.Join(root, "edited.txt"), []byte("a\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "kept.txt"), []byte("a\n"), 0o644)
+	commitAll(t, root, "initial")
+
+	// Edits from before the snapshot, one of which is edited again after.
+	os.WriteFile(filepath.Join(root, "edited.txt"), []byte("b\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "kept.txt"), []byte("b\n"), 0o644)
+	snap, err := TakeSnapshot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(root, "edited.txt"), []byte("c\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "new.txt"), []byte("n\n"), 0o644)
+
+	var touched []string
+	for _, f := range []string{"edited.txt", "kept.txt", "new.txt"} {
+		if snap.Touched(root, f) {
+			touched = append(touched, f)
+		}
+	}
+	if got := strings.Join(touched, ","); got != "edited.txt,new.txt" {
+		t.Errorf("Touched = %s", got)
+	}
+
+	committed, err := CommitFiles(root, "session", touched)
+	if err != nil || !committed {
+		t.Fatalf("CommitFiles = %v, %v", committed, err)
+	}
+	if files, _ := ChangedFiles(root, "HEAD"); strings.Join(files, ",") != "kept.txt" {
+		t.Errorf("expected only kept.txt left uncommitted, got %v", files)
+	}
+	if committed, err := CommitFiles(root, "again", touched); err != nil || committed {
+		t.Errorf("CommitFiles with nothing to commit = %v, %v", committed, err)
+	}
+}